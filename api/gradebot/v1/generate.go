@@ -0,0 +1,5 @@
+// Package gradebotv1 is the gRPC GradeService's, generated from
+// gradebot.proto by buf, with protoc-gen-go and protoc-gen-go-grpc.
+package gradebotv1
+
+//go:generate sh -c "cd ../.. && buf generate"
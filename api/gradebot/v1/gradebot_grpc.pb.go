@@ -0,0 +1,239 @@
+// The grading service's API: gradebot serve --grpc-addr serves it as gRPC,
+// from the stubs generated from this file (go generate ./api/...), and its
+// /v1 endpoints over HTTP with the proto3 JSON mapping. An autograding
+// cluster submits to its workers, and streams each check's result back as
+// it's graded.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: gradebot/v1/gradebot.proto
+
+package gradebotv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	GradeService_Submit_FullMethodName     = "/gradebot.v1.GradeService/Submit"
+	GradeService_GetResult_FullMethodName  = "/gradebot.v1.GradeService/GetResult"
+	GradeService_StreamLogs_FullMethodName = "/gradebot.v1.GradeService/StreamLogs"
+)
+
+// GradeServiceClient is the client API for GradeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GradeServiceClient interface {
+	// Submit queues a zipped submission for grading, with the student's token
+	// as "authorization: Bearer TOKEN" metadata. It fails with UNAUTHENTICATED
+	// without it, RESOURCE_EXHAUSTED when the student submitted too recently,
+	// and UNAVAILABLE when the queue is full.
+	// POST /v1/submissions?student=STUDENT, with the zip as the body and the
+	// token as an Authorization header.
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error)
+	// GetResult is a submission's state, and its report once graded.
+	// GET /v1/submissions/ID
+	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*GetResultResponse, error)
+	// StreamLogs streams a submission's check results as they're graded, then
+	// its report.
+	// GET /v1/submissions/ID/events, a JSON event per line.
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (GradeService_StreamLogsClient, error)
+}
+
+type gradeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGradeServiceClient(cc grpc.ClientConnInterface) GradeServiceClient {
+	return &gradeServiceClient{cc}
+}
+
+func (c *gradeServiceClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	out := new(SubmitResponse)
+	err := c.cc.Invoke(ctx, GradeService_Submit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gradeServiceClient) GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*GetResultResponse, error) {
+	out := new(GetResultResponse)
+	err := c.cc.Invoke(ctx, GradeService_GetResult_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gradeServiceClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (GradeService_StreamLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GradeService_ServiceDesc.Streams[0], GradeService_StreamLogs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gradeServiceStreamLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GradeService_StreamLogsClient interface {
+	Recv() (*CheckEvent, error)
+	grpc.ClientStream
+}
+
+type gradeServiceStreamLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *gradeServiceStreamLogsClient) Recv() (*CheckEvent, error) {
+	m := new(CheckEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GradeServiceServer is the server API for GradeService service.
+// All implementations must embed UnimplementedGradeServiceServer
+// for forward compatibility
+type GradeServiceServer interface {
+	// Submit queues a zipped submission for grading, with the student's token
+	// as "authorization: Bearer TOKEN" metadata. It fails with UNAUTHENTICATED
+	// without it, RESOURCE_EXHAUSTED when the student submitted too recently,
+	// and UNAVAILABLE when the queue is full.
+	// POST /v1/submissions?student=STUDENT, with the zip as the body and the
+	// token as an Authorization header.
+	Submit(context.Context, *SubmitRequest) (*SubmitResponse, error)
+	// GetResult is a submission's state, and its report once graded.
+	// GET /v1/submissions/ID
+	GetResult(context.Context, *GetResultRequest) (*GetResultResponse, error)
+	// StreamLogs streams a submission's check results as they're graded, then
+	// its report.
+	// GET /v1/submissions/ID/events, a JSON event per line.
+	StreamLogs(*StreamLogsRequest, GradeService_StreamLogsServer) error
+	mustEmbedUnimplementedGradeServiceServer()
+}
+
+// UnimplementedGradeServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedGradeServiceServer struct {
+}
+
+func (UnimplementedGradeServiceServer) Submit(context.Context, *SubmitRequest) (*SubmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedGradeServiceServer) GetResult(context.Context, *GetResultRequest) (*GetResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
+}
+func (UnimplementedGradeServiceServer) StreamLogs(*StreamLogsRequest, GradeService_StreamLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedGradeServiceServer) mustEmbedUnimplementedGradeServiceServer() {}
+
+// UnsafeGradeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GradeServiceServer will
+// result in compilation errors.
+type UnsafeGradeServiceServer interface {
+	mustEmbedUnimplementedGradeServiceServer()
+}
+
+func RegisterGradeServiceServer(s grpc.ServiceRegistrar, srv GradeServiceServer) {
+	s.RegisterService(&GradeService_ServiceDesc, srv)
+}
+
+func _GradeService_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GradeServiceServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GradeService_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GradeServiceServer).Submit(ctx, req.(*SubmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GradeService_GetResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GradeServiceServer).GetResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GradeService_GetResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GradeServiceServer).GetResult(ctx, req.(*GetResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GradeService_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GradeServiceServer).StreamLogs(m, &gradeServiceStreamLogsServer{stream})
+}
+
+type GradeService_StreamLogsServer interface {
+	Send(*CheckEvent) error
+	grpc.ServerStream
+}
+
+type gradeServiceStreamLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *gradeServiceStreamLogsServer) Send(m *CheckEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// GradeService_ServiceDesc is the grpc.ServiceDesc for GradeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GradeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gradebot.v1.GradeService",
+	HandlerType: (*GradeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Submit",
+			Handler:    _GradeService_Submit_Handler,
+		},
+		{
+			MethodName: "GetResult",
+			Handler:    _GradeService_GetResult_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _GradeService_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gradebot/v1/gradebot.proto",
+}
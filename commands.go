@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/jh125486/CSCE4600_gradebot/pkg/grader"
+)
+
+type gradeCmd struct {
+	options
+	canvasOptions
+	sheetsOptions
+	feedbackOptions
+	PathToDirs []string `name:"dir" default:"." help:"Path to scheduler directory, or a .zip/.tar.gz archive of one (repeatable)" type:"path" required:"true"`
+	Roster     string   `type:"existingdir" help:"Grade every subdirectory (and .zip/.tar.gz archive) of this directory as a submission (instead of --dir)"`
+	Repo       []string `placeholder:"URL" help:"Clone and grade this Git repository (repeatable, instead of --dir)"`
+	Repos      string   `type:"existingfile" placeholder:"FILE" help:"Clone and grade each Git repository listed in FILE, one URL per line"`
+	Ref        string   `help:"Check out this branch, tag or commit of each --repo before grading"`
+	Before     string   `placeholder:"DEADLINE" help:"Grade each --repo at its last commit before DEADLINE (by committer date), e.g. 2024-02-01T23:59 (local time) or RFC 3339"`
+	checkSelection
+
+	Gradescope bool `help:"Run as a Gradescope autograder: grade /autograder/submission (unless --dir is given) and write /autograder/results/results.json"`
+	List       bool `help:"Print the rubric that would be graded, with point values, and exit"`
+	TUI        bool `name:"tui" xor:"interactive" help:"Grade a single submission interactively: live progress, failures expandable to their diffs, and re-running a check with r"`
+
+	Watch         bool          `xor:"interactive" help:"Grade a single submission, then again each time its Go sources, go.mod or go.sum change, until interrupted"`
+	WatchInterval time.Duration `default:"1s" help:"How long --watch waits for changes to settle before re-grading"`
+
+	batchOptions
+}
+
+func (cmd gradeCmd) Run(ctx context.Context, kctx *kong.Context) error {
+	// the project is the subcommand's, e.g. project2; grade is project 1's.
+	return grader.Run(ctx, strings.Fields(kctx.Command())[0], cmd.config())
+}
+
+// config is the grader's config of the command's flags.
+func (cmd gradeCmd) config() grader.GradeConfig {
+	return grader.GradeConfig{
+		Config:         grader.Config(cmd.options),
+		CanvasConfig:   grader.CanvasConfig(cmd.canvasOptions),
+		SheetsConfig:   grader.SheetsConfig(cmd.sheetsOptions),
+		FeedbackConfig: grader.FeedbackConfig(cmd.feedbackOptions),
+		PathToDirs:     cmd.PathToDirs,
+		Roster:         cmd.Roster,
+		Repo:           cmd.Repo,
+		Repos:          cmd.Repos,
+		Ref:            cmd.Ref,
+		Before:         cmd.Before,
+		Selection:      grader.Selection(cmd.checkSelection),
+		Gradescope:     cmd.Gradescope,
+		List:           cmd.List,
+		TUI:            cmd.TUI,
+		Watch:          cmd.Watch,
+		WatchInterval:  cmd.WatchInterval,
+		BatchConfig:    grader.BatchConfig(cmd.batchOptions),
+	}
+}
+
+// batchCmd grades a roster: each immediate subdirectory (or archive) of Root
+// is a submission, graded in turn as the grade command's --roster does, with
+// its results in the gradebook.
+type batchCmd struct {
+	options
+	canvasOptions
+	sheetsOptions
+	feedbackOptions
+	Root    string `required:"" type:"existingdir" placeholder:"DIR" help:"Directory of the submissions, one per subdirectory or .zip/.tar.gz archive, named by student id"`
+	Project string `enum:"project1,project2" default:"project1" help:"Project the submissions are of: project1 or project2"`
+	checkSelection
+	batchOptions
+}
+
+func (cmd batchCmd) Run(ctx context.Context) error {
+	if cmd.Gradebook == "" {
+		return errors.New("--gradebook is required, e.g. grades.csv or grades.json")
+	}
+
+	return grader.Run(ctx, cmd.Project, gradeCmd{
+		options:         cmd.options,
+		canvasOptions:   cmd.canvasOptions,
+		sheetsOptions:   cmd.sheetsOptions,
+		feedbackOptions: cmd.feedbackOptions,
+		Roster:          cmd.Root,
+		checkSelection:  cmd.checkSelection,
+		batchOptions:    cmd.batchOptions,
+	}.config())
+}
+
+type validateConfigCmd struct {
+	Path string `arg:"" type:"existingfile" help:"Rubric config file (YAML or JSON)."`
+}
+
+func (cmd validateConfigCmd) Run() error {
+	return grader.ValidateRubric(cmd.Path)
+}
+
+type verifyCmd struct {
+	Token  string `arg:"" help:"Receipt or attestation printed by gradebot"`
+	Dir    string `type:"existingdir" help:"Also check that the receipt was for the sources in this directory"`
+	Binary string `type:"existingfile" help:"Check an attestation's binary digest against this gradebot build, e.g. the release's for the student's platform (by default this gradebot)"`
+	Key    string `type:"existingfile" placeholder:"FILE" help:"Public key of the receipt key it was signed with (NAME.pub of keygen --signing), by default the built-in one"`
+}
+
+func (cmd verifyCmd) Run() error {
+	return grader.Verify(grader.VerifyConfig(cmd))
+}
+
+type submitCmd struct {
+	Dir        string `arg:"" optional:"" type:"existingdir" default:"." help:"Submission to grade and package"`
+	Project    string `enum:"project1,project2" default:"project1" help:"Project the submission is of: project1 or project2"`
+	Key        string `type:"existingfile" placeholder:"FILE" help:"Instructor's public key file (see keygen), by default the built-in one"`
+	Output     string `short:"o" type:"path" placeholder:"FILE" help:"Sealed submission to write (default: the directory's name, with .gbsub)"`
+	ReceiptKey string `type:"existingfile" env:"GRADEBOT_RECEIPT_KEY" placeholder:"FILE" help:"Also sign a receipt of the score with this receipt key (see keygen --signing), where grading is trusted, e.g. a proctored lab"`
+}
+
+func (cmd submitCmd) Run(ctx context.Context) error {
+	return grader.Submit(ctx, grader.SubmitConfig(cmd))
+}
+
+type keygenCmd struct {
+	Name    string `arg:"" optional:"" default:"instructor" help:"Writes NAME.key (private: keep it) and NAME.pub (for students' submit --key, or with --signing, verify --key)"`
+	Signing bool   `help:"Generate a receipt key, signing receipts and attestations (--receipt-key), rather than one sealing submissions"`
+}
+
+func (cmd keygenCmd) Run() error {
+	return grader.Keygen(grader.KeygenConfig(cmd))
+}
+
+type unpackCmd struct {
+	Submission       string `arg:"" type:"existingfile" help:"Sealed submission (.gbsub) written by submit"`
+	Key              string `required:"" type:"existingfile" placeholder:"FILE" help:"Instructor's private key file (see keygen)"`
+	Out              string `type:"path" placeholder:"DIR" help:"Directory to extract to (default: the submission's name, less .gbsub)"`
+	ReceiptPublicKey string `type:"existingfile" placeholder:"FILE" help:"Public key to verify a signed receipt against the sources with (see verify --key), by default the built-in one"`
+}
+
+func (cmd unpackCmd) Run() error {
+	return grader.Unpack(grader.UnpackConfig(cmd))
+}
+
+type attestCmd struct {
+	ReceiptKey string `required:"" type:"existingfile" env:"GRADEBOT_RECEIPT_KEY" placeholder:"FILE" help:"Receipt key to sign the attestation with (see keygen --signing)"`
+}
+
+func (cmd attestCmd) Run() error {
+	return grader.Attest(grader.AttestConfig(cmd))
+}
+
+type serveCmd struct {
+	options
+	Addr         string        `default:":8080" help:"Address to listen on"`
+	Workers      int           `default:"1" help:"Submissions graded at once"`
+	QueueSize    int           `name:"queue" default:"16" help:"Submissions that may wait to be graded; more are turned away (503)"`
+	RateLimit    time.Duration `default:"5m" help:"Minimum time between one student's submissions (0 for no limit)"`
+	RunLog       string        `type:"path" placeholder:"FILE" help:"Append a JSON line per graded submission to FILE"`
+	ResultTTL    time.Duration `name:"result-ttl" default:"1h" help:"How long the /v1 API keeps a graded submission's result"`
+	GRPCAddr     string        `name:"grpc-addr" placeholder:"ADDR" help:"Also serve the /v1 API as gRPC GradeService on ADDR, e.g. :9090"`
+	Tokens       string        `required:"" type:"existingfile" placeholder:"FILE" help:"Students' tokens, a \"student token\" line each: a submission is only taken with its student's, as \"Authorization: Bearer TOKEN\""`
+	UnsafeNative bool          `help:"Grade submissions natively, as gradebot's user, rather than requiring --sandbox=docker"`
+}
+
+func (cmd serveCmd) Run(ctx context.Context) error {
+	return grader.Serve(ctx, grader.ServeConfig{
+		Config:       grader.Config(cmd.options),
+		Addr:         cmd.Addr,
+		Workers:      cmd.Workers,
+		QueueSize:    cmd.QueueSize,
+		RateLimit:    cmd.RateLimit,
+		RunLog:       cmd.RunLog,
+		ResultTTL:    cmd.ResultTTL,
+		GRPCAddr:     cmd.GRPCAddr,
+		Tokens:       cmd.Tokens,
+		UnsafeNative: cmd.UnsafeNative,
+	})
+}
+
+type goldenCmd struct {
+	Reference string        `arg:"" type:"existingfile|existingdir" help:"Reference scheduler: an executable, or a Go module directory to build"`
+	Inputs    string        `type:"existingdir" placeholder:"DIR" help:"Directory of input CSVs replacing the embedded ones by name (fcfs.csv, sjf.csv, sjfp.csv, rr.csv)"`
+	Out       string        `type:"path" default:"pkg/grader/testdata" placeholder:"DIR" help:"Directory to write the golden .out files to (by default the embedded ones, from the repository root)"`
+	Runs      int           `default:"3" help:"Run each case this many times, requiring identical output"`
+	Timeout   time.Duration `default:"10s" help:"Maximum run time of each reference run"`
+}
+
+func (cmd goldenCmd) Run(ctx context.Context) error {
+	return grader.Golden(ctx, grader.GoldenConfig(cmd))
+}
+
+type historyCmd struct {
+	File string  `arg:"" type:"existingfile" help:"Database of the grades recorded with --record"`
+	Dir  string  `type:"path" help:"Only show this submission directory's attempts"`
+	Jump float64 `default:"30" placeholder:"PCT" help:"Flag attempts scoring at least PCT percentage points more than the one before, for review (0 to disable)"`
+}
+
+func (cmd historyCmd) Run() error {
+	return grader.History(grader.HistoryConfig(cmd))
+}
+
+type leaderboardCmd struct {
+	Source string `arg:"" placeholder:"URL|FILE" help:"Leaderboard file written with --leaderboard, or an endpoint URL to get, answering with a JSON array of entries or a JSON line each"`
+	Top    int    `default:"20" placeholder:"N" help:"Show the top N handles (0 for all)"`
+}
+
+func (cmd leaderboardCmd) Run(ctx context.Context) error {
+	return grader.Leaderboard(ctx, grader.LeaderboardConfig(cmd))
+}
+
+type analyzeCmd struct {
+	Results  []string `arg:"" type:"existingfile" help:"Batch results written with --format=json (e.g. with -o results.json)"`
+	Messages int      `default:"10" placeholder:"N" help:"Show the N most common failure messages"`
+}
+
+func (cmd analyzeCmd) Run() error {
+	return grader.Analyze(grader.AnalyzeConfig(cmd))
+}
+
+// updateCmd replaces the running gradebot with the latest release's, after
+// verifying its archive against the release's checksums.
+type updateCmd struct {
+	Check bool `help:"Only report whether a newer release is out"`
+	Force bool `help:"Update even when this gradebot is the latest release (or a dev build)"`
+}
+
+func (cmd updateCmd) Run(ctx context.Context) error {
+	return grader.Update(ctx, grader.UpdateConfig(cmd))
+}
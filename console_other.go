@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// ownsConsole reports whether the process opened its own console window.
+// Outside Windows, a terminal always belongs to a shell (or a launcher that
+// keeps it open).
+func ownsConsole() bool { return false }
@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var getConsoleProcessList = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetConsoleProcessList")
+
+// ownsConsole reports whether the process opened its own console window, as
+// when double-clicked in Explorer: the console then has no other process
+// attached, such as the shell it was run from.
+func ownsConsole() bool {
+	pids := make([]uint32, 2)
+	n, _, _ := getConsoleProcessList.Call(uintptr(unsafe.Pointer(&pids[0])), uintptr(len(pids)))
+
+	return n == 1
+}
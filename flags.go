@@ -0,0 +1,139 @@
+package main
+
+import "time"
+
+// The flag groups the commands share. Each converts to the grader config of
+// the same fields (e.g. options to grader.Config), which the conversion
+// checks at compile time: a flag is added to both, in the same place.
+
+type options struct {
+	Debug     bool   `help:"Debug output."`
+	DiffLines int    `default:"40" placeholder:"N" help:"With --debug or --verbose, show about N lines of each mismatch diff, in whole hunks (0 for all)"`
+	Total     bool   `help:"Print total only (same as --format=total)"`
+	Summary   bool   `xor:"detail" help:"Print one line per check, PASS, FAIL or SKIP and its points, and log only warnings"`
+	Verbose   bool   `xor:"detail" help:"Also print each check's mismatch diffs (which show the expected output), scheduler stderr and run timings"`
+	Format    string `enum:"table,markdown,json,tap,github,total" default:"table" help:"Results format: table, markdown, json, tap, github (GitHub Actions annotations and GitHub Classroom points), or total"`
+	LogFormat string `enum:"text,json" default:"text" help:"Log format: text, or json (a record per line, with each check's logs labeled by check)"`
+	LogFile   string `type:"path" placeholder:"FILE" help:"Append logs to FILE instead of writing them to stderr"`
+
+	MinGoVersion string  `name:"min-go-version" default:"1.21" help:"Minimum Go toolchain version required to grade (empty to disable)"`
+	GoToolchain  string  `name:"go-toolchain" placeholder:"VERSION" help:"Build with this Go toolchain, e.g. 1.21.5, as the course pins it: set as GOTOOLCHAIN, so the go command downloads it if need be (not in the docker sandbox, which uses its image's)"`
+	ShowEnv      bool    `name:"show-env" help:"Print the grading environment and exit"`
+	NormalizeTo  int     `name:"normalize-to" placeholder:"N" help:"Scale the awarded total to N points (e.g. 100), rounded to two decimals"`
+	MinScore     float64 `name:"min-score" placeholder:"N" help:"Exit with status 2 when a total (normalized, if --normalize-to is set) is below N"`
+	FailOnError  bool    `help:"Exit with status 2 when any check reports an error, such as a failed build or mismatched output"`
+
+	Points map[string]int `mapsep:"," placeholder:"ID=N,..." help:"Override checks' possible points by identifier (see --only), e.g. fcfs=25,rr=15, over the rubric config's and answer key's"`
+
+	Timeout           time.Duration `default:"10s" help:"Maximum run time of each scheduler invocation (0 for no limit)"`
+	Strict            bool          `help:"Compare scheduler output byte for byte (no line ending/trailing whitespace normalization, no --epsilon)"`
+	Metrics           bool          `xor:"compare" help:"Grade scheduler output per metric (Gantt schedule, wait, turnaround and exit columns, each statistic), with credit for each correct one"`
+	Structured        bool          `xor:"compare" help:"Compare scheduler output as records of fields (split on spaces, commas and |), ignoring column spacing and table borders"`
+	Normalize         []string      `default:"ansi,eol,trailing,newline" enum:"ansi,eol,trailing,newline,none" help:"Normalizations before comparing scheduler output: ansi (strip color codes), eol (CRLF to LF), trailing (whitespace at line ends), newline (exactly one final newline), or none; off with --strict"`
+	Epsilon           float64       `default:"0.01" help:"Allow numbers in scheduler output to differ from the expected output by up to this much (0 to compare exactly)"`
+	SkipPreamble      bool          `help:"Ignore output printed before the expected output's first line, such as a banner or prompt (reported in the results)"`
+	Partial           bool          `default:"true" negatable:"" help:"Award partial credit by the fraction of matching output lines (--no-partial for all-or-nothing)"`
+	Hints             bool          `default:"true" negatable:"" help:"Hint at recognized mistakes in mismatched scheduler output, e.g. ignoring arrival times, or else with the rubric config's hint (--no-hints to leave them out)"`
+	Rubric            string        `type:"existingfile" help:"Rubric config (YAML or JSON) overriding point values and tolerances, see validate-config"`
+	MaxOutput         uint64        `default:"16" placeholder:"MiB" help:"Stop each scheduler run once it prints more than this many MiB, failing it with \"output exceeded limit\" (0 for no limit)"`
+	MemLimit          uint64        `placeholder:"MiB" help:"Limit each scheduler run's memory to this many MiB: its address space on Linux, Go programs reserving about 1 GiB at startup, or its committed memory on Windows"`
+	CPULimit          time.Duration `help:"Limit each scheduler run's CPU time, rounded up to whole seconds (Linux and Windows)"`
+	Sandbox           string        `enum:"none,docker" default:"none" help:"Build and run the scheduler natively, or in a locked-down Docker container (no network, read-only, limited)"`
+	SandboxImage      string        `default:"golang:1.21" placeholder:"IMAGE" help:"Docker image for --sandbox=docker"`
+	ProcLimit         uint64        `placeholder:"N" help:"Limit the processes and threads of gradebot's user while a scheduler runs, against fork bombs: on Linux, of gradebot's user, whose existing ones count too; on Windows, the scheduler's processes"`
+	StyleTools        []string      `default:"gofmt,vet,staticcheck" enum:"gofmt,vet,staticcheck" help:"Tools of the --style check, each worth an equal share of its points: gofmt, vet, and staticcheck (when installed)"`
+	Parallel          int           `placeholder:"N" help:"Run at most this many of the independent checks (scheduler runs, style) at once (default: all of them)"`
+	Random            int           `placeholder:"N" help:"Also grade N randomized process tables per algorithm, against a built-in reference scheduler"`
+	Repeat            int           `placeholder:"N" help:"Also check that N runs of each embedded input print the same output"`
+	Stress            int           `placeholder:"N" help:"Also run each algorithm on N generated processes (e.g. 50000), within --stress-budget"`
+	StressBudget      time.Duration `default:"5s" help:"Wall-clock budget of each --stress run"`
+	Robustness        bool          `help:"Also check that malformed inputs (empty, missing columns, non-numeric, garbage) are rejected gracefully"`
+	Fuzz              time.Duration `placeholder:"DURATION" help:"Also fuzz the scheduler with random mutations of the test inputs for DURATION (e.g. 10s), awarding points if none crashes or hangs it"`
+	Race              bool          `help:"Also rebuild the scheduler with -race and replay the embedded inputs, deducting points for each run with a data race"`
+	Style             bool          `help:"Also check the code style with --style-tools, awarding points when each tool is clean"`
+	Hygiene           bool          `help:"Also check for committed build artifacts, a missing .gitignore, and vendored or junk directories"`
+	History           bool          `help:"Also check a git checkout's history: at least --min-commits commits, on at least --min-commit-days days, with mostly descriptive messages"`
+	MinCommits        int           `default:"5" placeholder:"N" help:"Commits --history wants"`
+	CommitDays        int           `name:"min-commit-days" default:"2" placeholder:"N" help:"Distinct days (by author date) --history wants commits on"`
+	Forbidden         bool          `help:"Also reject source that imports, calls or names anything on a deny list: by default os/exec, net/..., plugin, os.StartProcess, syscall.Exec and ForkExec, and the expected output files (replace it under forbidden: in the --rubric config)"`
+	StudentTests      bool          `help:"Also run the submission's own tests with go test ./..., awarding points when they pass"`
+	TestTimeout       time.Duration `default:"2m" help:"Maximum run time of --student-tests and --coverage (0 for no limit)"`
+	Coverage          float64       `placeholder:"PCT" help:"Also grade the statement coverage of the submission's own tests, with full credit at PCT% (e.g. 70) and proportionally less below"`
+	Seed              int64         `help:"Random seed for --sample, --random and --fuzz, to reproduce a run (0 picks and reports one)"`
+	Deadline          string        `placeholder:"DEADLINE" help:"Deduct --late-penalty for each day (or part) a submission's last commit, or newest file outside git, is after DEADLINE, e.g. 2024-02-01T23:59 (local time) or RFC 3339"`
+	LatePenalty       float64       `default:"10" placeholder:"PCT" help:"Percent of the awarded points deducted per day late, with --deadline"`
+	MaxLatePenalty    float64       `default:"100" placeholder:"PCT" help:"Most percent of the awarded points deducted for lateness, with --deadline"`
+	Retries           int           `help:"Re-run a failing scheduler up to this many times and keep the best result, for nondeterministic output"`
+	BuildCmd          string        `placeholder:"CMD" help:"Build a non-Go scheduler with this command, run in its directory (requires --run-cmd)"`
+	MainPkg           string        `placeholder:"DIR" help:"Main package to build, relative to each submission (default: the root, or the module's only main package)"`
+	Lang              string        `enum:"auto,go,c,python" default:"auto" help:"Submission language (auto detects each one's from its files; the Go-only module and style checks are then awarded to C and Python)"`
+	RunCmd            string        `placeholder:"CMD" help:"Run a non-Go scheduler with this command, e.g. \"python3 scheduler.py\""`
+	Output            string        `short:"o" type:"path" placeholder:"FILE" help:"Also write the results to FILE, in the format its extension implies: .txt (table), .md (markdown), .json or .tap, else --format's"`
+	JUnit             string        `name:"junit" type:"path" placeholder:"FILE" help:"Also write a JUnit XML report to FILE, a test case per rubric item, for CI"`
+	Report            string        `type:"path" placeholder:"FILE" help:"Also write a standalone HTML report to FILE as grading evidence: each rubric table, and each check's stderr, timing and output diffs (which show the expected output); a FILE ending in .pdf is printed with Chrome, Chromium or wkhtmltopdf"`
+	Record            string        `type:"path" placeholder:"FILE" help:"Record each grade (time, sources hash, each check's points and the total) in FILE, a SQLite database, for gradebot history"`
+	NotifyURL         string        `name:"notify-url" placeholder:"URL" help:"POST a summary of each grade (directory, total and failed checks) to this webhook as it finishes, e.g. a Discord or Slack channel's"`
+	NotifyFormat      string        `enum:"auto,json,discord,slack" default:"auto" help:"--notify-url payload: json, discord, slack, or auto to pick by the URL's host"`
+	Leaderboard       string        `placeholder:"URL|FILE" help:"Opt in to the leaderboard: post each grade's anonymized handle, total and --stress runtime to URL, or append them to FILE (see gradebot leaderboard)"`
+	Handle            string        `placeholder:"NAME" help:"Handle on the --leaderboard (default: a random one per submission, kept in --handles-file)"`
+	HandlesFile       string        `type:"path" placeholder:"FILE" help:"File keeping each submission's random --leaderboard handle, by its path (default: gradebot's, in the user config directory)"`
+	Badge             string        `type:"path" placeholder:"FILE" help:"Also write an SVG badge of the total score to FILE, shields.io-style and colored by percentage, for a README"`
+	NoCache           bool          `help:"Always rebuild the scheduler instead of reusing a cached build of unchanged sources"`
+	RerunFailed       bool          `help:"Reuse the last --rerun-failed grade's passing results of unchanged submissions, re-running only the checks that failed"`
+	ReadmeWords       int           `name:"readme-min-words" default:"100" placeholder:"N" help:"Words of prose README.md needs for its share of the README points, besides name/EUID, build and algorithm sections and a code block"`
+	Module            bool          `help:"Also check the submission's go.mod: present, parsable, and free of third-party requirements (see --allow-deps)"`
+	ModulePrefix      string        `placeholder:"PATH" help:"Require the submission's go.mod module path to start with this prefix (implies --module)"`
+	AllowDeps         bool          `help:"Allow the --module check's go.mod to require third-party modules (by default only the standard library is)"`
+	Testdata          string        `type:"existingdir" placeholder:"DIR" help:"Directory of files overriding the embedded testdata by name, e.g. fcfs.csv, rr_q2.out, sjf.meta.json; others keep the embedded copies"`
+	Cases             string        `type:"existingdir" xor:"cases" help:"Directory of NAME.csv/NAME.out scheduler cases replacing the embedded testdata, e.g. fcfs.csv, rr_q2.csv"`
+	TestsURL          string        `name:"tests-url" xor:"cases" placeholder:"URL" help:"Download an answer key bundle (as for --key) at grade time, falling back to the embedded testdata when unreachable"`
+	TestsToken        string        `env:"GRADEBOT_TESTS_TOKEN" placeholder:"TOKEN" help:"Bearer token for --tests-url"`
+	TestsSecret       string        `env:"GRADEBOT_TESTS_SECRET" placeholder:"SECRET" help:"Require the --tests-url bundle to be signed with this HMAC secret, in URL.sig"`
+	Key               string        `type:"existingfile" xor:"cases" placeholder:"FILE" help:"Answer key bundle (JSON) with the scheduler cases and point values, replacing the embedded testdata"`
+	ReceiptKey        string        `type:"existingfile" env:"GRADEBOT_RECEIPT_KEY" placeholder:"FILE" help:"Print a receipt of each grade, signed with this private key (see keygen --signing), to check with verify; keep it where grading is trusted, not in students' builds"`
+	CheckTimeoutGrace time.Duration `name:"check-timeout-grace" default:"300ms" help:"Grace period for a killed scheduler's output to flush before cleanup"`
+}
+
+// canvasOptions publish a batch's grades to a Canvas LMS assignment.
+type canvasOptions struct {
+	CanvasURL        string `name:"canvas-url" placeholder:"URL" help:"Canvas instance to publish grades to, e.g. https://canvas.example.edu"`
+	CanvasToken      string `name:"canvas-token" env:"CANVAS_TOKEN" placeholder:"TOKEN" help:"Canvas API access token (better set in $CANVAS_TOKEN than on the command line)"`
+	CanvasCourse     string `name:"canvas-course" placeholder:"ID" help:"Canvas course ID"`
+	CanvasAssignment string `name:"canvas-assignment" placeholder:"ID" help:"Canvas assignment ID; grades and comments are published when set"`
+	CanvasUser       string `name:"canvas-user" default:"sis_login_id" placeholder:"KIND" help:"What a submission's directory name is in Canvas: sis_login_id, sis_user_id, or empty for the Canvas user ID"`
+}
+
+// sheetsOptions publish a batch's grades to a Google Sheet, as rows appended
+// with a service account's credentials.
+type sheetsOptions struct {
+	Sheet            string `placeholder:"ID" help:"Google Sheet to append a row per submission to (id, each rubric item's points, total, time, in the columns its header row names), by its spreadsheet ID, from its URL"`
+	SheetRange       string `default:"Sheet1" placeholder:"RANGE" help:"Sheet (or A1 range) of --sheet to append the rows after"`
+	SheetCredentials string `env:"GOOGLE_APPLICATION_CREDENTIALS" type:"path" placeholder:"FILE" help:"Service account key (JSON) for --sheet; the sheet must be shared with the account's email"`
+}
+
+// feedbackOptions ask an OpenAI-compatible chat completions endpoint for a
+// hint on each failed check.
+type feedbackOptions struct {
+	Feedback      bool   `help:"Add a short hint to each failed check, asked of an OpenAI-compatible endpoint (sends the check's message, output diffs and the submission's Go source to it)"`
+	FeedbackURL   string `name:"feedback-url" default:"https://api.openai.com/v1" placeholder:"URL" help:"Base URL of the --feedback endpoint, e.g. http://localhost:11434/v1 for a local model"`
+	FeedbackKey   string `name:"feedback-key" env:"FEEDBACK_API_KEY" placeholder:"KEY" help:"API key for --feedback-url (better set in $FEEDBACK_API_KEY than on the command line)"`
+	FeedbackModel string `name:"feedback-model" default:"gpt-4o-mini" placeholder:"MODEL" help:"Model to ask for --feedback"`
+}
+
+// checkSelection selects the rubric items graded.
+type checkSelection struct {
+	Only []string `sep:"," placeholder:"ID,..." help:"Run only these checks (compile, screenshot, readme, fcfs, sjf, sjfp, rr, the extra-credit priority and mlfq, and the optional module, style, hygiene, history, random, determinism, stress, robustness, fuzz, race, forbidden, tests, coverage)"`
+	Skip []string `sep:"," placeholder:"ID,..." help:"Skip these checks"`
+
+	FailFast bool `help:"Stop at the first check that fails, marking the rest skipped; checks run one at a time"`
+}
+
+// batchOptions are the options of grading a batch of submissions.
+type batchOptions struct {
+	Gradebook      string  `type:"path" placeholder:"FILE" help:"Also write each submission's per-item scores and total to FILE, as CSV (or JSON for a .json FILE), by directory name"`
+	DetectDupes    bool    `help:"In a batch, flag submissions whose Go source is identical or nearly so, ignoring comments and whitespace"`
+	Similarity     float64 `placeholder:"PCT" help:"In a batch, also rank the pairs of submissions sharing at least PCT% of either's Go source, MOSS-style: identifiers and literals are normalized, so renaming doesn't hide copying"`
+	SimilarityBase string  `type:"existingdir" placeholder:"DIR" help:"Ignore code shared with this starter code for --similarity"`
+
+	Sample string `placeholder:"N|N%" help:"Grade a random subset of the directories: a count, or a percentage like 25%"`
+}
@@ -1,283 +1,110 @@
+// Command gradebot grades CSCE 4600 submissions: its command line, whose
+// commands run package grader's grading.
 package main
 
 import (
 	"bufio"
-	"bytes"
-	_ "embed"
-	"errors"
+	"context"
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"os/signal"
+	"strconv"
+	"syscall"
 
 	"github.com/alecthomas/kong"
-	"github.com/jedib0t/go-pretty/v6/table"
-	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/jh125486/CSCE4600_gradebot/pkg/grader"
+	"golang.org/x/term"
 )
 
-// embedded testdata.
-var (
-	//go:embed testdata/fcfs.csv
-	fcfsIn []byte
-	//go:embed testdata/fcfs.out
-	fcfsOut []byte
-
-	//go:embed testdata/sjf.csv
-	sjfIn []byte
-	//go:embed testdata/sjf.out
-	sjfOut []byte
-
-	//go:embed testdata/sjfp.csv
-	sjfpIn []byte
-	//go:embed testdata/sjfp.out
-	sjfpOut []byte
-
-	//go:embed testdata/rr.csv
-	rrIn []byte
-	//go:embed testdata/rr.out
-	rrOut []byte
-)
-
-type (
-	grammar struct {
-		options
-		PathToDir string `name:"dir" default:"." help:"Path to scheduler directory" type:"path" required:"true"`
-	}
-	options struct {
-		Debug bool `help:"Debug output."`
-		Total bool `help:"Print total only"`
-	}
-)
+type grammar struct {
+	NoPause       bool             `help:"Don't wait for the return key before exiting (implied unless double-clicked on Windows)"`
+	NoUpdateCheck bool             `help:"Don't check for a newer gradebot release (also with $GRADEBOT_NO_UPDATE_CHECK)"`
+	Version       kong.VersionFlag `help:"Print gradebot's version and its embedded rubric's revision, then exit"`
+
+	Grade          gradeCmd          `cmd:"" default:"withargs" aliases:"project1" help:"Grade a project 1 (CPU scheduler) submission, out of ${project1_total} points, plus ${project1_extra_credit} of extra credit (default)."`
+	Project2       gradeCmd          `cmd:"" name:"project2" help:"Grade a project 2 (Unix shell) submission, out of ${project2_total} points, with the grade command's flags."`
+	Batch          batchCmd          `cmd:"" help:"Grade each submission directory of --root, then write their per-check scores and totals to a gradebook."`
+	ValidateConfig validateConfigCmd `cmd:"" name:"validate-config" help:"Check a rubric config for mistakes without grading."`
+	Verify         verifyCmd         `cmd:"" help:"Check a grade receipt's or attestation's signature."`
+	Submit         submitCmd         `cmd:"" help:"Grade a submission, then seal its sources and report (and a signed receipt, given the receipt key) into one file only the instructor's key opens."`
+	Keygen         keygenCmd         `cmd:"" help:"Generate the instructor's key pair for submit, or with --signing, for receipts."`
+	Unpack         unpackCmd         `cmd:"" help:"Decrypt and extract a sealed submission, verifying any receipt against its sources."`
+	Attest         attestCmd         `cmd:"" help:"Print a signed attestation of this gradebot: its binary's digest, rubric revision and testdata integrity."`
+	Serve          serveCmd          `cmd:"" help:"Grade zipped submissions POSTed over HTTP, responding with JSON results (or, with the /v1 API, queuing them to poll or stream)."`
+	Golden         goldenCmd         `cmd:"" help:"Regenerate the golden .out files by running a reference scheduler."`
+	History        historyCmd        `cmd:"" help:"Show each submission's progression across the grades recorded with --record."`
+	Leaderboard    leaderboardCmd    `cmd:"" help:"Rank the grades posted with --leaderboard, by score then --stress runtime."`
+	Analyze        analyzeCmd        `cmd:"" help:"Summarize a batch's JSON results: score distribution, pass rates, points lost and common failures by rubric item."`
+	Update         updateCmd         `cmd:"" help:"Replace gradebot with its latest release, verified against the release's checksums."`
+}
 
 func main() {
-	if err := kong.Parse(&grammar{},
+	grader.ExecLimited()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// a second interrupt falls back to the default handling, exiting immediately.
+		<-ctx.Done()
+		stop()
+	}()
+
+	var cli grammar
+	kctx := kong.Parse(&cli,
 		kong.Name("gradebot"),
-		kong.Description("Gradebot 9000 is a tool to grade your 4600 project 1."),
+		kong.Description("Gradebot 9000 is a tool to grade your 4600 projects."),
 		kong.UsageOnError(),
-	).Run(); err != nil {
-		slog.Error("error running gradebot", slog.String("err", err.Error()))
-	}
-	pauseForInput(os.Stdout, os.Stdin)
-}
-
-func pauseForInput(w io.Writer, r io.Reader) {
-	_, _ = fmt.Fprintf(w, "press 'return' key to continue...")
-	input := bufio.NewScanner(r)
-	input.Scan()
-}
-
-type (
-	Context struct {
-		srcDir string
-		binary string
-	}
-	Check  func(*Context) (Result, error)
-	Result struct {
-		label    string
-		awarded  int
-		possible int
-		message  string
-	}
-)
-
-func (o *options) setup() {
-	// Set up logging.
-	lvl := new(slog.LevelVar)
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: lvl,
-	}))
-	if o.Debug {
-		lvl.Set(slog.LevelDebug)
-	}
-	if o.Total {
-		lvl.Set(10)
-	}
-	slog.SetDefault(logger)
-}
-
-func (cmd grammar) Run() error {
-	cmd.options.setup()
-
-	var (
-		rubric  Context
-		results = make([]Result, 0)
+		helpVars(),
+		kong.BindTo(ctx, (*context.Context)(nil)),
 	)
-	rubric.srcDir = cmd.PathToDir
-	for _, check := range []Check{
-		CheckCompilable,
-		CheckScreenshotExists,
-		CheckREADMEExists,
-		CheckScheduler(Result{
-			label:    "First-come, first-serve scheduling",
-			possible: 20,
-		}, "-fcfs", fcfsIn, fcfsOut),
-		CheckScheduler(Result{
-			label:    "Shortest-job-first scheduling",
-			possible: 20,
-		}, "-sjf", sjfIn, sjfOut),
-		CheckScheduler(Result{
-			label:    "Shortest-job-first with priority scheduling",
-			possible: 20,
-		}, "-sjfp", sjfpIn, sjfpOut),
-		CheckScheduler(Result{
-			label:    "Round-robin scheduling",
-			possible: 10,
-		}, "-rr", rrIn, rrOut),
-	} {
-		result, err := check(&rubric)
-		if err != nil {
-			slog.Error(result.label, slog.String("err", err.Error()))
-		}
-		results = append(results, result)
-	}
-
-	printRubricResults(cmd.Total, results...)
-
-	// cleanup
-	_ = os.RemoveAll(rubric.binary)
-
-	return nil
-}
-
-func printRubricResults(onlyTotal bool, results ...Result) {
-	if onlyTotal {
-		totalPoints := 0
-		for i := range results {
-			totalPoints += results[i].awarded
+	// stale binaries grade with stale rubrics.
+	warnOutdated := func() {}
+	if !cli.NoUpdateCheck && kctx.Command() != "update" {
+		warnOutdated = grader.CheckForUpdate(ctx)
+	}
+	err := kctx.Run()
+	warnOutdated()
+	if err != nil {
+		slog.Error("error running gradebot", slog.String("err", err.Error()))
+		if ctx.Err() == nil && !cli.NoPause && !cli.Grade.TUI && !cli.Project2.TUI {
+			pauseForInput(os.Stdout, os.Stdin)
 		}
-		fmt.Println(totalPoints)
-		return
-	}
-
-	t := table.NewWriter()
-	t.AppendHeader(table.Row{"Rubric Item", "Error?", "Possible", "Awarded"})
-	t.SetStyle(table.StyleRounded)
-	t.SetColumnConfigs([]table.ColumnConfig{
-		{Number: 2, AlignFooter: text.AlignRight},
-	})
-
-	var (
-		possiblePoints int
-		totalPoints    int
-	)
-	for i := range results {
-		t.AppendRow([]any{results[i].label, results[i].message, results[i].possible, results[i].awarded})
-		possiblePoints += results[i].possible
-		totalPoints += results[i].awarded
-	}
-	t.AppendFooter(table.Row{"", "Total", possiblePoints, totalPoints})
-	fmt.Println(t.Render())
-}
-
-//region Checkers
-
-func CheckCompilable(c *Context) (Result, error) {
-	result := Result{
-		label:    "Compilable",
-		awarded:  0,
-		possible: 10,
+		// a failed gate isn't a failure to grade, so CI can tell them apart.
+		os.Exit(grader.ExitCode(err))
 	}
-	if err := os.Chdir(c.srcDir); err != nil {
-		return result, err
+	// the TUI is its own pause, and its key reader still holds stdin.
+	if ctx.Err() == nil && !cli.NoPause && !cli.Grade.TUI && !cli.Project2.TUI {
+		pauseForInput(os.Stdout, os.Stdin)
 	}
-	// check for Go in path.
-	if _, err := exec.LookPath("go"); err != nil {
-		result.message = "Go executable not found in path"
-		return result, err
-	}
-	// compile the scheduler.
-	if err := exec.Command("go", "build", "-o", "scheduler.bin").Run(); err != nil {
-		result.message = "scheduler is not compileable"
-		return result, err
-	}
-	c.binary = filepath.Join(c.srcDir, "scheduler.bin")
-
-	result.awarded += 10
-	slog.Debug("scheduler is compileable", slog.Int("pts", 10))
-
-	return result, nil
-
 }
 
-func CheckScreenshotExists(c *Context) (Result, error) {
-	result := Result{
-		label:    "Screenshot exists",
-		awarded:  0,
-		possible: 10,
+// helpVars are the variables interpolated in the help: gradebot's version,
+// and each project's total and extra credit with the default rubric, as a
+// rubric config or the optional checks' flags may change them.
+func helpVars() kong.Vars {
+	vars := kong.Vars{"version": grader.Version()}
+	for _, name := range grader.Projects() {
+		possible, extra := grader.ProjectTotals(name)
+		vars[name+"_total"] = strconv.Itoa(possible)
+		vars[name+"_extra_credit"] = strconv.Itoa(extra)
 	}
-	if c.binary == "" {
-		result.message = "scheduler was not compileable"
-		return result, errors.New("binary not found")
-	}
-	if _, err := os.Stat(filepath.Join(c.srcDir, "screenshot.png")); err != nil {
-		result.message = "screenshot.png not found"
-		return result, err
-	}
-	result.awarded += 10
-	slog.Debug("screenshot.png exists", slog.Int("pts", 10))
 
-	return result, nil
+	return vars
 }
 
-func CheckREADMEExists(c *Context) (Result, error) {
-	result := Result{
-		label:    "README.md exists",
-		awarded:  0,
-		possible: 10,
-	}
-	if c.binary == "" {
-		result.message = "scheduler was not compileable"
-		return result, errors.New("binary not found")
-	}
-	if _, err := os.Stat(filepath.Join(c.srcDir, "README.md")); err != nil {
-		result.message = "README.md not found"
-		return result, err
+// pauseForInput keeps a double-clicked console window open until the user
+// presses return. It does nothing unless both w and r are terminals, so CI
+// runs and redirected output never block, nor in a console the process
+// didn't open (see ownsConsole), as when run from a shell.
+func pauseForInput(w, r *os.File) {
+	if !isTerminal(w) || !isTerminal(r) || !ownsConsole() {
+		return
 	}
-	result.awarded += 10
-	slog.Debug("README.md exists", slog.Int("pts", 10))
-
-	return result, nil
+	_, _ = fmt.Fprintf(w, "press 'return' key to continue...")
+	input := bufio.NewScanner(r)
+	input.Scan()
 }
 
-func CheckScheduler(result Result, flag string, in, out []byte) func(c *Context) (Result, error) {
-	return func(c *Context) (Result, error) {
-		if c.binary == "" {
-			result.message = "scheduler was not compileable"
-			return result, errors.New("binary not found")
-		}
-
-		// run the scheduler
-		cmd := exec.Command(c.binary, flag)
-
-		// send embedded csv to stdin.
-		cmd.Stdin = bytes.NewReader(in)
-
-		var bb bytes.Buffer
-		cmd.Stdout = &bb
-		if err := cmd.Run(); err != nil {
-			result.message = "scheduler exited with error"
-			return result, err
-		}
-		if bb.String() == "" {
-			result.message = "scheduler ran with no output"
-			return result, nil
-		}
-
-		// compare output to expected output
-		if bytes.Compare(bb.Bytes(), out) != 0 {
-			result.message = "output does not match expected"
-			fmt.Print(flag, " expected:\n", string(out))
-			fmt.Print(flag, " actual:\n", bb.String())
-			return result, errors.New("output does not match expected")
-		}
-
-		result.awarded = result.possible
-		slog.Debug(fmt.Sprintf("%v Scheduler output matches expected", flag), slog.Int("pts", 30))
-
-		return result, nil
-	}
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
 }
-
-//endregion
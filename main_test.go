@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/jh125486/CSCE4600_gradebot/pkg/grader"
+)
+
+// parse parses args as gradebot's command line.
+func parse(t *testing.T, args ...string) (grammar, error) {
+	t.Helper()
+	var cli grammar
+	parser, err := kong.New(&cli, kong.Name("gradebot"), helpVars(), kong.Exit(func(int) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parser.Parse(args)
+
+	return cli, err
+}
+
+func TestBatchCmdFlags(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "root and gradebook", args: []string{"batch", "--root", root, "--gradebook", "grades.csv", "--only", "fcfs"}},
+		{name: "project", args: []string{"batch", "--root", root, "--gradebook", "grades.json", "--project", "project2"}},
+		{name: "no root", args: []string{"batch", "--gradebook", "grades.csv"}, wantErr: true},
+		{name: "missing root", args: []string{"batch", "--root", filepath.Join(root, "missing"), "--gradebook", "grades.csv"}, wantErr: true},
+		{name: "unknown project", args: []string{"batch", "--root", root, "--gradebook", "grades.csv", "--project", "project9"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse(t, tt.args...); (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, want error %t", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBatchCmdRequiresGradebook(t *testing.T) {
+	if err := (batchCmd{Root: t.TempDir()}).Run(context.Background()); err == nil {
+		t.Error("Run() without --gradebook: no error")
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	t.Setenv("GRADEBOT_TESTS_TOKEN", "")
+	t.Setenv("GRADEBOT_TESTS_SECRET", "")
+	t.Setenv("GRADEBOT_RECEIPT_KEY", "")
+	cli, err := parse(t, "grade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the library's defaults are the flags'.
+	if got, want := grader.Config(cli.Grade.options), grader.DefaultConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("the grade command's defaults =\n%+v\nwant grader.DefaultConfig()\n%+v", got, want)
+	}
+}
+
+func TestHelpVars(t *testing.T) {
+	vars := helpVars()
+	for name, want := range map[string]string{"project1_total": "100", "project1_extra_credit": "10", "project2_total": "100"} {
+		if vars[name] != want {
+			t.Errorf("helpVars()[%q] = %q, want %q", name, vars[name], want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package grader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// analyzeBuckets is the number of histogram buckets, of 100/analyzeBuckets
+// percent each.
+const analyzeBuckets = 10
+
+// AnalyzeConfig configures Analyze, as the analyze command's flags do.
+type AnalyzeConfig struct {
+	Results  []string
+	Messages int
+}
+
+// Analyze summarizes the batch results in cfg.Results: their score
+// distribution, pass rates, points lost and common failures by rubric item.
+func Analyze(cfg AnalyzeConfig) error {
+	var subs []submission
+	for _, path := range cfg.Results {
+		s, err := readJSONReports(path)
+		if err != nil {
+			return err
+		}
+		subs = append(subs, s...)
+	}
+	if len(subs) == 0 {
+		return errors.New("no graded submissions in the results")
+	}
+	stats := computeBatchStats(subs)
+	fmt.Printf("%d submissions: mean %.2f, median %.2f, min %d, max %d, std dev %.2f\n\n",
+		stats.Submissions, stats.Mean, stats.Median, stats.Min, stats.Max, stats.StdDev)
+	printHistogram(os.Stdout, subs)
+	printItemAnalysis(os.Stdout, subs, stats)
+	printCommonFailures(os.Stdout, subs, cfg.Messages)
+
+	return nil
+}
+
+// readJSONReports reads the graded submissions from a --format=json stream,
+// skipping its other documents, e.g. the batch statistics, and submissions
+// that couldn't be graded at all.
+func readJSONReports(path string) ([]submission, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var subs []submission
+	for dec := json.NewDecoder(f); ; {
+		var report jsonReport
+		if err := dec.Decode(&report); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if report.Dir != "" && report.Error == "" {
+			subs = append(subs, submission{dir: report.Dir, results: report.Results})
+		}
+	}
+
+	return subs, nil
+}
+
+// printHistogram prints the distribution of the submissions' percentages.
+func printHistogram(w io.Writer, subs []submission) {
+	var counts [analyzeBuckets]int
+	for _, s := range subs {
+		awarded, possible := s.totals()
+		// 100% goes in the top bucket.
+		counts[min(int(normalize(awarded, possible, 100))*analyzeBuckets/100, analyzeBuckets-1)]++
+	}
+	most := 1
+	for _, n := range counts {
+		most = max(most, n)
+	}
+	const width = 40
+	fmt.Fprintln(w, "Score distribution:")
+	for i := analyzeBuckets - 1; i >= 0; i-- {
+		lo, hi := i*100/analyzeBuckets, (i+1)*100/analyzeBuckets-1
+		if i == analyzeBuckets-1 {
+			hi = 100
+		}
+		fmt.Fprintf(w, "  %3d-%3d%% %-*s %d\n", lo, hi, width, strings.Repeat("█", counts[i]*width/most), counts[i])
+	}
+	fmt.Fprintln(w)
+}
+
+// printItemAnalysis prints each rubric item's pass rate and mean points
+// lost, most lost first: what the class struggled with.
+func printItemAnalysis(w io.Writer, subs []submission, stats batchStats) {
+	lost := make(map[string]int)
+	for _, s := range subs {
+		for _, r := range s.results {
+			lost[r.Label] += r.Possible - r.Awarded
+		}
+	}
+	items := append([]itemPassRate(nil), stats.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return float64(lost[items[i].Label])/float64(items[i].Graded) > float64(lost[items[j].Label])/float64(items[j].Graded)
+	})
+
+	t := table.NewWriter()
+	t.SetTitle("Rubric items, by points lost")
+	t.AppendHeader(table.Row{"Rubric Item", "Full marks", "Pass rate", "Mean lost"})
+	t.SetStyle(table.StyleRounded)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+	})
+	for _, item := range items {
+		t.AppendRow(table.Row{item.Label, fmt.Sprintf("%d/%d", item.Passed, item.Graded), fmt.Sprintf("%.0f%%", 100*item.Rate),
+			fmt.Sprintf("%.2f", float64(lost[item.Label])/float64(item.Graded))})
+	}
+	fmt.Fprintln(w, t.Render())
+}
+
+// printCommonFailures prints the n most common first lines of failed items'
+// messages, with how many submissions got each.
+func printCommonFailures(w io.Writer, subs []submission, n int) {
+	type failure struct{ label, message string }
+	counts := make(map[failure]int)
+	for _, s := range subs {
+		for _, r := range s.results {
+			if r.Awarded >= r.Possible {
+				continue
+			}
+			first, _, _ := strings.Cut(r.Message, "\n")
+			counts[failure{r.Label, first}]++
+		}
+	}
+	failures := make([]failure, 0, len(counts))
+	for f := range counts {
+		failures = append(failures, f)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if counts[failures[i]] != counts[failures[j]] {
+			return counts[failures[i]] > counts[failures[j]]
+		}
+		if failures[i].label != failures[j].label {
+			return failures[i].label < failures[j].label
+		}
+		return failures[i].message < failures[j].message
+	})
+	if n > 0 && len(failures) > n {
+		failures = failures[:n]
+	}
+
+	t := table.NewWriter()
+	t.SetTitle("Most common failures")
+	t.AppendHeader(table.Row{"Submissions", "Rubric Item", "Message"})
+	t.SetStyle(table.StyleRounded)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, Align: text.AlignRight},
+	})
+	for _, f := range failures {
+		// a mismatch message quotes whole lines of output.
+		t.AppendRow(table.Row{counts[f], f.label, text.Snip(f.message, 80, "…")})
+	}
+	if len(failures) == 0 {
+		t.AppendRow(table.Row{"", "none", ""})
+	}
+	fmt.Fprintln(w, t.Render())
+}
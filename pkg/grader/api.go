@@ -0,0 +1,217 @@
+package grader
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The serve command's /v1 API, GradeService in api/gradebot/v1/gradebot.proto
+// over HTTP with the proto3 JSON mapping (and as gRPC, in grpc.go): Submit
+// queues a submission, GetResult polls it and StreamLogs follows its checks
+// as they're graded, so a cluster can farm submissions out to gradebot
+// workers.
+
+// submission states, as the proto's State enum names them.
+const (
+	stateQueued  = "STATE_QUEUED"
+	stateRunning = "STATE_RUNNING"
+	stateDone    = "STATE_DONE"
+)
+
+// apiJob is a /v1 submission's progress.
+type apiJob struct {
+	id string
+
+	mu       sync.Mutex
+	state    string
+	results  []Result // graded so far, in the order they were
+	report   *jsonReport
+	finished time.Time
+	// changed is closed, and replaced, on every update, waking StreamLogs.
+	changed chan struct{}
+}
+
+type (
+	submitResponse struct {
+		ID string `json:"id"`
+	}
+	getResultResponse struct {
+		ID     string      `json:"id"`
+		State  string      `json:"state"`
+		Report *jsonReport `json:"report,omitempty"`
+	}
+	// checkEvent is a line of StreamLogs: a check's result, or the report
+	// that ends the stream.
+	checkEvent struct {
+		Result *Result     `json:"result,omitempty"`
+		Report *jsonReport `json:"report,omitempty"`
+	}
+)
+
+// update changes the job under its lock, then wakes its streams. It's a
+// no-op on a nil job, as a /grade submission has none.
+func (j *apiJob) update(f func()) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f()
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (j *apiJob) setState(state string) {
+	j.update(func() { j.state = state })
+}
+
+// add records a graded check, as Grade's OnResult.
+func (j *apiJob) add(r Result) {
+	j.update(func() { j.results = append(j.results, r) })
+}
+
+func (j *apiJob) finish(report jsonReport) {
+	j.update(func() {
+		j.state, j.report, j.finished = stateDone, &report, time.Now()
+	})
+}
+
+// since is the job's results from the nth on, its report once done, and the
+// channel closed on its next update.
+func (j *apiJob) since(n int) ([]Result, *jsonReport, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.results[min(n, len(j.results)):], j.report, j.changed
+}
+
+// handleSubmit is Submit: it queues the zip POSTed as the body, e.g.
+//
+//	curl -H "Authorization: Bearer $TOKEN" --data-binary @project.zip 'http://localhost:8080/v1/submissions?student=alice'
+//
+// responding 202 with the submission's id.
+func (s *server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	api, err := s.newAPIJob()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	job := gradeJob{done: make(chan jsonReport, 1), api: api}
+	if !s.enqueue(w, r, &job) {
+		s.forgetJob(api.id)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, submitResponse{ID: api.id})
+}
+
+// newAPIJob registers a /v1 submission, before it's queued, as a worker may
+// pick it up at once.
+func (s *server) newAPIJob() (*apiJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	api := &apiJob{id: id, state: stateQueued, changed: make(chan struct{})}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneJobs()
+	s.jobs[id] = api
+
+	return api, nil
+}
+
+// forgetJob unregisters a /v1 submission that was never queued.
+func (s *server) forgetJob(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+}
+
+// job is the /v1 submission with id, unless it's unknown or expired.
+func (s *server) job(id string) (*apiJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+
+	return job, ok
+}
+
+// handleSubmission is GetResult, at /v1/submissions/ID, and StreamLogs, at
+// /v1/submissions/ID/events: a checkEvent per line as each check is graded,
+// those graded already first, then the report.
+func (s *server) handleSubmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "GET a submission", http.StatusMethodNotAllowed)
+		return
+	}
+	id, events := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v1/submissions/"), "/events")
+	job, ok := s.job(id)
+	if !ok {
+		http.Error(w, "no such submission (or its result has expired)", http.StatusNotFound)
+		return
+	}
+	if !events {
+		job.mu.Lock()
+		resp := getResultResponse{ID: id, State: job.state, Report: job.report}
+		job.mu.Unlock()
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flush := http.NewResponseController(w).Flush
+	enc := json.NewEncoder(w)
+	for sent := 0; ; {
+		results, report, changed := job.since(sent)
+		for i := range results {
+			_ = enc.Encode(checkEvent{Result: &results[i]})
+		}
+		sent += len(results)
+		if report != nil {
+			_ = enc.Encode(checkEvent{Report: report})
+			_ = flush()
+			return
+		}
+		_ = flush()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// pruneJobs forgets the submissions graded more than ttl ago, under s.mu.
+func (s *server) pruneJobs() {
+	for id, job := range s.jobs {
+		job.mu.Lock()
+		expired := !job.finished.IsZero() && time.Since(job.finished) > s.ttl
+		job.mu.Unlock()
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
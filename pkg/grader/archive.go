@@ -0,0 +1,235 @@
+package grader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxArchiveBytes bounds how much an archive may extract to, so a zip bomb
+// can't fill the disk.
+const maxArchiveBytes = 256 << 20
+
+var archiveExts = []string{".zip", ".tar.gz", ".tgz"}
+
+// archiveExt returns the archive extension of path, or "" if it isn't one.
+func archiveExt(path string) string {
+	lower := strings.ToLower(path)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+
+	return ""
+}
+
+// extractArchives extracts each archive in dirs into a temporary directory,
+// replacing it with the submission's root there; directories are kept as is.
+// The returned func removes the extracted files.
+func extractArchives(dirs []string) ([]string, func(), error) {
+	var root string
+	cleanup := func() {
+		if root != "" {
+			_ = os.RemoveAll(root)
+		}
+	}
+	out := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		ext := archiveExt(dir)
+		if ext == "" {
+			out = append(out, dir)
+			continue
+		}
+		if fi, err := os.Stat(dir); err != nil || fi.IsDir() {
+			out = append(out, dir)
+			continue
+		}
+		if root == "" {
+			var err error
+			if root, err = os.MkdirTemp("", "gradebot-archives-"); err != nil {
+				return nil, nil, err
+			}
+		}
+		parent, err := os.MkdirTemp(root, "archive-")
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		// named after the archive, e.g. alice.zip is graded as .../alice.
+		dest := filepath.Join(parent, filepath.Base(dir[:len(dir)-len(ext)]))
+		if ext == ".zip" {
+			err = extractZip(dir, dest)
+		} else {
+			err = extractTarGz(dir, dest)
+		}
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("extracting %s: %w", dir, err)
+		}
+		out = append(out, submissionRoot(dest))
+	}
+
+	return out, cleanup, nil
+}
+
+// submissionRoot finds the Go module in an extracted archive: dir itself, or
+// a subdirectory one level down, as when the archive holds a project folder.
+func submissionRoot(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		return dir
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return dir
+	}
+	var (
+		subdirs []string
+		files   int
+	)
+	for _, e := range entries {
+		// e.g. __MACOSX, added by macOS's Compress.
+		if strings.HasPrefix(e.Name(), ".") || strings.HasPrefix(e.Name(), "__") {
+			continue
+		}
+		if !e.IsDir() {
+			files++
+			continue
+		}
+		sub := filepath.Join(dir, e.Name())
+		if _, err := os.Stat(filepath.Join(sub, "go.mod")); err == nil {
+			return sub
+		}
+		subdirs = append(subdirs, sub)
+	}
+	// no go.mod anywhere: a lone folder is still the project.
+	if len(subdirs) == 1 && files == 0 {
+		return subdirs[0]
+	}
+
+	return dir
+}
+
+// extractPath resolves an archive entry's name under dest, rejecting entries
+// that would escape it (e.g. "../../.bashrc").
+func extractPath(dest, name string) (string, error) {
+	path := filepath.Join(dest, filepath.FromSlash(name))
+	if path != dest && !strings.HasPrefix(path, dest+string(filepath.Separator)) {
+		return "", fmt.Errorf("entry %q is outside the archive", name)
+	}
+
+	return path, nil
+}
+
+func extractZip(archive, dest string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	var total int64
+	for _, f := range zr.File {
+		path, err := extractPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+			continue
+		case !mode.IsRegular():
+			// symlinks could point anywhere; submissions don't need them.
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		n, err := writeFile(path, rc, mode.Perm(), f.Modified, maxArchiveBytes-total)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		total += n
+	}
+
+	return nil
+}
+
+func extractTarGz(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path, err := extractPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			n, err := writeFile(path, tr, hdr.FileInfo().Mode().Perm(), hdr.ModTime, maxArchiveBytes-total)
+			if err != nil {
+				return err
+			}
+			total += n
+		}
+		// as with zip, links and special files are skipped.
+	}
+}
+
+// writeFile writes r to path, creating its directory, failing once more than
+// limit bytes are written. A nonzero modTime is kept, for --deadline.
+func writeFile(path string, r io.Reader, perm os.FileMode, modTime time.Time, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	// keep files readable by the grader, whatever the archive recorded.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm|0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > limit {
+		err = fmt.Errorf("archive extracts to more than %d MiB", maxArchiveBytes>>20)
+	}
+	if err == nil && !modTime.IsZero() {
+		err = os.Chtimes(path, modTime, modTime)
+	}
+
+	return n, err
+}
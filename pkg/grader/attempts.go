@@ -0,0 +1,228 @@
+package grader
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	_ "modernc.org/sqlite" // the --record database's driver, without cgo
+)
+
+// attempt is a recorded grade, a row of a --record database.
+type attempt struct {
+	Time     time.Time
+	Dir      string
+	Hash     string
+	Total    int
+	Possible int
+	Scores   map[string]int
+}
+
+// attemptsSchema is a --record database's: an attempt per graded submission,
+// and its checks' scores.
+const attemptsSchema = `
+CREATE TABLE IF NOT EXISTS attempts (
+	id       INTEGER PRIMARY KEY,
+	time     TEXT NOT NULL,
+	dir      TEXT NOT NULL,
+	hash     TEXT NOT NULL,
+	total    INTEGER NOT NULL,
+	possible INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS attempts_by_dir ON attempts (dir, time);
+CREATE TABLE IF NOT EXISTS scores (
+	attempt INTEGER NOT NULL REFERENCES attempts (id),
+	label   TEXT NOT NULL,
+	awarded INTEGER NOT NULL,
+	PRIMARY KEY (attempt, label)
+);`
+
+// openAttempts opens the --record SQLite database at path, creating it if
+// need be. Concurrent runs, e.g. a batch per section, wait their turn to
+// write rather than fail.
+func openAttempts(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(attemptsSchema); err != nil {
+		return nil, errors.Join(fmt.Errorf("%s: %w", path, err), db.Close())
+	}
+
+	return db, nil
+}
+
+// recordAttempts records the graded submissions in the --record database at
+// path, in one transaction.
+func recordAttempts(path string, graded []submission) (err error) {
+	db, err := openAttempts(path)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, s := range graded {
+		dir := s.name()
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		hash, err := contentHash(s.dir)
+		if err != nil {
+			return fmt.Errorf("recording %s: %w", s.name(), err)
+		}
+		total, possible := s.totals()
+		res, err := tx.Exec(`INSERT INTO attempts (time, dir, hash, total, possible) VALUES (?, ?, ?, ?, ?)`, now, dir, hash, total, possible)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, r := range s.results {
+			// as in Scores, a label's last result wins.
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO scores (attempt, label, awarded) VALUES (?, ?, ?)`, id, r.Label, r.Awarded); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// readAttempts reads a --record database's attempts, of dir only if set, in
+// the order recorded.
+func readAttempts(path, dir string) (attempts []attempt, err error) {
+	db, err := openAttempts(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	rows, err := db.Query(`SELECT a.id, a.time, a.dir, a.hash, a.total, a.possible, s.label, s.awarded
+		FROM attempts a LEFT JOIN scores s ON s.attempt = a.id
+		WHERE ? = '' OR a.dir = ?
+		ORDER BY a.id`, dir, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lastID := int64(-1)
+	for rows.Next() {
+		var (
+			id      int64
+			graded  string
+			a       attempt
+			label   sql.NullString
+			awarded sql.NullInt64
+		)
+		if err := rows.Scan(&id, &graded, &a.Dir, &a.Hash, &a.Total, &a.Possible, &label, &awarded); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			if a.Time, err = time.Parse(time.RFC3339Nano, graded); err != nil {
+				return nil, fmt.Errorf("%s: attempt %d: %w", path, id, err)
+			}
+			a.Scores = make(map[string]int)
+			attempts = append(attempts, a)
+			lastID = id
+		}
+		if label.Valid {
+			attempts[len(attempts)-1].Scores[label.String] = int(awarded.Int64)
+		}
+	}
+
+	return attempts, rows.Err()
+}
+
+// HistoryConfig configures History, as the history command's flags do.
+type HistoryConfig struct {
+	File string
+	Dir  string
+	Jump float64
+}
+
+// History prints each submission's progression across the grades
+// recorded in cfg.File.
+func History(cfg HistoryConfig) error {
+	dir := cfg.Dir
+	if dir != "" {
+		var err error
+		if dir, err = filepath.Abs(dir); err != nil {
+			return err
+		}
+	}
+	attempts, err := readAttempts(cfg.File, dir)
+	if err != nil {
+		return err
+	}
+	if dir != "" && len(attempts) == 0 {
+		return fmt.Errorf("no attempts of %s in %s", dir, cfg.File)
+	}
+	byDir := make(map[string][]attempt)
+	for _, a := range attempts {
+		byDir[a.Dir] = append(byDir[a.Dir], a)
+	}
+	for _, dir := range sortedKeys(byDir) {
+		printAttempts(dir, byDir[dir], cfg.Jump)
+	}
+
+	return nil
+}
+
+// printAttempts prints a submission's attempts in order, with the change in
+// total and the checks it came from, flagging jumps of at least jump
+// percentage points.
+func printAttempts(dir string, attempts []attempt, jump float64) {
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].Time.Before(attempts[j].Time) })
+	t := table.NewWriter()
+	t.SetTitle(dir)
+	t.AppendHeader(table.Row{"#", "Graded", "Sources", "Total", "Change", "Checks changed"})
+	t.SetStyle(table.StyleRounded)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 4, Align: text.AlignRight},
+		{Number: 5, Align: text.AlignRight},
+	})
+	for i, a := range attempts {
+		change, changed := "", ""
+		if i > 0 {
+			prev := attempts[i-1]
+			change = fmt.Sprintf("%+d", a.Total-prev.Total)
+			if a.Hash == prev.Hash {
+				changed = "(same sources)"
+			}
+			var labels []string
+			for _, label := range sortedKeys(a.Scores) {
+				if d := a.Scores[label] - prev.Scores[label]; d != 0 {
+					labels = append(labels, fmt.Sprintf("%s %+d", label, d))
+				}
+			}
+			if len(labels) > 0 {
+				changed = strings.Join(labels, "\n")
+			}
+			if jump > 0 && normalize(a.Total, a.Possible, 100)-normalize(prev.Total, prev.Possible, 100) >= jump {
+				change += " (jump)"
+			}
+		}
+		hash := a.Hash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		t.AppendRow(table.Row{i + 1, a.Time.Local().Format("2006-01-02 15:04"), hash, fmt.Sprintf("%d/%d", a.Total, a.Possible), change, changed})
+	}
+	fmt.Println(t.Render())
+}
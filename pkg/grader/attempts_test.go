@@ -0,0 +1,105 @@
+package grader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRecordAttempts(t *testing.T) {
+	root := t.TempDir()
+	alice, bob := filepath.Join(root, "alice"), filepath.Join(root, "bob")
+	for _, dir := range []string{alice, bob} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db := filepath.Join(root, "history.db")
+	graded := []submission{
+		{dir: alice, results: []Result{{Label: "Compiles", Awarded: 10, Possible: 10}, {Label: "FCFS", Awarded: 5, Possible: 20}}},
+		{dir: bob, results: []Result{{Label: "Compiles", Possible: 10}}},
+	}
+	if err := recordAttempts(db, graded); err != nil {
+		t.Fatal(err)
+	}
+	graded[0].results[1].Awarded = 20
+	if err := recordAttempts(db, graded[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts, err := readAttempts(db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		dir             string
+		total, possible int
+		scores          map[string]int
+	}
+	var got []row
+	for _, a := range attempts {
+		if a.Time.IsZero() || a.Hash == "" {
+			t.Errorf("attempt %+v has no time or hash", a)
+		}
+		got = append(got, row{a.Dir, a.Total, a.Possible, a.Scores})
+	}
+	want := []row{
+		{alice, 15, 30, map[string]int{"Compiles": 10, "FCFS": 5}},
+		{bob, 0, 10, map[string]int{"Compiles": 0}},
+		{alice, 30, 30, map[string]int{"Compiles": 10, "FCFS": 20}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readAttempts() = %+v, want %+v", got, want)
+	}
+
+	attempts, err = readAttempts(db, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[0].Hash != attempts[1].Hash {
+		t.Errorf("readAttempts(%s) = %+v, want its 2 attempts, of the same sources", alice, attempts)
+	}
+
+	if _, err := readAttempts(filepath.Join(alice, "main.go"), ""); err == nil {
+		t.Error("readAttempts() of a file that isn't a database: no error")
+	}
+}
+
+func TestRecordAttemptsConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db := filepath.Join(t.TempDir(), "history.db")
+	// as though a batch per section, sharing a --record database.
+	const runs = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- recordAttempts(db, []submission{{dir: dir, results: []Result{{Label: "Compiles", Awarded: i, Possible: 10}}}})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	attempts, err := readAttempts(db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != runs {
+		t.Errorf("readAttempts() = %d attempts, want %d", len(attempts), runs)
+	}
+}
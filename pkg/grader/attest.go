@@ -0,0 +1,253 @@
+package grader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// testdataSumsFile lists the SHA-256 of each embedded testdata file, as
+// sha256sum writes them; the golden command rewrites it.
+const testdataSumsFile = "SHA256SUMS"
+
+//go:embed testdata/SHA256SUMS
+var testdataSums []byte
+
+// attestation is the signed record of the gradebot that graded, so an
+// instructor can tell a student's pasted results came from an unmodified
+// release: its binary's digest and rubric revision, and whether its testdata
+// matched the checksums it was built with.
+type attestation struct {
+	Kind     string `json:"kind"` // attestationKind, telling it apart from a receipt
+	Version  string `json:"version"`
+	Rubric   string `json:"rubric"`
+	Binary   string `json:"binary"` // SHA-256 of the executable
+	Testdata string `json:"testdata"`
+	Platform string `json:"platform"`
+	Time     int64  `json:"time"` // Unix seconds
+}
+
+const attestationKind = "attestation"
+
+// embeddedTestdata is the embedded testdata, by path under testdata.
+func embeddedTestdata() map[string][]byte {
+	files := map[string][]byte{
+		"fcfs.csv": fcfsIn, "fcfs.out": fcfsOut,
+		"sjf.csv": sjfIn, "sjf.out": sjfOut,
+		"sjfp.csv": sjfpIn, "sjfp.out": sjfpOut,
+		"rr.csv": rrIn, "rr.out": rrOut, "rr_q1.out": rrQ1Out, "rr_q2.out": rrQ2Out,
+		"rr_q10.out": rrQ10Out,
+	}
+	for name, b := range embeddedExtraCredit {
+		files[name] = b
+	}
+	for _, fsys := range []fs.FS{goldenMetaFS, shellSessions} {
+		_ = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			b, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				return err
+			}
+			files[strings.TrimPrefix(path, "testdata/")] = b
+			return nil
+		})
+	}
+
+	return files
+}
+
+// sumTestdata renders the files' SHA256SUMS, sorted by path.
+func sumTestdata(files map[string][]byte) []byte {
+	var b bytes.Buffer
+	for _, name := range sortedKeys(files) {
+		sum := sha256.Sum256(files[name])
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+
+	return b.Bytes()
+}
+
+// verifyTestdata lists the embedded testdata files not matching the
+// embedded SHA256SUMS: changed, missing from it or missing from the build.
+func verifyTestdata() []string {
+	want := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(testdataSums))
+	for s.Scan() {
+		if fields := strings.Fields(s.Text()); len(fields) == 2 {
+			want[fields[1]] = fields[0]
+		}
+	}
+	var bad []string
+	files := embeddedTestdata()
+	for _, name := range sortedKeys(files) {
+		sum := sha256.Sum256(files[name])
+		if want[name] != hex.EncodeToString(sum[:]) {
+			bad = append(bad, name)
+		}
+		delete(want, name)
+	}
+
+	return append(bad, sortedKeys(want)...)
+}
+
+// testdataStatus is "ok", or the files failing verifyTestdata.
+func testdataStatus() string {
+	if bad := verifyTestdata(); len(bad) > 0 {
+		return "modified: " + strings.Join(bad, ", ")
+	}
+
+	return "ok"
+}
+
+// writeTestdataSums rewrites dir's SHA256SUMS, of the embedded testdata
+// files in it.
+func writeTestdataSums(dir string) error {
+	files := make(map[string][]byte)
+	for name := range embeddedTestdata() {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		files[name] = b
+	}
+
+	return os.WriteFile(filepath.Join(dir, testdataSumsFile), sumTestdata(files), 0o644)
+}
+
+// executableDigest is the SHA-256 of the running executable.
+var executableDigest = sync.OnceValues(func() (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	return fileDigest(self)
+})
+
+// newAttestation attests to the running gradebot.
+func newAttestation() (attestation, error) {
+	digest, err := executableDigest()
+	if err != nil {
+		return attestation{}, fmt.Errorf("attestation: %w", err)
+	}
+
+	return attestation{
+		Kind:     attestationKind,
+		Version:  gradebotVersion(),
+		Rubric:   rubricRevision(),
+		Binary:   digest,
+		Testdata: testdataStatus(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Time:     time.Now().Unix(),
+	}, nil
+}
+
+// signedAttestation signs an attestation of the running gradebot with key,
+// or is "" without one.
+func signedAttestation(key ed25519.PrivateKey) (string, error) {
+	if key == nil {
+		return "", nil
+	}
+	a, err := newAttestation()
+	if err != nil {
+		return "", err
+	}
+
+	return signToken(key, a)
+}
+
+func (a attestation) print(w io.Writer) {
+	fmt.Fprintf(w, "gradebot: %s (%s)\n", a.Version, a.Platform)
+	fmt.Fprintf(w, "rubric:   %s\n", a.Rubric)
+	fmt.Fprintf(w, "binary:   sha256:%s\n", a.Binary)
+	fmt.Fprintf(w, "testdata: %s\n", a.Testdata)
+}
+
+// AttestConfig configures Attest, as the attest command's flags do.
+type AttestConfig struct {
+	ReceiptKey string
+}
+
+// Attest prints a signed attestation of this gradebot.
+func Attest(cfg AttestConfig) error {
+	key, err := loadSigningKey(cfg.ReceiptKey)
+	if err != nil {
+		return err
+	}
+	a, err := newAttestation()
+	if err != nil {
+		return err
+	}
+	token, err := signToken(key, a)
+	if err != nil {
+		return err
+	}
+	a.print(os.Stdout)
+	fmt.Printf("Attestation: %s\n", token)
+
+	return nil
+}
+
+// verifyAttestation reports how the attestation compares to this gradebot,
+// or to the build at binary, and errs if it was of a modified one.
+func verifyAttestation(a attestation, binary string) error {
+	fmt.Printf("valid attestation, made at %s:\n", time.Unix(a.Time, 0).Format(time.RFC3339))
+	a.print(os.Stdout)
+	var errs []error
+	if a.Testdata != "ok" {
+		errs = append(errs, fmt.Errorf("its testdata was modified (%s)", strings.TrimPrefix(a.Testdata, "modified: ")))
+	}
+	if rubric := rubricRevision(); a.Rubric != rubric {
+		errs = append(errs, fmt.Errorf("its rubric %s isn't this gradebot's (%s)", a.Rubric, rubric))
+	}
+
+	digest, err := executableDigest()
+	what := "this gradebot"
+	if binary != "" {
+		digest, err = fileDigest(binary)
+		what = binary
+	}
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case a.Binary == digest:
+		fmt.Printf("binary matches %s\n", what)
+	case binary == "" && (a.Platform != runtime.GOOS+"/"+runtime.GOARCH || a.Version != gradebotVersion()):
+		// another release, or platform, is another binary.
+		fmt.Printf("binary not checked: this gradebot isn't %s's %s build (see --binary)\n", a.Version, a.Platform)
+	default:
+		errs = append(errs, fmt.Errorf("its binary doesn't match %s", what))
+	}
+
+	return errors.Join(errs...)
+}
+
+// fileDigest is the SHA-256 of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,32 @@
+package grader
+
+import "testing"
+
+func TestAttestationVerify(t *testing.T) {
+	key, pubFile := testReceiptKey(t)
+	token, err := signedAttestation(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(VerifyConfig{Token: token, Key: pubFile}); err != nil {
+		t.Errorf("verify of this gradebot's attestation: %v", err)
+	}
+
+	// a modified gradebot's, as it would sign it if it had the key.
+	a, err := newAttestation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Testdata, a.Binary = "modified: fcfs.out", "0000"
+	modified, err := signToken(key, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(VerifyConfig{Token: modified, Key: pubFile}); err == nil {
+		t.Error("verify of a modified gradebot's attestation: no error")
+	}
+
+	if token, err := signedAttestation(nil); token != "" || err != nil {
+		t.Errorf("signedAttestation() without a key = %q, %v, want none", token, err)
+	}
+}
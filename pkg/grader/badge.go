@@ -0,0 +1,95 @@
+package grader
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"strings"
+)
+
+// badgeColors are shields.io's colors by the least percentage they're for,
+// best first.
+var badgeColors = []struct {
+	pct   float64
+	color string
+}{
+	{90, "#4c1"},    // brightgreen
+	{80, "#97ca00"}, // green
+	{70, "#a4a61d"}, // yellowgreen
+	{60, "#dfb317"}, // yellow
+	{50, "#fe7d37"}, // orange
+	{0, "#e05d44"},  // red
+}
+
+// badgeTemplate is shields.io's flat badge: a label, a value on a colored
+// background, and their widths.
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">
+  <title>%[3]s: %[4]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[5]d" height="20" fill="%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]g" y="15" fill="#010101" fill-opacity=".3">%[3]s</text>
+    <text x="%[7]g" y="14">%[3]s</text>
+    <text x="%[8]g" y="15" fill="#010101" fill-opacity=".3">%[4]s</text>
+    <text x="%[8]g" y="14">%[4]s</text>
+  </g>
+</svg>
+`
+
+// writeBadge writes the graded submission's total to path as an SVG badge,
+// colored by its percentage, for a README.
+func writeBadge(path string, opts options, s submission) error {
+	awarded, possible := s.totals()
+	pct := 0.0
+	if possible > 0 {
+		pct = 100 * float64(awarded) / float64(possible)
+	}
+	value := fmt.Sprintf("%d/%d", awarded, possible)
+	if opts.NormalizeTo > 0 {
+		value = fmt.Sprintf("%g/%d", normalize(awarded, possible, opts.NormalizeTo), opts.NormalizeTo)
+	}
+	color := badgeColors[len(badgeColors)-1].color
+	for _, c := range badgeColors {
+		if pct >= c.pct {
+			color = c.color
+			break
+		}
+	}
+
+	const label = "grade"
+	// each side is padded by 5px around its text.
+	lw, vw := badgeTextWidth(label)+10, badgeTextWidth(value)+10
+	svg := fmt.Sprintf(badgeTemplate, lw+vw, lw, html.EscapeString(label), html.EscapeString(value), vw, color,
+		float64(lw)/2, float64(lw)+float64(vw)/2)
+
+	return os.WriteFile(path, []byte(svg), 0o644)
+}
+
+// badgeTextWidth estimates the width, in pixels, of s in 11px Verdana, as
+// there are no font metrics to measure it with.
+func badgeTextWidth(s string) int {
+	var w float64
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iljtf.,:;!|' ", r):
+			w += 4
+		case strings.ContainsRune("/()", r):
+			w += 5
+		case strings.ContainsRune("mwMW%", r):
+			w += 11
+		default:
+			w += 7
+		}
+	}
+
+	return int(math.Ceil(w))
+}
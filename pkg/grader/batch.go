@@ -0,0 +1,314 @@
+package grader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// submission is one graded submission directory of a batch.
+type submission struct {
+	dir string
+	// source is the archive dir was extracted from, if it was: dir is only
+	// where it was graded.
+	source  string
+	results []Result
+	env     *manifest // the environment it was graded in, if recorded
+}
+
+// name is how s is reported: its directory, or archive, as given.
+func (s submission) name() string {
+	if s.source != "" {
+		return s.source
+	}
+
+	return s.dir
+}
+
+// id is the submission's student, e.g. in a gradebook: the name of its
+// directory, or of its archive less the extension (alice, of alice.zip).
+func (s submission) id() string {
+	base := filepath.Base(s.name())
+
+	return base[:len(base)-len(archiveExt(base))]
+}
+
+func (s submission) totals() (awarded, possible int) {
+	return resultTotals(s.results)
+}
+
+// resultTotals are the points awarded and possible of results; extra-credit
+// points are awarded above the possible total.
+func resultTotals(results []Result) (awarded, possible int) {
+	for _, r := range results {
+		awarded += r.Awarded
+		if !r.ExtraCredit {
+			possible += r.Possible
+		}
+	}
+
+	return awarded, possible
+}
+
+// rosterDirs lists the (non-hidden) subdirectories and archives of root, sorted by name.
+func rosterDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, e := range entries {
+		if (e.IsDir() || (e.Type().IsRegular() && archiveExt(e.Name()) != "")) && !strings.HasPrefix(e.Name(), ".") {
+			dirs = append(dirs, filepath.Join(root, e.Name()))
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("roster %s has no submission directories", root)
+	}
+
+	return dirs, nil
+}
+
+// printBatchSummary prints one row per submission, sorted by directory name.
+func printBatchSummary(w io.Writer, opts options, subs []submission) {
+	sorted := append([]submission(nil), subs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name() < sorted[j].name() })
+
+	switch opts.format() {
+	case "json", "tap", "github":
+		// each submission was already emitted as its own JSON document, TAP
+		// stream or workflow commands.
+	case "total":
+		for _, s := range sorted {
+			awarded, _ := s.totals()
+			fmt.Fprintf(w, "%s\t%d\n", s.name(), awarded)
+		}
+	default:
+		t := table.NewWriter()
+		t.SetTitle("Batch summary")
+		t.AppendHeader(table.Row{"Submission", "Possible", "Total"})
+		t.SetStyle(table.StyleRounded)
+		t.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 1, AlignFooter: text.AlignRight},
+		})
+		for _, s := range sorted {
+			awarded, possible := s.totals()
+			t.AppendRow(table.Row{s.name(), possible, awarded})
+		}
+		t.AppendFooter(table.Row{"Submissions", len(sorted), ""})
+		fmt.Fprintln(w, opts.render(t))
+	}
+}
+
+// batchStats summarizes the total scores of a batch, and how many
+// submissions earned full marks on each rubric item.
+type batchStats struct {
+	Submissions int            `json:"submissions"`
+	Mean        float64        `json:"mean"`
+	Median      float64        `json:"median"`
+	Min         int            `json:"min"`
+	Max         int            `json:"max"`
+	StdDev      float64        `json:"stddev"`
+	Items       []itemPassRate `json:"items"`
+}
+
+type itemPassRate struct {
+	Label  string  `json:"label"`
+	Passed int     `json:"passed"`
+	Graded int     `json:"graded"`
+	Rate   float64 `json:"rate"`
+}
+
+func computeBatchStats(subs []submission) batchStats {
+	stats := batchStats{Submissions: len(subs)}
+	if len(subs) == 0 {
+		return stats
+	}
+
+	totals := make([]int, 0, len(subs))
+	var (
+		items []itemPassRate
+		index = make(map[string]int)
+	)
+	for _, s := range subs {
+		awarded, _ := s.totals()
+		totals = append(totals, awarded)
+		for _, r := range s.results {
+			i, ok := index[r.Label]
+			if !ok {
+				i = len(items)
+				index[r.Label] = i
+				items = append(items, itemPassRate{Label: r.Label})
+			}
+			items[i].Graded++
+			if r.Awarded == r.Possible {
+				items[i].Passed++
+			}
+		}
+	}
+	for i := range items {
+		items[i].Rate = float64(items[i].Passed) / float64(items[i].Graded)
+	}
+	stats.Items = items
+
+	sort.Ints(totals)
+	stats.Min, stats.Max = totals[0], totals[len(totals)-1]
+	if n := len(totals); n%2 == 1 {
+		stats.Median = float64(totals[n/2])
+	} else {
+		stats.Median = float64(totals[n/2-1]+totals[n/2]) / 2
+	}
+	var sum float64
+	for _, t := range totals {
+		sum += float64(t)
+	}
+	stats.Mean = sum / float64(len(totals))
+	var sq float64
+	for _, t := range totals {
+		sq += (float64(t) - stats.Mean) * (float64(t) - stats.Mean)
+	}
+	// population standard deviation: the batch is the whole section, not a sample of it.
+	stats.StdDev = math.Sqrt(sq / float64(len(totals)))
+
+	return stats
+}
+
+// printBatchStats prints aggregate statistics for the batch.
+func printBatchStats(w io.Writer, opts options, subs []submission) {
+	stats := computeBatchStats(subs)
+
+	switch opts.format() {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Stats batchStats `json:"stats"`
+		}{stats}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	case "total", "tap", "github":
+		// keep the output to one "dir<TAB>total" line per submission, or to
+		// TAP streams or workflow commands.
+	default:
+		t := table.NewWriter()
+		t.SetTitle("Batch statistics")
+		t.AppendHeader(table.Row{"Rubric Item", "Full marks", "Pass rate"})
+		t.SetStyle(table.StyleRounded)
+		t.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 2, Align: text.AlignRight, AlignFooter: text.AlignRight},
+			{Number: 3, Align: text.AlignRight, AlignFooter: text.AlignRight},
+		})
+		for _, item := range stats.Items {
+			t.AppendRow(table.Row{item.Label, fmt.Sprintf("%d/%d", item.Passed, item.Graded), fmt.Sprintf("%.0f%%", 100*item.Rate)})
+		}
+		t.AppendFooter(table.Row{"Mean / Median", fmt.Sprintf("%.2f", stats.Mean), fmt.Sprintf("%.2f", stats.Median)})
+		t.AppendFooter(table.Row{"Min / Max", stats.Min, stats.Max})
+		t.AppendFooter(table.Row{"Std dev", "", fmt.Sprintf("%.2f", stats.StdDev)})
+		fmt.Fprintln(w, opts.render(t))
+	}
+}
+
+// gradebookRow is one submission's scores, keyed by rubric item label.
+type gradebookRow struct {
+	ID       string         `json:"id"`
+	Dir      string         `json:"dir"`
+	Scores   map[string]int `json:"scores"`
+	Total    int            `json:"total"`
+	Possible int            `json:"possible"`
+}
+
+// writeGradebook writes each submission's per-item scores and total to path,
+// as JSON for a .json path and CSV otherwise. A submission's id is its
+// directory or archive name, e.g. the student's username in a roster.
+func writeGradebook(path string, subs []submission) error {
+	sorted := append([]submission(nil), subs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name() < sorted[j].name() })
+
+	var (
+		labels []string
+		rows   []gradebookRow
+	)
+	for _, s := range sorted {
+		row := gradebookRow{ID: s.id(), Dir: s.name(), Scores: make(map[string]int)}
+		row.Total, row.Possible = s.totals()
+		for _, r := range s.results {
+			if !slices.Contains(labels, r.Label) {
+				labels = append(labels, r.Label)
+			}
+			row.Scores[r.Label] = r.Awarded
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			return err
+		}
+	} else {
+		cw := csv.NewWriter(&buf)
+		_ = cw.Write(append(append([]string{"id"}, labels...), "total", "possible"))
+		for _, row := range rows {
+			record := []string{row.ID}
+			for _, label := range labels {
+				// an item that wasn't graded for this submission is left blank.
+				if pts, ok := row.Scores[label]; ok {
+					record = append(record, strconv.Itoa(pts))
+				} else {
+					record = append(record, "")
+				}
+			}
+			_ = cw.Write(append(record, strconv.Itoa(row.Total), strconv.Itoa(row.Possible)))
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// sampleDirs randomly selects a subset of dirs, sized by n: either a count
+// ("10") or a percentage of dirs ("25%"). The selection keeps the original
+// order and is reproducible for a given seed.
+func sampleDirs(dirs []string, n string, seed int64) ([]string, error) {
+	var size int
+	if pct, ok := strings.CutSuffix(n, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil || p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid sample percentage %q", n)
+		}
+		size = max(1, int(float64(len(dirs))*p/100+0.5))
+	} else {
+		c, err := strconv.Atoi(n)
+		if err != nil || c <= 0 {
+			return nil, fmt.Errorf("invalid sample size %q", n)
+		}
+		size = min(c, len(dirs))
+	}
+
+	picked := rand.New(rand.NewSource(seed)).Perm(len(dirs))[:size]
+	sort.Ints(picked)
+	sample := make([]string, 0, size)
+	for _, i := range picked {
+		sample = append(sample, dirs[i])
+	}
+
+	return sample, nil
+}
@@ -0,0 +1,72 @@
+package grader
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRosterDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"alice", "bob", ".git"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"carol.zip", "dave.tar.gz", "notes.txt", ".hidden.zip"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs, err := rosterDirs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, dir := range dirs {
+		names = append(names, filepath.Base(dir))
+	}
+	if want := []string{"alice", "bob", "carol.zip", "dave.tar.gz"}; !slices.Equal(names, want) {
+		t.Errorf("rosterDirs() = %v, want %v", names, want)
+	}
+
+	if _, err := rosterDirs(t.TempDir()); err == nil {
+		t.Error("rosterDirs() of an empty directory: no error")
+	}
+}
+
+func TestSubmissionID(t *testing.T) {
+	tests := []struct {
+		s        submission
+		name, id string
+	}{
+		{s: submission{dir: "roster/alice"}, name: "roster/alice", id: "alice"},
+		// extracted, and found a level down, but named for its archive.
+		{s: submission{dir: "/tmp/gradebot-archives-1/archive-2/bob/project", source: "roster/bob.zip"}, name: "roster/bob.zip", id: "bob"},
+		{s: submission{dir: "/tmp/x/carol", source: "roster/carol.TAR.GZ"}, name: "roster/carol.TAR.GZ", id: "carol"},
+	}
+	for _, tt := range tests {
+		if name, id := tt.s.name(), tt.s.id(); name != tt.name || id != tt.id {
+			t.Errorf("%+v: name() = %q, id() = %q, want %q, %q", tt.s, name, id, tt.name, tt.id)
+		}
+	}
+}
+
+func TestWriteGradebookArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grades.csv")
+	if err := writeGradebook(path, []submission{
+		{dir: "/tmp/gradebot-archives-1/archive-2/bob/project", source: "roster/bob.zip", results: []Result{{Label: "Compiles", Awarded: 10, Possible: 10}}},
+		{dir: "roster/alice", results: []Result{{Label: "Compiles", Awarded: 5, Possible: 10}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,Compiles,total,possible\nalice,5,5,10\nbob,10,10,10\n"; string(b) != want {
+		t.Errorf("gradebook =\n%s\nwant\n%s", b, want)
+	}
+}
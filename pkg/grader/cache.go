@@ -0,0 +1,83 @@
+package grader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// binaryCacheDir holds compiled schedulers, named by sourceHash, so regrading
+// an unchanged submission skips the build.
+func binaryCacheDir() string {
+	return filepath.Join(os.TempDir(), "gradebot-cache")
+}
+
+// sourceHash hashes everything that determines the build: the .go files,
+// go.mod and go.sum under dir, the main package built, and the Go toolchain
+// (pinned, if it is) and target platform.
+func sourceHash(ctx context.Context, dir, pkg, pinned string) (string, error) {
+	version, err := exec.CommandContext(ctx, "go", "env", "GOVERSION").Output()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, _ = io.WriteString(h, strings.TrimSpace(string(version))+"\x00"+pinned+"\x00"+runtime.GOOS+"/"+runtime.GOARCH+"\x00"+pkg+"\x00")
+	if err := hashSources(h, dir); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contentHash hashes just the submission's Go sources, independent of the
+// toolchain, identifying what was graded.
+func contentHash(dir string) (string, error) {
+	h := sha256.New()
+	if err := hashSources(h, dir); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashSources writes the .go files, go.mod and go.sum under dir (and their paths) to h.
+func hashSources(h io.Writer, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			// like the go command, ignore hidden and underscore-prefixed directories.
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// the path is hashed too, so renaming or moving a file invalidates the cache.
+		_, _ = io.WriteString(h, filepath.ToSlash(rel)+"\x00")
+		_, err = io.Copy(h, f)
+		_, _ = h.Write([]byte{0})
+
+		return err
+	})
+}
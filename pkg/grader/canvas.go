@@ -0,0 +1,110 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CanvasConfig publishes a batch's grades to a Canvas LMS assignment.
+type CanvasConfig struct {
+	CanvasURL        string
+	CanvasToken      string
+	CanvasCourse     string
+	CanvasAssignment string
+	CanvasUser       string
+}
+
+func (o CanvasConfig) enabled() bool { return o.CanvasAssignment != "" }
+
+func (o CanvasConfig) validate() error {
+	if !o.enabled() {
+		return nil
+	}
+	var missing []string
+	for _, f := range []struct{ flag, value string }{
+		{"--canvas-url", o.CanvasURL},
+		{"--canvas-token", o.CanvasToken},
+		{"--canvas-course", o.CanvasCourse},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.flag)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("--canvas-assignment also requires %s", strings.Join(missing, ", "))
+	}
+	if u, err := url.Parse(o.CanvasURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid --canvas-url %q", o.CanvasURL)
+	}
+
+	return nil
+}
+
+// publishToCanvas sets each submission's grade, awarded points (normalized,
+// if at all) and a comment itemizing the results. Every submission is
+// attempted; the errors are returned together.
+func publishToCanvas(ctx context.Context, o CanvasConfig, opts options, subs []submission) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	var errs []error
+	for _, s := range subs {
+		user := s.id()
+		if o.CanvasUser != "" {
+			user = o.CanvasUser + ":" + user
+		}
+		awarded, possible := s.totals()
+		grade := strconv.Itoa(awarded)
+		if opts.NormalizeTo > 0 {
+			grade = strconv.FormatFloat(normalize(awarded, possible, opts.NormalizeTo), 'f', -1, 64)
+		}
+
+		form := url.Values{
+			"submission[posted_grade]": {grade},
+			"comment[text_comment]":    {canvasComment(s, awarded, possible)},
+		}
+		endpoint := fmt.Sprintf("%s/api/v1/courses/%s/assignments/%s/submissions/%s",
+			strings.TrimRight(o.CanvasURL, "/"), url.PathEscape(o.CanvasCourse), url.PathEscape(o.CanvasAssignment), url.PathEscape(user))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", user, err))
+			continue
+		}
+		req.Header.Set("Authorization", "Bearer "+o.CanvasToken)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", user, err))
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			errs = append(errs, fmt.Errorf("%s: canvas responded %s: %s", user, resp.Status, strings.TrimSpace(string(body))))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("publishing to canvas: %w", err)
+	}
+
+	return nil
+}
+
+// canvasComment itemizes a submission's results for its Canvas comment.
+func canvasComment(s submission, awarded, possible int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "gradebot: %d/%d\n", awarded, possible)
+	for _, r := range s.results {
+		fmt.Fprintf(&sb, "\n%s: %d/%d", r.Label, r.Awarded, r.Possible)
+		if msg := r.reportMessage(); msg != "" {
+			fmt.Fprintf(&sb, " - %s", strings.ReplaceAll(msg, "\n", "\n  "))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,183 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// schedulerCase is an input and expected-output pair from a --cases directory.
+type schedulerCase struct {
+	name string
+	args []string
+	in   []byte
+	out  []byte
+}
+
+// caseAlgorithms are the scheduler algorithms a case file name may start with.
+var caseAlgorithms = []string{"fcfs", "sjf", "sjfp", "rr"}
+
+// loadCases discovers NAME.csv/NAME.out pairs in dir, grouped by algorithm.
+// The algorithm (and so the scheduler flag) is inferred from NAME up to its
+// first "_", and a "_qN" part passes a time quantum, e.g. "rr_q2.csv" runs
+// "-rr -q 2". Algorithms without cases keep the embedded testdata.
+func loadCases(dir string) (map[string][]schedulerCase, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if name, ok := strings.CutSuffix(e.Name(), ".csv"); ok {
+			names[name] = true
+		} else if name, ok := strings.CutSuffix(e.Name(), ".out"); ok {
+			names[name] = true
+		}
+	}
+
+	var (
+		cases = make(map[string][]schedulerCase)
+		errs  []error
+	)
+	for _, name := range sortedKeys(names) {
+		in, errIn := os.ReadFile(filepath.Join(dir, name+".csv"))
+		out, errOut := os.ReadFile(filepath.Join(dir, name+".out"))
+		switch {
+		case errors.Is(errIn, os.ErrNotExist):
+			errs = append(errs, fmt.Errorf("%s.out has no matching %s.csv", name, name))
+			continue
+		case errors.Is(errOut, os.ErrNotExist):
+			errs = append(errs, fmt.Errorf("%s.csv has no matching %s.out", name, name))
+			continue
+		case errIn != nil || errOut != nil:
+			errs = append(errs, errors.Join(errIn, errOut))
+			continue
+		}
+		algorithm, args, err := caseArgs(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cases[algorithm] = append(cases[algorithm], schedulerCase{name: name, args: args, in: in, out: out})
+	}
+	if len(errs) == 0 && len(cases) == 0 {
+		errs = append(errs, errors.New("no .csv/.out case pairs found"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("cases %s: %w", dir, err)
+	}
+
+	return cases, nil
+}
+
+// testdataFiles are the names of the embedded testdata files --testdata may override.
+var testdataFiles = []string{
+	"fcfs.csv", "fcfs.out", "sjf.csv", "sjf.out", "sjfp.csv", "sjfp.out",
+	"rr.csv", "rr.out", "rr_q1.out", "rr_q2.out", "rr_q10.out",
+	"priority.csv", "priority.out", "mlfq.csv", "mlfq.out",
+	"fcfs.meta.json", "sjf.meta.json", "sjfp.meta.json", "rr.meta.json", "priority.meta.json", "mlfq.meta.json",
+}
+
+// loadTestdata reads the files in dir that override embedded testdata, by name.
+func loadTestdata(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == testdataSumsFile {
+			continue
+		}
+		if !slices.Contains(testdataFiles, e.Name()) {
+			slog.Warn("ignoring unknown testdata file", slog.String("file", filepath.Join(dir, e.Name())))
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[e.Name()] = b
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("testdata %s: no files named like the embedded testdata (%s)", dir, strings.Join(testdataFiles, ", "))
+	}
+
+	return files, nil
+}
+
+// caseArgs infers the scheduler arguments from a case name.
+func caseArgs(name string) (string, []string, error) {
+	parts := strings.Split(name, "_")
+	algorithm := parts[0]
+	if !slices.Contains(caseAlgorithms, algorithm) {
+		return "", nil, fmt.Errorf("%s: name must start with one of %s", name, strings.Join(caseAlgorithms, ", "))
+	}
+	args := []string{"-" + algorithm}
+	for _, part := range parts[1:] {
+		if q, ok := strings.CutPrefix(part, "q"); ok {
+			if _, err := strconv.Atoi(q); err == nil {
+				args = append(args, quantumFlag, q)
+			}
+		}
+	}
+
+	return algorithm, args, nil
+}
+
+// CheckCases grades an algorithm against cases from a --cases directory,
+// awarding proportional credit for each case whose output matches.
+func CheckCases(result Result, algorithm string, cases []schedulerCase) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		fields, err := c.fieldSpecs(algorithm)
+		if err != nil {
+			result.Message = "invalid expected output metadata"
+			return result, err
+		}
+
+		var (
+			passed  int
+			credit  float64
+			reports []string
+			errs    []error
+		)
+		for _, sc := range cases {
+			partial, msg, err := runScheduler(c, sc.in, golden{out: sc.out, fields: fields}, sc.args...)
+			credit += partial
+			if msg != "" {
+				reports = append(reports, fmt.Sprintf("%s: %s", sc.name, msg))
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", sc.name, err))
+				}
+				continue
+			}
+			passed++
+			reports = append(reports, sc.name+": pass")
+			c.log.Debug("Scheduler output matches expected", slog.String("case", sc.name))
+			if passed == 1 {
+				c.probeStdin(golden{out: sc.out, fields: fields}, sc.args)
+			}
+		}
+
+		result.Awarded = int(math.Round(float64(result.Possible) * credit / float64(len(cases))))
+		if passed < len(cases) {
+			result.Message = strings.Join(reports, "\n")
+		}
+
+		return result, errors.Join(errs...)
+	}
+}
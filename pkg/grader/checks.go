@@ -0,0 +1,339 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+func CheckCompilable(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelCompilable,
+		Awarded:  0,
+		Possible: c.opts.possible(labelCompilable),
+	}
+	if len(c.opts.RunCmd) > 0 {
+		var build [][]string
+		if len(c.opts.BuildCmd) > 0 {
+			build = [][]string{c.opts.BuildCmd}
+		}
+		return checkBuildCmd(c, result, build, c.opts.RunCmd)
+	}
+	if c.lang != "" && c.lang != langGo {
+		return checkLanguage(c, result)
+	}
+	// the main package may be nested, e.g. in cmd/scheduler.
+	pkg, err := mainPackage(c.srcDir, c.opts.MainPkg)
+	if err != nil {
+		result.Message = err.Error()
+		return result, err
+	}
+	if pkg != "." {
+		c.log.Debug("building nested main package", slog.String("pkg", pkg))
+	}
+	if c.opts.Sandbox == sandboxDocker {
+		return checkSandboxed(c, result, pkg)
+	}
+	// check for Go in path.
+	if _, err := exec.LookPath("go"); err != nil {
+		result.Message = "Go executable not found in path"
+		return result, err
+	}
+	work, err := c.buildDir()
+	if err != nil {
+		result.Message = "could not create a build directory"
+		return result, err
+	}
+	binary := filepath.Join(work, binaryName())
+	var cached string
+	if !c.opts.NoCache {
+		hash, err := sourceHash(c.ctx, c.srcDir, pkg, c.opts.GoToolchain)
+		if err != nil {
+			c.log.Debug("not caching the build", slog.String("err", err.Error()))
+		} else {
+			cached = filepath.Join(binaryCacheDir(), hash+filepath.Ext(binaryName()))
+			if checkExecutable(cached) == nil {
+				c.binary, c.cached = cached, true
+				c.run = []string{c.binary}
+				result.Awarded = result.Possible
+				result.Message = checkFlags(c)
+				c.log.Debug("reusing cached scheduler build", slog.String("binary", cached), slog.Int("pts", result.Possible))
+				return result, nil
+			}
+			// build next to the cache entry, then rename it into place, so
+			// concurrent runs never see a partial binary.
+			if err := os.MkdirAll(binaryCacheDir(), 0o755); err == nil {
+				binary = cached + fmt.Sprintf(".%d.tmp", os.Getpid())
+			} else {
+				cached = ""
+			}
+		}
+	}
+	// compile the scheduler in its directory, leaving the binary out of it.
+	cmd := c.goCommand(c.ctx, "build", "-o", binary, pkg)
+	stderr := &tailBuffer{limit: maxStderrBytes}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		result.Message = "scheduler is not compileable"
+		// an environmental failure says so, rather than blame the code.
+		if msg := toolchainMismatch(c, stderr.String()); msg != "" {
+			result.Message = msg
+		}
+		_ = os.RemoveAll(binary)
+		return result, err
+	}
+	// a library-only package (no package main/func main) builds fine but produces no executable.
+	if err := checkExecutable(binary); err != nil {
+		result.Message = "no main package / executable produced"
+		_ = os.RemoveAll(binary)
+		return result, err
+	}
+	c.binary = binary
+	if cached != "" {
+		if err := os.Rename(binary, cached); err == nil {
+			c.binary, c.cached = cached, true
+		}
+	}
+	c.run = []string{c.binary}
+
+	result.Awarded = result.Possible
+	result.Message = checkFlags(c)
+	c.log.Debug("scheduler is compileable", slog.Int("pts", result.Possible))
+
+	return result, nil
+}
+
+// checkBuildCmd builds a non-Go submission with the build commands, if any (an
+// interpreted one needs none), and sets it up to run with run.
+func checkBuildCmd(c *Context, result Result, build [][]string, run []string) (Result, error) {
+	for _, args := range build {
+		stderr := &tailBuffer{limit: maxStderrBytes}
+		cmd := exec.CommandContext(c.ctx, args[0], args[1:]...)
+		cmd.Dir = c.srcDir
+		cmd.Stdout = stderr
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			result.Message = "scheduler is not compileable"
+			if tail := tailLines(stderr.String(), stderrTailLines); len(tail) > 0 {
+				result.Message += ":\n" + strings.Join(tail, "\n")
+			}
+			return result, err
+		}
+	}
+	c.run = run
+
+	result.Awarded = result.Possible
+	result.Message = checkFlags(c)
+	c.log.Debug("scheduler is buildable", slog.String("run", strings.Join(c.run, " ")), slog.Int("pts", result.Possible))
+
+	return result, nil
+}
+
+// buildDir returns the submission's temp build directory, creating it on
+// first use; it's removed after grading.
+func (c *Context) buildDir() (string, error) {
+	if c.work == "" {
+		work, err := os.MkdirTemp("", "gradebot-build-")
+		if err != nil {
+			return "", err
+		}
+		c.work = work
+	}
+
+	return c.work, nil
+}
+
+// binaryName is the platform-appropriate name of the compiled scheduler; Windows
+// only executes files with an .exe extension.
+func binaryName() string {
+	if runtime.GOOS == "windows" {
+		return "scheduler.exe"
+	}
+
+	return "scheduler.bin"
+}
+
+func checkExecutable(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("build produced no executable: %w", err)
+	}
+	if !fi.Mode().IsRegular() || (runtime.GOOS != "windows" && fi.Mode().Perm()&0o111 == 0) {
+		return fmt.Errorf("build output %q is not an executable", path)
+	}
+
+	return nil
+}
+
+func CheckModule(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelModule,
+		Awarded:  0,
+		Possible: c.opts.possible(labelModule),
+	}
+	if c.lang != "" && c.lang != langGo {
+		return notApplicable(result, c.lang)
+	}
+	b, err := os.ReadFile(filepath.Join(c.srcDir, "go.mod"))
+	if err != nil {
+		result.Message = "go.mod missing"
+		return result, err
+	}
+	mod := parseGoMod(b)
+	// every failed constraint is reported, not just the first.
+	var problems []error
+	switch {
+	case mod.module == "":
+		problems = append(problems, errors.New("no module directive"))
+	case checkModulePath(mod.module) != nil:
+		problems = append(problems, checkModulePath(mod.module))
+	case !strings.HasPrefix(mod.module, c.opts.ModulePrefix):
+		problems = append(problems, fmt.Errorf("unexpected module path %q, want prefix %q", mod.module, c.opts.ModulePrefix))
+	}
+	// the grading toolchain (pinned, or else installed), if known, must be
+	// able to build it.
+	if err := checkGoDirective(mod.goVersion, c.buildGoVersion()); err != nil {
+		problems = append(problems, err)
+	}
+	if len(mod.requires) > 0 && !c.opts.AllowDeps {
+		problems = append(problems, fmt.Errorf("requires %s, but only the standard library is allowed", strings.Join(mod.requires, ", ")))
+	}
+	if len(problems) > 0 {
+		msgs := make([]string, len(problems))
+		for i, p := range problems {
+			msgs[i] = "go.mod: " + p.Error()
+		}
+		result.Message = strings.Join(msgs, "\n")
+		return result, errors.Join(problems...)
+	}
+	result.Awarded = result.Possible
+	c.log.Debug("go.mod is valid", slog.String("module", mod.module), slog.String("go", mod.goVersion), slog.Int("pts", result.Possible))
+
+	return result, nil
+}
+
+// screenshotExts are the image formats accepted for screenshot.*.
+var screenshotExts = []string{".png", ".jpg", ".jpeg", ".gif"}
+
+func CheckScreenshotExists(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelScreenshot,
+		Awarded:  0,
+		Possible: c.opts.possible(labelScreenshot),
+	}
+	// source-inspection checks don't need the binary, so they run even when compilation fails.
+	entries, err := os.ReadDir(c.srcDir)
+	if err != nil {
+		result.Message = "screenshot not found"
+		return result, err
+	}
+	// any case-insensitive screenshot.{png,jpg,jpeg,gif}, e.g. Screenshot.PNG.
+	var candidates []string
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		if e.Type().IsRegular() && strings.HasPrefix(name, "screenshot.") && slices.Contains(screenshotExts, filepath.Ext(name)) {
+			candidates = append(candidates, e.Name())
+		}
+	}
+	if len(candidates) == 0 {
+		result.Message = "screenshot.png not found (also accepted: .jpg, .jpeg, .gif)"
+		return result, errors.New("screenshot not found")
+	}
+
+	var invalid []string
+	for i, name := range candidates {
+		found, err := checkImage(filepath.Join(c.srcDir, name))
+		if err != nil {
+			invalid = append(invalid, name+": "+err.Error())
+			continue
+		}
+		result.Message = name + ": " + found
+		if others := append(candidates[:i:i], candidates[i+1:]...); len(others) > 0 {
+			result.Message += fmt.Sprintf(" (also found %s)", strings.Join(others, ", "))
+		}
+		result.Awarded = result.Possible
+		c.log.Debug("screenshot exists", slog.String("file", name), slog.Int("pts", result.Possible))
+
+		return result, nil
+	}
+	result.Message = strings.Join(invalid, "\n")
+
+	return result, errors.New("screenshot is not a valid image")
+}
+
+// minimum screenshot file size and dimensions, against placeholders and icons.
+const (
+	minScreenshotBytes  = 1 << 10
+	minScreenshotWidth  = 200
+	minScreenshotHeight = 100
+)
+
+// checkImage decodes the file's header as a PNG, JPEG or GIF, so an empty or
+// renamed text file isn't accepted, and checks its size. It describes the
+// image, e.g. "1280x720 png, 85 KiB".
+func checkImage(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.Size() == 0 {
+		return "", errors.New("empty file")
+	}
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		// say what it is instead, e.g. text/plain.
+		head := make([]byte, 512)
+		n, _ := f.ReadAt(head, 0)
+		return "", fmt.Errorf("not a PNG, JPEG or GIF image (%s)", http.DetectContentType(head[:n]))
+	}
+	found := fmt.Sprintf("%dx%d %s, %d KiB", cfg.Width, cfg.Height, format, (fi.Size()+512)>>10)
+	switch {
+	case fi.Size() < minScreenshotBytes:
+		return "", fmt.Errorf("%dx%d %s of only %d bytes (want at least %d KiB)", cfg.Width, cfg.Height, format, fi.Size(), minScreenshotBytes>>10)
+	case cfg.Width < minScreenshotWidth || cfg.Height < minScreenshotHeight:
+		return "", fmt.Errorf("%s, too small (want at least %dx%d)", found, minScreenshotWidth, minScreenshotHeight)
+	}
+
+	return found, nil
+}
+
+func CheckREADMEExists(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelREADME,
+		Awarded:  0,
+		Possible: c.opts.possible(labelREADME),
+	}
+	// source-inspection checks don't need the binary, so they run even when compilation fails.
+	b, err := os.ReadFile(filepath.Join(c.srcDir, "README.md"))
+	if err != nil {
+		result.Message = "README.md not found"
+		return result, err
+	}
+	// partial credit for each content requirement met.
+	problems, total := readmeProblems(string(b), c.opts.ReadmeWords)
+	result.Awarded = int(math.Round(float64(result.Possible) * float64(total-len(problems)) / float64(total)))
+	if len(problems) > 0 {
+		result.Message = strings.Join(problems, "\n")
+		return result, fmt.Errorf("README.md misses %d of %d requirements", len(problems), total)
+	}
+	c.log.Debug("README.md meets the requirements", slog.Int("pts", result.Possible))
+
+	return result, nil
+}
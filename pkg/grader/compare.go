@@ -0,0 +1,464 @@
+package grader
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// goldenMetaFS holds optional per-algorithm metadata, testdata/<name>.meta.json,
+// describing how individual fields of the golden output are compared:
+//
+//	{"fields": {"Average wait": {"precision": 2}, "Throughput": {"tolerance": 0.01}}}
+//
+// Field names are either a metric label (the text before ": " in lines like
+// "Average wait: 3.40") or a schedule table column header (e.g. "WAIT").
+// Precision rounds both values to that many decimals before comparing, and
+// tolerance allows an absolute difference. Unlisted fields compare exactly,
+// apart from the --epsilon allowed for any number.
+//
+//go:embed testdata/*.meta.json
+var goldenMetaFS embed.FS
+
+type (
+	golden struct {
+		out    []byte
+		fields map[string]fieldSpec
+		// epsilon, when positive, allows numbers anywhere in a line to differ by
+		// up to that much (see numericMismatch).
+		epsilon float64
+	}
+	fieldSpec struct {
+		Precision *int    `json:"precision" yaml:"precision"`
+		Tolerance float64 `json:"tolerance" yaml:"tolerance"`
+	}
+)
+
+// loadFieldSpecs loads the metadata for the named algorithm, if any: override,
+// when set, else the embedded copy.
+func loadFieldSpecs(name string, override []byte) (map[string]fieldSpec, error) {
+	b := override
+	if b == nil {
+		var err error
+		b, err = goldenMetaFS.ReadFile("testdata/" + name + ".meta.json")
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	var meta struct {
+		Fields map[string]fieldSpec `json:"fields"`
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("%s metadata: %w", name, err)
+	}
+	for field, spec := range meta.Fields {
+		if spec.Tolerance < 0 || (spec.Precision != nil && *spec.Precision < 0) {
+			return nil, fmt.Errorf("%s metadata: field %q: precision and tolerance must be non-negative", name, field)
+		}
+	}
+
+	return meta.Fields, nil
+}
+
+// ansiEscape matches ANSI/VT100 escape sequences: CSI sequences such as colors
+// ("\x1b[1;31m") and cursor movement, OSC sequences such as window titles, and
+// two-character escapes.
+var ansiEscape = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// normalizations are the steps of normalizeOutput, for --normalize.
+var normalizations = []string{"ansi", "eol", "trailing", "newline"}
+
+// normalizeOutput applies the normalization steps (all of them when steps is
+// nil): "ansi" strips ANSI escape sequences, "eol" converts CRLF line endings
+// to LF, "trailing" trims trailing whitespace from every line, and "newline"
+// ends the output with exactly one newline.
+func normalizeOutput(b []byte, steps []string) []byte {
+	if steps == nil {
+		steps = normalizations
+	}
+	if slices.Contains(steps, "ansi") {
+		b = ansiEscape.ReplaceAll(b, nil)
+	}
+	if slices.Contains(steps, "eol") {
+		b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	}
+	if slices.Contains(steps, "trailing") {
+		lines := bytes.Split(b, []byte("\n"))
+		for i := range lines {
+			lines[i] = bytes.TrimRight(lines[i], " \t\r")
+		}
+		b = bytes.Join(lines, []byte("\n"))
+	}
+	if slices.Contains(steps, "newline") {
+		b = append(bytes.TrimRight(b, "\n"), '\n')
+	}
+
+	return b
+}
+
+// normalizeLine applies the per-line normalization steps to a single line
+// without its newline, as normalizeOutput would.
+func normalizeLine(line string, steps []string) string {
+	if steps == nil {
+		steps = normalizations
+	}
+	if slices.Contains(steps, "ansi") {
+		line = ansiEscape.ReplaceAllString(line, "")
+	}
+	if slices.Contains(steps, "eol") {
+		line = strings.TrimSuffix(line, "\r")
+	}
+	if slices.Contains(steps, "trailing") {
+		line = strings.TrimRight(line, " \t\r")
+	}
+
+	return line
+}
+
+// trimPreamble drops whatever actual prints before the expected output's first
+// line, such as a banner or an "Enter algorithm:" prompt, and returns how many
+// lines were dropped. The earliest match is the anchor, so output after it is
+// still compared in full; a prompt without a newline is dropped from the start
+// of the anchor line.
+func trimPreamble(actual, expected []byte) ([]byte, int) {
+	first, _, _ := strings.Cut(string(expected), "\n")
+	if strings.TrimSpace(first) == "" {
+		return actual, 0
+	}
+	var re *regexp.Regexp
+	if strings.Contains(first, regexMarkerOpen) {
+		var err error
+		if re, err = goldenLinePattern(first); err != nil {
+			return actual, 0
+		}
+	}
+	lines := strings.SplitAfter(string(actual), "\n")
+	for i, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		switch {
+		case re != nil && re.MatchString(text):
+		case re == nil && strings.HasSuffix(text, first):
+			lines[i] = first + line[len(text):]
+		default:
+			continue
+		}
+		trimmed := i
+		if len(lines[i]) < len(line) {
+			trimmed++ // the prompt counts as a line of preamble
+		}
+		return []byte(strings.Join(lines[i:], "")), trimmed
+	}
+
+	return actual, 0
+}
+
+// compareGolden compares actual to the golden output, returning a description
+// of the first mismatch, or "" when they match.
+func compareGolden(actual []byte, want golden) (string, error) {
+	matched, err := matchGolden(actual, want.out)
+	if err != nil || matched {
+		return "", err
+	}
+	if len(want.fields) == 0 && want.epsilon <= 0 {
+		return firstLineMismatch(actual, want.out), nil
+	}
+
+	return compareFields(actual, want)
+}
+
+func firstLineMismatch(actual, expected []byte) string {
+	act := strings.Split(string(actual), "\n")
+	exp := strings.Split(string(expected), "\n")
+	for i := 0; i < min(len(act), len(exp)); i++ {
+		if act[i] != exp[i] && !strings.Contains(exp[i], regexMarkerOpen) {
+			return diverged(i+1, countLines(expected), fmt.Sprintf("got %q, want %q", act[i], exp[i]))
+		}
+	}
+
+	return diverged(min(len(act), len(exp))+1, countLines(expected), fmt.Sprintf("got %d lines, want %d", len(act), len(exp)))
+}
+
+// compareFields compares line by line, applying the field specs to metric lines
+// and schedule table cells that differ, then the numeric epsilon.
+func compareFields(actual []byte, want golden) (string, error) {
+	act := strings.Split(string(actual), "\n")
+	exp := strings.Split(string(want.out), "\n")
+	if len(act) != len(exp) {
+		return firstLineMismatch(actual, want.out), nil
+	}
+
+	var header []string
+	for i := range exp {
+		if cells := tableCells(exp[i]); cells != nil && !anyNumeric(cells) {
+			header = cells
+		}
+		detail, err := lineMismatch(act[i], exp[i], header, want)
+		if err != nil {
+			return "", fmt.Errorf("golden line %d: %w", i+1, err)
+		}
+		if detail != "" {
+			return diverged(i+1, countLines(want.out), detail), nil
+		}
+	}
+
+	return "", nil
+}
+
+// lineMismatch compares a single line, returning "" when it matches.
+func lineMismatch(act, exp string, header []string, want golden) (string, error) {
+	if act == exp {
+		return "", nil
+	}
+	if strings.Contains(exp, regexMarkerOpen) {
+		re, err := goldenLinePattern(exp)
+		if err != nil {
+			return "", err
+		}
+		if re.MatchString(act) {
+			return "", nil
+		}
+		return fmt.Sprintf("got %q, want %q", act, exp), nil
+	}
+	detail := fmt.Sprintf("got %q, want %q", act, exp)
+	if len(want.fields) > 0 {
+		detail = compareLineFields(act, exp, header, want.fields)
+	}
+	if detail != "" && want.epsilon > 0 && !numericMismatch(act, exp, want.epsilon) {
+		return "", nil
+	}
+
+	return detail, nil
+}
+
+// numericToken splits a line into numbers and runs of other non-space text.
+var numericToken = regexp.MustCompile(`\d+(?:\.\d+)?|[^\s\d]+`)
+
+// numericMismatch reports whether act and exp differ by more than numbers
+// within epsilon of each other. Text tokens must match exactly, and spacing
+// may differ only alongside a differently formatted number (as in a padded
+// table cell), so whitespace-only differences are still mismatches.
+func numericMismatch(act, exp string, epsilon float64) bool {
+	actTokens := numericToken.FindAllString(act, -1)
+	expTokens := numericToken.FindAllString(exp, -1)
+	if len(actTokens) != len(expTokens) {
+		return true
+	}
+	reformatted := false
+	for i := range expTokens {
+		if actTokens[i] == expTokens[i] {
+			continue
+		}
+		a, errA := strconv.ParseFloat(actTokens[i], 64)
+		e, errE := strconv.ParseFloat(expTokens[i], 64)
+		// allow for float representation error at the epsilon boundary.
+		if errA != nil || errE != nil || math.Abs(a-e) > epsilon+1e-9 {
+			return true
+		}
+		reformatted = true
+	}
+
+	return !reformatted
+}
+
+// countMatchingLines compares actual to expected index by index, returning how
+// many of the expected (non-trailing) lines match.
+func countMatchingLines(actual []byte, want golden) (matched, total int, err error) {
+	act := strings.Split(strings.TrimSuffix(string(actual), "\n"), "\n")
+	exp := strings.Split(strings.TrimSuffix(string(want.out), "\n"), "\n")
+
+	var header []string
+	for i := range exp {
+		if cells := tableCells(exp[i]); cells != nil && !anyNumeric(cells) {
+			header = cells
+		}
+		if i >= len(act) {
+			continue
+		}
+		detail, err := lineMismatch(act[i], exp[i], header, want)
+		if err != nil {
+			return 0, 0, fmt.Errorf("golden line %d: %w", i+1, err)
+		}
+		if detail == "" {
+			matched++
+		}
+	}
+
+	// extra output lines count against the total, so nothing can be appended for free.
+	return matched, max(len(act), countLines(want.out)), nil
+}
+
+// countLines counts lines, not counting the empty "line" after a final newline.
+func countLines(b []byte) int {
+	return len(strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"))
+}
+
+// diverged describes where a comparison stopped matching, and how much of the
+// expected output that covers.
+func diverged(line, total int, detail string) string {
+	return fmt.Sprintf("diverged at line %d of ~%d: %s", line, total, detail)
+}
+
+// compareLineFields returns "" when the lines match under the field specs.
+func compareLineFields(act, exp string, header []string, fields map[string]fieldSpec) string {
+	// metric lines, e.g. "Average wait: 3.40".
+	if expLabel, expVal, ok := strings.Cut(exp, ": "); ok {
+		actLabel, actVal, ok := strings.Cut(act, ": ")
+		if !ok || actLabel != expLabel {
+			return fmt.Sprintf("got %q, want %q", act, exp)
+		}
+		return compareField(expLabel, actVal, expVal, fields)
+	}
+
+	// schedule table rows, e.g. "| A1 | 3 | 4 |".
+	expCells, actCells := tableCells(exp), tableCells(act)
+	if expCells == nil || len(expCells) != len(actCells) {
+		return fmt.Sprintf("got %q, want %q", act, exp)
+	}
+	for j := range expCells {
+		if actCells[j] == expCells[j] {
+			continue
+		}
+		column := strconv.Itoa(j + 1)
+		if j < len(header) {
+			column = header[j]
+		}
+		if detail := compareField(column, actCells[j], expCells[j], fields); detail != "" {
+			return "row " + expCells[0] + ", " + detail
+		}
+	}
+
+	return ""
+}
+
+func compareField(name, act, exp string, fields map[string]fieldSpec) string {
+	mismatch := fmt.Sprintf("%s: got %s, want %s", name, act, exp)
+	spec, ok := fields[name]
+	if !ok {
+		if act == exp {
+			return ""
+		}
+		return mismatch
+	}
+	a, errA := strconv.ParseFloat(strings.TrimSpace(act), 64)
+	e, errE := strconv.ParseFloat(strings.TrimSpace(exp), 64)
+	if errA != nil || errE != nil {
+		return mismatch
+	}
+	if spec.Precision != nil {
+		scale := math.Pow(10, float64(*spec.Precision))
+		a, e = math.Round(a*scale)/scale, math.Round(e*scale)/scale
+	}
+	// allow for float representation error at the tolerance boundary.
+	if diff := math.Abs(a - e); diff > spec.Tolerance+1e-9 {
+		return fmt.Sprintf("%s (off by %g)", mismatch, math.Round(diff*1e6)/1e6)
+	}
+
+	return ""
+}
+
+// tableCells splits a "| a | b |" row into trimmed cells, or returns nil.
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	if len(line) < 2 || !strings.HasPrefix(line, "|") || !strings.HasSuffix(line, "|") {
+		return nil
+	}
+	cells := strings.Split(line[1:len(line)-1], "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+
+	return cells
+}
+
+func anyNumeric(cells []string) bool {
+	for _, c := range cells {
+		if _, err := strconv.ParseFloat(c, 64); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Golden (expected output) files are compared literally, except for
+// {{regex:PATTERN}} markers, which match PATTERN (RE2 syntax) against the
+// corresponding part of the actual output line. For example:
+//
+//	Generated at {{regex:\d{4}-\d{2}-\d{2}}} by the scheduler
+//
+// Markers never span lines. A marker ends at the last "}}" of a run of closing
+// braces, so PATTERN may end in a repetition like \d{2}.
+const (
+	regexMarkerOpen  = "{{regex:"
+	regexMarkerClose = "}}"
+)
+
+// matchGolden reports whether actual matches the expected golden output.
+func matchGolden(actual, expected []byte) (bool, error) {
+	if !bytes.Contains(expected, []byte(regexMarkerOpen)) {
+		return bytes.Equal(actual, expected), nil
+	}
+
+	expLines := strings.Split(string(expected), "\n")
+	actLines := strings.Split(string(actual), "\n")
+	if len(expLines) != len(actLines) {
+		return false, nil
+	}
+	for i := range expLines {
+		if !strings.Contains(expLines[i], regexMarkerOpen) {
+			if expLines[i] != actLines[i] {
+				return false, nil
+			}
+			continue
+		}
+		re, err := goldenLinePattern(expLines[i])
+		if err != nil {
+			return false, fmt.Errorf("golden line %d: %w", i+1, err)
+		}
+		if !re.MatchString(actLines[i]) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// goldenLinePattern compiles a golden line into an anchored regexp, quoting
+// everything outside of the regex markers.
+func goldenLinePattern(line string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for {
+		start := strings.Index(line, regexMarkerOpen)
+		if start < 0 {
+			break
+		}
+		patStart := start + len(regexMarkerOpen)
+		end := strings.Index(line[patStart:], regexMarkerClose)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated %q marker", regexMarkerOpen)
+		}
+		end += patStart
+		for end+len(regexMarkerClose) < len(line) && line[end+len(regexMarkerClose)] == '}' {
+			end++
+		}
+		sb.WriteString(regexp.QuoteMeta(line[:start]))
+		sb.WriteString("(?:" + line[patStart:end] + ")")
+		line = line[end+len(regexMarkerClose):]
+	}
+	sb.WriteString(regexp.QuoteMeta(line))
+	sb.WriteString("$")
+
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,252 @@
+package grader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	const in = "\x1b[1;31mFCFS\x1b[0m  \r\nrow\t\r\n\x1b]0;title\x07done\n\n\n"
+	tests := []struct {
+		steps []string
+		want  string
+	}{
+		{steps: nil, want: "FCFS\nrow\ndone\n"},
+		{steps: []string{"ansi"}, want: "FCFS  \r\nrow\t\r\ndone\n\n\n"},
+		{steps: []string{"eol"}, want: "\x1b[1;31mFCFS\x1b[0m  \nrow\t\n\x1b]0;title\x07done\n\n\n"},
+		{steps: []string{"trailing"}, want: "\x1b[1;31mFCFS\x1b[0m\nrow\n\x1b]0;title\x07done\n\n\n"},
+		{steps: []string{"newline"}, want: "\x1b[1;31mFCFS\x1b[0m  \r\nrow\t\r\n\x1b]0;title\x07done\n"},
+		{steps: []string{}, want: in},
+	}
+	for _, tt := range tests {
+		if got := string(normalizeOutput([]byte(in), tt.steps)); got != tt.want {
+			t.Errorf("normalizeOutput(%q) = %q, want %q", tt.steps, got, tt.want)
+		}
+	}
+	// "newline" adds a missing final newline, too.
+	if got := string(normalizeOutput([]byte("a"), []string{"newline"})); got != "a\n" {
+		t.Errorf("normalizeOutput(newline) = %q, want %q", got, "a\n")
+	}
+}
+
+func TestNormalizeLine(t *testing.T) {
+	// a line normalizes as it would as part of the output.
+	for _, line := range []string{"\x1b[32mok\x1b[0m \r", "plain", "tab\t", "\x1b[2K\rprogress"} {
+		for _, steps := range [][]string{nil, {"ansi"}, {"eol"}, {"trailing"}, {"ansi", "trailing"}} {
+			want := strings.TrimSuffix(string(normalizeOutput([]byte(line+"\n"), steps)), "\n")
+			if got := normalizeLine(line, steps); got != want {
+				t.Errorf("normalizeLine(%q, %q) = %q, want %q", line, steps, got, want)
+			}
+		}
+	}
+}
+
+func TestTrimPreamble(t *testing.T) {
+	tests := []struct {
+		name, actual, expected, want string
+		trimmed                      int
+	}{
+		{name: "none", actual: "FCFS\nA\n", expected: "FCFS\nA\n", want: "FCFS\nA\n"},
+		{name: "banner", actual: "Scheduler v1\n\nFCFS\nA\n", expected: "FCFS\nA\n", want: "FCFS\nA\n", trimmed: 2},
+		{name: "prompt", actual: "Enter algorithm: FCFS\nA\n", expected: "FCFS\nA\n", want: "FCFS\nA\n", trimmed: 1},
+		{name: "earliest anchor", actual: "x\nFCFS\nFCFS\n", expected: "FCFS\nA\n", want: "FCFS\nFCFS\n", trimmed: 1},
+		{name: "regex anchor", actual: "hi\nrun 42\nA\n", expected: "run {{regex:\\d+}}\nA\n", want: "run 42\nA\n", trimmed: 1},
+		{name: "no anchor", actual: "other\n", expected: "FCFS\n", want: "other\n"},
+		{name: "blank first line", actual: "x\n\nA\n", expected: "\nA\n", want: "x\n\nA\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, trimmed := trimPreamble([]byte(tt.actual), []byte(tt.expected))
+			if string(got) != tt.want || trimmed != tt.trimmed {
+				t.Errorf("trimPreamble() = %q, %d, want %q, %d", got, trimmed, tt.want, tt.trimmed)
+			}
+		})
+	}
+}
+
+func TestGoldenLinePattern(t *testing.T) {
+	tests := []struct {
+		line    string
+		match   []string
+		nomatch []string
+		wantErr bool
+	}{
+		{line: `Generated at {{regex:\d{4}-\d{2}-\d{2}}} by the scheduler`,
+			match:   []string{"Generated at 2024-01-31 by the scheduler"},
+			nomatch: []string{"Generated at 24-01-31 by the scheduler", "Generated at 2024-01-31 by the scheduler!"}},
+		// text outside the markers is literal.
+		{line: `Avg (ms): {{regex:[0-9.]+}} [{{regex:\w+}}]`,
+			match:   []string{"Avg (ms): 3.40 [ok]"},
+			nomatch: []string{"Avg xms): 3.40 [ok]", "Avg (ms): 3.40 ok"}},
+		{line: `| {{regex:A\d}} | 3 |`, match: []string{"| A1 | 3 |"}, nomatch: []string{"| B1 | 3 |"}},
+		{line: "plain", match: []string{"plain"}, nomatch: []string{"plainer"}},
+		{line: "open {{regex:\\d+", wantErr: true},
+		{line: "bad {{regex:(}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		re, err := goldenLinePattern(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("goldenLinePattern(%q) error = %v, want error %t", tt.line, err, tt.wantErr)
+			continue
+		}
+		for _, s := range tt.match {
+			if !re.MatchString(s) {
+				t.Errorf("goldenLinePattern(%q) doesn't match %q", tt.line, s)
+			}
+		}
+		for _, s := range tt.nomatch {
+			if re.MatchString(s) {
+				t.Errorf("goldenLinePattern(%q) matches %q", tt.line, s)
+			}
+		}
+	}
+}
+
+func TestMatchGolden(t *testing.T) {
+	tests := []struct {
+		name, actual, expected string
+		want, wantErr          bool
+	}{
+		{name: "equal", actual: "a\nb\n", expected: "a\nb\n", want: true},
+		{name: "different", actual: "a\nc\n", expected: "a\nb\n"},
+		{name: "marker", actual: "at 12:30\nb\n", expected: "at {{regex:\\d+:\\d+}}\nb\n", want: true},
+		{name: "marker mismatch", actual: "at noon\nb\n", expected: "at {{regex:\\d+:\\d+}}\nb\n"},
+		{name: "literal line by a marker", actual: "at 12:30\nc\n", expected: "at {{regex:\\d+:\\d+}}\nb\n"},
+		{name: "line count", actual: "at 12:30\n", expected: "at {{regex:\\d+:\\d+}}\nb\n"},
+		{name: "bad pattern", actual: "x\n", expected: "{{regex:(}}\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchGolden([]byte(tt.actual), []byte(tt.expected))
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("matchGolden() = %t, %v, want %t, error %t", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompareGolden(t *testing.T) {
+	two := 2
+	fields := map[string]fieldSpec{
+		"Average wait": {Precision: &two},
+		"Throughput":   {Tolerance: 0.01},
+		"WAIT":         {Tolerance: 0.5},
+	}
+	const table = "| ID | WAIT | TAT |\n| A1 | 3 | 4 |\n"
+	tests := []struct {
+		name     string
+		actual   string
+		want     golden
+		mismatch string // a prefix of the description, "" for a match
+	}{
+		{name: "exact", actual: "a\nb\n", want: golden{out: []byte("a\nb\n")}},
+		{name: "first line", actual: "a\nx\n", want: golden{out: []byte("a\nb\n")}, mismatch: `diverged at line 2 of ~2: got "x", want "b"`},
+		{name: "shorter", actual: "a\n", want: golden{out: []byte("a\nb\n")}, mismatch: `diverged at line 2 of ~2: got "", want "b"`},
+		{name: "precision", actual: "Average wait: 3.404\n", want: golden{out: []byte("Average wait: 3.40\n"), fields: fields}},
+		{name: "precision exceeded", actual: "Average wait: 3.41\n", want: golden{out: []byte("Average wait: 3.40\n"), fields: fields},
+			mismatch: "diverged at line 1 of ~1: Average wait: got 3.41, want 3.40 (off by 0.01)"},
+		{name: "tolerance", actual: "Throughput: 0.51\n", want: golden{out: []byte("Throughput: 0.50\n"), fields: fields}},
+		{name: "tolerance exceeded", actual: "Throughput: 0.52\n", want: golden{out: []byte("Throughput: 0.50\n"), fields: fields},
+			mismatch: "diverged at line 1 of ~1: Throughput: got 0.52, want 0.50"},
+		{name: "unlisted field", actual: "Average turnaround: 7.1\n", want: golden{out: []byte("Average turnaround: 7.0\n"), fields: fields},
+			mismatch: "diverged at line 1 of ~1: Average turnaround: got 7.1, want 7.0"},
+		{name: "metric label", actual: "Avg wait: 3.40\n", want: golden{out: []byte("Average wait: 3.40\n"), fields: fields},
+			mismatch: `diverged at line 1 of ~1: got "Avg wait: 3.40"`},
+		{name: "table column", actual: "| ID | WAIT | TAT |\n| A1 | 3.5 | 4 |\n", want: golden{out: []byte(table), fields: fields}},
+		{name: "table column exceeded", actual: "| ID | WAIT | TAT |\n| A1 | 3 | 5 |\n", want: golden{out: []byte(table), fields: fields},
+			mismatch: "diverged at line 2 of ~2: row A1, TAT: got 5, want 4"},
+		{name: "epsilon", actual: "wait 3.41 of 10\n", want: golden{out: []byte("wait 3.40 of 10\n"), epsilon: 0.01}},
+		{name: "epsilon padded cell", actual: "| A1 | 3.0 |\n", want: golden{out: []byte("| A1 |   3 |\n"), epsilon: 0.01}},
+		{name: "epsilon exceeded", actual: "wait 3.42 of 10\n", want: golden{out: []byte("wait 3.40 of 10\n"), epsilon: 0.01},
+			mismatch: `diverged at line 1 of ~1: got "wait 3.42 of 10"`},
+		{name: "epsilon text", actual: "wait 3.40 of 11 procs\n", want: golden{out: []byte("wait 3.40 of 11 jobs\n"), epsilon: 0.01},
+			mismatch: "diverged at line 1 of ~1"},
+		{name: "epsilon whitespace", actual: "wait  3.40\n", want: golden{out: []byte("wait 3.40\n"), epsilon: 0.01},
+			mismatch: "diverged at line 1 of ~1"},
+		{name: "regex with fields", actual: "run 7\nAverage wait: 3.401\n", want: golden{out: []byte("run {{regex:\\d+}}\nAverage wait: 3.40\n"), fields: fields}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compareGolden([]byte(tt.actual), tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == "") != (tt.mismatch == "") || !strings.HasPrefix(got, tt.mismatch) {
+				t.Errorf("compareGolden() = %q, want %q", got, tt.mismatch)
+			}
+		})
+	}
+}
+
+func TestNumericMismatch(t *testing.T) {
+	tests := []struct {
+		act, exp string
+		epsilon  float64
+		want     bool
+	}{
+		{act: "3.41", exp: "3.40", epsilon: 0.01},
+		// the boundary, despite 3.41-3.40 > 0.01 in floating point.
+		{act: "x 3.41 y", exp: "x 3.40 y", epsilon: 0.01},
+		{act: "3.42", exp: "3.40", epsilon: 0.01, want: true},
+		{act: "3 4", exp: "3 4 5", epsilon: 1, want: true},
+		{act: "a 3", exp: "b 3", epsilon: 1, want: true},
+		// identical tokens with different spacing aren't a numeric difference.
+		{act: "a  3", exp: "a 3", epsilon: 1, want: true},
+	}
+	for _, tt := range tests {
+		if got := numericMismatch(tt.act, tt.exp, tt.epsilon); got != tt.want {
+			t.Errorf("numericMismatch(%q, %q, %g) = %t, want %t", tt.act, tt.exp, tt.epsilon, got, tt.want)
+		}
+	}
+}
+
+func TestCountMatchingLines(t *testing.T) {
+	tests := []struct {
+		name           string
+		actual         string
+		want           golden
+		matched, total int
+		wantErr        bool
+	}{
+		{name: "all", actual: "a\nb\nc\n", want: golden{out: []byte("a\nb\nc\n")}, matched: 3, total: 3},
+		{name: "one off", actual: "a\nx\nc\n", want: golden{out: []byte("a\nb\nc\n")}, matched: 2, total: 3},
+		// index by index: a missing line shifts the rest out of place.
+		{name: "missing line", actual: "a\nc\n", want: golden{out: []byte("a\nb\nc\n")}, matched: 1, total: 3},
+		{name: "extra lines", actual: "a\nb\nc\nd\ne\n", want: golden{out: []byte("a\nb\nc\n")}, matched: 3, total: 5},
+		{name: "no final newline", actual: "a\nb", want: golden{out: []byte("a\nb\n")}, matched: 2, total: 2},
+		{name: "regex", actual: "at 9\nb\n", want: golden{out: []byte("at {{regex:\\d}}\nb\n")}, matched: 2, total: 2},
+		{name: "epsilon", actual: "3.41\n3.5\n", want: golden{out: []byte("3.40\n3.40\n"), epsilon: 0.01}, matched: 1, total: 2},
+		{name: "bad pattern", actual: "x\n", want: golden{out: []byte("{{regex:(}}\n")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, total, err := countMatchingLines([]byte(tt.actual), tt.want)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("countMatchingLines() error = %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && (matched != tt.matched || total != tt.total) {
+				t.Errorf("countMatchingLines() = %d/%d, want %d/%d", matched, total, tt.matched, tt.total)
+			}
+		})
+	}
+}
+
+func TestLoadFieldSpecs(t *testing.T) {
+	for _, name := range []string{"fcfs", "sjf", "sjfp", "rr", "priority", "mlfq"} {
+		if _, err := loadFieldSpecs(name, nil); err != nil {
+			t.Errorf("loadFieldSpecs(%q) error = %v", name, err)
+		}
+	}
+	if specs, err := loadFieldSpecs("none", nil); specs != nil || err != nil {
+		t.Errorf("loadFieldSpecs() without metadata = %v, %v", specs, err)
+	}
+	specs, err := loadFieldSpecs("x", []byte(`{"fields": {"WAIT": {"precision": 1, "tolerance": 0.5}}}`))
+	if err != nil || specs["WAIT"].Tolerance != 0.5 || *specs["WAIT"].Precision != 1 {
+		t.Errorf("loadFieldSpecs(override) = %+v, %v", specs, err)
+	}
+	for _, bad := range []string{`{"fields": {"WAIT": {"tolerance": -1}}}`, `{"fields": {"WAIT": {"precision": -1}}}`, `{`} {
+		if _, err := loadFieldSpecs("x", []byte(bad)); err == nil {
+			t.Errorf("loadFieldSpecs(%s): no error", bad)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package grader
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type (
+	// GradeConfig configures Run: the submissions graded, and how. Its fields
+	// are the grade command's flags, e.g. PathToDirs is --dir.
+	GradeConfig struct {
+		Config
+		CanvasConfig
+		SheetsConfig
+		FeedbackConfig
+		// PathToDirs are the submission directories or archives graded,
+		// unless Roster, Repo or Repos says otherwise.
+		PathToDirs []string
+		Roster     string
+		Repo       []string
+		Repos      string
+		Ref        string
+		Before     string
+		Selection
+
+		Gradescope bool
+		List       bool
+		TUI        bool
+
+		Watch         bool
+		WatchInterval time.Duration
+
+		BatchConfig
+	}
+	// Selection selects the rubric items graded, by identifier (see
+	// checkIDs).
+	Selection struct {
+		Only []string
+		Skip []string
+
+		FailFast bool
+	}
+	// BatchConfig is how a batch of submissions is graded and reported.
+	BatchConfig struct {
+		Gradebook      string
+		DetectDupes    bool
+		Similarity     float64
+		SimilarityBase string
+
+		Sample string
+	}
+	// Config is how submissions are graded and their results reported, as
+	// the grade command's flags of the same names set it, e.g. MinGoVersion
+	// is --min-go-version; DefaultConfig is their defaults. MaxOutput and
+	// MemLimit are in MiB.
+	Config struct {
+		Debug     bool
+		DiffLines int
+		Total     bool
+		Summary   bool
+		Verbose   bool
+		Format    string
+		LogFormat string
+		LogFile   string
+
+		MinGoVersion string
+		GoToolchain  string
+		ShowEnv      bool
+		NormalizeTo  int
+		MinScore     float64
+		FailOnError  bool
+
+		Points map[string]int
+
+		Timeout           time.Duration
+		Strict            bool
+		Metrics           bool
+		Structured        bool
+		Normalize         []string
+		Epsilon           float64
+		SkipPreamble      bool
+		Partial           bool
+		Hints             bool
+		Rubric            string
+		MaxOutput         uint64
+		MemLimit          uint64
+		CPULimit          time.Duration
+		Sandbox           string
+		SandboxImage      string
+		ProcLimit         uint64
+		StyleTools        []string
+		Parallel          int
+		Random            int
+		Repeat            int
+		Stress            int
+		StressBudget      time.Duration
+		Robustness        bool
+		Fuzz              time.Duration
+		Race              bool
+		Style             bool
+		Hygiene           bool
+		History           bool
+		MinCommits        int
+		CommitDays        int
+		Forbidden         bool
+		StudentTests      bool
+		TestTimeout       time.Duration
+		Coverage          float64
+		Seed              int64
+		Deadline          string
+		LatePenalty       float64
+		MaxLatePenalty    float64
+		Retries           int
+		BuildCmd          string
+		MainPkg           string
+		Lang              string
+		RunCmd            string
+		Output            string
+		JUnit             string
+		Report            string
+		Record            string
+		NotifyURL         string
+		NotifyFormat      string
+		Leaderboard       string
+		Handle            string
+		HandlesFile       string
+		Badge             string
+		NoCache           bool
+		RerunFailed       bool
+		ReadmeWords       int
+		Module            bool
+		ModulePrefix      string
+		AllowDeps         bool
+		Testdata          string
+		Cases             string
+		TestsURL          string
+		TestsToken        string
+		TestsSecret       string
+		Key               string
+		ReceiptKey        string
+		CheckTimeoutGrace time.Duration
+	}
+	// options are a run's Config, with what it loads and captures for the
+	// reports.
+	options struct {
+		Config
+		// receiptKey is ReceiptKey's, loaded.
+		receiptKey ed25519.PrivateKey
+		// attestation is the signed attestation of this gradebot, with a
+		// receipt key, in each report.
+		attestation string
+		// manifest is the environment the reported submission was graded in.
+		manifest *manifest
+	}
+)
+
+// DefaultConfig is the grade command's defaults.
+func DefaultConfig() Config {
+	return Config{
+		DiffLines:         40,
+		Format:            "table",
+		LogFormat:         "text",
+		MinGoVersion:      "1.21",
+		Timeout:           10 * time.Second,
+		Normalize:         []string{"ansi", "eol", "trailing", "newline"},
+		Epsilon:           0.01,
+		Partial:           true,
+		Hints:             true,
+		MaxOutput:         16,
+		Sandbox:           "none",
+		SandboxImage:      "golang:1.21",
+		StyleTools:        []string{"gofmt", "vet", "staticcheck"},
+		StressBudget:      5 * time.Second,
+		MinCommits:        5,
+		CommitDays:        2,
+		TestTimeout:       2 * time.Minute,
+		LatePenalty:       10,
+		MaxLatePenalty:    100,
+		Lang:              "auto",
+		NotifyFormat:      "auto",
+		ReadmeWords:       100,
+		CheckTimeoutGrace: 300 * time.Millisecond,
+	}
+}
+
+// setup sets up logging, returning where the logs go: --log-file, or stderr.
+func (o *Config) setup() (io.Writer, error) {
+	w := io.Writer(os.Stderr)
+	if o.LogFile != "" {
+		// left open for the rest of the run.
+		f, err := os.OpenFile(o.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	opts := &slog.HandlerOptions{Level: o.logLevel()}
+	if o.LogFormat == "json" {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, opts)))
+	} else {
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	}
+
+	return w, nil
+}
+
+func (o *Config) logLevel() slog.Level {
+	switch {
+	case o.format() == "total":
+		return 10
+	case o.Summary:
+		return slog.LevelWarn
+	case o.Debug:
+		return slog.LevelDebug
+	}
+
+	return slog.LevelInfo
+}
+
+func (o *Config) format() string {
+	if o.Total {
+		return "total"
+	}
+
+	return o.Format
+}
+
+// needsGo is whether grading builds with the grader's Go toolchain: not for
+// C or Python submissions, with a --run-cmd, or in the docker sandbox.
+func (o *Config) needsGo() bool {
+	return o.Lang != "c" && o.Lang != "python" && o.RunCmd == "" && o.Sandbox != sandboxDocker
+}
+
+// gradeOptions loads the rubric config, cases and answer key, and returns the
+// grading options they and the flags select.
+func (o *Config) gradeOptions(ctx context.Context) (Options, error) {
+	if o.Parallel < 0 || o.Random < 0 || o.Repeat < 0 || o.Stress < 0 {
+		return Options{}, errors.New("--parallel, --random, --repeat and --stress must not be negative")
+	}
+	if o.Coverage < 0 || o.Coverage > 100 {
+		return Options{}, fmt.Errorf("--coverage %g is not a percentage", o.Coverage)
+	}
+	var deadline time.Time
+	if o.Deadline != "" {
+		var err error
+		if deadline, err = parseDeadline(o.Deadline); err != nil {
+			return Options{}, err
+		}
+		if o.LatePenalty < 0 || o.MaxLatePenalty < 0 || o.MaxLatePenalty > 100 {
+			return Options{}, errors.New("--late-penalty and --max-late-penalty must be percentages")
+		}
+	}
+	// an empty (not nil) list applies no normalizations.
+	normalize := make([]string, 0, len(o.Normalize))
+	for _, step := range o.Normalize {
+		if step != "none" {
+			normalize = append(normalize, step)
+		}
+	}
+	// one seed for the whole run, so every submission gets the same tables.
+	seed := o.Seed
+	if (o.Random > 0 || o.Stress > 0 || o.Fuzz > 0) && seed == 0 {
+		seed = time.Now().Unix()
+	}
+	toolchain := o.GoToolchain
+	if toolchain != "" {
+		toolchain = "go" + strings.TrimPrefix(toolchain, "go")
+		if !goVersionPattern.MatchString(strings.TrimPrefix(toolchain, "go")) {
+			return Options{}, fmt.Errorf("--go-toolchain %q is not a Go version, e.g. 1.21.5", o.GoToolchain)
+		}
+	}
+	if o.BuildCmd != "" && o.RunCmd == "" {
+		return Options{}, errors.New("--build-cmd requires --run-cmd")
+	}
+	sandbox := o.Sandbox
+	if sandbox == "none" {
+		sandbox = ""
+	}
+	lang := o.Lang
+	if lang == "auto" {
+		lang = ""
+	}
+	if lang != "" && o.RunCmd != "" {
+		return Options{}, errors.New("--lang doesn't apply with --run-cmd")
+	}
+	if sandbox != "" && o.RunCmd != "" {
+		return Options{}, errors.New("--sandbox only builds Go schedulers, not with --run-cmd")
+	}
+	if !limitsSupported && sandbox == "" && (o.MemLimit > 0 || o.CPULimit > 0 || o.ProcLimit > 0) {
+		return Options{}, fmt.Errorf("--mem-limit, --cpu-limit and --proc-limit aren't supported on %s, only Linux and Windows, or with --sandbox=docker", runtime.GOOS)
+	}
+	var cfg rubricConfig
+	if o.Rubric != "" {
+		var err error
+		if cfg, err = loadRubricConfig(o.Rubric); err != nil {
+			return Options{}, err
+		}
+		if err := cfg.validate(); err != nil {
+			return Options{}, fmt.Errorf("invalid rubric config %s: %w", o.Rubric, err)
+		}
+	}
+
+	var forbidden *denyList
+	if o.Forbidden {
+		if forbidden = cfg.Forbidden; forbidden == nil {
+			forbidden = defaultDenyList()
+		}
+	}
+
+	var cases map[string][]schedulerCase
+	if len(cfg.Cases) > 0 {
+		if o.Cases != "" || o.Key != "" || o.TestsURL != "" {
+			return Options{}, fmt.Errorf("rubric config %s has cases, so --cases, --key and --tests-url can't be used", o.Rubric)
+		}
+		var err error
+		if cases, err = cfg.cases(); err != nil {
+			return Options{}, err
+		}
+	}
+	if o.Cases != "" {
+		var err error
+		if cases, err = loadCases(o.Cases); err != nil {
+			return Options{}, err
+		}
+	}
+	var testdata map[string][]byte
+	if o.Testdata != "" {
+		var err error
+		if testdata, err = loadTestdata(o.Testdata); err != nil {
+			return Options{}, err
+		}
+	}
+	points := cfg.Points
+	if o.Key != "" || o.TestsURL != "" {
+		var (
+			key answerKey
+			ok  = true // false when falling back to the embedded testdata
+			err error
+		)
+		if o.Key != "" {
+			key, err = loadAnswerKey(o.Key)
+		} else {
+			key, ok, err = o.remoteAnswerKey(ctx)
+		}
+		if err != nil {
+			return Options{}, err
+		}
+		if ok {
+			slog.Debug("using answer key", slog.String("key", o.Key+o.TestsURL), slog.String("assignment", key.Assignment))
+			cases = key.cases()
+		}
+		// the rubric config's points take precedence over the key's.
+		points = make(map[string]int, len(key.Points)+len(cfg.Points))
+		for label, pts := range key.Points {
+			points[label] = pts
+		}
+		for label, pts := range cfg.Points {
+			points[label] = pts
+		}
+	}
+	if len(o.Points) > 0 {
+		merged := make(map[string]int, len(points)+len(o.Points))
+		for label, pts := range points {
+			merged[label] = pts
+		}
+		ids := checkIDs()
+		for _, id := range sortedKeys(o.Points) {
+			label, ok := ids[id]
+			if !ok {
+				return Options{}, fmt.Errorf("--points: unknown check %q (known: %s)", id, strings.Join(sortedKeys(ids), ", "))
+			}
+			if o.Points[id] < 0 {
+				return Options{}, fmt.Errorf("--points: %s has negative points %d", id, o.Points[id])
+			}
+			merged[label] = o.Points[id]
+		}
+		points = merged
+	}
+	if len(cfg.checks) > 0 {
+		merged := make(map[string]int, len(points)+len(cfg.checks))
+		for _, sc := range cfg.checks {
+			merged[sc.Label] = sc.Points
+		}
+		for label, pts := range points {
+			merged[label] = pts
+		}
+		points = merged
+	}
+
+	return Options{
+		OnResult: func(r Result) {
+			slog.Debug("check complete", slog.String("check", r.Label), slog.Int("awarded", r.Awarded), slog.Int("possible", r.Possible))
+		},
+		TimeoutGrace: o.CheckTimeoutGrace,
+		Timeout:      o.Timeout,
+		Strict:       o.Strict,
+		Structured:   o.Structured,
+		Metrics:      o.Metrics,
+		Epsilon:      o.Epsilon,
+		Normalize:    normalize,
+		Style:        o.Style,
+		StyleTools:   o.StyleTools,
+		Partial:      o.Partial,
+		SkipPreamble: o.SkipPreamble,
+		Debug:        o.Debug,
+		DiffLines:    o.DiffLines,
+		Points:       points,
+		Hints:        o.Hints,
+		ItemHints:    cfg.Hints,
+		Tolerances:   cfg.Tolerances,
+		Cases:        cases,
+		Testdata:     testdata,
+		ScriptChecks: cfg.checks,
+		Module:       o.Module || o.ModulePrefix != "",
+		ModulePrefix: o.ModulePrefix,
+		AllowDeps:    o.AllowDeps,
+		ReadmeWords:  o.ReadmeWords,
+		NoCache:      o.NoCache,
+		RerunFailed:  o.RerunFailed,
+		Retries:      o.Retries,
+		Deadline:     deadline,
+		LatePenalty:  o.LatePenalty,
+		MaxPenalty:   o.MaxLatePenalty,
+		Parallel:     o.Parallel,
+		Random:       o.Random,
+		Seed:         seed,
+		Repeat:       o.Repeat,
+		Stress:       o.Stress,
+		StressBudget: o.StressBudget,
+		Robustness:   o.Robustness,
+		Fuzz:         o.Fuzz,
+		Race:         o.Race,
+		Hygiene:      o.Hygiene,
+		History:      o.History,
+		MinCommits:   o.MinCommits,
+		CommitDays:   o.CommitDays,
+		Forbidden:    forbidden,
+		StudentTests: o.StudentTests,
+		TestTimeout:  o.TestTimeout,
+		Coverage:     o.Coverage,
+		BuildCmd:     strings.Fields(o.BuildCmd),
+		RunCmd:       strings.Fields(o.RunCmd),
+		Lang:         lang,
+		MainPkg:      o.MainPkg,
+		GoToolchain:  toolchain,
+		MaxOutput:    o.MaxOutput << 20,
+		MemLimit:     o.MemLimit << 20,
+		CPULimit:     o.CPULimit,
+		ProcLimit:    o.ProcLimit,
+		Sandbox:      sandbox,
+		SandboxImage: o.SandboxImage,
+		LogLevel:     o.logLevel(),
+		LogJSON:      o.LogFormat == "json",
+	}, nil
+}
@@ -0,0 +1,58 @@
+package grader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CheckDeterminism runs the scheduler on each embedded input n times,
+// awarding proportional credit for each whose runs all print the same, to
+// catch map iteration order and uninitialized value bugs.
+func CheckDeterminism(result Result, n int) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		var (
+			stable  int
+			reports []string
+		)
+		for _, run := range goldenRuns {
+			in := c.opts.fixture(run.in, embeddedInputs[run.in])
+			name := strings.Join(run.args, " ")
+			first := execScheduler(c, in, run.args)
+			differs := ""
+			for i := 2; i <= n && differs == ""; i++ {
+				if c.ctx.Err() != nil {
+					return result, c.ctx.Err()
+				}
+				again := execScheduler(c, in, run.args)
+				switch {
+				case (first.err == nil) != (again.err == nil), first.timedOut != again.timedOut:
+					differs = fmt.Sprintf("run %d failed differently than run 1", i)
+				case !bytes.Equal(again.stdout, first.stdout):
+					differs = fmt.Sprintf("output differed between runs 1 and %d, %s", i, firstLineMismatch(again.stdout, first.stdout))
+				}
+			}
+			if differs != "" {
+				reports = append(reports, name+": "+differs)
+				continue
+			}
+			stable++
+		}
+
+		result.Awarded = int(math.Round(float64(result.Possible) * float64(stable) / float64(len(goldenRuns))))
+		if stable < len(goldenRuns) {
+			result.Message = strings.Join(reports, "\n")
+			return result, errors.New("nondeterministic output")
+		}
+		result.Message = fmt.Sprintf("same output in %d runs of each input", n)
+
+		return result, nil
+	}
+}
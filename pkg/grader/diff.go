@@ -0,0 +1,199 @@
+package grader
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxLCSCells bounds the LCS table; larger inputs fall back to a positional diff.
+const maxLCSCells = 4_000_000
+
+type diffOp struct {
+	kind byte // ' ', '-' (expected only), or '+' (actual only)
+	line string
+}
+
+// unifiedDiff renders a line-based unified diff of expected vs. actual, with
+// expected lines in green and actual lines in red when color is set. With a
+// positive limit, only whole hunks within that many lines are shown (always
+// at least the first), followed by a count of what was left out.
+func unifiedDiff(name string, expected, actual []byte, color bool, limit int) string {
+	ops := diffLines(splitLines(expected), splitLines(actual))
+	paint := func(c text.Color, s string) string {
+		if !color {
+			return s
+		}
+		return c.Sprint(s)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(paint(text.Bold, "--- expected "+name) + "\n")
+	sb.WriteString(paint(text.Bold, "+++ actual "+name) + "\n")
+	all := hunks(ops)
+	shown := 0
+	for i, h := range all {
+		if limit > 0 && i > 0 && shown+1+h.to-h.from > limit {
+			left := 0
+			for _, rest := range all[i:] {
+				left += 1 + rest.to - rest.from
+			}
+			sb.WriteString(fmt.Sprintf("... %d more hunk(s), %d line(s); see all with --diff-lines=0\n", len(all)-i, left))
+			break
+		}
+		shown += 1 + h.to - h.from
+		sb.WriteString(paint(text.FgCyan, fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.expStart, h.expLen, h.actStart, h.actLen)) + "\n")
+		for _, op := range ops[h.from:h.to] {
+			switch op.kind {
+			case '-':
+				sb.WriteString(paint(text.FgGreen, "-"+op.line) + "\n")
+			case '+':
+				sb.WriteString(paint(text.FgRed, "+"+op.line) + "\n")
+			default:
+				sb.WriteString(" " + op.line + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// diffLines computes an edit script from a to b via longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	if len(a)*len(b) > maxLCSCells {
+		return positionalDiff(a, b)
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var (
+		ops  []diffOp
+		i, j int
+	)
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops
+}
+
+// positionalDiff compares line by line, for inputs too large for LCS.
+func positionalDiff(a, b []string) []diffOp {
+	var ops []diffOp
+	for i := 0; i < max(len(a), len(b)); i++ {
+		switch {
+		case i < len(a) && i < len(b) && a[i] == b[i]:
+			ops = append(ops, diffOp{' ', a[i]})
+		default:
+			if i < len(a) {
+				ops = append(ops, diffOp{'-', a[i]})
+			}
+			if i < len(b) {
+				ops = append(ops, diffOp{'+', b[i]})
+			}
+		}
+	}
+
+	return ops
+}
+
+type hunk struct {
+	from, to         int // range of ops
+	expStart, expLen int
+	actStart, actLen int
+}
+
+// hunks groups changed ops with diffContext lines of surrounding context.
+func hunks(ops []diffOp) []hunk {
+	var (
+		out     []hunk
+		expLine = make([]int, len(ops)+1) // 1-based line numbers before each op
+		actLine = make([]int, len(ops)+1)
+	)
+	expLine[0], actLine[0] = 1, 1
+	for i, op := range ops {
+		expLine[i+1], actLine[i+1] = expLine[i], actLine[i]
+		if op.kind != '+' {
+			expLine[i+1]++
+		}
+		if op.kind != '-' {
+			actLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+		from := max(0, i-diffContext)
+		to := i
+		// extend while the next change is within 2*diffContext unchanged lines.
+		for k := i; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				to = k + 1
+			} else if k-to >= 2*diffContext {
+				break
+			}
+		}
+		to = min(len(ops), to+diffContext)
+		h := hunk{from: from, to: to, expStart: expLine[from], actStart: actLine[from]}
+		h.expLen = expLine[to] - expLine[from]
+		h.actLen = actLine[to] - actLine[from]
+		out = append(out, h)
+		i = to - 1
+	}
+
+	return out
+}
+
+// formatDiff renders the mismatch for the scheduler run named by args.
+// Escape characters left in the output (with --strict) are shown as "\x1b"
+// rather than allowed to garble the terminal.
+func formatDiff(opts Options, args []string, expected, actual []byte) string {
+	return unifiedDiff("("+strings.Join(args, " ")+")", expected, bytes.ReplaceAll(actual, []byte("\x1b"), []byte(`\x1b`)),
+		opts.colorDiff(), opts.DiffLines)
+}
@@ -0,0 +1,199 @@
+package grader
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+const (
+	// shingleSize is the number of consecutive tokens hashed together when
+	// comparing submissions.
+	shingleSize = 8
+	// dupeThreshold is the similarity at which a pair of submissions is flagged.
+	dupeThreshold = 0.9
+)
+
+// dupePair is a pair of submissions with (nearly) the same source.
+type dupePair struct {
+	A          string  `json:"a"`
+	B          string  `json:"b"`
+	Similarity float64 `json:"similarity"`
+}
+
+type fingerprint struct {
+	dir      string
+	shingles map[uint64]struct{}
+}
+
+// fingerprintDir tokenizes the submission's .go files, which drops comments
+// and whitespace, and hashes each run of shingleSize tokens.
+func fingerprintDir(dir string) (fingerprint, error) {
+	fp := fingerprint{dir: dir, shingles: make(map[uint64]struct{})}
+	var tokens []string
+	err := scanGoSource(dir, func(_ token.Position, _ token.Token, lit string) {
+		tokens = append(tokens, lit)
+	})
+	if err != nil {
+		return fp, err
+	}
+
+	for i := 0; i+shingleSize <= len(tokens); i++ {
+		fp.shingles[hashTokens(tokens[i:i+shingleSize])] = struct{}{}
+	}
+
+	return fp, nil
+}
+
+// scanGoSource calls fn with each token of the .go files under dir, but for
+// comments and the semicolons inserted at line breaks, skipping hidden, _ and
+// vendor directories. lit is the token's text.
+func scanGoSource(dir string, fn func(pos token.Position, tok token.Token, lit string)) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_") || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		var s scanner.Scanner
+		fset := token.NewFileSet()
+		// syntax errors don't matter here, the tokens are all that's compared.
+		s.Init(fset.AddFile(filepath.ToSlash(rel), -1, len(src)), src, nil, 0)
+		for {
+			pos, tok, lit := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			// automatically inserted semicolons follow the line breaks, not the code.
+			if tok == token.SEMICOLON && lit == "\n" {
+				continue
+			}
+			if lit == "" {
+				lit = tok.String()
+			}
+			fn(fset.Position(pos), tok, lit)
+		}
+		return nil
+	})
+}
+
+// hashTokens hashes a run of tokens.
+func hashTokens(tokens []string) uint64 {
+	h := fnv.New64a()
+	for _, t := range tokens {
+		_, _ = h.Write([]byte(t))
+		_, _ = h.Write([]byte{0})
+	}
+
+	return h.Sum64()
+}
+
+// similarity is the Jaccard index of the fingerprints' shingles.
+func similarity(a, b fingerprint) float64 {
+	if len(a.shingles) > len(b.shingles) {
+		a, b = b, a
+	}
+	shared := 0
+	for s := range a.shingles {
+		if _, ok := b.shingles[s]; ok {
+			shared++
+		}
+	}
+	union := len(a.shingles) + len(b.shingles) - shared
+	if union == 0 {
+		return 0
+	}
+
+	return float64(shared) / float64(union)
+}
+
+// findDuplicates compares every pair of submissions, returning those at least
+// dupeThreshold similar, most similar first.
+func findDuplicates(subs []submission) []dupePair {
+	var fps []fingerprint
+	for _, s := range subs {
+		fp, err := fingerprintDir(s.dir)
+		if err != nil {
+			slog.Warn("skipping duplicate detection", slog.String("dir", s.name()), slog.String("err", err.Error()))
+			continue
+		}
+		fp.dir = s.name()
+		if len(fp.shingles) > 0 {
+			fps = append(fps, fp)
+		}
+	}
+
+	var pairs []dupePair
+	for i := range fps {
+		for j := i + 1; j < len(fps); j++ {
+			if sim := similarity(fps[i], fps[j]); sim >= dupeThreshold {
+				pairs = append(pairs, dupePair{A: fps[i].dir, B: fps[j].dir, Similarity: sim})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Similarity > pairs[j].Similarity })
+
+	return pairs
+}
+
+// printDuplicates reports the flagged pairs of submissions.
+func printDuplicates(w io.Writer, opts options, pairs []dupePair) {
+	switch opts.format() {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Duplicates []dupePair `json:"duplicates"`
+		}{append([]dupePair{}, pairs...)}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	case "total", "tap", "github":
+		// stdout is for scores (and logging is quieted), so flag them on stderr.
+		for _, p := range pairs {
+			fmt.Fprintf(os.Stderr, "possible duplicate submissions: %s %s (%.0f%% similar)\n", p.A, p.B, 100*p.Similarity)
+		}
+	default:
+		t := table.NewWriter()
+		t.SetTitle("Possible duplicate submissions")
+		t.AppendHeader(table.Row{"Submission", "Submission", "Similarity"})
+		t.SetStyle(table.StyleRounded)
+		t.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 3, Align: text.AlignRight},
+		})
+		for _, p := range pairs {
+			sim := fmt.Sprintf("%.0f%%", 100*p.Similarity)
+			if p.Similarity == 1 {
+				sim = "identical"
+			}
+			t.AppendRow(table.Row{p.A, p.B, sim})
+		}
+		if len(pairs) == 0 {
+			t.AppendRow(table.Row{"none found", "", ""})
+		}
+		fmt.Fprintln(w, opts.render(t))
+	}
+}
@@ -0,0 +1,275 @@
+package grader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// goToolchain describes the Go toolchain found in PATH.
+type goToolchain struct {
+	path    string
+	version string // e.g. "1.21.5"
+}
+
+func detectGoToolchain() (goToolchain, error) {
+	var tc goToolchain
+	path, err := exec.LookPath("go")
+	if err != nil {
+		return tc, err
+	}
+	tc.path = path
+	// the installed toolchain's version, not the one a go.mod in the
+	// working directory would switch to.
+	cmd := exec.Command(path, "env", "GOVERSION")
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	out, err := cmd.Output()
+	if err != nil {
+		return tc, fmt.Errorf("determining go version: %w", err)
+	}
+	tc.version = strings.TrimPrefix(strings.TrimSpace(string(out)), "go")
+
+	return tc, nil
+}
+
+// checkMinGoVersion returns an error when the toolchain is older than minimum.
+// An empty minimum disables the check.
+func checkMinGoVersion(tc goToolchain, minimum string) error {
+	if minimum == "" {
+		return nil
+	}
+	if tc.path == "" {
+		return errors.New("go executable not found in path")
+	}
+	if compareGoVersions(tc.version, minimum) < 0 {
+		return fmt.Errorf("go %s is older than the required minimum go %s; please upgrade your Go toolchain", tc.version, minimum)
+	}
+
+	return nil
+}
+
+// goCommand is the go command running args in the submission's directory,
+// as the pinned toolchain, if any.
+func (c *Context) goCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = c.srcDir
+	if c.opts.GoToolchain != "" {
+		cmd.Env = append(os.Environ(), "GOTOOLCHAIN="+c.opts.GoToolchain)
+	}
+
+	return cmd
+}
+
+// buildGoVersion is the version of the Go building the submission (see
+// goVersionFor).
+func (c *Context) buildGoVersion() string {
+	return goVersionFor(c.opts)
+}
+
+// goVersionFor is the version of the Go building submissions with opts: the
+// pinned toolchain's, or else the installed one's, if found.
+func goVersionFor(opts Options) string {
+	if opts.GoToolchain != "" {
+		return strings.TrimPrefix(opts.GoToolchain, "go")
+	}
+	tc, _ := detectGoToolchain()
+
+	return tc.version
+}
+
+// toolchainMismatch explains a failed build that's down to the Go building it
+// rather than the submission: go.mod's go directive is newer than the pinned
+// toolchain (the student's Go is too new for the course) or the installed
+// one (the grader's is too old), the pinned toolchain couldn't be had, or the
+// installation is broken. It's "" for any other failure.
+func toolchainMismatch(c *Context, stderr string) string {
+	// the go command's error is its last line, after any progress.
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	switch {
+	case strings.Contains(stderr, "does not match go tool version"):
+		return "the Go installation is broken, its compiler and standard library differing (" + last + "): reinstall Go"
+	case c.opts.GoToolchain != "" && strings.Contains(stderr, "go: download "+c.opts.GoToolchain):
+		return fmt.Sprintf("could not get the pinned Go toolchain %s (%s)", c.opts.GoToolchain, last)
+	}
+	b, err := os.ReadFile(filepath.Join(c.srcDir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	declared, building := parseGoMod(b).goVersion, c.buildGoVersion()
+	if declared == "" || building == "" || compareGoVersions(declared, building) <= 0 {
+		return ""
+	}
+	if c.opts.GoToolchain != "" {
+		return fmt.Sprintf("your Go is too new: go.mod says go %s, but the course builds with go %s; run go mod edit -go=%s", declared, building, building)
+	}
+
+	return fmt.Sprintf("your Go is too old: go.mod says go %s, but go %s is installed; install go %s or later", declared, building, declared)
+}
+
+// compareGoVersions compares dotted Go versions like "1.21.5" and "1.22",
+// ignoring any pre-release suffix ("1.22rc1" compares as "1.22").
+func compareGoVersions(a, b string) int {
+	pa, pb := goVersionParts(a), goVersionParts(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func goVersionParts(v string) []int {
+	v = strings.TrimPrefix(v, "go")
+	if i := strings.IndexFunc(v, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+
+	return parts
+}
+
+func printEnv(minGoVersion, goToolchain string) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleRounded)
+	t.AppendHeader(table.Row{"Environment", "Value"})
+	t.AppendRow(table.Row{"OS/Arch", runtime.GOOS + "/" + runtime.GOARCH})
+
+	tc, err := detectGoToolchain()
+	if err != nil {
+		t.AppendRow(table.Row{"Go toolchain", err.Error()})
+	} else {
+		t.AppendRow(table.Row{"Go toolchain", "go" + tc.version + " (" + tc.path + ")"})
+	}
+	minStatus := "disabled"
+	if minGoVersion != "" {
+		minStatus = "go" + minGoVersion + " (ok)"
+		if err := checkMinGoVersion(tc, minGoVersion); err != nil {
+			minStatus = "go" + minGoVersion + " (NOT MET)"
+		}
+	}
+	t.AppendRow(table.Row{"Minimum Go", minStatus})
+	pinned := "none (the installed Go builds)"
+	if goToolchain != "" {
+		pinned = "go" + strings.TrimPrefix(goToolchain, "go") + " (GOTOOLCHAIN)"
+	}
+	t.AppendRow(table.Row{"Pinned Go", pinned})
+
+	fmt.Println(t.Render())
+}
+
+// manifest is the environment a submission was graded in, in its report, so
+// two runs' differences can be told, as when it "passed on my machine".
+type manifest struct {
+	Platform string    `json:"platform"`
+	Go       string    `json:"go"` // the toolchain building it
+	Gradebot string    `json:"gradebot"`
+	Rubric   string    `json:"rubric"`
+	Locale   string    `json:"locale"`
+	Time     time.Time `json:"time"`
+	// Tree is the SHA-256 of the submission's files (but .git), and their
+	// paths.
+	Tree string `json:"tree"`
+}
+
+// captureManifest records the environment dir is graded in with opts.
+func captureManifest(dir string, opts Options) manifest {
+	m := manifest{
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Go:       "unknown",
+		Gradebot: gradebotVersion(),
+		Rubric:   rubricRevision(),
+		Locale:   locale(),
+		Time:     time.Now().Truncate(time.Second),
+	}
+	if v := goVersionFor(opts); v != "" {
+		m.Go = "go" + v
+	}
+	if tree, err := treeHash(dir); err != nil {
+		m.Tree = "unknown (" + err.Error() + ")"
+	} else {
+		m.Tree = "sha256:" + tree
+	}
+
+	return m
+}
+
+// String is the manifest on a line, for the table and TAP reports.
+func (m manifest) String() string {
+	return fmt.Sprintf("%s, %s, gradebot %s (rubric %s), locale %s, %s, tree %s",
+		m.Platform, m.Go, m.Gradebot, m.Rubric, m.Locale, m.Time.Format(time.RFC3339), m.Tree)
+}
+
+// locale is the locale the environment sets, as the C library would pick it.
+func locale() string {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+
+	return "unset"
+}
+
+// treeHash hashes every file under dir but .git's, with its path.
+func treeHash(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, _ = io.WriteString(h, filepath.ToSlash(rel)+"\x00")
+		_, err = io.Copy(h, f)
+		_, _ = h.Write([]byte{0})
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,168 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// schedulerRun is the outcome of one execution of the scheduler.
+type schedulerRun struct {
+	stdout   []byte
+	stderr   string // the tail, at most maxStderrBytes
+	state    *os.ProcessState
+	timedOut bool
+	// outputExceeded is set when the run printed more than MaxOutput, and
+	// was killed.
+	outputExceeded bool
+	// stopped is set when the run was killed at its first mismatch.
+	stopped bool
+	err     error
+}
+
+// execScheduler runs the scheduler with args, feeding in on stdin.
+func execScheduler(c *Context, in []byte, args []string) schedulerRun {
+	return execSchedulerStream(c, in, args, nil)
+}
+
+// execSchedulerStream runs the scheduler like execScheduler, also writing its
+// output to stream, when set, which may stop the run early.
+func execSchedulerStream(c *Context, in []byte, args []string, stream *outputStream) schedulerRun {
+	ctx, kill := context.WithCancel(c.ctx)
+	defer kill()
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	args = c.flags.rewrite(args)
+	if c.input == inputFile && in != nil {
+		path, err := writeInputFile(in)
+		if err != nil {
+			return schedulerRun{err: err}
+		}
+		defer os.Remove(path)
+		args, in = append(slices.Clip(args), path), nil
+	}
+
+	// run the scheduler
+	// a relative command path resolves against the submission directory.
+	cmd := exec.CommandContext(ctx, c.run[0], append(c.run[1:len(c.run):len(c.run)], args...)...)
+	cmd.Dir = c.srcDir
+	if c.opts.Sandbox != "" {
+		sandboxCommand(cmd)
+	} else {
+		killProcessGroup(cmd)
+	}
+	// don't hang on pipes held open by a killed (or orphaned) child.
+	cmd.WaitDelay = c.opts.TimeoutGrace
+
+	// send embedded csv to stdin.
+	cmd.Stdin = bytes.NewReader(in)
+
+	// a runaway print loop is killed, rather than buffered until gradebot
+	// runs out of memory.
+	stdout := &cappedBuffer{limit: c.opts.MaxOutput, exceeded: kill}
+	stderr := &tailBuffer{limit: maxStderrBytes}
+	cmd.Stdout = stdout
+	if stream != nil {
+		stream.stop = kill
+		cmd.Stdout = io.MultiWriter(stdout, stream)
+	}
+	cmd.Stderr = stderr
+	// a sandbox's limits are docker's, not rlimits on the docker client.
+	if c.opts.Sandbox == "" {
+		if err := applyLimits(cmd, c.opts); err != nil {
+			c.log.Warn("could not set scheduler resource limits", slog.String("err", err.Error()))
+		}
+	}
+	start := time.Now()
+	err := cmd.Run()
+	c.usage.add(cmd.ProcessState, time.Since(start))
+	if cmd.ProcessState == nil && transientStartError(err) && c.transient != nil {
+		c.transient.Store(true)
+	}
+
+	return schedulerRun{
+		stdout:         textOutput(stdout.buf),
+		stderr:         stderr.String(),
+		state:          cmd.ProcessState,
+		timedOut:       errors.Is(ctx.Err(), context.DeadlineExceeded),
+		outputExceeded: stdout.over,
+		stopped:        stream != nil && stream.stopped,
+		err:            err,
+	}
+}
+
+// transientStartError reports whether a scheduler failed to start for a
+// reason that may pass: its binary still open for writing (ETXTBSY, as when
+// another process forked while it was), or no processes or memory to spare.
+func transientStartError(err error) bool {
+	return errors.Is(err, syscall.ETXTBSY) || errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.ENOMEM)
+}
+
+// writeInputFile writes a scheduler's input to a temporary file, for a
+// scheduler reading its input from a file argument.
+func writeInputFile(in []byte) (string, error) {
+	f, err := os.CreateTemp("", "gradebot-input-*.csv")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(in)
+	if err := errors.Join(err, f.Close()); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// ignoresStdin reports whether the scheduler still matches want when given
+// no input at all, as when it reads a hardcoded file instead of stdin.
+func ignoresStdin(c *Context, want golden, args []string) bool {
+	run := execScheduler(c, nil, args)
+	if run.err != nil || len(run.stdout) == 0 {
+		return false
+	}
+	actual := run.stdout
+	if !c.opts.Strict {
+		actual, want.out = normalizeOutput(actual, c.opts.Normalize), normalizeOutput(want.out, c.opts.Normalize)
+		want.epsilon = c.opts.Epsilon
+	}
+	if c.opts.SkipPreamble {
+		actual, _ = trimPreamble(actual, want.out)
+	}
+	mismatch, err := compareGolden(actual, want)
+
+	return err == nil && mismatch == ""
+}
+
+// outOfMemory reports whether stderr shows a failed allocation, as when the
+// address space limit is hit.
+func outOfMemory(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+
+	return strings.Contains(stderr, "out of memory") ||
+		strings.Contains(stderr, "cannot allocate memory") ||
+		strings.Contains(stderr, "failed to reserve") // the Go runtime, at startup
+}
+
+// outOfProcesses reports whether stderr shows a failed fork or thread
+// creation, as when the process limit is hit.
+func outOfProcesses(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+
+	return strings.Contains(stderr, "resource temporarily unavailable") ||
+		strings.Contains(stderr, "failed to create new os thread") || // the Go runtime
+		strings.Contains(stderr, "pthread_create failed") ||
+		strings.Contains(stderr, "not enough quota") // a job object's process limit
+}
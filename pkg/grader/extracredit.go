@@ -0,0 +1,214 @@
+package grader
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// extra-credit algorithms' embedded testdata.
+var (
+	//go:embed testdata/priority.csv
+	priorityIn []byte
+	//go:embed testdata/priority.out
+	priorityOut []byte
+
+	//go:embed testdata/mlfq.csv
+	mlfqIn []byte
+	//go:embed testdata/mlfq.out
+	mlfqOut []byte
+)
+
+// mlfqQuanta are the time quanta of the multilevel feedback queue's levels
+// but the last, which is first-come, first-served.
+var mlfqQuanta = []int{2, 4}
+
+// extraCredit is an extra-credit algorithm, graded against the built-in
+// simulator's golden output (see the golden command).
+type extraCredit struct {
+	algorithm string // its flag, less the dash
+	title     string
+	in, out   string // testdata file names
+}
+
+var extraCredits = []extraCredit{
+	{algorithm: "priority", title: "Preemptive priority", in: "priority.csv", out: "priority.out"},
+	{algorithm: "mlfq", title: "Multilevel feedback queue", in: "mlfq.csv", out: "mlfq.out"},
+}
+
+// simulatePriority schedules procs (sorted by arrival) by priority, lowest
+// first, one time unit at a time: a higher priority arrival preempts the
+// running process, unlike an equal one, and equal priorities run in order of
+// arrival, a preempted process included.
+func simulatePriority(procs []*simProc) (gantt []simSlice, done []*simProc, ok bool) {
+	var (
+		ready []*simProc
+		cur   *simProc
+		next  int
+		t     = procs[0].arrival
+	)
+	before := func(a, b *simProc) bool {
+		return a.priority < b.priority || (a.priority == b.priority && a.arrival < b.arrival)
+	}
+	for len(done) < len(procs) {
+		for next < len(procs) && procs[next].arrival <= t {
+			ready = append(ready, procs[next])
+			next++
+		}
+		best := -1
+		for i, p := range ready {
+			if best < 0 || before(p, ready[best]) {
+				best = i
+			}
+		}
+		if best >= 0 && (cur == nil || ready[best].priority < cur.priority) {
+			if cur != nil {
+				ready = append(ready, cur)
+			}
+			cur = ready[best]
+			ready = append(ready[:best], ready[best+1:]...)
+		}
+		if cur == nil {
+			return nil, nil, false
+		}
+		gantt = addSlice(gantt, cur.id, t, t+1)
+		cur.remaining--
+		t++
+		if cur.remaining == 0 {
+			cur.exit = t
+			done = append(done, cur)
+			cur = nil
+		}
+	}
+
+	return gantt, done, true
+}
+
+// simulateMLFQ schedules procs (sorted by arrival) on a multilevel feedback
+// queue: arrivals join the top level, the head of the highest nonempty level
+// runs for its quantum (see mlfqQuanta), or to completion on the last, and a
+// process using its whole quantum moves to the back of the next level down.
+// A running process isn't preempted by arrivals.
+func simulateMLFQ(procs []*simProc) (gantt []simSlice, done []*simProc, ok bool) {
+	var (
+		levels = make([][]*simProc, len(mlfqQuanta)+1)
+		next   int
+		t      = procs[0].arrival
+	)
+	for len(done) < len(procs) {
+		for next < len(procs) && procs[next].arrival <= t {
+			levels[0] = append(levels[0], procs[next])
+			next++
+		}
+		l := 0
+		for l < len(levels) && len(levels[l]) == 0 {
+			l++
+		}
+		if l == len(levels) {
+			return nil, nil, false
+		}
+		cur := levels[l][0]
+		levels[l] = levels[l][1:]
+		run := cur.remaining
+		if l < len(mlfqQuanta) {
+			run = min(mlfqQuanta[l], run)
+		}
+		gantt = addSlice(gantt, cur.id, t, t+run)
+		t += run
+		cur.remaining -= run
+		if cur.remaining == 0 {
+			cur.exit = t
+			done = append(done, cur)
+			continue
+		}
+		// arrivals during the quantum queue first, but on another level.
+		for next < len(procs) && procs[next].arrival <= t {
+			levels[0] = append(levels[0], procs[next])
+			next++
+		}
+		down := min(l+1, len(levels)-1)
+		levels[down] = append(levels[down], cur)
+	}
+
+	return gantt, done, true
+}
+
+// golden renders the algorithm's expected output for the input.
+func (ec extraCredit) golden(in []byte) ([]byte, error) {
+	procs, err := parseSimProcs(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ec.in, err)
+	}
+	simulate := simulatePriority
+	if ec.algorithm == "mlfq" {
+		simulate = simulateMLFQ
+	}
+	gantt, done, ok := simulate(procs)
+	if !ok {
+		return nil, fmt.Errorf("%s: the CPU is idle at some point, which the schedule leaves unspecified", ec.in)
+	}
+	var out bytes.Buffer
+	writeSchedule(&out, ec.title, gantt, done)
+
+	return out.Bytes(), nil
+}
+
+// writeExtraCreditGoldens regenerates the extra-credit algorithms' golden outputs in dir
+// from their inputs there, or else the embedded ones.
+func writeExtraCreditGoldens(dir string) error {
+	for _, ec := range extraCredits {
+		in, err := os.ReadFile(filepath.Join(dir, ec.in))
+		if errors.Is(err, os.ErrNotExist) {
+			in = embeddedExtraCredit[ec.in]
+		} else if err != nil {
+			return err
+		}
+		out, err := ec.golden(in)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, ec.out)
+		status := "updated"
+		if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, out) {
+			status = "unchanged"
+		} else if err := os.WriteFile(path, out, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", path, status)
+	}
+
+	return nil
+}
+
+// embeddedExtraCredit are the extra-credit algorithms' embedded testdata, by
+// file name.
+var embeddedExtraCredit = map[string][]byte{
+	"priority.csv": priorityIn, "priority.out": priorityOut,
+	"mlfq.csv": mlfqIn, "mlfq.out": mlfqOut,
+}
+
+// CheckExtraCredit grades an extra-credit algorithm like CheckScheduler, once
+// the scheduler is found to accept its flag: one that doesn't (or fails
+// without output) didn't attempt it, and is awarded nothing without an error.
+func CheckExtraCredit(result Result, flag string, in, out []byte) func(c *Context) (Result, error) {
+	result.ExtraCredit = true
+	check := CheckScheduler(result, flag, in, out)
+
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, nil
+		}
+		probe := execScheduler(c, in, []string{flag})
+		if unrecognizedFlag(probe.stderr) || (probe.err != nil && len(bytes.TrimSpace(probe.stdout)) == 0) {
+			result.Message = fmt.Sprintf("not attempted (the scheduler doesn't accept %s)", c.flags.flag(strings.TrimPrefix(flag, "-")))
+			return result, nil
+		}
+
+		return check(c)
+	}
+}
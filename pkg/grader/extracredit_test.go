@@ -0,0 +1,71 @@
+package grader
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExtraCreditGoldens(t *testing.T) {
+	embedded := map[string][2][]byte{
+		"priority": {priorityIn, priorityOut},
+		"mlfq":     {mlfqIn, mlfqOut},
+	}
+	for _, ec := range extraCredits {
+		t.Run(ec.algorithm, func(t *testing.T) {
+			got, err := ec.golden(embedded[ec.algorithm][0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := embedded[ec.algorithm][1]; !bytes.Equal(got, want) {
+				t.Errorf("golden() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSimulatePriority(t *testing.T) {
+	procs := []*simProc{
+		{id: "A", burst: 3, priority: 2, remaining: 3},
+		{id: "B", burst: 1, arrival: 1, priority: 1, remaining: 1},
+		{id: "C", burst: 1, arrival: 2, priority: 2, remaining: 1},
+	}
+	gantt, done, ok := simulatePriority(procs)
+	if !ok {
+		t.Fatal("simulatePriority() = not ok")
+	}
+	// B preempts A; C, of A's priority, doesn't, and waits after it.
+	want := []simSlice{{"A", 0, 1}, {"B", 1, 2}, {"A", 2, 4}, {"C", 4, 5}}
+	if len(gantt) != len(want) {
+		t.Fatalf("simulatePriority() gantt = %v, want %v", gantt, want)
+	}
+	for i := range want {
+		if gantt[i] != want[i] {
+			t.Fatalf("simulatePriority() gantt = %v, want %v", gantt, want)
+		}
+	}
+	if len(done) != 3 || done[2].id != "C" || done[2].exit != 5 {
+		t.Errorf("simulatePriority() done = %v, want C last, at 5", done)
+	}
+}
+
+func TestSimulateMLFQ(t *testing.T) {
+	procs := []*simProc{
+		{id: "A", burst: 8, remaining: 8},
+		{id: "B", burst: 1, arrival: 1, remaining: 1},
+	}
+	gantt, _, ok := simulateMLFQ(procs)
+	if !ok {
+		t.Fatal("simulateMLFQ() = not ok")
+	}
+	// A uses its top-level quantum, B runs, then A its second level's and
+	// the rest first-come, first-served.
+	want := []simSlice{{"A", 0, 2}, {"B", 2, 3}, {"A", 3, 9}}
+	if len(gantt) != len(want) {
+		t.Fatalf("simulateMLFQ() gantt = %v, want %v", gantt, want)
+	}
+	for i := range want {
+		if gantt[i] != want[i] {
+			t.Fatalf("simulateMLFQ() gantt = %v, want %v", gantt, want)
+		}
+	}
+}
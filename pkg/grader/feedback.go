@@ -0,0 +1,194 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// maxFeedbackSource and maxFeedbackDiff cap how much of the source and
+	// of the diffs are sent with each request, in bytes.
+	maxFeedbackSource = 16 << 10
+	maxFeedbackDiff   = 4 << 10
+	// feedbackPrompt instructs the model; the hints are read by students.
+	feedbackPrompt = `You are a teaching assistant for an operating systems course. A student's CPU scheduler assignment failed an autograder check. ` +
+		`In at most three sentences, point out the most likely mistake and what to look at to fix it. ` +
+		`Don't write code for them, and don't quote the expected output.`
+)
+
+// FeedbackConfig asks an OpenAI-compatible chat completions endpoint for a
+// hint on each failed check.
+type FeedbackConfig struct {
+	Feedback      bool
+	FeedbackURL   string
+	FeedbackKey   string
+	FeedbackModel string
+}
+
+func (o FeedbackConfig) validate() error {
+	if !o.Feedback {
+		return nil
+	}
+	if u, err := url.Parse(o.FeedbackURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid --feedback-url %q", o.FeedbackURL)
+	}
+	if o.FeedbackModel == "" {
+		return errors.New("--feedback requires --feedback-model")
+	}
+
+	return nil
+}
+
+type (
+	chatMessage struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	chatRequest struct {
+		Model       string        `json:"model"`
+		Messages    []chatMessage `json:"messages"`
+		MaxTokens   int           `json:"max_tokens"`
+		Temperature float64       `json:"temperature"`
+	}
+	chatResponse struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+)
+
+// addFeedback sets the Hint of each of the submission's failed results that
+// doesn't have one. A hint that can't be had is logged and left out; the
+// grade doesn't depend on it.
+func addFeedback(ctx context.Context, o FeedbackConfig, dir string, results []Result) {
+	source, err := sourceExcerpt(dir)
+	if err != nil {
+		slog.Warn("sending no source for feedback", slog.String("dir", dir), slog.String("err", err.Error()))
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	for i := range results {
+		r := &results[i]
+		if r.Awarded >= r.Possible || r.Hint != "" || ctx.Err() != nil {
+			continue
+		}
+		hint, err := askFeedback(ctx, client, o, feedbackQuestion(*r, source))
+		if err != nil {
+			slog.Warn("no feedback", slog.String("dir", dir), slog.String("item", r.Label), slog.String("err", err.Error()))
+			continue
+		}
+		r.Hint = hint
+	}
+}
+
+// feedbackQuestion describes the failed result to the model.
+func feedbackQuestion(r Result, source string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Check: %s (%d/%d points)\n\nResult:\n%s\n", r.Label, r.Awarded, r.Possible, r.Message)
+	if r.Diff != "" {
+		fmt.Fprintf(&sb, "\nOutput diff (- expected, + actual):\n%s\n", truncate(r.Diff, maxFeedbackDiff))
+	}
+	if r.Stderr != "" {
+		fmt.Fprintf(&sb, "\nStderr:\n%s\n", truncate(r.Stderr, maxFeedbackDiff))
+	}
+	if source != "" {
+		fmt.Fprintf(&sb, "\nSource:\n%s", source)
+	}
+
+	return sb.String()
+}
+
+// askFeedback sends the question to the chat completions endpoint, returning
+// the answer.
+func askFeedback(ctx context.Context, client *http.Client, o FeedbackConfig, question string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: o.FeedbackModel,
+		Messages: []chatMessage{
+			{Role: "system", Content: feedbackPrompt},
+			{Role: "user", Content: question},
+		},
+		MaxTokens:   200,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(o.FeedbackURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.FeedbackKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.FeedbackKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("feedback endpoint responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var chat chatResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&chat); err != nil {
+		return "", fmt.Errorf("reading feedback: %w", err)
+	}
+	if len(chat.Choices) == 0 || strings.TrimSpace(chat.Choices[0].Message.Content) == "" {
+		return "", errors.New("feedback endpoint gave no answer")
+	}
+
+	return strings.TrimSpace(chat.Choices[0].Message.Content), nil
+}
+
+// sourceExcerpt is the submission's non-test Go source, each file under its
+// name, up to maxFeedbackSource bytes.
+func sourceExcerpt(dir string) (string, error) {
+	var sb strings.Builder
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_") || d.Name() == "vendor" || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") || strings.HasSuffix(d.Name(), "_test.go") || sb.Len() >= maxFeedbackSource {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(&sb, "// %s\n%s\n", filepath.ToSlash(rel), src)
+		return nil
+	})
+
+	return truncate(sb.String(), maxFeedbackSource), err
+}
+
+// truncate cuts s to at most n bytes, at a line break, noting the cut.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if i := strings.LastIndexByte(s[:n], '\n'); i > 0 {
+		n = i
+	}
+
+	return s[:n] + "\n[truncated]"
+}
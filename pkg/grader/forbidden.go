@@ -0,0 +1,204 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxViolations caps how many forbidden API uses are listed in the message.
+const maxViolations = 10
+
+// denyList is what the forbidden API check rejects, as configured under
+// "forbidden" in a rubric config:
+//
+//	forbidden:
+//	  imports: [os/exec, net/...]
+//	  calls: [os.StartProcess]
+//	  strings: [fcfs.out]
+type denyList struct {
+	// Imports are import paths; "net/..." matches net and every package below it.
+	Imports []string `yaml:"imports"`
+	// Calls are package-qualified functions, e.g. os.StartProcess.
+	Calls []string `yaml:"calls"`
+	// Strings are substrings string literals mustn't contain, e.g. the
+	// expected output file names.
+	Strings []string `yaml:"strings"`
+}
+
+// defaultDenyList rejects running other programs, the network, and naming
+// the embedded expected output files.
+func defaultDenyList() *denyList {
+	deny := &denyList{
+		Imports: []string{"os/exec", "net/...", "plugin"},
+		Calls:   []string{"os.StartProcess", "syscall.Exec", "syscall.ForkExec"},
+	}
+	for _, run := range goldenRuns {
+		deny.Strings = append(deny.Strings, run.out)
+	}
+
+	return deny
+}
+
+// validate reports malformed entries, prefixed with the config key.
+func (d denyList) validate() error {
+	var errs []error
+	for _, imp := range d.Imports {
+		if strings.TrimSuffix(imp, "/...") == "" {
+			errs = append(errs, fmt.Errorf("forbidden: empty import %q", imp))
+		}
+	}
+	for _, call := range d.Calls {
+		if i := strings.LastIndex(call, "."); i <= strings.LastIndex(call, "/") || i == len(call)-1 {
+			errs = append(errs, fmt.Errorf("forbidden: call %q isn't a package-qualified function, e.g. os.StartProcess", call))
+		}
+	}
+	for _, s := range d.Strings {
+		if s == "" {
+			errs = append(errs, errors.New("forbidden: empty string"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deniedImport reports whether the import path is on the deny list.
+func (d denyList) deniedImport(imp string) bool {
+	for _, deny := range d.Imports {
+		if prefix, ok := strings.CutSuffix(deny, "/..."); ok {
+			if imp == prefix || strings.HasPrefix(imp, prefix+"/") {
+				return true
+			}
+		} else if imp == deny {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckForbidden parses the submission's Go source (not its tests) and
+// rejects it for any import, call or string literal on the deny list, so
+// it can't shell out or fetch the answers.
+func CheckForbidden(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelForbidden,
+		Awarded:  0,
+		Possible: c.opts.possible(labelForbidden),
+	}
+	if c.lang != "" && c.lang != langGo {
+		return notApplicable(result, c.lang)
+	}
+	deny := c.opts.Forbidden
+	var violations []string
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(c.srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			// like the go command, ignore hidden, underscore-prefixed and
+			// testdata directories, and vendored packages aren't the student's.
+			if p != c.srcDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		rel, _ := filepath.Rel(c.srcDir, p)
+		f, err := parser.ParseFile(fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			// the compile check reports syntax errors.
+			c.log.Debug("not checking unparsable file", slog.String("file", rel), slog.String("err", err.Error()))
+			return nil
+		}
+		for _, v := range deny.violations(fset, f) {
+			violations = append(violations, filepath.ToSlash(rel)+":"+v)
+		}
+		return nil
+	})
+	if err != nil {
+		result.Message = "could not read the source"
+		return result, err
+	}
+
+	if len(violations) > 0 {
+		result.Message = strings.Join(violations[:min(len(violations), maxViolations)], "\n")
+		if len(violations) > maxViolations {
+			result.Message += fmt.Sprintf("\n(and %d more)", len(violations)-maxViolations)
+		}
+		return result, fmt.Errorf("%d forbidden API use(s)", len(violations))
+	}
+	result.Awarded = result.Possible
+	c.log.Debug("no forbidden APIs", slog.Int("pts", result.Possible))
+
+	return result, nil
+}
+
+// violations lists f's uses of the deny list, each as "line: what".
+func (d denyList) violations(fset *token.FileSet, f *ast.File) []string {
+	var found []string
+	line := func(n ast.Node) int { return fset.Position(n.Pos()).Line }
+
+	// the names the file's imports are referred to by.
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		imp, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if d.deniedImport(imp) {
+			found = append(found, fmt.Sprintf("%d: imports %s", line(spec), imp))
+		}
+		name := path.Base(imp)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = imp
+	}
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ImportSpec:
+			// import paths aren't string literals for Strings.
+			return false
+		case *ast.SelectorExpr:
+			if id, ok := n.X.(*ast.Ident); ok {
+				if imp, ok := imports[id.Name]; ok {
+					for _, call := range d.Calls {
+						if call == imp+"."+n.Sel.Name {
+							found = append(found, fmt.Sprintf("%d: uses %s", line(n), call))
+						}
+					}
+				}
+			}
+		case *ast.BasicLit:
+			if n.Kind != token.STRING {
+				break
+			}
+			s, err := strconv.Unquote(n.Value)
+			if err != nil {
+				break
+			}
+			for _, deny := range d.Strings {
+				if strings.Contains(s, deny) {
+					found = append(found, fmt.Sprintf("%d: string %s contains %q", line(n), n.Value, deny))
+				}
+			}
+		}
+		return true
+	})
+
+	return found
+}
@@ -0,0 +1,181 @@
+package grader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// fuzzRunTimeout bounds each fuzzed run (or Timeout, if shorter): longer
+	// is a hang.
+	fuzzRunTimeout = 2 * time.Second
+	// fuzzMinimizeRuns bounds the runs minimizing a crashing input.
+	fuzzMinimizeRuns = 200
+	// fuzzQuoteBytes is how much of the crashing input the message quotes.
+	fuzzQuoteBytes = 200
+)
+
+// fuzzTokens are what mutations insert: CSV syntax, signs, digits and bytes
+// a parser may not expect.
+var fuzzTokens = []string{",", "\n", "\r\n", "-", "0", "9", " ", "\"", "\x00", "\xff", ",,", "-1"}
+
+// fuzzNumbers replace the input's numbers: zero, negatives, and values
+// overflowing 32 and 64 bits.
+var fuzzNumbers = []string{"0", "-1", "-2147483649", "2147483648", "9223372036854775808", "99999999999999999999", "1e9", "0x10", ""}
+
+var numberPattern = regexp.MustCompile(`[0-9]+`)
+
+// fuzzArgs are the algorithm flags the fuzzed inputs are run with, in turn.
+var fuzzArgs = [][]string{{"-fcfs"}, {"-sjf"}, {"-sjfp"}, {"-rr", quantumFlag, "2"}}
+
+// mutate returns a copy of in with one to four random mutations: a byte
+// changed, a token inserted, a range deleted, a line duplicated, a number
+// replaced, or the end cut off.
+func mutate(rng *rand.Rand, in []byte) []byte {
+	out := bytes.Clone(in)
+	for n := 1 + rng.Intn(4); n > 0; n-- {
+		if len(out) == 0 {
+			out = append(out, fuzzTokens[rng.Intn(len(fuzzTokens))]...)
+			continue
+		}
+		i := rng.Intn(len(out))
+		switch rng.Intn(6) {
+		case 0:
+			out[i] = byte(rng.Intn(256))
+		case 1:
+			out = append(out[:i:i], append([]byte(fuzzTokens[rng.Intn(len(fuzzTokens))]), out[i:]...)...)
+		case 2:
+			out = append(out[:i:i], out[min(len(out), i+1+rng.Intn(16)):]...)
+		case 3:
+			lines := bytes.SplitAfter(out, []byte("\n"))
+			j := rng.Intn(len(lines))
+			out = bytes.Join(append(lines[:j+1:j+1], lines[j:]...), nil)
+		case 4:
+			if nums := numberPattern.FindAllIndex(out, -1); len(nums) > 0 {
+				loc := nums[rng.Intn(len(nums))]
+				out = append(out[:loc[0]:loc[0]], append([]byte(fuzzNumbers[rng.Intn(len(fuzzNumbers))]), out[loc[1]:]...)...)
+			}
+		case 5:
+			out = out[:i]
+		}
+	}
+
+	return out
+}
+
+// fuzzCrash is how the run went wrong, or "" if it didn't: rejecting an input
+// with an error is fine, but not panicking, crashing, hanging or printing
+// without end.
+func fuzzCrash(run schedulerRun) string {
+	switch {
+	case run.timedOut:
+		return "hung"
+	case run.outputExceeded:
+		return "printed without end"
+	case strings.Contains(run.stderr, "panic:") || strings.Contains(run.stderr, "goroutine "):
+		return "panicked"
+	case run.state != nil && !run.state.Exited():
+		return fmt.Sprintf("crashed (%v)", run.err)
+	}
+
+	return ""
+}
+
+// minimizeCrash shrinks a crashing input while it still crashes (see
+// crashes), within fuzzMinimizeRuns runs and until the deadline: dropping
+// lines, then ever smaller byte ranges.
+func minimizeCrash(in []byte, crashes func([]byte) bool, deadline time.Time) []byte {
+	runs := 0
+	try := func(candidate []byte) bool {
+		if runs >= fuzzMinimizeRuns || time.Now().After(deadline) {
+			return false
+		}
+		runs++
+		return crashes(candidate)
+	}
+	lines := bytes.SplitAfter(in, []byte("\n"))
+	for i := 0; i < len(lines); {
+		candidate := append(lines[:i:i], lines[i+1:]...)
+		if try(bytes.Join(candidate, nil)) {
+			lines = candidate
+			continue
+		}
+		i++
+	}
+	in = bytes.Join(lines, nil)
+	for chunk := len(in) / 2; chunk >= 1; chunk /= 2 {
+		for i := 0; i+chunk <= len(in); {
+			candidate := append(in[:i:i], in[i+chunk:]...)
+			if try(candidate) {
+				in = candidate
+				continue
+			}
+			i += chunk
+		}
+	}
+
+	return in
+}
+
+// CheckFuzz runs the scheduler on random mutations of the testdata's inputs
+// (see mutate) for the budget, awarding the points if none crashes it (see
+// fuzzCrash). A crashing input is minimized, then quoted in the message, and
+// in full with the run's stderr.
+func CheckFuzz(result Result, seed int64, budget time.Duration) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		fuzz := *c
+		fuzz.opts.Timeout = fuzzRunTimeout
+		if c.opts.Timeout > 0 {
+			fuzz.opts.Timeout = min(c.opts.Timeout, fuzzRunTimeout)
+		}
+		corpus := [][]byte{
+			c.opts.fixture("fcfs.csv", fcfsIn), c.opts.fixture("sjf.csv", sjfIn),
+			c.opts.fixture("sjfp.csv", sjfpIn), c.opts.fixture("rr.csv", rrIn),
+		}
+		rng := rand.New(rand.NewSource(seed))
+		deadline := time.Now().Add(budget)
+		runs := 0
+		for ; time.Now().Before(deadline); runs++ {
+			args := fuzzArgs[runs%len(fuzzArgs)]
+			in := mutate(rng, corpus[rng.Intn(len(corpus))])
+			run := execScheduler(&fuzz, in, args)
+			if c.ctx.Err() != nil {
+				return result, c.ctx.Err()
+			}
+			crash := fuzzCrash(run)
+			if crash == "" {
+				continue
+			}
+
+			// the same crash on a smaller input, in as long again.
+			in = minimizeCrash(in, func(candidate []byte) bool {
+				return c.ctx.Err() == nil && fuzzCrash(execScheduler(&fuzz, candidate, args)) == crash
+			}, time.Now().Add(budget))
+			run = execScheduler(&fuzz, in, args)
+			name := strings.Join(args, " ")
+			quoted := strconv.Quote(string(in[:min(len(in), fuzzQuoteBytes)]))
+			if len(in) > fuzzQuoteBytes {
+				quoted += "..."
+			}
+			result.Message = fmt.Sprintf("%s %s on a %d-byte input, after %d inputs (--seed %d):\n%s", name, crash, len(in), runs+1, seed, quoted)
+			c.stderr = append(c.stderr, fmt.Sprintf("$ scheduler %s < crashing input\n%s\n--- stderr\n%s",
+				name, in, strings.Join(tailLines(run.stderr, reportStderrLines), "\n")))
+			return result, fmt.Errorf("fuzzed input %s the scheduler", crash)
+		}
+		result.Awarded = result.Possible
+		result.Message = fmt.Sprintf("no crashes in %d fuzzed inputs (--seed %d)", runs, seed)
+
+		return result, nil
+	}
+}
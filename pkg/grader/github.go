@@ -0,0 +1,128 @@
+package grader
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// The github format is for a GitHub Actions workflow, such as GitHub
+// Classroom's autograding one: a workflow command per rubric item not given
+// full marks, which the run shows as an annotation, then the points as
+// Classroom reads them, from the log's "Points N/M" line and from the step's
+// result output (see classroomResult). With $GITHUB_STEP_SUMMARY set, the
+// results table is also the job's summary. The outputs are written once per
+// run, after every report is (see writeGitHubOutputs), however many times
+// the results are rendered.
+
+var (
+	workflowMessage  = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	workflowProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// classroomResult is the result output Classroom's autograding reporter
+// reads, base64 encoded: a test per rubric item.
+type classroomResult struct {
+	Version  int             `json:"version"`
+	Status   string          `json:"status"` // pass, or fail
+	MaxScore int             `json:"max_score"`
+	Tests    []classroomTest `json:"tests"`
+}
+
+type classroomTest struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Score   int    `json:"score"`
+	Message string `json:"message,omitempty"`
+}
+
+// printGitHub prints the results as GitHub Actions workflow commands: an
+// error for a check losing points, a warning for one skipped and a notice
+// for unearned extra credit, and the total.
+func printGitHub(w io.Writer, opts options, dir string, results []Result, total, possible int) {
+	for _, r := range results {
+		var level string
+		switch {
+		case r.Skipped:
+			level = "warning"
+		case r.Awarded == r.Possible:
+			continue
+		case r.ExtraCredit:
+			level = "notice"
+		default:
+			level = "error"
+		}
+		msg := r.reportMessage()
+		if r.Error != "" {
+			msg = strings.TrimPrefix(msg+"\n"+r.Error, "\n")
+		}
+		fmt.Fprintf(w, "::%s title=%s::%s\n", level, workflowProperty.Replace(r.Label+" ("+r.points()+")"), workflowMessage.Replace(msg))
+	}
+	points := fmt.Sprintf("%d/%d", total, possible)
+	if opts.NormalizeTo > 0 {
+		points = fmt.Sprintf("%.2f/%d", normalize(total, possible, opts.NormalizeTo), opts.NormalizeTo)
+	}
+	fmt.Fprintf(w, "::notice title=%s::total %s\n", workflowProperty.Replace(dir), points)
+	fmt.Fprintf(w, "Points %s\n", points)
+}
+
+// writeGitHubOutputs appends the graded submissions to the step's outputs
+// and summary, where the workflow has them: $GITHUB_OUTPUT gets points and
+// result (see classroomResult), and $GITHUB_STEP_SUMMARY the markdown results
+// tables. A batch's points are the sum of its submissions', and its tests
+// are named by submission.
+func writeGitHubOutputs(opts options, graded []submission) error {
+	var errs []error
+	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
+		result := classroomResult{Version: 1, Status: "pass"}
+		var total int
+		for _, s := range graded {
+			awarded, possible := s.totals()
+			total, result.MaxScore = total+awarded, result.MaxScore+possible
+			for _, r := range s.results {
+				test := classroomTest{Name: r.Label, Status: "pass", Score: r.Awarded, Message: r.Message}
+				if len(graded) > 1 {
+					test.Name = s.name() + ": " + r.Label
+				}
+				if r.Awarded < r.Possible && !r.ExtraCredit {
+					test.Status, result.Status = "fail", "fail"
+				}
+				result.Tests = append(result.Tests, test)
+			}
+		}
+		b, err := json.Marshal(result)
+		if err == nil {
+			err = appendFile(path, fmt.Sprintf("points=%d/%d\nresult=%s\n", total, result.MaxScore, base64.StdEncoding.EncodeToString(b)))
+		}
+		errs = append(errs, err)
+	}
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		var summary strings.Builder
+		md := opts
+		md.Format, md.Total = "markdown", false
+		for _, s := range graded {
+			md.manifest = s.env
+			fmt.Fprintf(&summary, "### %s\n\n", s.name())
+			printRubricResults(&summary, md, s.name(), s.results...)
+		}
+		errs = append(errs, appendFile(path, summary.String()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// appendFile appends s to the file at path, as the workflow's files are
+// shared by its steps.
+func appendFile(path, s string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(s)
+
+	return errors.Join(err, f.Close())
+}
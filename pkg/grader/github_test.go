@@ -0,0 +1,138 @@
+package grader
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkflowEscaping(t *testing.T) {
+	tests := []struct {
+		in, message, property string
+	}{
+		{in: "plain", message: "plain", property: "plain"},
+		{in: "50%", message: "50%25", property: "50%25"},
+		{in: "a\nb\r\nc", message: "a%0Ab%0D%0Ac", property: "a%0Ab%0D%0Ac"},
+		{in: "FCFS (5/20): got 1, want 2", message: "FCFS (5/20): got 1, want 2", property: "FCFS (5/20)%3A got 1%2C want 2"},
+		// an escape sequence in the input isn't unescaped later.
+		{in: "%0A", message: "%250A", property: "%250A"},
+	}
+	for _, tt := range tests {
+		if got := workflowMessage.Replace(tt.in); got != tt.message {
+			t.Errorf("workflowMessage(%q) = %q, want %q", tt.in, got, tt.message)
+		}
+		if got := workflowProperty.Replace(tt.in); got != tt.property {
+			t.Errorf("workflowProperty(%q) = %q, want %q", tt.in, got, tt.property)
+		}
+	}
+}
+
+func TestPrintGitHub(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		want   string // the result's workflow command, if any
+	}{
+		{name: "full marks", result: Result{Label: "FCFS", Awarded: 20, Possible: 20}},
+		{name: "lost points", result: Result{Label: "FCFS", Awarded: 5, Possible: 20, Message: "diverged at line 2\nsee diff"},
+			want: "::error title=FCFS (5/20)::diverged at line 2%0Asee diff\n"},
+		{name: "error", result: Result{Label: "FCFS", Possible: 20, Message: "crashed", Error: "exit status 2"},
+			want: "::error title=FCFS (0/20)::crashed%0Aexit status 2\n"},
+		{name: "skipped", result: Result{Label: "SJF", Possible: 20, Skipped: true, Message: "skipped: compile failed"},
+			want: "::warning title=SJF (0/20)::skipped: compile failed\n"},
+		{name: "extra credit", result: Result{Label: "MLFQ", Possible: 5, ExtraCredit: true, Message: "not implemented"},
+			want: "::notice title=MLFQ (0/+5)::not implemented\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			printGitHub(&sb, options{}, "sub", []Result{tt.result}, 7, 20)
+			want := tt.want + "::notice title=sub::total 7/20\nPoints 7/20\n"
+			if got := sb.String(); got != want {
+				t.Errorf("printGitHub() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteGitHubOutputs(t *testing.T) {
+	graded := []submission{{dir: "sub", results: []Result{
+		{Label: "Compiles", Awarded: 10, Possible: 10},
+		{Label: "FCFS", Awarded: 5, Possible: 20, Message: "diverged"},
+		{Label: "MLFQ", Possible: 5, ExtraCredit: true, Message: "not implemented"},
+	}}}
+	tests := []struct {
+		name   string
+		graded []submission
+		points string
+		result classroomResult
+	}{
+		{
+			name:   "submission",
+			graded: graded,
+			points: "15/30",
+			result: classroomResult{Version: 1, Status: "fail", MaxScore: 30, Tests: []classroomTest{
+				{Name: "Compiles", Status: "pass", Score: 10},
+				{Name: "FCFS", Status: "fail", Score: 5, Message: "diverged"},
+				// extra credit never fails the run.
+				{Name: "MLFQ", Status: "pass", Message: "not implemented"},
+			}},
+		},
+		{
+			name:   "batch",
+			graded: append(graded[:1:1], submission{dir: "other", results: []Result{{Label: "Compiles", Awarded: 10, Possible: 10}}}),
+			points: "25/40",
+			result: classroomResult{Version: 1, Status: "fail", MaxScore: 40, Tests: []classroomTest{
+				{Name: "sub: Compiles", Status: "pass", Score: 10},
+				{Name: "sub: FCFS", Status: "fail", Score: 5, Message: "diverged"},
+				{Name: "sub: MLFQ", Status: "pass", Message: "not implemented"},
+				{Name: "other: Compiles", Status: "pass", Score: 10},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			output, summary := filepath.Join(dir, "output"), filepath.Join(dir, "summary")
+			t.Setenv("GITHUB_OUTPUT", output)
+			t.Setenv("GITHUB_STEP_SUMMARY", summary)
+			if err := writeGitHubOutputs(options{}, tt.graded); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+			if len(lines) != 2 || lines[0] != "points="+tt.points || !strings.HasPrefix(lines[1], "result=") {
+				t.Fatalf("GITHUB_OUTPUT =\n%s\nwant points=%s and a result", b, tt.points)
+			}
+			payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(lines[1], "result="))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got classroomResult
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.result) {
+				t.Errorf("result = %+v, want %+v", got, tt.result)
+			}
+
+			b, err = os.ReadFile(summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.graded {
+				if !strings.Contains(string(b), "### "+s.dir+"\n") {
+					t.Errorf("GITHUB_STEP_SUMMARY has no section for %s:\n%s", s.dir, b)
+				}
+			}
+		})
+	}
+}
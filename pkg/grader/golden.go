@@ -0,0 +1,170 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// goldenRun is how an embedded golden output file is produced: the scheduler
+// run with args on an input file.
+type goldenRun struct {
+	out, in string
+	args    []string
+}
+
+// goldenRuns mirror the rubric's scheduler checks (see rubricItems).
+var goldenRuns = []goldenRun{
+	{out: "fcfs.out", in: "fcfs.csv", args: []string{"-fcfs"}},
+	{out: "sjf.out", in: "sjf.csv", args: []string{"-sjf"}},
+	{out: "sjfp.out", in: "sjfp.csv", args: []string{"-sjfp"}},
+	{out: "rr_q1.out", in: "rr.csv", args: []string{"-rr", quantumFlag, "1"}},
+	{out: "rr_q2.out", in: "rr.csv", args: []string{"-rr", quantumFlag, "2"}},
+	{out: "rr.out", in: "rr.csv", args: []string{"-rr", quantumFlag, "4"}},
+	{out: "rr_q10.out", in: "rr.csv", args: []string{"-rr", quantumFlag, "10"}},
+}
+
+// embeddedInputs are the embedded scheduler inputs, by file name.
+var embeddedInputs = map[string][]byte{
+	"fcfs.csv": fcfsIn,
+	"sjf.csv":  sjfIn,
+	"sjfp.csv": sjfpIn,
+	"rr.csv":   rrIn,
+}
+
+// GoldenConfig configures Golden, as the golden command's flags do.
+type GoldenConfig struct {
+	Reference string
+	Inputs    string
+	Out       string
+	Runs      int
+	Timeout   time.Duration
+}
+
+// Golden regenerates the golden .out files by running cfg.Reference.
+func Golden(ctx context.Context, cfg GoldenConfig) error {
+	if cfg.Runs < 1 {
+		return errors.New("--runs must be at least 1")
+	}
+	reference := cfg.Reference
+	if fi, err := os.Stat(reference); err == nil && fi.IsDir() {
+		tmp, err := os.MkdirTemp("", "gradebot-reference-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		binary := filepath.Join(tmp, binaryName())
+		build := exec.CommandContext(ctx, "go", "build", "-o", binary)
+		build.Dir = reference
+		if out, err := build.CombinedOutput(); err != nil {
+			return fmt.Errorf("building the reference scheduler: %w\n%s", err, bytes.TrimSpace(out))
+		}
+		reference = binary
+	} else if abs, err := filepath.Abs(reference); err == nil {
+		reference = abs
+	}
+
+	if err := os.MkdirAll(cfg.Out, 0o755); err != nil {
+		return err
+	}
+	for _, run := range goldenRuns {
+		in := embeddedInputs[run.in]
+		if cfg.Inputs != "" {
+			if b, err := os.ReadFile(filepath.Join(cfg.Inputs, run.in)); err == nil {
+				in = b
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		out, err := cfg.deterministic(ctx, reference, in, run.args)
+		if err != nil {
+			return fmt.Errorf("%s: %w", run.out, err)
+		}
+
+		path := filepath.Join(cfg.Out, run.out)
+		status := "updated"
+		if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, out) {
+			status = "unchanged"
+		} else if err := os.WriteFile(path, out, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", path, status)
+	}
+	// inputs are copied alongside, so the directory works with --testdata.
+	if cfg.Inputs != "" {
+		for name := range embeddedInputs {
+			if b, err := os.ReadFile(filepath.Join(cfg.Inputs, name)); err == nil {
+				if err := os.WriteFile(filepath.Join(cfg.Out, name), b, 0o644); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	// the extra-credit goldens are the built-in simulator's, as a reference
+	// scheduler needn't do them.
+	if err := writeExtraCreditGoldens(cfg.Out); err != nil {
+		return err
+	}
+	// the embedded testdata's checksums, for attest, are kept up to date.
+	if _, err := os.Stat(filepath.Join(cfg.Out, testdataSumsFile)); err == nil {
+		if err := writeTestdataSums(cfg.Out); err != nil {
+			return fmt.Errorf("%s: %w", testdataSumsFile, err)
+		}
+	}
+
+	return nil
+}
+
+// deterministic runs the reference cmd.Runs times, returning its output when
+// every run printed the same.
+func (cfg GoldenConfig) deterministic(ctx context.Context, reference string, in []byte, args []string) ([]byte, error) {
+	var first []byte
+	for i := 0; i < cfg.Runs; i++ {
+		out, err := cfg.runReference(ctx, reference, in, args)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			first = out
+			continue
+		}
+		if !bytes.Equal(out, first) {
+			return nil, fmt.Errorf("reference output differs between runs 1 and %d, %s", i+1,
+				firstLineMismatch(out, first))
+		}
+	}
+	if len(first) == 0 {
+		return nil, errors.New("reference printed nothing")
+	}
+
+	return first, nil
+}
+
+func (cfg GoldenConfig) runReference(ctx context.Context, reference string, in []byte, args []string) ([]byte, error) {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	var stdout, stderr bytes.Buffer
+	run := exec.CommandContext(ctx, reference, args...)
+	killProcessGroup(run)
+	run.WaitDelay = time.Second
+	run.Stdin = bytes.NewReader(in)
+	run.Stdout = &stdout
+	run.Stderr = &stderr
+	if err := run.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("reference timed out after %s", cfg.Timeout)
+		}
+		return nil, fmt.Errorf("reference %v: %w\n%s", args, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	// golden files have Unix line endings, wherever they're generated.
+	return textOutput(stdout.Bytes()), nil
+}
@@ -0,0 +1,102 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// goMod is what CheckModule inspects of a go.mod.
+type goMod struct {
+	// module is the module directive's path, and goVersion the go
+	// directive's version; either is empty when missing.
+	module, goVersion string
+	// requires are the required module paths, in order.
+	requires []string
+}
+
+// parseGoMod reads the module, go and require directives, including require
+// blocks, ignoring comments and everything else.
+func parseGoMod(gomod []byte) goMod {
+	var (
+		m       goMod
+		inBlock bool
+	)
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock:
+			if fields[0] == ")" {
+				inBlock = false
+				continue
+			}
+			m.requires = append(m.requires, unquote(fields[0]))
+		case len(fields) < 2:
+		case fields[0] == "module" && m.module == "":
+			// a quoted path may contain spaces.
+			m.module = unquote(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "module")))
+		case fields[0] == "go":
+			m.goVersion = fields[1]
+		case fields[0] == "require" && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "require":
+			m.requires = append(m.requires, unquote(fields[1]))
+		}
+	}
+
+	return m
+}
+
+func unquote(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+
+	return s
+}
+
+// checkModulePath rejects paths the go command wouldn't accept, e.g. "my
+// scheduler": each /-separated element must be non-empty ASCII letters,
+// digits and -._~, not starting or ending with a dot.
+func checkModulePath(path string) error {
+	if strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("module path %q has a leading or trailing slash", path)
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "" {
+			return fmt.Errorf("module path %q has an empty element", path)
+		}
+		if strings.HasPrefix(elem, ".") || strings.HasSuffix(elem, ".") {
+			return fmt.Errorf("module path %q has an element starting or ending with a dot", path)
+		}
+		for _, r := range elem {
+			if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("-._~", r)) {
+				return fmt.Errorf("module path %q contains %q", path, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkGoDirective rejects a missing or malformed go version, or one newer
+// than the grading toolchain's (if known), which couldn't build it.
+func checkGoDirective(version, toolchain string) error {
+	switch {
+	case version == "":
+		return errors.New("no go directive")
+	case !goVersionPattern.MatchString(version):
+		return fmt.Errorf("malformed go version %q, e.g. 1.21", version)
+	case toolchain != "" && compareGoVersions(version, toolchain) > 0:
+		return fmt.Errorf("go %s is newer than the grader's go %s", version, toolchain)
+	}
+
+	return nil
+}
+
+// goVersionPattern matches go directive versions like 1.21, 1.21.5 and 1.22rc1.
+var goVersionPattern = regexp.MustCompile(`^1\.[0-9]+(\.[0-9]+|(rc|beta)[0-9]+)?$`)
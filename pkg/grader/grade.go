@@ -0,0 +1,562 @@
+package grader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+)
+
+type (
+	// Options configures a Grade run.
+	Options struct {
+		// OnResult, if set, is called as each check completes.
+		OnResult func(Result)
+		// OnProgress, if set, is called as a scheduler run's output is
+		// compared, every so often and once when the run ends.
+		OnProgress func(Progress)
+		// TimeoutGrace is how long to wait for output pipes to drain after the
+		// scheduler is killed or exits, before giving up on them.
+		TimeoutGrace time.Duration
+		// Timeout bounds each scheduler run; zero means no limit.
+		Timeout time.Duration
+		// Strict compares scheduler output byte for byte, without normalizing
+		// line endings and trailing whitespace.
+		Strict bool
+		// Structured compares output as records of fields, ignoring spacing and
+		// table borders, unless Strict.
+		Structured bool
+		// Metrics grades output per metric (see compareMetrics), unless Strict.
+		Metrics bool
+		// Normalize selects normalizeOutput's steps, unless Strict; nil means all.
+		Normalize []string
+		// Epsilon allows numbers in the output to differ by up to this much, unless Strict.
+		Epsilon float64
+		// SkipPreamble compares output from the first line matching the
+		// expected output's first line, ignoring anything printed before it.
+		SkipPreamble bool
+		// Partial awards credit for the fraction of expected lines that match.
+		Partial bool
+		// Debug prints a diff of mismatched scheduler output.
+		Debug bool
+		// KeepDiffs keeps the diffs of mismatched scheduler output in
+		// Result.Diff, for --feedback.
+		KeepDiffs bool
+		// Points overrides the possible points of rubric items, by label.
+		Points map[string]int
+		// Hints adds a hint to failed results: the hint engine's (see
+		// hintRules) for mismatched output, or else the item's ItemHints.
+		Hints     bool
+		ItemHints map[string]string
+		// Tolerances overrides golden output field comparisons, by field.
+		Tolerances map[string]fieldSpec
+		// Only and Skip select rubric items by identifier (e.g. "rr", "readme").
+		Only, Skip []string
+		// FailFast skips the remaining checks once one returns an error. The
+		// checks then run in rubric order, one at a time.
+		FailFast bool
+		// GoToolchain, when set (e.g. "go1.21.5"), is the toolchain the go
+		// commands building and vetting the submission run as, via GOTOOLCHAIN.
+		GoToolchain string
+		// MaxOutput, when set, bounds (in bytes) the output captured of each
+		// scheduler run, which is killed once it exceeds it.
+		MaxOutput uint64
+		// MemLimit and CPULimit, when set, bound each scheduler run's address
+		// space (committed memory on Windows, in bytes) and CPU time. Only
+		// supported on Linux and Windows, or with Sandbox.
+		MemLimit uint64
+		CPULimit time.Duration
+		// ProcLimit, when set, is the RLIMIT_NPROC of each scheduler run. The
+		// kernel counts all of the user's processes and threads against it, and
+		// doesn't apply it to root. On Windows it limits the scheduler's
+		// processes, with a job object.
+		ProcLimit uint64
+		// Sandbox, when "docker", builds and runs the scheduler in a container
+		// of SandboxImage; the limits then apply to the container.
+		Sandbox, SandboxImage string
+		// Style also checks the code style, with StyleTools (see styleTools);
+		// nil means all of them.
+		Style      bool
+		StyleTools []string
+		// Parallel bounds how many independent checks run at once; zero
+		// means no limit.
+		Parallel int
+		// Random, when set, also grades this many randomized process tables
+		// per algorithm, generated from Seed.
+		Random int
+		Seed   int64
+		// Repeat, when above 1, also checks that this many runs of each
+		// embedded input print the same.
+		Repeat int
+		// Stress, when set, also runs each algorithm on a table of this many
+		// processes, which must finish within StressBudget.
+		Stress       int
+		StressBudget time.Duration
+		// Robustness also checks that malformed inputs are rejected gracefully.
+		Robustness bool
+		// Fuzz, when positive, is how long to fuzz the scheduler's input
+		// handling for (see CheckFuzz).
+		Fuzz time.Duration
+		// Race also replays the embedded inputs on a -race build.
+		Race bool
+		// Hygiene also checks the submission for files that don't belong.
+		Hygiene bool
+		// History also checks a git checkout's history for at least
+		// MinCommits commits, on CommitDays days.
+		History                bool
+		MinCommits, CommitDays int
+		// Forbidden, when set, also rejects source using anything on it.
+		Forbidden *denyList
+		// StudentTests also runs the submission's own tests, within
+		// TestTimeout (zero means no limit).
+		StudentTests bool
+		TestTimeout  time.Duration
+		// Coverage, when set, also grades the tests' statement coverage, in
+		// percent, with full credit at this much.
+		Coverage float64
+		// Deadline, when set, adds a late penalty row (see latePenalty) of
+		// LatePenalty percent per day, up to MaxPenalty percent.
+		Deadline                time.Time
+		LatePenalty, MaxPenalty float64
+		// Retries re-runs a failing scheduler up to this many times, keeping the best result.
+		Retries int
+		// LogLevel filters the checks' logs; nil means slog.LevelInfo.
+		LogLevel slog.Leveler
+		// LogJSON formats the checks' logs as JSON records.
+		LogJSON bool
+		// Out receives mismatch diffs (with Debug); nil means stdout.
+		Out io.Writer
+		// DiffLines caps each mismatch diff at about this many lines, in whole
+		// hunks; zero means no limit.
+		DiffLines int
+		// BuildCmd and RunCmd build and run a non-Go scheduler, in its
+		// directory. With no RunCmd, the submission is built per its language.
+		BuildCmd, RunCmd []string
+		// MainPkg, if set, is the main package to build, relative to the
+		// submission, instead of finding it.
+		MainPkg string
+		// Lang, if set, is the submissions' language (e.g. "c"), instead of
+		// detecting each one's from its files.
+		Lang string
+		// NoCache always rebuilds the scheduler, instead of reusing a cached build.
+		NoCache bool
+		// RerunFailed reuses the passing results of the last RerunFailed
+		// grade of dir, when nothing they depend on has changed.
+		RerunFailed bool
+		// ReadmeWords is how many words of prose README.md needs, besides
+		// its required sections and a code block.
+		ReadmeWords int
+		// Module also checks the submission's go.mod, and ModulePrefix, when
+		// set, is its required module path prefix.
+		Module       bool
+		ModulePrefix string
+		// AllowDeps allows go.mod requirements, besides the standard library.
+		AllowDeps bool
+		// Cases replaces the embedded scheduler testdata, by algorithm.
+		Cases map[string][]schedulerCase
+		// Testdata overrides embedded testdata files, by name (e.g. "fcfs.csv").
+		Testdata map[string][]byte
+		// ScriptChecks are the rubric config's script checks, graded last.
+		ScriptChecks []scriptCheck
+		// Project selects the rubric (see projects); empty is defaultProject's.
+		Project string
+	}
+	Context struct {
+		// ctx is cancelled on interrupt, stopping any running command.
+		ctx context.Context
+		// log collects the running check's logs into its result.
+		log    *slog.Logger
+		opts   Options
+		srcDir string
+		binary string
+		// cached is set when binary is in the build cache, so it's kept after grading.
+		cached bool
+		// run is the command that runs the built scheduler, before its flags.
+		run []string
+		// lang is the submission's language; empty with a RunCmd.
+		lang string
+		// stderr collects the check's scheduler runs' stderr, for Result.Stderr.
+		stderr []string
+		// diffs collects the check's output mismatch diffs, for Result.Diff,
+		// and hints the hint engine's hints for them, for Result.Hint.
+		diffs, hints []string
+		// warnings are what the check noticed of a passing run, for
+		// Result.Warning.
+		warnings []string
+		// work is the temp dir builds write to (see buildDir), never the submission.
+		work string
+		// usage collects the check's scheduler runs' resource use, for
+		// Result.Usage.
+		usage *runUsage
+		// flags and input are the styles of the scheduler's algorithm flags
+		// and input, as detected by the Compilable check.
+		flags flagStyle
+		input inputStyle
+		// label is the running check's, and progress its OnProgress.
+		label    string
+		progress func(Progress)
+		// transient is set when a scheduler run failed for want of the
+		// system rather than the scheduler, e.g. it couldn't be started, so
+		// the check may be retried (see rubricItem.retries).
+		transient *atomic.Bool
+	}
+	Check  func(*Context) (Result, error)
+	Result struct {
+		Label    string `json:"label"`
+		Awarded  int    `json:"awarded"`
+		Possible int    `json:"possible"`
+		Message  string `json:"message"`
+		// Error is the error the check returned, if any.
+		Error string `json:"error,omitempty"`
+		// Stderr is the tail of what each of the check's scheduler runs wrote
+		// to stderr, under the run's arguments.
+		Stderr string `json:"stderr,omitempty"`
+		// Logs are the check's log lines, in order.
+		Logs []string `json:"logs,omitempty"`
+		// Duration is the wall-clock time the check took.
+		Duration time.Duration `json:"duration_ns"`
+		// Usage is the resources the check's scheduler runs used, if any.
+		Usage *runUsage `json:"usage,omitempty"`
+		// Hint is a suggestion of what to fix, if the check failed.
+		Hint string `json:"hint,omitempty"`
+		// Warning is what looks wrong, a line each, though it didn't cost
+		// points, e.g. output identical without any input.
+		Warning string `json:"warning,omitempty"`
+		// ExtraCredit results award points above the total: their possible
+		// points aren't counted in it (see resultTotals).
+		ExtraCredit bool `json:"extra_credit,omitempty"`
+		// Skipped results weren't graded, e.g. as a check they depend on
+		// failed: they're awarded nothing, and the message says why.
+		Skipped bool `json:"skipped,omitempty"`
+		// Diff is the check's output mismatches, with KeepDiffs; it's only in
+		// the --report, for instructors, as it gives the expected output away.
+		Diff string `json:"-"`
+	}
+	// Progress is how far a check's scheduler run has got through the
+	// expected output, for Options.OnProgress.
+	Progress struct {
+		Label string   `json:"label"`
+		Args  []string `json:"args"`
+		// Line is how many lines of output have been read, of about Total
+		// expected.
+		Line  int `json:"line"`
+		Total int `json:"total"`
+	}
+)
+
+// Percent is how much of the expected output has been read, at most 100.
+func (p Progress) Percent() float64 {
+	return 100 * float64(min(p.Line, p.Total)) / float64(max(p.Total, 1))
+}
+
+// possible returns the points a rubric item is worth.
+func (o Options) possible(label string) int {
+	if pts, ok := o.Points[label]; ok {
+		return pts
+	}
+
+	return defaultPoints[label]
+}
+
+// skipped is the result of an item that isn't run, for the reason.
+func (o Options) skipped(item rubricItem, reason string) Result {
+	return Result{
+		Label:       item.label,
+		Possible:    o.possible(item.label),
+		Message:     "skipped: " + reason,
+		Skipped:     true,
+		ExtraCredit: slices.Contains(extraCreditLabels, item.label),
+	}
+}
+
+// Grade runs the rubric against the submission in dir, as a graph: each
+// check starts once the ones it's after have finished (see rubricItem). Once
+// ctx is cancelled, running checks are stopped and the remaining ones are
+// skipped.
+func Grade(ctx context.Context, dir string, opts Options) []Result {
+	return gradeItems(ctx, dir, opts, selectItems(rubricItems(opts), opts.Only, opts.Skip))
+}
+
+// gradeItems runs the items against the submission in dir, as Grade does
+// the rubric's.
+func gradeItems(ctx context.Context, dir string, opts Options, items []rubricItem) (results []Result) {
+	var (
+		rubric Context
+		mu     sync.Mutex
+		failed bool // with FailFast, checks run one at a time
+		index  = make(map[string]int, len(items))
+		// setups are the contexts the setup items left, e.g. with the binary.
+		setups = make([]*Context, len(items))
+	)
+	results = make([]Result, len(items))
+	for i, item := range items {
+		index[item.id] = i
+	}
+	// an absolute source path keeps the binary path valid regardless of the working directory.
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	rubric.ctx = ctx
+	rubric.srcDir = dir
+	rubric.opts = opts
+	if len(opts.RunCmd) == 0 {
+		rubric.lang = opts.Lang
+		if rubric.lang == "" {
+			rubric.lang = detectLanguage(dir)
+		}
+	}
+	// cleanup, even when a check bails out early or the run is interrupted.
+	defer func() {
+		for _, setup := range setups {
+			if setup == nil {
+				continue
+			}
+			if setup.binary != "" && !setup.cached {
+				_ = os.RemoveAll(setup.binary)
+			}
+			if setup.work != "" {
+				_ = os.RemoveAll(setup.work)
+			}
+		}
+	}()
+	// with RerunFailed, unchanged passes are reused, and the rest re-run
+	// and cached for next time.
+	var reused map[int]Result
+	if opts.RerunFailed {
+		if keys, err := resultKeys(dir, opts, items); err != nil {
+			slog.Warn("could not hash the submission for --rerun-failed", slog.String("err", err.Error()))
+		} else {
+			cache := loadResultCache(dir)
+			reused = cachedPasses(cache, keys, items)
+			if len(reused) > 0 {
+				slog.Info("reusing unchanged passing results", slog.String("dir", dir), slog.Int("checks", len(reused)))
+			}
+			defer func() {
+				for i, item := range items {
+					cache[item.id] = cachedResult{Key: keys[item.id], Result: results[i]}
+				}
+				if ctx.Err() == nil {
+					if err := cache.save(dir); err != nil {
+						slog.Warn("could not cache results for --rerun-failed", slog.String("err", err.Error()))
+					}
+				}
+			}()
+		}
+	}
+	run := func(i int, item rubricItem) {
+		if ctx.Err() != nil {
+			results[i] = opts.skipped(item, "interrupted")
+			return
+		}
+		if r, ok := reused[i]; ok {
+			results[i] = r
+			if opts.OnResult != nil {
+				mu.Lock()
+				opts.OnResult(r)
+				mu.Unlock()
+			}
+			return
+		}
+		if failed {
+			results[i] = opts.skipped(item, "an earlier check failed")
+			return
+		}
+		// a check whose dependency failed (or was skipped) isn't run: its
+		// failure would only repeat the dependency's.
+		for _, id := range item.needs {
+			if j, ok := index[id]; ok && (results[j].Error != "" || results[j].Skipped) {
+				result := opts.skipped(item, results[j].Label+" failed")
+				results[i] = result
+				if opts.OnResult != nil {
+					mu.Lock()
+					opts.OnResult(result)
+					mu.Unlock()
+				}
+				return
+			}
+		}
+		// each check logs into its own buffer, via its own copy of the context,
+		// with what the setup items it needs set up.
+		var logs logLines
+		logger := newCheckLogger(&logs, opts.LogLevel, opts.LogJSON)
+		if opts.LogJSON {
+			logger = logger.With(slog.String("check", item.label))
+		}
+		attempt := func() Context {
+			check := rubric
+			for _, id := range item.needs {
+				if j, ok := index[id]; ok && setups[j] != nil {
+					check = *setups[j]
+				}
+			}
+			check.log, check.usage, check.transient = logger, &runUsage{}, &atomic.Bool{}
+			check.label = item.label
+			if opts.OnProgress != nil {
+				check.progress = func(p Progress) {
+					mu.Lock()
+					defer mu.Unlock()
+					opts.OnProgress(p)
+				}
+			}
+			return check
+		}
+		check := attempt()
+		start := time.Now()
+		result, err := runCheck(item, &check)
+		// a transient failure, e.g. a scheduler that couldn't be started, is
+		// retried afresh, as often as the item's policy allows.
+		for n := 0; err != nil && check.transient.Load() && n < item.retries && ctx.Err() == nil; n++ {
+			logger.Info("retrying after a transient failure", slog.String("err", err.Error()), slog.Int("retry", n+1))
+			check = attempt()
+			result, err = runCheck(item, &check)
+		}
+		result.Duration = time.Since(start)
+		if err != nil && ctx.Err() != nil {
+			result.Message = "interrupted"
+		}
+		if err != nil {
+			result.Error = err.Error()
+			// errors are summarized after the results table, unless the logs
+			// are for machines.
+			if opts.LogJSON {
+				check.log.Error(result.Label, slog.String("err", err.Error()))
+			}
+			if opts.FailFast {
+				failed = true
+			}
+		}
+		result.Logs = logs.lines
+		result.Stderr = strings.Join(check.stderr, "\n")
+		result.Diff = strings.Join(check.diffs, "\n")
+		result.Warning = strings.Join(check.warnings, "\n")
+		if check.usage.Runs > 0 {
+			result.Usage = check.usage
+		}
+		if opts.Hints && result.Awarded < result.Possible {
+			result.Hint = strings.Join(check.hints, "\n")
+			if result.Hint == "" {
+				result.Hint = opts.ItemHints[result.Label]
+			}
+		}
+		if item.setup {
+			// the checks needing it use what it set up, e.g. the binary.
+			check.log, check.stderr, check.diffs, check.hints, check.warnings, check.usage, check.transient = nil, nil, nil, nil, nil, nil, nil
+			setups[i] = &check
+		}
+		// results go in fixed slots so the report order doesn't depend on completion order.
+		results[i] = result
+		if opts.OnResult != nil {
+			// callbacks are serialized so they never need their own locking.
+			mu.Lock()
+			opts.OnResult(result)
+			mu.Unlock()
+		}
+	}
+
+	// the late penalty, if any, is of the checks' points, after them all.
+	if !opts.Deadline.IsZero() {
+		defer func() {
+			results = append(results, latePenalty(ctx, dir, opts, results))
+		}()
+	}
+	// ready items start the costliest first, at most Parallel at a time; with
+	// FailFast, one at a time in rubric order, so "first" failure means that.
+	limit := opts.Parallel
+	if opts.FailFast {
+		limit = 1
+	}
+	var (
+		started  = make([]bool, len(items))
+		finished = make([]bool, len(items))
+		done     = make(chan int)
+		running  int
+	)
+	ready := func(i int) bool {
+		for _, id := range items[i].dependencies() {
+			if j, ok := index[id]; ok && !finished[j] {
+				return false
+			}
+		}
+		return true
+	}
+	for remaining := len(items); remaining > 0; remaining-- {
+		var next []int
+		for i := range items {
+			if !started[i] && ready(i) {
+				next = append(next, i)
+			}
+		}
+		if !opts.FailFast {
+			sort.SliceStable(next, func(a, b int) bool { return items[next[a]].weight() > items[next[b]].weight() })
+		}
+		for _, i := range next {
+			if limit > 0 && running == limit {
+				break
+			}
+			started[i], running = true, running+1
+			go func(i int) {
+				run(i, items[i])
+				done <- i
+			}(i)
+		}
+		if running == 0 {
+			// what's left waits on itself: a cycle, which the rubric mustn't have.
+			for i, item := range items {
+				if !started[i] {
+					results[i] = opts.skipped(item, "its dependencies form a cycle")
+				}
+			}
+			break
+		}
+		i := <-done
+		finished[i], running = true, running-1
+	}
+
+	return results
+}
+
+// runCheck runs the item's check, turning a panic into a zero-point result
+// with the panic's stack as its stderr, so one broken check can't take down
+// the whole run.
+func runCheck(item rubricItem, c *Context) (result Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = Result{Label: item.label, Possible: c.opts.possible(item.label), Message: fmt.Sprintf("check panicked: %v", r)}
+			err = fmt.Errorf("panic: %v", r)
+			c.stderr = append(c.stderr, fmt.Sprintf("panic: %v\n\n%s", r, debug.Stack()))
+		}
+	}()
+
+	return item.check(c)
+}
+
+func (o Options) out() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+
+	return o.Out
+}
+
+// colorDiff reports whether diffs are colorized: only for a terminal, and
+// not when $NO_COLOR is set.
+func (o Options) colorDiff() bool {
+	f, ok := o.out().(*os.File)
+
+	return ok && isTerminal(f) && os.Getenv("NO_COLOR") == ""
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,321 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testItems returns rubric items of the specs, "id" or "id<need,need",
+// whose checks record the order they start in, and fail when their id is in
+// failing.
+func testItems(started *[]string, failing ...string) func(specs ...string) []rubricItem {
+	var mu sync.Mutex
+	return func(specs ...string) []rubricItem {
+		var items []rubricItem
+		for _, spec := range specs {
+			id, needs, _ := strings.Cut(spec, "<")
+			item := rubricItem{id: id, label: id}
+			if needs != "" {
+				item.needs = strings.Split(needs, ",")
+			}
+			item.check = func(c *Context) (Result, error) {
+				mu.Lock()
+				*started = append(*started, id)
+				mu.Unlock()
+				result := Result{Label: id, Possible: 10, Awarded: 10}
+				if slices.Contains(failing, id) {
+					result.Awarded = 0
+					return result, errors.New("failed")
+				}
+				return result, nil
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+}
+
+func TestGradeItemsOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		items   func(func(...string) []rubricItem) []rubricItem
+		failing []string
+		started []string
+		skipped map[string]string // by id, the reason
+	}{
+		{
+			name: "costliest first",
+			opts: Options{Parallel: 1},
+			items: func(items func(...string) []rubricItem) []rubricItem {
+				its := items("cheap", "costly", "middling")
+				its[1].cost, its[2].cost = 5, 3
+				return its
+			},
+			started: []string{"costly", "middling", "cheap"},
+		},
+		{
+			name: "needs first",
+			opts: Options{Parallel: 1},
+			items: func(items func(...string) []rubricItem) []rubricItem {
+				its := items("compile", "fcfs<compile", "style")
+				its[1].cost = 5
+				return its
+			},
+			// fcfs, the costliest, waits for compile, then goes first.
+			started: []string{"compile", "fcfs", "style"},
+		},
+		{
+			name: "after",
+			opts: Options{Parallel: 1},
+			items: func(items func(...string) []rubricItem) []rubricItem {
+				its := items("last", "first")
+				its[0].after, its[0].cost = []string{"first"}, 5
+				return its
+			},
+			started: []string{"first", "last"},
+		},
+		{
+			name: "failed need",
+			items: func(items func(...string) []rubricItem) []rubricItem {
+				its := items("compile", "fcfs<compile", "style")
+				its[2].after = []string{"compile"}
+				return its
+			},
+			failing: []string{"compile"},
+			// what's after a failure still runs; what needs it doesn't.
+			started: []string{"compile", "style"},
+			skipped: map[string]string{"fcfs": "skipped: compile failed"},
+		},
+		{
+			name:    "fail fast",
+			opts:    Options{FailFast: true, Parallel: 4},
+			items:   func(items func(...string) []rubricItem) []rubricItem { return items("cheap", "fails", "costly") },
+			failing: []string{"fails"},
+			// in rubric order, whatever the costs, until the first failure.
+			started: []string{"cheap", "fails"},
+			skipped: map[string]string{"costly": "skipped: an earlier check failed"},
+		},
+		{
+			name: "cycle",
+			items: func(items func(...string) []rubricItem) []rubricItem {
+				return items("a", "b<c", "c<b")
+			},
+			started: []string{"a"},
+			skipped: map[string]string{"b": "skipped: its dependencies form a cycle", "c": "skipped: its dependencies form a cycle"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var started []string
+			items := tt.items(testItems(&started, tt.failing...))
+			results := gradeItems(context.Background(), t.TempDir(), tt.opts, items)
+
+			if tt.opts.Parallel == 1 || tt.opts.FailFast {
+				if !slices.Equal(started, tt.started) {
+					t.Errorf("started %v, want %v", started, tt.started)
+				}
+			} else {
+				want := slices.Clone(tt.started)
+				slices.Sort(started)
+				slices.Sort(want)
+				if !slices.Equal(started, want) {
+					t.Errorf("started %v, want %v", started, want)
+				}
+			}
+			// results are in rubric order, whatever the order they ran in.
+			if len(results) != len(items) {
+				t.Fatalf("%d results, want %d", len(results), len(items))
+			}
+			for i, item := range items {
+				r := results[i]
+				if r.Label != item.label {
+					t.Errorf("result %d is %s's, want %s's", i, r.Label, item.label)
+				}
+				if reason, ok := tt.skipped[item.id]; ok != r.Skipped || r.Skipped && r.Message != reason {
+					t.Errorf("%s: skipped %t (%q), want %t (%q)", item.id, r.Skipped, r.Message, ok, reason)
+				}
+			}
+		})
+	}
+}
+
+func TestGradeItemsParallel(t *testing.T) {
+	const n, limit = 6, 3
+	var (
+		mu            sync.Mutex
+		running, peak int
+		starts        = make(chan struct{}, n)
+		release       = make(chan struct{})
+	)
+	var items []rubricItem
+	for i := 0; i < n; i++ {
+		id := string(rune('a' + i))
+		items = append(items, rubricItem{id: id, label: id, check: func(c *Context) (Result, error) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			starts <- struct{}{}
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return Result{Label: c.label}, nil
+		}})
+	}
+	done := make(chan []Result)
+	go func() { done <- gradeItems(context.Background(), t.TempDir(), Options{Parallel: limit}, items) }()
+
+	// the first limit checks start at once, and no more until one's done.
+	for i := 0; i < limit; i++ {
+		<-starts
+	}
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	if running != limit {
+		t.Errorf("%d checks running, want %d", running, limit)
+	}
+	mu.Unlock()
+	close(release)
+	if results := <-done; len(results) != n {
+		t.Errorf("%d results, want %d", len(results), n)
+	}
+	if peak != limit {
+		t.Errorf("at most %d checks ran at once, want %d", peak, limit)
+	}
+}
+
+func TestGradeItemsInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started []string
+	items := testItems(&started)("first", "second<first")
+	first := items[0].check
+	items[0].check = func(c *Context) (Result, error) {
+		cancel()
+		return first(c)
+	}
+	results := gradeItems(ctx, t.TempDir(), Options{}, items)
+	if !slices.Equal(started, []string{"first"}) {
+		t.Errorf("started %v, want just first", started)
+	}
+	if r := results[1]; !r.Skipped || r.Message != "skipped: interrupted" {
+		t.Errorf("second: %+v, want skipped as interrupted", r)
+	}
+}
+
+func TestGradeItemsPanic(t *testing.T) {
+	items := []rubricItem{{id: "boom", label: "Boom", check: func(*Context) (Result, error) { panic("oops") }}}
+	results := gradeItems(context.Background(), t.TempDir(), Options{}, items)
+	if r := results[0]; r.Message != "check panicked: oops" || r.Error == "" || !strings.Contains(r.Stderr, "panic: oops") {
+		t.Errorf("result %+v, want the panic, with its stack", r)
+	}
+}
+
+func TestGradeItemsOnResult(t *testing.T) {
+	var started []string
+	var labels []string
+	opts := Options{OnResult: func(r Result) { labels = append(labels, r.Label) }}
+	gradeItems(context.Background(), t.TempDir(), opts, testItems(&started, "compile")("compile", "fcfs<compile"))
+	slices.Sort(labels)
+	if !slices.Equal(labels, []string{"compile", "fcfs"}) {
+		t.Errorf("OnResult called for %v, want compile and the skipped fcfs", labels)
+	}
+}
+
+func TestSelectItems(t *testing.T) {
+	var started []string
+	items := testItems(&started)("compile", "fcfs<compile", "sjf<compile", "style")
+	tests := []struct {
+		only, skip []string
+		want       []string
+	}{
+		{want: []string{"compile", "fcfs", "sjf", "style"}},
+		// an item's needs are kept for it.
+		{only: []string{"fcfs"}, want: []string{"compile", "fcfs"}},
+		{skip: []string{"fcfs", "style"}, want: []string{"compile", "sjf"}},
+		{only: []string{"style"}, want: []string{"style"}},
+	}
+	for _, tt := range tests {
+		var ids []string
+		for _, item := range selectItems(items, tt.only, tt.skip) {
+			ids = append(ids, item.id)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("selectItems(only %v, skip %v) = %v, want %v", tt.only, tt.skip, ids, tt.want)
+		}
+	}
+}
+
+func TestNeedsGo(t *testing.T) {
+	tests := []struct {
+		opts Config
+		want bool
+	}{
+		{opts: Config{Lang: "auto", Sandbox: "none"}, want: true},
+		{opts: Config{Lang: "go", Sandbox: "none"}, want: true},
+		{opts: Config{Lang: "c", Sandbox: "none"}},
+		{opts: Config{Lang: "python", Sandbox: "none"}},
+		{opts: Config{Lang: "auto", Sandbox: "none", RunCmd: "python3 scheduler.py"}},
+		{opts: Config{Lang: "auto", Sandbox: sandboxDocker}},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.needsGo(); got != tt.want {
+			t.Errorf("needsGo() with --lang %s --sandbox %s --run-cmd %q = %t, want %t", tt.opts.Lang, tt.opts.Sandbox, tt.opts.RunCmd, got, tt.want)
+		}
+	}
+}
+
+func TestProbeStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	const in, out = "A 1 2\n", "A 1 2\n"
+	tests := []struct {
+		name      string
+		scheduler string // a shell script
+		check     func(c *Context) (Result, error)
+		runs      int
+		warned    bool
+	}{
+		{name: "reads stdin", scheduler: "cat", runs: 2,
+			check: CheckScheduler(Result{Label: "FCFS", Possible: 10}, "-fcfs", []byte(in), []byte(out))},
+		{name: "hardcoded", scheduler: "printf 'A 1 2\\n'", runs: 2, warned: true,
+			check: CheckScheduler(Result{Label: "FCFS", Possible: 10}, "-fcfs", []byte(in), []byte(out))},
+		// the probe runs once, whatever the quanta.
+		{name: "round robin", scheduler: "printf 'A 1 2\\n'", runs: 4, warned: true,
+			check: CheckRoundRobin(Result{Label: "RR", Possible: 10}, []byte(in),
+				quantumCase{1, []byte(out)}, quantumCase{2, []byte(out)}, quantumCase{4, []byte(out)})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Context{
+				ctx:    context.Background(),
+				log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+				opts:   Options{Timeout: time.Minute, MaxOutput: 1 << 20},
+				srcDir: t.TempDir(),
+				usage:  &runUsage{},
+				run:    []string{"/bin/sh", "-c", tt.scheduler, "sh"},
+			}
+			result, err := tt.check(c)
+			// a warning doesn't fail the check.
+			if err != nil || result.Awarded != result.Possible || result.Message != "" {
+				t.Errorf("check = %+v, %v, want full marks", result, err)
+			}
+			if warned := len(c.warnings) > 0; warned != tt.warned {
+				t.Errorf("warnings %q, want warned %t", c.warnings, tt.warned)
+			}
+			if c.usage.Runs != tt.runs {
+				t.Errorf("%d runs, want %d", c.usage.Runs, tt.runs)
+			}
+		})
+	}
+}
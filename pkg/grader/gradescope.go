@@ -0,0 +1,69 @@
+package grader
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The Gradescope autograder layout: the submission is unpacked to
+// gradescopeSubmissionDir, and the results are read from gradescopeResultsFile.
+const (
+	gradescopeSubmissionDir = "/autograder/submission"
+	gradescopeResultsFile   = "/autograder/results/results.json"
+)
+
+// gradescopeReport is Gradescope's autograder results.json; each rubric item is a test.
+type gradescopeReport struct {
+	Score         float64          `json:"score"`
+	ExecutionTime int              `json:"execution_time"` // seconds
+	Tests         []gradescopeTest `json:"tests"`
+}
+
+type gradescopeTest struct {
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	MaxScore int    `json:"max_score"`
+	Status   string `json:"status"`
+	Output   string `json:"output,omitempty"`
+}
+
+// writeGradescope writes the submission's results to path in Gradescope's
+// autograder schema. The score is normalized like the results, if at all.
+func writeGradescope(path string, opts options, s submission) error {
+	awarded, possible := s.totals()
+	report := gradescopeReport{Score: float64(awarded)}
+	if opts.NormalizeTo > 0 {
+		report.Score = normalize(awarded, possible, opts.NormalizeTo)
+	}
+	var elapsed time.Duration
+	for _, r := range s.results {
+		test := gradescopeTest{Name: r.Label, Score: r.Awarded, MaxScore: r.Possible, Status: "passed", Output: r.reportMessage()}
+		switch {
+		case r.ExtraCredit:
+			// Gradescope takes points over the max as extra credit.
+			test.MaxScore = 0
+		case r.Awarded < r.Possible:
+			test.Status = "failed"
+		}
+		if len(r.Logs) > 0 {
+			test.Output = strings.TrimPrefix(test.Output+"\n\n"+strings.Join(r.Logs, "\n"), "\n\n")
+		}
+		report.Tests = append(report.Tests, test)
+		elapsed += r.Duration
+	}
+	report.ExecutionTime = int(math.Ceil(elapsed.Seconds()))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
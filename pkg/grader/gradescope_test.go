@@ -0,0 +1,43 @@
+package grader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteGradescope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results", "results.json")
+	s := submission{dir: "alice", results: []Result{
+		{Label: "Compiles", Awarded: 10, Possible: 10},
+		{Label: "FCFS", Awarded: 5, Possible: 20},
+		{Label: "MLFQ", Possible: 5, ExtraCredit: true},
+		{Label: "Priority", Awarded: 5, Possible: 5, ExtraCredit: true},
+	}}
+	if err := writeGradescope(path, options{Config: Config{NormalizeTo: 10}}, s); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report gradescopeReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Score != 6.67 {
+		t.Errorf("score = %g, want 6.67, of 20/30", report.Score)
+	}
+	// extra credit is points over the max, not a failure.
+	want := []gradescopeTest{
+		{Name: "Compiles", Score: 10, MaxScore: 10, Status: "passed"},
+		{Name: "FCFS", Score: 5, MaxScore: 20, Status: "failed"},
+		{Name: "MLFQ", Status: "passed"},
+		{Name: "Priority", Score: 5, Status: "passed"},
+	}
+	if !reflect.DeepEqual(report.Tests, want) {
+		t.Errorf("tests = %+v, want %+v", report.Tests, want)
+	}
+}
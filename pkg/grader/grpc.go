@@ -0,0 +1,187 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	gradebotv1 "github.com/jh125486/CSCE4600_gradebot/api/gradebot/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The /v1 API as gRPC, serve's --grpc-addr: GradeService from the stubs
+// generated from api/gradebot/v1/gradebot.proto, over the same jobs as the
+// HTTP endpoints in api.go.
+
+// grpcServer is GradeService, over s's queue and jobs.
+type grpcServer struct {
+	gradebotv1.UnimplementedGradeServiceServer
+	s *server
+	// ctx is serve's: its streams end when it does, as the workers stop.
+	ctx context.Context
+}
+
+// newGRPCServer is a grpc.Server serving GradeService for s until ctx is
+// done, taking the largest upload the HTTP endpoints do.
+func newGRPCServer(ctx context.Context, s *server) *grpc.Server {
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(maxUploadBytes + 1<<10))
+	gradebotv1.RegisterGradeServiceServer(srv, grpcServer{s: s, ctx: ctx})
+
+	return srv
+}
+
+func (g grpcServer) Submit(ctx context.Context, req *gradebotv1.SubmitRequest) (*gradebotv1.SubmitResponse, error) {
+	api, err := g.s.newAPIJob()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+	// the student's token, in "authorization" metadata as over HTTP.
+	var token string
+	if auth := metadata.ValueFromIncomingContext(ctx, "authorization"); len(auth) > 0 {
+		token = bearerToken(auth[0])
+	}
+	job := gradeJob{done: make(chan jsonReport, 1), api: api}
+	if err := g.s.submit(req.GetStudent(), token, remote, req.GetArchive(), &job); err != nil {
+		g.s.forgetJob(api.id)
+		return nil, submitStatus(err)
+	}
+
+	return &gradebotv1.SubmitResponse{Id: api.id}, nil
+}
+
+func (g grpcServer) GetResult(ctx context.Context, req *gradebotv1.GetResultRequest) (*gradebotv1.GetResultResponse, error) {
+	job, ok := g.s.job(req.GetId())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no such submission (or its result has expired)")
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	return &gradebotv1.GetResultResponse{Id: job.id, State: protoState(job.state), Report: protoReport(job.report)}, nil
+}
+
+// StreamLogs sends a CheckEvent as each check is graded, those graded
+// already first, then the report, as the HTTP endpoint's lines.
+func (g grpcServer) StreamLogs(req *gradebotv1.StreamLogsRequest, stream gradebotv1.GradeService_StreamLogsServer) error {
+	job, ok := g.s.job(req.GetId())
+	if !ok {
+		return status.Error(codes.NotFound, "no such submission (or its result has expired)")
+	}
+	for sent := 0; ; {
+		results, report, changed := job.since(sent)
+		for i := range results {
+			event := &gradebotv1.CheckEvent{Event: &gradebotv1.CheckEvent_Result{Result: protoResult(results[i])}}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+		sent += len(results)
+		if report != nil {
+			return stream.Send(&gradebotv1.CheckEvent{Event: &gradebotv1.CheckEvent_Report{Report: protoReport(report)}})
+		}
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-g.ctx.Done():
+			return status.Error(codes.Unavailable, "the server is stopping")
+		}
+	}
+}
+
+// submitStatus is the gRPC status of a submission submit turned away.
+func submitStatus(err error) error {
+	var rejected *submitError
+	if !errors.As(err, &rejected) {
+		return status.Error(codes.Internal, "internal error")
+	}
+	code := codes.Internal
+	switch rejected.status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+
+	return status.Error(code, rejected.msg)
+}
+
+func protoState(state string) gradebotv1.State {
+	return gradebotv1.State(gradebotv1.State_value[state])
+}
+
+func protoReport(r *jsonReport) *gradebotv1.Report {
+	if r == nil {
+		return nil
+	}
+	report := &gradebotv1.Report{
+		Dir:         r.Dir,
+		Total:       int32(r.Total),
+		Possible:    int32(r.Possible),
+		Normalized:  r.Normalized,
+		Error:       r.Error,
+		Attestation: r.Attestation,
+		Receipt:     r.Receipt,
+	}
+	for _, result := range r.Results {
+		report.Results = append(report.Results, protoResult(result))
+	}
+	if r.RawTotal != nil {
+		raw := int32(*r.RawTotal)
+		report.RawTotal = &raw
+	}
+	if m := r.Environment; m != nil {
+		report.Environment = &gradebotv1.Environment{
+			Platform: m.Platform,
+			Go:       m.Go,
+			Gradebot: m.Gradebot,
+			Rubric:   m.Rubric,
+			Locale:   m.Locale,
+			Time:     timestamppb.New(m.Time),
+			Tree:     m.Tree,
+		}
+	}
+
+	return report
+}
+
+func protoResult(r Result) *gradebotv1.Result {
+	result := &gradebotv1.Result{
+		Label:       r.Label,
+		Awarded:     int32(r.Awarded),
+		Possible:    int32(r.Possible),
+		Message:     r.Message,
+		Error:       r.Error,
+		Stderr:      r.Stderr,
+		Logs:        r.Logs,
+		DurationNs:  int64(r.Duration),
+		Hint:        r.Hint,
+		ExtraCredit: r.ExtraCredit,
+		Skipped:     r.Skipped,
+		Warning:     r.Warning,
+	}
+	if u := r.Usage; u != nil {
+		result.Usage = &gradebotv1.Usage{
+			Runs:         int32(u.Runs),
+			WallNs:       int64(u.Wall),
+			UserNs:       int64(u.User),
+			SysNs:        int64(u.Sys),
+			PeakRssBytes: u.PeakRSS,
+		}
+	}
+
+	return result
+}
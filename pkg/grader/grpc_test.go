@@ -0,0 +1,209 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	gradebotv1 "github.com/jh125486/CSCE4600_gradebot/api/gradebot/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testGRPC serves GradeService for s over an in-memory connection, for the
+// test's duration.
+func testGRPC(t *testing.T, s *server) gradebotv1.GradeServiceClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(ctx, s)
+	go func() { _ = srv.Serve(lis) }()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		srv.GracefulStop()
+	})
+
+	return gradebotv1.NewGradeServiceClient(conn)
+}
+
+func testServer(queue int) *server {
+	return &server{
+		limit:  time.Minute,
+		queue:  make(chan gradeJob, queue),
+		last:   make(map[string]time.Time),
+		jobs:   make(map[string]*apiJob),
+		ttl:    time.Hour,
+		tokens: map[string]string{"alice": "alice-token", "bob": "bob-token"},
+	}
+}
+
+// withToken is ctx, sending token as a Submit's "authorization".
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPCSubmit(t *testing.T) {
+	s := testServer(1)
+	client := testGRPC(t, s)
+	zip := []byte("PK\x03\x04 not much of a zip")
+	tests := []struct {
+		name    string
+		token   string
+		req     *gradebotv1.SubmitRequest
+		want    codes.Code
+		wantMsg string
+	}{
+		{name: "bad student", token: "alice-token", req: &gradebotv1.SubmitRequest{Student: "../alice", Archive: zip}, want: codes.InvalidArgument},
+		{name: "no token", req: &gradebotv1.SubmitRequest{Student: "alice", Archive: zip}, want: codes.Unauthenticated},
+		{name: "another's token", token: "bob-token", req: &gradebotv1.SubmitRequest{Student: "alice", Archive: zip}, want: codes.Unauthenticated},
+		{name: "not a zip", token: "alice-token", req: &gradebotv1.SubmitRequest{Student: "alice", Archive: []byte("main.go")}, want: codes.InvalidArgument, wantMsg: "submission is not a zip"},
+		{name: "queued", token: "alice-token", req: &gradebotv1.SubmitRequest{Student: "alice", Archive: zip}, want: codes.OK},
+		{name: "too soon", token: "alice-token", req: &gradebotv1.SubmitRequest{Student: "alice", Archive: zip}, want: codes.ResourceExhausted},
+		{name: "queue full", token: "bob-token", req: &gradebotv1.SubmitRequest{Student: "bob", Archive: zip}, want: codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Submit(withToken(context.Background(), tt.token), tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("Submit() = %v, want %v", err, tt.want)
+			}
+			if tt.wantMsg != "" && status.Convert(err).Message() != tt.wantMsg {
+				t.Errorf("Submit() message = %q, want %q", status.Convert(err).Message(), tt.wantMsg)
+			}
+			if err != nil {
+				return
+			}
+			job := <-s.queue
+			t.Cleanup(func() { _ = os.RemoveAll(job.tmp) })
+			if job.student != "alice" || job.api == nil || job.api.id != resp.GetId() || job.remote == "" {
+				t.Errorf("queued %+v, want alice's, as %s", job, resp.GetId())
+			}
+			// taking the place of the worker, so the queue fills up again.
+			s.queue <- job
+		})
+	}
+	// only the queued submission is kept.
+	if len(s.jobs) != 1 {
+		t.Errorf("%d jobs, want just the queued one", len(s.jobs))
+	}
+}
+
+func TestGRPCGetResult(t *testing.T) {
+	s := testServer(0)
+	client := testGRPC(t, s)
+	if _, err := client.GetResult(context.Background(), &gradebotv1.GetResultRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetResult() of an unknown id = %v, want NotFound", err)
+	}
+
+	job, err := s.newAPIJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.GetResult(context.Background(), &gradebotv1.GetResultRequest{Id: job.id})
+	if err != nil || resp.GetState() != gradebotv1.State_STATE_QUEUED || resp.GetReport() != nil {
+		t.Errorf("GetResult() = %v, %v, want queued, without a report", resp, err)
+	}
+	job.finish(jsonReport{Dir: "alice", Total: 5, Possible: 10})
+	resp, err = client.GetResult(context.Background(), &gradebotv1.GetResultRequest{Id: job.id})
+	if err != nil || resp.GetState() != gradebotv1.State_STATE_DONE || resp.GetReport().GetTotal() != 5 {
+		t.Errorf("GetResult() = %v, %v, want done, with its report", resp, err)
+	}
+}
+
+func TestGRPCStreamLogs(t *testing.T) {
+	s := testServer(0)
+	client := testGRPC(t, s)
+	job, err := s.newAPIJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.setState(stateRunning)
+	job.add(Result{Label: "Compiles", Awarded: 10, Possible: 10})
+
+	stream, err := client.StreamLogs(context.Background(), &gradebotv1.StreamLogsRequest{Id: job.id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// graded already, then as it's graded.
+	event, err := stream.Recv()
+	if err != nil || event.GetResult().GetLabel() != "Compiles" {
+		t.Fatalf("first event = %v, %v, want Compiles's result", event, err)
+	}
+	job.add(Result{Label: "FCFS", Possible: 20})
+	if event, err := stream.Recv(); err != nil || event.GetResult().GetLabel() != "FCFS" {
+		t.Fatalf("second event = %v, %v, want FCFS's result", event, err)
+	}
+	job.finish(jsonReport{Dir: "alice", Total: 10, Possible: 30})
+	if event, err := stream.Recv(); err != nil || event.GetReport().GetPossible() != 30 {
+		t.Fatalf("third event = %v, %v, want the report", event, err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("after the report: %v, want the end of the stream", err)
+	}
+
+	stream, err = client.StreamLogs(context.Background(), &gradebotv1.StreamLogsRequest{Id: "nope"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("StreamLogs() of an unknown id = %v, want NotFound", err)
+	}
+}
+
+func TestProtoReport(t *testing.T) {
+	raw, normalized := 12, 0.5
+	when := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	got := protoReport(&jsonReport{
+		Dir:         "alice",
+		Total:       10,
+		Possible:    20,
+		RawTotal:    &raw,
+		Normalized:  &normalized,
+		Environment: &manifest{Platform: "linux/amd64", Go: "go1.21.5", Time: when, Tree: "sha256:abc"},
+		Results: []Result{{
+			Label:       "FCFS",
+			Awarded:     10,
+			Possible:    20,
+			Duration:    2 * time.Second,
+			Usage:       &runUsage{Runs: 3, Wall: time.Second, PeakRSS: 1 << 20},
+			ExtraCredit: true,
+		}},
+	})
+	if got.GetRawTotal() != 12 || got.GetNormalized() != 0.5 || got.GetEnvironment().GetTime().AsTime() != when ||
+		got.GetEnvironment().GetGo() != "go1.21.5" || got.GetEnvironment().GetTree() != "sha256:abc" {
+		t.Errorf("protoReport() = %v", got)
+	}
+	r := got.GetResults()[0]
+	if r.GetDurationNs() != int64(2*time.Second) || r.GetUsage().GetRuns() != 3 || r.GetUsage().GetWallNs() != int64(time.Second) ||
+		r.GetUsage().GetPeakRssBytes() != 1<<20 || !r.GetExtraCredit() {
+		t.Errorf("protoReport() result = %v", r)
+	}
+	if protoReport(nil) != nil {
+		t.Error("protoReport(nil) isn't nil")
+	}
+	for state, want := range map[string]gradebotv1.State{
+		stateQueued:  gradebotv1.State_STATE_QUEUED,
+		stateRunning: gradebotv1.State_STATE_RUNNING,
+		stateDone:    gradebotv1.State_STATE_DONE,
+		"":           gradebotv1.State_STATE_UNSPECIFIED,
+	} {
+		if got := protoState(state); got != want {
+			t.Errorf("protoState(%q) = %v, want %v", state, got, want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package grader
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hintRules are the hint engine's rules, most specific first: each looks at
+// a mismatched run's output, and returns a hint for the first mistake it
+// recognizes, or "". Hints only describe the student's own output, never
+// the expected one.
+var hintRules = []func(h hintInput) string{
+	hintNoTable,
+	hintMissingRows,
+	hintNotPreempted,
+	hintFCFSOrder,
+	hintTurnaroundIsBurst,
+	hintFromTimeZero,
+	hintInconsistentRow,
+	hintAverages,
+}
+
+// hintInput is what the rules look at.
+type hintInput struct {
+	// algorithm is the run's first flag, e.g. "rr", and quantum its -q, or 0.
+	algorithm string
+	quantum   int
+	act, exp  scheduleMetrics
+	// rows are act's schedule table rows with every numeric column, in
+	// arrival order.
+	rows []hintRow
+}
+
+type hintRow struct {
+	id                                                 string
+	priority, burst, arrival, wait, turnaround, exitAt int
+}
+
+// hintFor is the first hint of hintRules for a run with args whose actual
+// output doesn't match the expected output, or "".
+func hintFor(args []string, expected, actual []byte) string {
+	h := hintInput{act: parseMetrics(actual), exp: parseMetrics(expected)}
+	if len(args) > 0 {
+		h.algorithm = strings.TrimLeft(args[0], "-")
+	}
+	if i := slices.Index(args, quantumFlag); i >= 0 && i+1 < len(args) {
+		h.quantum, _ = strconv.Atoi(args[i+1])
+	}
+	for _, id := range sortedKeys(h.act.table) {
+		cells := h.act.table[id]
+		row, ok := hintRow{id: id}, true
+		for _, col := range []struct {
+			name string
+			v    *int
+		}{
+			{"PRIORITY", &row.priority}, {"BURST", &row.burst}, {"ARRIVAL", &row.arrival},
+			{"WAIT", &row.wait}, {"TURNAROUND", &row.turnaround}, {"EXIT", &row.exitAt},
+		} {
+			n, err := strconv.Atoi(cells[col.name])
+			ok = ok && err == nil
+			*col.v = n
+		}
+		if ok {
+			h.rows = append(h.rows, row)
+		}
+	}
+	// ties in arrival go in ID order, as the input usually lists them.
+	sort.SliceStable(h.rows, func(i, j int) bool { return h.rows[i].arrival < h.rows[j].arrival })
+
+	for _, rule := range hintRules {
+		if hint := rule(h); hint != "" {
+			return hint
+		}
+	}
+
+	return ""
+}
+
+func hintNoTable(h hintInput) string {
+	if len(h.exp.table) == 0 || len(h.act.table) > 0 {
+		return ""
+	}
+
+	return "no schedule table was found in the output: check its header row and that each process's row has a value in every column"
+}
+
+func hintMissingRows(h hintInput) string {
+	if len(h.act.table) >= len(h.exp.table) {
+		return ""
+	}
+
+	return fmt.Sprintf("the schedule table has %d of the %d processes: is every input line read, including the last one?", len(h.act.table), len(h.exp.table))
+}
+
+func hintNotPreempted(h hintInput) string {
+	if h.algorithm != "rr" || h.quantum <= 0 || len(h.act.gantt) == 0 || hasRepeats(h.act.gantt) {
+		return ""
+	}
+	for _, r := range h.rows {
+		if r.burst > h.quantum {
+			return fmt.Sprintf("no process is ever preempted, though %s's burst is longer than the quantum: is the time slice applied?", r.id)
+		}
+	}
+
+	return ""
+}
+
+func hintFCFSOrder(h hintInput) string {
+	var picked string
+	switch h.algorithm {
+	case "sjf":
+		picked = "by shortest burst"
+	case "sjfp":
+		picked = "by priority"
+	case "rr":
+		picked = "in time slices"
+	default:
+		return ""
+	}
+	fcfs := make([]string, len(h.rows))
+	for i, r := range h.rows {
+		fcfs[i] = r.id
+	}
+	if len(fcfs) < 2 || !slices.Equal(h.act.gantt, fcfs) || slices.Equal(h.exp.gantt, fcfs) {
+		return ""
+	}
+
+	return "the Gantt schedule is in first-come, first-serve order: processes that have arrived should be picked " + picked + ", not by arrival alone"
+}
+
+func hintTurnaroundIsBurst(h hintInput) string {
+	if len(h.rows) < 2 {
+		return ""
+	}
+	for _, r := range h.rows {
+		if r.turnaround != r.burst {
+			return ""
+		}
+	}
+
+	return "every process's TURNAROUND equals its BURST, as if none ever waited: turnaround runs from ARRIVAL to EXIT, so it includes the wait"
+}
+
+func hintFromTimeZero(h hintInput) string {
+	var arrivals, fromZero int
+	for _, r := range h.rows {
+		if r.arrival == 0 {
+			continue
+		}
+		arrivals++
+		if r.turnaround == r.exitAt || r.wait == r.exitAt-r.burst {
+			fromZero++
+		}
+	}
+	if arrivals == 0 || fromZero < arrivals {
+		return ""
+	}
+
+	return "WAIT and TURNAROUND are measured from time 0, ignoring arrival times: measure them from each process's ARRIVAL"
+}
+
+func hintInconsistentRow(h hintInput) string {
+	for _, r := range h.rows {
+		switch {
+		case r.turnaround != r.exitAt-r.arrival:
+			return fmt.Sprintf("%s's TURNAROUND (%d) isn't its EXIT minus its ARRIVAL (%d)", r.id, r.turnaround, r.exitAt-r.arrival)
+		case r.wait != r.turnaround-r.burst:
+			return fmt.Sprintf("%s's WAIT (%d) isn't its TURNAROUND minus its BURST (%d)", r.id, r.wait, r.turnaround-r.burst)
+		}
+	}
+
+	return ""
+}
+
+func hintAverages(h hintInput) string {
+	if len(h.rows) == 0 || len(h.rows) < len(h.act.table) {
+		return ""
+	}
+	var wait, turnaround int
+	for _, r := range h.rows {
+		wait += r.wait
+		turnaround += r.turnaround
+	}
+	type stat struct {
+		label, hint string
+		want        float64
+	}
+	n := float64(len(h.rows))
+	stats := []stat{
+		{"Average wait", "the mean of the WAIT column", float64(wait) / n},
+		{"Average turnaround", "the mean of the TURNAROUND column", float64(turnaround) / n},
+	}
+	// throughput is over the Gantt schedule's span, which needn't start at 0.
+	if len(h.act.times) > 1 {
+		first, err1 := strconv.Atoi(h.act.times[0])
+		last, err2 := strconv.Atoi(h.act.times[len(h.act.times)-1])
+		if err1 == nil && err2 == nil && last > first {
+			stats = append(stats, stat{"Throughput", "the number of processes over the Gantt schedule's length", n / float64(last-first)})
+		}
+	}
+	for _, st := range stats {
+		got, err := strconv.ParseFloat(h.act.stats[st.label], 64)
+		// the output is rounded to two places.
+		if err == nil && math.Abs(got-st.want) > 0.006 {
+			return fmt.Sprintf("%s (%s) isn't %s: check for integer division, or dividing by the wrong count", st.label, h.act.stats[st.label], st.hint)
+		}
+	}
+
+	return ""
+}
+
+// hasRepeats reports whether any element appears more than once.
+func hasRepeats(s []string) bool {
+	seen := make(map[string]bool, len(s))
+	for _, v := range s {
+		if seen[v] {
+			return true
+		}
+		seen[v] = true
+	}
+
+	return false
+}
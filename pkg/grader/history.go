@@ -0,0 +1,77 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// trivialMessages are commit subjects that say nothing, including the
+// GitHub web editor's defaults, e.g. "Update main.go" or "Add files via upload".
+var trivialMessages = regexp.MustCompile(`(?i)^(\W*|\w{1,3}|wip|update[sd]?|fix(es|ed)?|changes?|commit|stuff|done|final|test(ing)?|minor( changes)?|(initial|first) commit|add files via upload|(update|create|add|delete) [\w.-]+)\W*$`)
+
+// CheckHistory inspects a git checkout's history for a single-dump
+// submission, awarding an equal share of the points for each of: at least
+// MinCommits commits, made on at least CommitDays days (by author date), and
+// mostly descriptive commit messages.
+func CheckHistory(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelHistory,
+		Awarded:  0,
+		Possible: c.opts.possible(labelHistory),
+	}
+	if _, err := os.Stat(filepath.Join(c.srcDir, ".git")); err != nil {
+		result.Awarded = result.Possible
+		result.Message = "not applicable: not a git checkout"
+		return result, nil
+	}
+	// subject and author date (in the author's zone), one commit per line.
+	out, err := runGit(c.ctx, c.srcDir, "log", "--format=%ad %s", "--date=short", "HEAD", "--")
+	if err != nil {
+		result.Message = "could not read the git history"
+		return result, err
+	}
+
+	var (
+		commits int
+		days    = make(map[string]bool)
+		trivial []string
+	)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		day, subject, _ := strings.Cut(line, " ")
+		commits++
+		days[day] = true
+		if trivialMessages.MatchString(strings.TrimSpace(subject)) {
+			trivial = append(trivial, fmt.Sprintf("%q", subject))
+		}
+	}
+
+	var problems []string
+	if commits < c.opts.MinCommits {
+		problems = append(problems, fmt.Sprintf("%d commit(s), want at least %d", commits, c.opts.MinCommits))
+	}
+	if len(days) < c.opts.CommitDays {
+		problems = append(problems, fmt.Sprintf("commits on %d day(s), want at least %d", len(days), c.opts.CommitDays))
+	}
+	// more than half is a habit, not a slip.
+	if 2*len(trivial) > commits {
+		problems = append(problems, fmt.Sprintf("%d of %d commit messages say nothing, e.g. %s", len(trivial), commits, trivial[0]))
+	}
+	result.Awarded = int(math.Round(float64(result.Possible) * float64(3-len(problems)) / 3))
+	if len(problems) > 0 {
+		result.Message = strings.Join(problems, "\n")
+		return result, errors.New("history looks like a single dump")
+	}
+	result.Message = fmt.Sprintf("%d commits on %d days", commits, len(days))
+	c.log.Debug("git history", slog.Int("commits", commits), slog.Int("days", len(days)), slog.Int("pts", result.Possible))
+
+	return result, nil
+}
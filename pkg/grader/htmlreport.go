@@ -0,0 +1,171 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pdfPrinters are the programs --report can print a PDF with, and how, in
+// order of preference.
+var pdfPrinters = []struct {
+	name string
+	args func(html, pdf string) []string
+}{
+	{"chromium", chromePDFArgs},
+	{"chromium-browser", chromePDFArgs},
+	{"google-chrome", chromePDFArgs},
+	{"google-chrome-stable", chromePDFArgs},
+	{"wkhtmltopdf", func(html, pdf string) []string { return []string{"--quiet", html, pdf} }},
+}
+
+func chromePDFArgs(html, pdf string) []string {
+	return []string{"--headless", "--disable-gpu", "--no-pdf-header-footer", "--print-to-pdf=" + pdf, "file://" + filepath.ToSlash(html)}
+}
+
+// htmlReport is what reportTemplate renders.
+type htmlReport struct {
+	Generated   time.Time
+	Submissions []htmlSubmission
+}
+
+type htmlSubmission struct {
+	Dir             string
+	Total, Possible int
+	RawTotal        *int
+	Results         []Result
+	Elapsed         time.Duration
+	Environment     *manifest
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"diffClass": func(line string) string {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			return "file"
+		case strings.HasPrefix(line, "@@"):
+			return "hunk"
+		case strings.HasPrefix(line, "-"):
+			return "exp"
+		case strings.HasPrefix(line, "+"):
+			return "act"
+		}
+		return ""
+	},
+	"lines": func(s string) []string { return strings.Split(strings.TrimRight(s, "\n"), "\n") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>gradebot report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: .3em .6em; text-align: left; vertical-align: top; }
+td.n { text-align: right; white-space: nowrap; }
+tr.fail td:first-child { border-left: 4px solid #e05d44; }
+tr.pass td:first-child { border-left: 4px solid #4c1; }
+tr.skip td { color: #888; }
+tr.skip td:first-child { border-left: 4px solid #ccc; }
+tfoot td { font-weight: bold; }
+pre { background: #f6f8fa; padding: .6em; overflow-x: auto; white-space: pre-wrap; }
+pre span { display: block; }
+.file { font-weight: bold; } .hunk { color: #0969da; } .exp { background: #e6ffec; } .act { background: #ffebe9; }
+.hint { color: #9a6700; }
+.env { color: #666; font-size: .9em; }
+section { page-break-inside: avoid; }
+h2 { border-bottom: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>gradebot report</h1>
+<p>Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}. Diffs show expected output (-) against the submission's (+).</p>
+{{range .Submissions}}
+<h2>{{.Dir}}: {{.Total}}/{{.Possible}}</h2>
+{{with .Environment}}<p class="env">Graded on {{.Platform}} with {{.Go}}, gradebot {{.Gradebot}} (rubric {{.Rubric}}), locale {{.Locale}}, at {{.Time.Format "2006-01-02 15:04:05 MST"}}; tree {{.Tree}}.</p>
+{{end}}<table>
+<thead><tr><th>Rubric Item</th><th>Awarded</th><th>Possible</th><th>Time</th><th>Message</th></tr></thead>
+<tbody>
+{{range .Results}}<tr class="{{if .Skipped}}skip{{else if lt .Awarded .Possible}}fail{{else}}pass{{end}}"><td>{{.Label}}</td><td class="n">{{if .Skipped}}skipped{{else}}{{.Awarded}}{{end}}</td><td class="n">{{.Possible}}</td><td class="n">{{ms .Duration}}</td><td>{{.Message}}</td></tr>
+{{end}}</tbody>
+<tfoot>
+{{with .RawTotal}}<tr><td>Before late penalty</td><td class="n">{{.}}</td><td></td><td></td><td></td></tr>
+{{end}}<tr><td>Total</td><td class="n">{{.Total}}</td><td class="n">{{.Possible}}</td><td class="n">{{ms .Elapsed}}</td><td></td></tr>
+</tfoot>
+</table>
+{{range .Results}}{{if or .Error .Hint .Warning .Stderr .Diff}}
+<section>
+<h3>{{.Label}} ({{.Awarded}}/{{.Possible}})</h3>
+{{if .Error}}<p>Error: {{.Error}}</p>{{end}}
+{{with .Hint}}{{range lines .}}<p class="hint">hint: {{.}}</p>{{end}}{{end}}
+{{with .Warning}}{{range lines .}}<p class="hint">warning: {{.}}</p>{{end}}{{end}}
+{{with .Stderr}}<p>Stderr:</p>
+<pre>{{.}}</pre>{{end}}
+{{with .Diff}}<p>Output diff:</p>
+<pre>{{range lines .}}<span class="{{diffClass .}}">{{.}}</span>{{end}}</pre>{{end}}
+</section>
+{{end}}{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// writeHTMLReport writes the graded submissions to path as a standalone HTML
+// report, for grading evidence: each rubric table, and each check's error,
+// hints, stderr and output diffs. A path ending in .pdf is printed to PDF
+// with the first of pdfPrinters on the PATH.
+func writeHTMLReport(ctx context.Context, path string, graded []submission) error {
+	report := htmlReport{Generated: time.Now()}
+	for _, s := range graded {
+		hs := htmlSubmission{Dir: s.name(), Results: s.results, Environment: s.env}
+		hs.Total, hs.Possible = s.totals()
+		if raw, penalized := rawTotal(s.results); penalized {
+			hs.RawTotal = &raw
+		}
+		for _, r := range s.results {
+			hs.Elapsed += r.Duration
+		}
+		report.Submissions = append(report.Submissions, hs)
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".pdf") {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		return errors.Join(reportTemplate.Execute(f, report), f.Close())
+	}
+
+	tmp, err := os.CreateTemp("", "gradebot-report-*.html")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := errors.Join(reportTemplate.Execute(tmp, report), tmp.Close()); err != nil {
+		return err
+	}
+	pdf, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for _, p := range pdfPrinters {
+		bin, err := exec.LookPath(p.name)
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, bin, p.args(tmp.Name(), pdf)...).CombinedOutput(); err != nil {
+			return fmt.Errorf("printing %s with %s: %w: %s", path, p.name, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("printing %s needs Chrome, Chromium or wkhtmltopdf on the PATH (or write .html and print it from a browser)", path)
+}
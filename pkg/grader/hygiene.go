@@ -0,0 +1,141 @@
+package grader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxHygieneFindings caps how many files are listed per problem.
+const maxHygieneFindings = 5
+
+var (
+	// artifactNames and artifactExts are build outputs and OS litter that
+	// don't belong in a submission.
+	artifactNames = []string{"scheduler.bin", ".DS_Store", "Thumbs.db", "desktop.ini"}
+	artifactExts  = []string{".exe", ".o", ".a", ".so", ".dll", ".dylib", ".test", ".pyc"}
+	// junkDirs are vendored or generated directories, editor settings and
+	// archive litter.
+	junkDirs = []string{"vendor", "node_modules", "__pycache__", "__MACOSX", ".idea", ".vscode"}
+	// executableMagic are the headers of ELF, Mach-O and PE executables, as
+	// from a go build without -o.
+	executableMagic = [][]byte{
+		[]byte("\x7fELF"),
+		{0xfe, 0xed, 0xfa, 0xce}, {0xfe, 0xed, 0xfa, 0xcf}, {0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe},
+		[]byte("MZ"),
+	}
+)
+
+// CheckHygiene flags what shouldn't be in a submission: build artifacts, a
+// missing .gitignore, and vendored or junk directories, awarding an equal
+// share of the points for each that's clean. In a git checkout only
+// committed files count.
+func CheckHygiene(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelHygiene,
+		Awarded:  0,
+		Possible: c.opts.possible(labelHygiene),
+	}
+	files, err := submissionFiles(c)
+	if err != nil {
+		result.Message = "could not list the submission's files"
+		return result, err
+	}
+
+	var artifacts, junk []string
+	for _, file := range files {
+		dirs := strings.Split(path.Dir(file), "/")
+		if i := slices.IndexFunc(dirs, func(d string) bool { return slices.Contains(junkDirs, d) }); i >= 0 {
+			if dir := strings.Join(dirs[:i+1], "/") + "/"; !slices.Contains(junk, dir) {
+				junk = append(junk, dir)
+			}
+			continue
+		}
+		name := path.Base(file)
+		if slices.Contains(artifactNames, name) || slices.Contains(artifactExts, path.Ext(name)) || isExecutable(filepath.Join(c.srcDir, file)) {
+			artifacts = append(artifacts, file)
+		}
+	}
+
+	var problems []string
+	if len(artifacts) > 0 {
+		problems = append(problems, "build artifacts: "+listFindings(artifacts))
+	}
+	if _, err := os.Stat(filepath.Join(c.srcDir, ".gitignore")); err != nil {
+		problems = append(problems, "no .gitignore")
+	}
+	if len(junk) > 0 {
+		problems = append(problems, "vendored or junk directories: "+listFindings(junk))
+	}
+	result.Awarded = int(math.Round(float64(result.Possible) * float64(3-len(problems)) / 3))
+	if len(problems) > 0 {
+		result.Message = strings.Join(problems, "\n")
+		return result, errors.New("submission has files that don't belong")
+	}
+
+	return result, nil
+}
+
+// submissionFiles lists the submission's files, slash-separated and relative
+// to it: those committed, in a git checkout, or else every file but .git's.
+func submissionFiles(c *Context) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(c.srcDir, ".git")); err == nil {
+		cmd := exec.CommandContext(c.ctx, "git", "ls-files", "-z")
+		cmd.Dir = c.srcDir
+		if out, err := cmd.Output(); err == nil {
+			return strings.FieldsFunc(string(out), func(r rune) bool { return r == 0 }), nil
+		}
+	}
+	var files []string
+	err := filepath.WalkDir(c.srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			rel, _ := filepath.Rel(c.srcDir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// isExecutable reports whether the file starts like a compiled executable.
+func isExecutable(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 4)
+	n, _ := io.ReadFull(f, head)
+	for _, magic := range executableMagic {
+		if bytes.HasPrefix(head[:n], magic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listFindings lists the first few findings, noting how many more there are.
+func listFindings(findings []string) string {
+	if len(findings) <= maxHygieneFindings {
+		return strings.Join(findings, ", ")
+	}
+
+	return fmt.Sprintf("%s (and %d more)", strings.Join(findings[:maxHygieneFindings], ", "), len(findings)-maxHygieneFindings)
+}
@@ -0,0 +1,413 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// rubric item labels, as shown in the results table and referenced by rubric configs.
+const (
+	labelModule      = "go.mod present"
+	labelCompilable  = "Compilable"
+	labelScreenshot  = "Screenshot exists"
+	labelREADME      = "README.md exists"
+	labelFCFS        = "First-come, first-serve scheduling"
+	labelSJF         = "Shortest-job-first scheduling"
+	labelSJFP        = "Shortest-job-first with priority scheduling"
+	labelRR          = "Round-robin scheduling"
+	labelPriority    = "Preemptive priority scheduling (extra credit)"
+	labelMLFQ        = "Multilevel feedback queue scheduling (extra credit)"
+	labelRandom      = "Randomized inputs"
+	labelDeterminism = "Deterministic output"
+	labelStress      = "Performance (large inputs)"
+	labelRobustness  = "Malformed input handling"
+	labelFuzz        = "Fuzzed input handling"
+	labelRace        = "Race detector"
+	labelForbidden   = "No forbidden APIs"
+	labelHygiene     = "Repository hygiene"
+	labelHistory     = "Git history"
+	labelStyle       = "Code style (gofmt, go vet)"
+	labelTests       = "Student unit tests"
+	labelCoverage    = "Test coverage"
+
+	labelShellBuiltins = "Shell builtins (cd)"
+	labelShellEnv      = "Shell environment (env)"
+	labelShellPipes    = "Pipes"
+	labelShellRedirect = "Redirection"
+	labelShellExit     = "Shell exit"
+)
+
+// rubricItem describes a check in the rubric: a node of the graph Grade
+// runs, after the items it depends on, which come before it in the rubric.
+type rubricItem struct {
+	id    string // stable identifier, for --only/--skip
+	label string
+	// needs are the items whose setup the check uses, e.g. compile's binary:
+	// it's skipped, rather than run, unless they passed.
+	needs []string
+	// after are the items it only runs after, whatever their results.
+	after []string
+	// setup items' context, e.g. the binary, is that of the items needing them.
+	setup bool
+	// cost is the check's relative running time (1 when unset), so the
+	// costliest ready checks start first.
+	cost int
+	// retries is how many times the check re-runs after a transient failure,
+	// e.g. a scheduler that couldn't be started.
+	retries int
+	check   Check
+}
+
+// needsBuild are the needs of a check of the built binary.
+var needsBuild = []string{"compile"}
+
+// dependencies are the ids of the items it runs after: those it needs, and
+// those it's after.
+func (item rubricItem) dependencies() []string {
+	return append(slices.Clip(item.needs), item.after...)
+}
+
+func (item rubricItem) weight() int {
+	return max(item.cost, 1)
+}
+
+// project is a course project: its rubric, whose checks embed their own
+// testdata, graded with its subcommand.
+type project struct {
+	items func(Options) []rubricItem
+}
+
+// defaultProject is the project the grade command grades.
+const defaultProject = "project1"
+
+// projects are the course's projects, by subcommand.
+var projects = map[string]project{
+	"project1": {items: schedulerItems},
+	"project2": {items: shellItems},
+}
+
+// Projects are the names of the course's projects, in order.
+func Projects() []string {
+	return sortedKeys(projects)
+}
+
+// ProjectTotals are the possible points of the named project's default
+// rubric, and its extra credit.
+func ProjectTotals(name string) (possible, extraCredit int) {
+	return rubricTotals(Options{Project: name})
+}
+
+// rubricItems returns a fresh rubric of the options' project, as scheduler
+// checks carry their result state.
+func rubricItems(opts Options) []rubricItem {
+	p, ok := projects[opts.Project]
+	if !ok {
+		p = projects[defaultProject]
+	}
+
+	return p.items(opts)
+}
+
+// schedulerItems is project 1's rubric, of the CPU scheduler.
+func schedulerItems(opts Options) []rubricItem {
+	var items []rubricItem
+	// hygiene is opt-in, with --hygiene, and first: a build may write to the submission.
+	if opts.Hygiene {
+		items = append(items, rubricItem{id: "hygiene", label: labelHygiene, check: CheckHygiene})
+	}
+	// as is the git history, with --history.
+	if opts.History {
+		items = append(items, rubricItem{id: "history", label: labelHistory, check: CheckHistory})
+	}
+	// as is the go.mod, with --module, before the build that resolves it.
+	if opts.Module {
+		items = append(items, rubricItem{id: "module", label: labelModule, check: CheckModule})
+	}
+	items = append(items, []rubricItem{
+		{id: "compile", label: labelCompilable, setup: true, cost: 5, check: CheckCompilable},
+		{id: "screenshot", label: labelScreenshot, check: CheckScreenshotExists},
+		{id: "readme", label: labelREADME, check: CheckREADMEExists},
+		{id: "fcfs", label: labelFCFS, needs: needsBuild, retries: 1, check: opts.schedulerCheck(labelFCFS, "fcfs",
+			CheckScheduler(Result{
+				Label:    labelFCFS,
+				Possible: opts.possible(labelFCFS),
+			}, "-fcfs", opts.fixture("fcfs.csv", fcfsIn), opts.fixture("fcfs.out", fcfsOut)))},
+		{id: "sjf", label: labelSJF, needs: needsBuild, retries: 1, check: opts.schedulerCheck(labelSJF, "sjf",
+			CheckScheduler(Result{
+				Label:    labelSJF,
+				Possible: opts.possible(labelSJF),
+			}, "-sjf", opts.fixture("sjf.csv", sjfIn), opts.fixture("sjf.out", sjfOut)))},
+		{id: "sjfp", label: labelSJFP, needs: needsBuild, retries: 1, check: opts.schedulerCheck(labelSJFP, "sjfp",
+			CheckScheduler(Result{
+				Label:    labelSJFP,
+				Possible: opts.possible(labelSJFP),
+			}, "-sjfp", opts.fixture("sjfp.csv", sjfpIn), opts.fixture("sjfp.out", sjfpOut)))},
+		{id: "rr", label: labelRR, needs: needsBuild, cost: 4, retries: 1, check: opts.schedulerCheck(labelRR, "rr",
+			CheckRoundRobin(Result{
+				Label:    labelRR,
+				Possible: opts.possible(labelRR),
+			}, opts.fixture("rr.csv", rrIn),
+				quantumCase{quantum: 1, out: opts.fixture("rr_q1.out", rrQ1Out)},
+				quantumCase{quantum: 2, out: opts.fixture("rr_q2.out", rrQ2Out)},
+				quantumCase{quantum: 4, out: opts.fixture("rr.out", rrOut)},
+				// longer than every burst: round-robin is first-come, first-served.
+				quantumCase{quantum: 10, out: opts.fixture("rr_q10.out", rrQ10Out)},
+			))},
+		{id: "priority", label: labelPriority, needs: needsBuild, retries: 1,
+			check: CheckExtraCredit(Result{
+				Label:    labelPriority,
+				Possible: opts.possible(labelPriority),
+			}, "-priority", opts.fixture("priority.csv", priorityIn), opts.fixture("priority.out", priorityOut))},
+		{id: "mlfq", label: labelMLFQ, needs: needsBuild, retries: 1,
+			check: CheckExtraCredit(Result{
+				Label:    labelMLFQ,
+				Possible: opts.possible(labelMLFQ),
+			}, "-mlfq", opts.fixture("mlfq.csv", mlfqIn), opts.fixture("mlfq.out", mlfqOut))},
+	}...)
+	// randomized inputs, repeated runs, large inputs, malformed inputs,
+	// fuzzing and the race detector are opt-in, with --random, --repeat,
+	// --stress, --robustness, --fuzz and --race.
+	if opts.Random > 0 {
+		items = append(items, rubricItem{id: "random", label: labelRandom, needs: needsBuild, cost: 5, retries: 1,
+			check: CheckRandom(Result{
+				Label:    labelRandom,
+				Possible: opts.possible(labelRandom),
+			}, opts.Seed, opts.Random)})
+	}
+
+	if opts.Repeat > 1 {
+		items = append(items, rubricItem{id: "determinism", label: labelDeterminism, needs: needsBuild, cost: 3, retries: 1,
+			check: CheckDeterminism(Result{
+				Label:    labelDeterminism,
+				Possible: opts.possible(labelDeterminism),
+			}, opts.Repeat)})
+	}
+	if opts.Stress > 0 {
+		items = append(items, rubricItem{id: "stress", label: labelStress, needs: needsBuild, cost: 8, retries: 1,
+			check: CheckStress(Result{
+				Label:    labelStress,
+				Possible: opts.possible(labelStress),
+			}, opts.Seed, opts.Stress, opts.StressBudget)})
+	}
+	if opts.Robustness {
+		items = append(items, rubricItem{id: "robustness", label: labelRobustness, needs: needsBuild, cost: 3, retries: 1,
+			check: CheckRobustness(Result{
+				Label:    labelRobustness,
+				Possible: opts.possible(labelRobustness),
+			})})
+	}
+	if opts.Fuzz > 0 {
+		items = append(items, rubricItem{id: "fuzz", label: labelFuzz, needs: needsBuild, cost: 8, retries: 1,
+			check: CheckFuzz(Result{
+				Label:    labelFuzz,
+				Possible: opts.possible(labelFuzz),
+			}, opts.Seed, opts.Fuzz)})
+	}
+	if opts.Race {
+		items = append(items, rubricItem{id: "race", label: labelRace, needs: needsBuild, cost: 8, retries: 1, check: CheckRace})
+	}
+
+	if opts.Forbidden != nil {
+		items = append(items, rubricItem{id: "forbidden", label: labelForbidden, check: CheckForbidden})
+	}
+
+	// as are the code style, the submission's own tests and their coverage,
+	// with --style, --student-tests and --coverage.
+	if opts.Style {
+		items = append(items, rubricItem{id: "style", label: labelStyle, cost: 3, check: CheckStyle})
+	}
+	if opts.StudentTests {
+		items = append(items, rubricItem{id: "tests", label: labelTests, cost: 6, check: CheckTests})
+	}
+	if opts.Coverage > 0 {
+		items = append(items, rubricItem{id: "coverage", label: labelCoverage, cost: 6, check: CheckCoverage})
+	}
+	// and the rubric config's script checks.
+	for _, sc := range opts.ScriptChecks {
+		items = append(items, rubricItem{id: sc.ID, label: sc.Label, needs: sc.needs(), check: CheckScript(sc)})
+	}
+	// hygiene is of the submission as submitted, so it's before everything.
+	if opts.Hygiene {
+		for i := range items[1:] {
+			items[i+1].after = append(items[i+1].after, "hygiene")
+		}
+	}
+
+	return items
+}
+
+// shellItems is project 2's rubric, of the Unix shell: golden sessions run
+// on its built binary, as the scheduler's fixtures are.
+func shellItems(opts Options) []rubricItem {
+	var items []rubricItem
+	if opts.Module {
+		items = append(items, rubricItem{id: "module", label: labelModule, check: CheckModule})
+	}
+	items = append(items, rubricItem{id: "compile", label: labelCompilable, setup: true, cost: 5, check: CheckCompilable})
+	for _, s := range []struct{ id, label, session string }{
+		{"builtins", labelShellBuiltins, "builtins"},
+		{"env", labelShellEnv, "env"},
+		{"pipes", labelShellPipes, "pipes"},
+		{"redirect", labelShellRedirect, "redirect"},
+		{"exit", labelShellExit, "exit"},
+	} {
+		items = append(items, rubricItem{id: s.id, label: s.label, needs: needsBuild, retries: 1,
+			check: CheckShellSession(Result{Label: s.label, Possible: opts.possible(s.label)}, s.session)})
+	}
+	if opts.Style {
+		items = append(items, rubricItem{id: "style", label: labelStyle, cost: 3, check: CheckStyle})
+	}
+	for _, sc := range opts.ScriptChecks {
+		items = append(items, rubricItem{id: sc.ID, label: sc.Label, needs: sc.needs(), check: CheckScript(sc)})
+	}
+
+	return items
+}
+
+// fixture returns the named testdata file, from Testdata if overridden.
+func (o Options) fixture(name string, embedded []byte) []byte {
+	if b, ok := o.Testdata[name]; ok {
+		return b
+	}
+
+	return embedded
+}
+
+// schedulerCheck returns the check for the algorithm's --cases, if any, or else the embedded one.
+func (o Options) schedulerCheck(label, algorithm string, embedded Check) Check {
+	if cases, ok := o.Cases[algorithm]; ok {
+		return CheckCases(Result{Label: label, Possible: o.possible(label)}, algorithm, cases)
+	}
+
+	return embedded
+}
+
+// everyItem enables every optional rubric item.
+var everyItem = Options{Module: true, Style: true, Hygiene: true, History: true, Random: 1, Repeat: 2, Stress: 1, Robustness: true, Fuzz: time.Second, Race: true, Forbidden: &denyList{}, StudentTests: true, Coverage: 1}
+
+// projectLabels lists the project's rubric item labels, in rubric order,
+// including the optional ones.
+func projectLabels(name string) []string {
+	opts := everyItem
+	opts.Project = name
+	var labels []string
+	for _, item := range rubricItems(opts) {
+		labels = append(labels, item.label)
+	}
+
+	return labels
+}
+
+// rubricLabels lists every project's rubric item labels, as a rubric config
+// may be for any of them.
+func rubricLabels() []string {
+	var labels []string
+	for _, name := range sortedKeys(projects) {
+		for _, label := range projectLabels(name) {
+			if !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+
+	return labels
+}
+
+// checkIDs maps each rubric item's stable identifier, of every project, to
+// its label.
+func checkIDs() map[string]string {
+	ids := make(map[string]string)
+	for _, name := range sortedKeys(projects) {
+		opts := everyItem
+		opts.Project = name
+		for _, item := range rubricItems(opts) {
+			ids[item.id] = item.label
+		}
+	}
+
+	return ids
+}
+
+// validateCheckIDs rejects unknown identifiers, so a typo doesn't silently run nothing.
+func validateCheckIDs(flag string, ids []string) error {
+	known := checkIDs()
+	for _, id := range ids {
+		if _, ok := known[id]; !ok {
+			return fmt.Errorf("%s: unknown check %q (known: %s)", flag, id, strings.Join(sortedKeys(known), ", "))
+		}
+	}
+
+	return nil
+}
+
+// optionalFlags are the flags enabling the optional checks, by identifier.
+var optionalFlags = map[string]string{
+	"module":      "--module",
+	"style":       "--style",
+	"hygiene":     "--hygiene",
+	"history":     "--history",
+	"random":      "--random",
+	"determinism": "--repeat",
+	"stress":      "--stress",
+	"robustness":  "--robustness",
+	"fuzz":        "--fuzz",
+	"race":        "--race",
+	"forbidden":   "--forbidden",
+	"tests":       "--student-tests",
+	"coverage":    "--coverage",
+}
+
+// validateOnlyEnabled rejects --only identifiers of optional checks that
+// aren't enabled, which would otherwise quietly run nothing.
+func validateOnlyEnabled(only []string, opts Options) error {
+	var (
+		items = rubricItems(opts)
+		errs  []error
+	)
+	for _, id := range only {
+		flag, optional := optionalFlags[id]
+		switch {
+		case slices.ContainsFunc(items, func(item rubricItem) bool { return item.id == id }):
+		case optional:
+			errs = append(errs, fmt.Errorf("--only %s: the check is optional, enable it with %s", id, flag))
+		default:
+			errs = append(errs, fmt.Errorf("--only %s: not a check of this project", id))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// selectItems filters the rubric by --only and --skip. The items a selected
+// one needs are kept too, e.g. the compile check for a check of the binary.
+func selectItems(items []rubricItem, only, skip []string) []rubricItem {
+	if len(only) == 0 && len(skip) == 0 {
+		return items
+	}
+	selected := func(id string) bool {
+		return (len(only) == 0 || slices.Contains(only, id)) && !slices.Contains(skip, id)
+	}
+	// needs come before the items needing them, so from the end, each one
+	// kept is known by the time it's reached.
+	keep := make(map[string]bool)
+	for i := len(items) - 1; i >= 0; i-- {
+		if selected(items[i].id) || keep[items[i].id] {
+			keep[items[i].id] = true
+			for _, id := range items[i].needs {
+				keep[id] = true
+			}
+		}
+	}
+
+	var filtered []rubricItem
+	for _, item := range items {
+		if keep[item.id] {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
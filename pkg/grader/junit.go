@@ -0,0 +1,116 @@
+package grader
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// JUnit XML, as read by CI systems: a test suite per submission, and a test
+// case per rubric item. As with TAP, only full marks pass, and skipped checks,
+// and extra credit not earned, are skipped cases.
+type (
+	junitSuites struct {
+		XMLName xml.Name     `xml:"testsuites"`
+		Tests   int          `xml:"tests,attr"`
+		Fails   int          `xml:"failures,attr"`
+		Skips   int          `xml:"skipped,attr"`
+		Time    string       `xml:"time,attr"`
+		Suites  []junitSuite `xml:"testsuite"`
+	}
+	junitSuite struct {
+		Name  string `xml:"name,attr"`
+		Tests int    `xml:"tests,attr"`
+		Fails int    `xml:"failures,attr"`
+		Skips int    `xml:"skipped,attr"`
+		Time  string `xml:"time,attr"`
+		// Properties are the environment it was graded in.
+		Properties []junitProperty `xml:"properties>property,omitempty"`
+		Cases      []junitCase     `xml:"testcase"`
+	}
+	junitProperty struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		Skipped   *junitSkipped `xml:"skipped,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+		SystemErr string        `xml:"system-err,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Body    string `xml:",chardata"`
+	}
+	junitSkipped struct {
+		Message string `xml:"message,attr"`
+	}
+)
+
+// writeJUnit writes the graded submissions to path as a JUnit XML report.
+func writeJUnit(path string, graded []submission) error {
+	var (
+		report junitSuites
+		total  time.Duration
+	)
+	for _, s := range graded {
+		suite := junitSuite{Name: s.name(), Tests: len(s.results)}
+		if m := s.env; m != nil {
+			suite.Properties = []junitProperty{
+				{"platform", m.Platform}, {"go", m.Go}, {"gradebot", m.Gradebot}, {"rubric", m.Rubric},
+				{"locale", m.Locale}, {"time", m.Time.Format(time.RFC3339)}, {"tree", m.Tree},
+			}
+		}
+		var elapsed time.Duration
+		for _, r := range s.results {
+			tc := junitCase{
+				Name:      r.Label,
+				ClassName: s.name(),
+				Time:      junitSeconds(r.Duration),
+				SystemOut: strings.Join(r.Logs, "\n"),
+				SystemErr: r.Stderr,
+			}
+			switch {
+			case r.Skipped:
+				tc.Skipped = &junitSkipped{Message: strings.TrimPrefix(r.Message, "skipped: ")}
+				suite.Skips++
+			case r.Awarded < r.Possible && r.ExtraCredit:
+				// not a failure, as it's not in the total.
+				tc.Skipped = &junitSkipped{Message: fmt.Sprintf("extra credit %d/%d", r.Awarded, r.Possible)}
+				suite.Skips++
+			case r.Awarded < r.Possible:
+				first, _, _ := strings.Cut(r.Message, "\n")
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("%d/%d: %s", r.Awarded, r.Possible, first),
+					Body:    r.reportMessage(),
+				}
+				suite.Fails++
+			}
+			elapsed += r.Duration
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Time = junitSeconds(elapsed)
+		report.Tests += suite.Tests
+		report.Fails += suite.Fails
+		report.Skips += suite.Skips
+		total += elapsed
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = junitSeconds(total)
+
+	b, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), append(b, '\n')...), 0o644)
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
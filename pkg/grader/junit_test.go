@@ -0,0 +1,47 @@
+package grader
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	graded := []submission{{dir: "alice", results: []Result{
+		{Label: "Compiles", Awarded: 10, Possible: 10},
+		{Label: "FCFS", Awarded: 5, Possible: 20, Message: "diverged"},
+		{Label: "SJF", Possible: 20, Skipped: true, Message: "skipped: Compiles failed"},
+		{Label: "MLFQ", Possible: 5, ExtraCredit: true},
+		{Label: "Priority", Awarded: 5, Possible: 5, ExtraCredit: true},
+	}}}
+	if err := writeJUnit(path, graded); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(b, &report); err != nil {
+		t.Fatal(err)
+	}
+	// extra credit not earned is skipped, not failed.
+	if report.Tests != 5 || report.Fails != 1 || report.Skips != 2 {
+		t.Errorf("%d tests, %d failures, %d skipped, want 5, 1, 2", report.Tests, report.Fails, report.Skips)
+	}
+	cases := report.Suites[0].Cases
+	if c := cases[1]; c.Failure == nil || c.Failure.Message != "5/20: diverged" {
+		t.Errorf("FCFS = %+v, want failed 5/20", c)
+	}
+	if c := cases[2]; c.Skipped == nil || c.Skipped.Message != "Compiles failed" {
+		t.Errorf("SJF = %+v, want skipped", c)
+	}
+	if c := cases[3]; c.Failure != nil || c.Skipped == nil || c.Skipped.Message != "extra credit 0/5" {
+		t.Errorf("MLFQ = %+v, want skipped as extra credit", c)
+	}
+	if c := cases[4]; c.Failure != nil || c.Skipped != nil {
+		t.Errorf("Priority = %+v, want passed", c)
+	}
+}
@@ -0,0 +1,130 @@
+package grader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// keySchemaVersion is the answer key bundle format this gradebot reads.
+const keySchemaVersion = 1
+
+// answerKey is a versioned bundle of scheduler cases and point values, for
+// --key, so a new assignment doesn't need new embedded testdata:
+//
+//	{
+//	  "schema_version": 1,
+//	  "assignment": "CSCE4600 project 1, fall 2026",
+//	  "points": {"Round-robin scheduling": 15},
+//	  "cases": [
+//	    {"name": "fcfs", "algorithm": "fcfs", "input": "...", "output": "..."},
+//	    {"name": "rr_q2", "algorithm": "rr", "args": ["-rr", "-q", "2"], "input": "...", "output": "..."}
+//	  ]
+//	}
+//
+// Points are keyed by rubric item label, as in a rubric config (which
+// overrides them). A case's args default to its algorithm's flag.
+type answerKey struct {
+	SchemaVersion int            `json:"schema_version"`
+	Assignment    string         `json:"assignment"`
+	Points        map[string]int `json:"points"`
+	Cases         []keyCase      `json:"cases"`
+}
+
+type keyCase struct {
+	Name      string   `json:"name"`
+	Algorithm string   `json:"algorithm"`
+	Args      []string `json:"args"`
+	Input     string   `json:"input"`
+	Output    string   `json:"output"`
+}
+
+func loadAnswerKey(path string) (answerKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return answerKey{}, err
+	}
+
+	return parseAnswerKey(b, path)
+}
+
+// parseAnswerKey parses and validates an answer key; path names it in errors.
+func parseAnswerKey(b []byte, path string) (answerKey, error) {
+	var key answerKey
+	// check the version first, so an incompatible bundle isn't reported as unknown fields.
+	var version struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(b, &version); err != nil {
+		return key, fmt.Errorf("parsing answer key %s: %w", path, err)
+	}
+	switch version.SchemaVersion {
+	case keySchemaVersion:
+	case 0:
+		return key, fmt.Errorf("answer key %s: missing schema_version", path)
+	default:
+		return key, fmt.Errorf("answer key %s: unsupported schema_version %d (this gradebot reads version %d)",
+			path, version.SchemaVersion, keySchemaVersion)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&key); err != nil {
+		return key, fmt.Errorf("parsing answer key %s: %w", path, err)
+	}
+	if err := key.validate(); err != nil {
+		return key, fmt.Errorf("invalid answer key %s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// validate reports every problem with the key, not just the first.
+func (key answerKey) validate() error {
+	var errs []error
+	if len(key.Points) > 0 {
+		errs = append(errs, rubricConfig{Points: key.Points}.validate())
+	}
+	if len(key.Cases) == 0 {
+		errs = append(errs, errors.New("cases: no cases"))
+	}
+	names := make(map[string]bool)
+	for i, kc := range key.Cases {
+		if kc.Name == "" {
+			errs = append(errs, fmt.Errorf("cases[%d]: missing name", i))
+		} else if names[kc.Name] {
+			errs = append(errs, fmt.Errorf("cases[%d]: duplicate name %q", i, kc.Name))
+		}
+		names[kc.Name] = true
+		if !slices.Contains(caseAlgorithms, kc.Algorithm) {
+			errs = append(errs, fmt.Errorf("cases[%d]: algorithm must be one of %s", i, strings.Join(caseAlgorithms, ", ")))
+		}
+		if kc.Output == "" {
+			errs = append(errs, fmt.Errorf("cases[%d]: missing output", i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// cases groups the key's cases by algorithm, as for --cases.
+func (key answerKey) cases() map[string][]schedulerCase {
+	cases := make(map[string][]schedulerCase)
+	for _, kc := range key.Cases {
+		args := kc.Args
+		if len(args) == 0 {
+			args = []string{"-" + kc.Algorithm}
+		}
+		cases[kc.Algorithm] = append(cases[kc.Algorithm], schedulerCase{
+			name: kc.Name,
+			args: args,
+			in:   []byte(kc.Input),
+			out:  []byte(kc.Output),
+		})
+	}
+
+	return cases
+}
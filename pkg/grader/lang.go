@@ -0,0 +1,144 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// submission languages, for --lang.
+const (
+	langGo     = "go"
+	langC      = "c"
+	langPython = "python"
+)
+
+// detectLanguage guesses the submission's language from its files: go.mod or
+// .go files for Go, a CMakeLists.txt, Makefile or .c files for C, and .py
+// files for Python. Go is the default, so a broken submission still gets
+// the Go checks' messages.
+func detectLanguage(dir string) string {
+	has := func(pattern string) bool {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		return len(matches) > 0
+	}
+	switch {
+	case has("go.mod"), has("*.go"):
+		return langGo
+	case has("CMakeLists.txt"), has("Makefile"), has("makefile"), has("*.c"):
+		return langC
+	case has("*.py"):
+		return langPython
+	}
+
+	return langGo
+}
+
+// languageCommands returns how to build (if at all) and run a C or Python
+// submission in dir, with build outputs in work where the build allows.
+//
+// C is built with CMake, make, or else cc on its .c files; the first two must
+// produce an executable named scheduler (make's in dir). Python runs
+// scheduler.py, main.py, or the only .py file, after a syntax check.
+func languageCommands(lang, dir, work string) (build [][]string, run []string, err error) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch lang {
+	case langC:
+		scheduler := "scheduler"
+		if runtime.GOOS == "windows" {
+			scheduler += ".exe"
+		}
+		switch {
+		case exists("CMakeLists.txt"):
+			out := filepath.Join(work, "build")
+			build = [][]string{{"cmake", "-S", ".", "-B", out}, {"cmake", "--build", out}}
+			run = []string{filepath.Join(out, scheduler)}
+		case exists("Makefile"), exists("makefile"):
+			build = [][]string{{"make"}}
+			run = []string{filepath.Join(dir, scheduler)}
+		default:
+			sources, _ := filepath.Glob(filepath.Join(dir, "*.c"))
+			if len(sources) == 0 {
+				return nil, nil, errors.New("no .c files, Makefile or CMakeLists.txt")
+			}
+			output := filepath.Join(work, binaryName())
+			cc := "cc"
+			if runtime.GOOS == "windows" {
+				// MinGW's, as Windows has no cc.
+				cc = "gcc"
+			}
+			build = [][]string{append(append([]string{cc, "-O2", "-o", output}, sources...), "-lm")}
+			run = []string{output}
+		}
+	case langPython:
+		python := "python3"
+		if runtime.GOOS == "windows" {
+			python = "python"
+		}
+		var entry string
+		for _, name := range []string{"scheduler.py", "main.py"} {
+			if exists(name) {
+				entry = name
+				break
+			}
+		}
+		if entry == "" {
+			sources, _ := filepath.Glob(filepath.Join(dir, "*.py"))
+			if len(sources) != 1 {
+				return nil, nil, errors.New("no scheduler.py or main.py entrypoint")
+			}
+			entry = filepath.Base(sources[0])
+		}
+		// compiling, without writing __pycache__ into the submission, catches syntax errors.
+		build = [][]string{{python, "-c", "import sys; compile(open(sys.argv[1]).read(), sys.argv[1], 'exec')", entry}}
+		run = []string{python, entry}
+	default:
+		return nil, nil, fmt.Errorf("unknown language %q", lang)
+	}
+
+	return build, run, nil
+}
+
+// checkLanguage builds a C or Python submission and sets it up to run, as
+// with --build-cmd and --run-cmd.
+func checkLanguage(c *Context, result Result) (Result, error) {
+	if c.opts.Sandbox != "" {
+		result.Message = "the sandbox only builds Go submissions, not " + languageName(c.lang)
+		return result, errors.New("unsupported sandbox language")
+	}
+	work, err := c.buildDir()
+	if err != nil {
+		result.Message = "could not create a build directory"
+		return result, err
+	}
+	build, run, err := languageCommands(c.lang, c.srcDir, work)
+	if err != nil {
+		result.Message = "scheduler is not compileable: " + err.Error()
+		return result, err
+	}
+
+	return checkBuildCmd(c, result, build, run)
+}
+
+// notApplicable awards a Go-only check's points to another language's
+// submission, so totals are comparable across languages.
+func notApplicable(result Result, lang string) (Result, error) {
+	result.Awarded = result.Possible
+	result.Message = "not applicable to a " + languageName(lang) + " submission"
+
+	return result, nil
+}
+
+func languageName(lang string) string {
+	if lang == langC {
+		return "C"
+	}
+
+	return strings.ToUpper(lang[:1]) + lang[1:]
+}
@@ -0,0 +1,101 @@
+package grader
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// labelLate labels the late penalty row Grade appends with a Deadline. It
+// isn't a rubric item: it's worth no points, and awards minus the penalty.
+const labelLate = "Late penalty"
+
+// submittedAt is when the submission was last changed: its last commit's
+// time in a git checkout, or else its newest file's modification time.
+func submittedAt(ctx context.Context, dir string) (time.Time, string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		if out, err := runGit(ctx, dir, "log", "-1", "--format=%ct", "HEAD", "--"); err == nil {
+			if secs, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64); err == nil {
+				return time.Unix(secs, 0), "last commit", nil
+			}
+		}
+	}
+	var newest time.Time
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(newest) {
+			newest = fi.ModTime()
+		}
+		return nil
+	})
+	if err == nil && newest.IsZero() {
+		err = fmt.Errorf("no files in %s", dir)
+	}
+
+	return newest, "newest file", err
+}
+
+// latePenalty is the late penalty row for results: opts.LatePenalty percent
+// of the awarded points per day, or part of one, after the deadline, up to
+// opts.MaxPenalty percent.
+func latePenalty(ctx context.Context, dir string, opts Options, results []Result) Result {
+	result := Result{Label: labelLate}
+	at, source, err := submittedAt(ctx, dir)
+	if err != nil {
+		result.Message = "could not tell when it was submitted"
+		result.Error = err.Error()
+		return result
+	}
+	when := fmt.Sprintf("%s %s", source, at.Local().Format("2006-01-02 15:04"))
+	late := at.Sub(opts.Deadline)
+	if late <= 0 {
+		result.Message = "on time (" + when + ")"
+		return result
+	}
+	days := int(math.Ceil(late.Hours() / 24))
+	pct := min(float64(days)*opts.LatePenalty, opts.MaxPenalty)
+	var awarded int
+	for _, r := range results {
+		awarded += r.Awarded
+	}
+	result.Awarded = -int(math.Round(float64(awarded) * pct / 100))
+	result.Message = fmt.Sprintf("%d day(s) late (%s): -%g%%", days, when, pct)
+
+	return result
+}
+
+// rawTotal is the awarded total before any late penalty, and whether there
+// was one.
+func rawTotal(results []Result) (int, bool) {
+	var (
+		total     int
+		penalized bool
+	)
+	for _, r := range results {
+		if r.Label == labelLate && r.Awarded < 0 {
+			penalized = true
+			continue
+		}
+		total += r.Awarded
+	}
+
+	return total, penalized
+}
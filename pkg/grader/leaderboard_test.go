@@ -0,0 +1,63 @@
+package grader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPostLeaderboardHandles(t *testing.T) {
+	dir := t.TempDir()
+	board, handlesFile := filepath.Join(dir, "board.jsonl"), filepath.Join(dir, "handles.json")
+	// two students of the same name, in different sections.
+	graded := []submission{
+		{dir: filepath.Join(dir, "section1", "alice"), results: []Result{{Label: "Compiles", Awarded: 10, Possible: 10}}},
+		{dir: filepath.Join(dir, "section2", "alice"), results: []Result{{Label: "Compiles", Possible: 10}}},
+	}
+	for i := 0; i < 2; i++ {
+		if err := postLeaderboard(context.Background(), board, "", handlesFile, graded); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := postLeaderboard(context.Background(), board, "ace", handlesFile, graded[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var handles []string
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var e leaderboardEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		handles = append(handles, e.Handle)
+	}
+	if len(handles) != 5 {
+		t.Fatalf("%d entries, want 5", len(handles))
+	}
+	if handles[0] == handles[1] {
+		t.Errorf("both alices are %s", handles[0])
+	}
+	// kept from run to run.
+	if handles[2] != handles[0] || handles[3] != handles[1] {
+		t.Errorf("handles %q changed from the first run", handles[:4])
+	}
+	if handles[4] != "ace" {
+		t.Errorf("--handle's entry is %s, want ace", handles[4])
+	}
+
+	kept, err := loadHandles(handlesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept.handles) != 2 || kept.handles[graded[0].dir] != handles[0] {
+		t.Errorf("handles file = %v, want each alice's", kept.handles)
+	}
+}
@@ -0,0 +1,77 @@
+//go:build linux || windows
+
+package grader
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// limitExecArg makes gradebot act as a launcher that limits itself and then
+// runs the scheduler: Go can't limit a child between creating and starting
+// it, with rlimits on Linux or a job object on Windows.
+const limitExecArg = "__exec-limited"
+
+// limitsSupported is whether --mem-limit, --cpu-limit and --proc-limit apply
+// to a native scheduler here.
+const limitsSupported = true
+
+// applyLimits rewrites cmd to run through the launcher, limiting the
+// scheduler's memory, CPU time and processes.
+func applyLimits(cmd *exec.Cmd, opts Options) error {
+	if opts.MemLimit == 0 && opts.CPULimit <= 0 && opts.ProcLimit == 0 {
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	secs := uint64(math.Ceil(max(opts.CPULimit, 0).Seconds()))
+	cmd.Args = append([]string{self, limitExecArg,
+		strconv.FormatUint(opts.MemLimit, 10), strconv.FormatUint(secs, 10),
+		strconv.FormatUint(opts.ProcLimit, 10), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+
+	return nil
+}
+
+// ExecLimited runs as the launcher, never returning, when applyLimits
+// started this process as one. A program grading with Options' limits calls
+// it first thing in main, as the launcher is the program itself.
+func ExecLimited() {
+	if len(os.Args) > 1 && os.Args[1] == limitExecArg {
+		execLimited(os.Args[2:])
+	}
+}
+
+// execLimited is the launcher: args are the memory limit in bytes, the CPU
+// limit in seconds and the process limit (0 for none), then the scheduler
+// path and its arguments. It never returns.
+func execLimited(args []string) {
+	if len(args) < 4 {
+		launchFailed(fmt.Errorf("%s: missing arguments", limitExecArg))
+	}
+	mem, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		launchFailed(err)
+	}
+	cpu, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		launchFailed(err)
+	}
+	nproc, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		launchFailed(err)
+	}
+	launchFailed(runLimited(mem, cpu, nproc, args[3:]))
+}
+
+// launchFailed exits the launcher as a shell does for a command it couldn't
+// run.
+func launchFailed(err error) {
+	fmt.Fprintln(os.Stderr, "gradebot: "+err.Error())
+	os.Exit(126)
+}
@@ -0,0 +1,50 @@
+//go:build linux
+
+package grader
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// runLimited sets the launcher's rlimits, which the scheduler inherits, and
+// execs it; it only returns on failure.
+func runLimited(mem, cpu, nproc uint64, argv []string) error {
+	if mem > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: mem, Max: mem}); err != nil {
+			return fmt.Errorf("RLIMIT_AS: %w", err)
+		}
+	}
+	if cpu > 0 {
+		// SIGXCPU at the soft limit, SIGKILL a second later if that's ignored.
+		if err := unix.Setrlimit(unix.RLIMIT_CPU, &unix.Rlimit{Cur: cpu, Max: cpu + 1}); err != nil {
+			return fmt.Errorf("RLIMIT_CPU: %w", err)
+		}
+	}
+	if nproc > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_NPROC, &unix.Rlimit{Cur: nproc, Max: nproc}); err != nil {
+			return fmt.Errorf("RLIMIT_NPROC: %w", err)
+		}
+	}
+
+	return syscall.Exec(argv[0], argv, os.Environ())
+}
+
+// cpuLimitExceeded reports whether the kernel stopped the process for using
+// up its CPU time limit.
+func cpuLimitExceeded(state *os.ProcessState, limit time.Duration) bool {
+	if state == nil || limit <= 0 {
+		return false
+	}
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return false
+	}
+
+	return ws.Signal() == syscall.SIGXCPU ||
+		ws.Signal() == syscall.SIGKILL && state.UserTime()+state.SystemTime() >= limit
+}
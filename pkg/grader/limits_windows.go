@@ -0,0 +1,81 @@
+//go:build windows
+
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// cpuExceededExit is the launcher's exit code when the job object stopped
+// the scheduler at its CPU time limit, as 128+SIGXCPU would be on Linux.
+const cpuExceededExit = 152
+
+// runLimited puts the launcher in a job object limiting each process's
+// committed memory and user time, and the processes at once, then runs the
+// scheduler in it and exits as it does. The launcher joins before starting
+// the scheduler, so nothing it starts escapes the job.
+func runLimited(mem, cpu, nproc uint64, argv []string) error {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return fmt.Errorf("job object: %w", err)
+	}
+	var info windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if mem > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_MEMORY
+		info.ProcessMemoryLimit = uintptr(mem)
+	}
+	limit := time.Duration(cpu) * time.Second
+	if cpu > 0 {
+		// per process, as RLIMIT_CPU is, so the launcher's own time doesn't
+		// count; in 100ns ticks.
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_TIME
+		info.BasicLimitInformation.PerProcessUserTimeLimit = int64(limit / 100)
+	}
+	if nproc > 0 {
+		// counting the launcher.
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+		info.BasicLimitInformation.ActiveProcessLimit = uint32(min(nproc+1, 1<<32-1))
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		return fmt.Errorf("job object limits: %w", err)
+	}
+	self, err := windows.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	if err := windows.AssignProcessToJobObject(job, self); err != nil {
+		return fmt.Errorf("joining the job object: %w", err)
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	var exit *exec.ExitError
+	switch {
+	case errors.As(err, &exit):
+		if cpu > 0 && exit.UserTime() >= limit {
+			os.Exit(cpuExceededExit)
+		}
+		os.Exit(exit.ExitCode())
+	case err != nil:
+		return err
+	}
+	os.Exit(0)
+
+	return nil
+}
+
+// cpuLimitExceeded reports whether the launcher's job object stopped the
+// scheduler for using up its CPU time limit.
+func cpuLimitExceeded(state *os.ProcessState, limit time.Duration) bool {
+	return state != nil && limit > 0 && state.ExitCode() == cpuExceededExit
+}
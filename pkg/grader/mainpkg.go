@@ -0,0 +1,77 @@
+package grader
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// mainPackage returns the package to go build in the module at dir, as a
+// "./" relative path: override if set (--main-pkg), the root if it's a main
+// package, or else the module's only main package, e.g. "./cmd/scheduler".
+// Of several, one named scheduler is preferred.
+func mainPackage(dir, override string) (string, error) {
+	if override != "" {
+		pkg := "./" + strings.TrimPrefix(filepath.ToSlash(filepath.Clean(override)), "./")
+		if fi, err := os.Stat(filepath.Join(dir, override)); err != nil || !fi.IsDir() {
+			return pkg, fmt.Errorf("main package %s not found", pkg)
+		}
+		return pkg, nil
+	}
+	if isMainPackage(dir) {
+		return ".", nil
+	}
+	var mains []string
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == dir {
+			return nil
+		}
+		// like the go command, ignore hidden, underscore-prefixed, vendor and testdata directories.
+		name := d.Name()
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" {
+			return filepath.SkipDir
+		}
+		// a nested module isn't part of this one.
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+			return filepath.SkipDir
+		}
+		if isMainPackage(path) {
+			rel, _ := filepath.Rel(dir, path)
+			mains = append(mains, "./"+filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	switch len(mains) {
+	case 0:
+		// the build then reports why there's no executable.
+		return ".", nil
+	case 1:
+		return mains[0], nil
+	}
+	if i := slices.IndexFunc(mains, func(pkg string) bool { return filepath.Base(pkg) == "scheduler" }); i >= 0 {
+		return mains[i], nil
+	}
+
+	return "", fmt.Errorf("several main packages (%s), choose one with --main-pkg", strings.Join(mains, ", "))
+}
+
+// isMainPackage reports whether dir's non-test Go files are package main.
+func isMainPackage(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err == nil && f.Name.Name == "main" {
+			return true
+		}
+	}
+
+	return false
+}
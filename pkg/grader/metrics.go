@@ -0,0 +1,143 @@
+package grader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// inputColumns are schedule table columns that echo the input, so they
+// aren't graded as metrics.
+var inputColumns = []string{"ID", "PRIORITY", "BURST", "ARRIVAL"}
+
+// scheduleMetrics are the graded parts of scheduler output, for --metrics.
+type scheduleMetrics struct {
+	// gantt is the Gantt schedule's processes and times, each as printed.
+	gantt, times []string
+	// columns are the schedule table's columns, in order.
+	columns []string
+	// table holds the schedule table's cells, by process ID then column.
+	table map[string]map[string]string
+	// stats are the summary lines, e.g. "Average wait" to "5.00".
+	stats map[string]string
+	// statOrder is the summary labels, in order.
+	statOrder []string
+}
+
+// parseMetrics finds the Gantt schedule (the first "|" line after a "Gantt"
+// heading, or the first "|" line at all, and the line of times after it),
+// the schedule table (as for --structured), and the "Label: value" lines.
+func parseMetrics(out []byte) scheduleMetrics {
+	m := scheduleMetrics{table: make(map[string]map[string]string), stats: make(map[string]string)}
+	lines := strings.Split(string(out), "\n")
+
+	start := 0
+	for i, line := range lines {
+		if strings.Contains(strings.ToLower(line), "gantt") {
+			start = i + 1
+			break
+		}
+	}
+	for i := start; i < len(lines); i++ {
+		if !strings.HasPrefix(strings.TrimSpace(lines[i]), "|") {
+			continue
+		}
+		m.gantt = strings.FieldsFunc(lines[i], func(r rune) bool { return unicode.IsSpace(r) || r == '|' })
+		for _, next := range lines[i+1:] {
+			if fields := strings.Fields(next); len(fields) > 0 {
+				m.times = fields
+				break
+			}
+		}
+		break
+	}
+
+	for _, r := range parseRecords(out) {
+		if r.header == nil || len(r.fields) == 0 {
+			continue
+		}
+		if m.columns == nil {
+			m.columns = r.header
+		}
+		row := make(map[string]string, len(r.header))
+		for j, name := range r.header {
+			row[name] = r.fields[j]
+		}
+		m.table[r.fields[0]] = row
+	}
+
+	for _, line := range lines {
+		label, value, ok := strings.Cut(strings.TrimSpace(line), ": ")
+		if !ok || strings.ContainsAny(label, "|") {
+			continue
+		}
+		if _, seen := m.stats[label]; !seen {
+			m.statOrder = append(m.statOrder, label)
+		}
+		m.stats[label] = strings.TrimSpace(value)
+	}
+
+	return m
+}
+
+// compareMetrics grades actual's metrics against want's: the Gantt schedule,
+// each computed table column (every process's value must match), and each
+// summary statistic. diverged describes each wrong metric.
+func compareMetrics(actual []byte, want golden) (matched, total int, diverged []string) {
+	act, exp := parseMetrics(actual), parseMetrics(want.out)
+
+	if len(exp.gantt) > 0 {
+		total++
+		switch {
+		case !slices.Equal(act.gantt, exp.gantt):
+			diverged = append(diverged, fmt.Sprintf("Gantt schedule: got %s, want %s",
+				strings.Join(act.gantt, " "), strings.Join(exp.gantt, " ")))
+		case !slices.Equal(act.times, exp.times):
+			diverged = append(diverged, fmt.Sprintf("Gantt times: got %s, want %s",
+				strings.Join(act.times, " "), strings.Join(exp.times, " ")))
+		default:
+			matched++
+		}
+	}
+
+	for _, column := range exp.columns {
+		if slices.Contains(inputColumns, column) {
+			continue
+		}
+		total++
+		detail := ""
+		for _, id := range sortedKeys(exp.table) {
+			got, ok := act.table[id][column]
+			if !ok {
+				detail = fmt.Sprintf("%s: no row %s", column, id)
+				break
+			}
+			if d := compareField(column, got, exp.table[id][column], want.fields); d != "" {
+				detail = "row " + id + ", " + d
+				break
+			}
+		}
+		if detail != "" {
+			diverged = append(diverged, detail)
+			continue
+		}
+		matched++
+	}
+
+	for _, label := range exp.statOrder {
+		total++
+		got, ok := act.stats[label]
+		if !ok {
+			diverged = append(diverged, label+": missing")
+			continue
+		}
+		if d := compareField(label, got, exp.stats[label], want.fields); d != "" {
+			diverged = append(diverged, d)
+			continue
+		}
+		matched++
+	}
+
+	return matched, total, diverged
+}
@@ -0,0 +1,121 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxNotifyText is as long as a Discord message can be; Slack's are longer.
+const maxNotifyText = 2000
+
+type (
+	// notification is the --notify-format=json payload.
+	notification struct {
+		Dir      string         `json:"dir"`
+		Total    int            `json:"total"`
+		Possible int            `json:"possible"`
+		Failed   []notifyFailed `json:"failed"`
+	}
+	notifyFailed struct {
+		Label    string `json:"label"`
+		Awarded  int    `json:"awarded"`
+		Possible int    `json:"possible"`
+		Message  string `json:"message,omitempty"`
+	}
+)
+
+// notifyFormat is the payload format for the webhook at rawURL: format, or
+// with "auto" the one its host implies.
+func notifyFormat(format, rawURL string) string {
+	if format != "auto" {
+		return format
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "json"
+	}
+	switch host := strings.ToLower(u.Hostname()); {
+	case host == "hooks.slack.com":
+		return "slack"
+	case (host == "discord.com" || host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return "discord"
+	}
+
+	return "json"
+}
+
+// notify posts a summary of the graded submission to the webhook. A failed
+// notification is logged; the grade doesn't depend on it.
+func notify(ctx context.Context, rawURL, format string, s submission) {
+	n := notification{Dir: s.name(), Failed: []notifyFailed{}}
+	n.Total, n.Possible = s.totals()
+	for _, r := range s.results {
+		if r.Awarded < r.Possible {
+			first, _, _ := strings.Cut(r.Message, "\n")
+			n.Failed = append(n.Failed, notifyFailed{Label: r.Label, Awarded: r.Awarded, Possible: r.Possible, Message: first})
+		}
+	}
+
+	var payload any = n
+	switch notifyFormat(format, rawURL) {
+	case "discord":
+		payload = map[string]string{"content": notifyText(n)}
+	case "slack":
+		payload = map[string]string{"text": notifyText(n)}
+	}
+	body, err := json.Marshal(payload)
+	if err == nil {
+		err = postNotification(ctx, rawURL, body)
+	}
+	if err != nil {
+		slog.Warn("no notification", slog.String("dir", s.name()), slog.String("err", err.Error()))
+	}
+}
+
+// notifyText is the chat message for a notification: the total, then a line
+// per failed check.
+func notifyText(n notification) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "gradebot: %s scored %d/%d", n.Dir, n.Total, n.Possible)
+	for _, f := range n.Failed {
+		line := fmt.Sprintf("\n- %s: %d/%d", f.Label, f.Awarded, f.Possible)
+		if f.Message != "" {
+			line += " (" + f.Message + ")"
+		}
+		sb.WriteString(line)
+	}
+	if sb.Len() <= maxNotifyText {
+		return sb.String()
+	}
+
+	return truncate(sb.String(), maxNotifyText-len("\n[truncated]"))
+}
+
+func postNotification(ctx context.Context, rawURL string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
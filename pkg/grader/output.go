@@ -0,0 +1,128 @@
+package grader
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+const (
+	// maxStderrBytes is how much of the scheduler's stderr is retained (the tail).
+	maxStderrBytes = 64 << 10
+	// stderrTailLines is how many trailing stderr lines are shown in a result message.
+	stderrTailLines = 5
+	// reportStderrLines is how many trailing stderr lines of each run are kept
+	// in a result's Stderr.
+	reportStderrLines = 20
+	// maxStderrLineLen truncates each shown stderr line.
+	maxStderrLineLen = 200
+)
+
+// tailBuffer is an io.Writer that keeps only the last limit bytes written.
+type tailBuffer struct {
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+		b.truncated = true
+	}
+
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
+
+// cappedBuffer is an io.Writer keeping the first limit bytes written (all
+// of them with no limit), calling exceeded once more are, e.g. to kill the
+// process writing them, rather than grow without bound.
+type cappedBuffer struct {
+	limit    uint64
+	buf      []byte
+	over     bool
+	exceeded func()
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.over {
+		return len(p), nil
+	}
+	if room := b.limit - uint64(len(b.buf)); b.limit > 0 && uint64(len(p)) > room {
+		b.buf = append(b.buf, p[:room]...)
+		b.over = true
+		b.exceeded()
+		return len(p), nil
+	}
+	b.buf = append(b.buf, p...)
+
+	return len(p), nil
+}
+
+// textOutput undoes the CRLF line endings of Windows' text-mode stdout (as
+// of C and Python schedulers there), so output compares the same as on
+// Linux, even with --strict.
+func textOutput(b []byte) []byte {
+	if runtime.GOOS != "windows" {
+		return b
+	}
+
+	return bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+}
+
+// tailLines returns the last n non-empty lines of s, each truncated to a sane length.
+func tailLines(s string, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line == "" {
+			continue
+		}
+		if len(line) > maxStderrLineLen {
+			line = line[:maxStderrLineLen] + "…"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines
+}
+
+// logLines collects a check's log records, one per line, so they can be
+// reported with its result instead of interleaving with concurrent checks.
+type logLines struct {
+	lines []string
+}
+
+func (l *logLines) Write(p []byte) (int, error) {
+	l.lines = append(l.lines, strings.TrimRight(string(p), "\n"))
+
+	return len(p), nil
+}
+
+// newCheckLogger returns a logger writing to w, as text or JSON, without
+// timestamps so the collected lines read the same on every run.
+func newCheckLogger(w io.Writer, level slog.Leveler, json bool) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	if json {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+
+	return slog.New(slog.NewTextHandler(w, opts))
+}
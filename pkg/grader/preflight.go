@@ -0,0 +1,197 @@
+package grader
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// unrecognizedFlagErrors are how common flag parsers report an unknown flag:
+// the standard library's flag package, and pflag (as used by cobra).
+var unrecognizedFlagErrors = []string{
+	"flag provided but not defined",
+	"unknown flag",
+	"unknown shorthand flag",
+}
+
+// unrecognizedFlag reports whether stderr shows the scheduler rejected a flag.
+func unrecognizedFlag(stderr string) bool {
+	for _, e := range unrecognizedFlagErrors {
+		if strings.Contains(stderr, e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkFlags runs the scheduler with -h, and with no arguments, to confirm it
+// accepts each algorithm's flag and requires one, and detects its flag and
+// input styles for the scheduler checks (see detectInvocation). It returns a note for the
+// Compilable result ("" when all is well); the scheduler checks still decide
+// the points. Only project 1's scheduler has algorithm flags.
+func checkFlags(c *Context) string {
+	if c.opts.Project != "" && c.opts.Project != defaultProject {
+		return ""
+	}
+	help := execScheduler(c, nil, []string{"-h"})
+	if len(help.stdout) == 0 && unrecognizedFlag(help.stderr) {
+		help = execScheduler(c, nil, []string{"--help"})
+	}
+	usage := string(help.stdout) + help.stderr
+
+	var notes []string
+	c.flags, c.input = detectInvocation(c, usage)
+	if c.flags != flagSingleDash {
+		notes = append(notes, fmt.Sprintf("ran with %s algorithm flags, e.g. %s (detected)", c.flags, c.flags.flag("fcfs")))
+	}
+	if c.input == inputFile {
+		notes = append(notes, "ran with the input file's path as the last argument, rather than on stdin (detected)")
+	}
+	var missing []string
+	for _, algorithm := range caseAlgorithms {
+		if !c.flags.mentioned(usage, algorithm) {
+			missing = append(missing, c.flags.flag(algorithm))
+		}
+	}
+	switch {
+	case len(missing) == len(caseAlgorithms):
+		// no usage message to go on (or not one from a flag parser).
+		c.log.Debug("scheduler usage lists no algorithm flags", slog.String("usage", strings.Join(tailLines(usage, stderrTailLines), "\n")))
+	case len(missing) > 0:
+		notes = append(notes, fmt.Sprintf("scheduler does not accept %s (not in its -h usage)", strings.Join(missing, ", ")))
+	}
+
+	// given input but no algorithm flag, it should fail rather than pick one.
+	if bare := execScheduler(c, c.opts.fixture("fcfs.csv", fcfsIn), nil); bare.err == nil && len(bare.stdout) > 0 {
+		notes = append(notes, "runs without an algorithm flag (one of "+strings.Join(algorithmFlags(), ", ")+" should be required)")
+	}
+	if len(notes) > 0 {
+		c.log.Warn("scheduler flags", slog.String("notes", strings.Join(notes, "; ")))
+	}
+
+	return strings.Join(notes, "\n")
+}
+
+// flagStyle is a scheduler's convention for its algorithm flags.
+type flagStyle int
+
+const (
+	// flagSingleDash is the assignment's, e.g. -fcfs.
+	flagSingleDash flagStyle = iota
+	// flagDoubleDash is GNU-style, e.g. --fcfs.
+	flagDoubleDash
+	// flagPositional is a subcommand-like argument, e.g. fcfs.
+	flagPositional
+)
+
+func (s flagStyle) String() string {
+	switch s {
+	case flagDoubleDash:
+		return "double-dash"
+	case flagPositional:
+		return "positional"
+	}
+
+	return "single-dash"
+}
+
+// flag is the algorithm's flag in the style.
+func (s flagStyle) flag(algorithm string) string {
+	switch s {
+	case flagDoubleDash:
+		return "--" + algorithm
+	case flagPositional:
+		return algorithm
+	}
+
+	return "-" + algorithm
+}
+
+// rewrite rewrites the algorithm flags of args, as the checks write them
+// (e.g. -rr -q 2), to the style; other flags are left as they are.
+func (s flagStyle) rewrite(args []string) []string {
+	if s == flagSingleDash {
+		return args
+	}
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = arg
+		if algorithm := strings.TrimPrefix(arg, "-"); arg != algorithm && slices.Contains(caseAlgorithms, algorithm) {
+			out[i] = s.flag(algorithm)
+		}
+	}
+
+	return out
+}
+
+// mentioned reports whether the usage lists the algorithm in the style, as
+// for usageMentions; a positional one as a word of its own.
+func (s flagStyle) mentioned(usage, algorithm string) bool {
+	if s == flagPositional {
+		return regexp.MustCompile(`(^|[^\w-])` + regexp.QuoteMeta(algorithm) + `($|[^\w-])`).MatchString(usage)
+	}
+
+	return usageMentions(usage, algorithm)
+}
+
+// inputStyle is how a scheduler reads its input.
+type inputStyle int
+
+const (
+	// inputStdin is the assignment's: the CSV on stdin.
+	inputStdin inputStyle = iota
+	// inputFile is the CSV's path as the last argument, as the assignment
+	// once allowed.
+	inputFile
+)
+
+// detectInvocation finds the styles the scheduler takes its algorithm flags
+// and input in, by running FCFS with each until one prints output: stdin
+// before a file argument and, for the flags, first the style its usage
+// suggests, then the assignment's, then the rest. With none working, they're
+// the assignment's, for the scheduler checks to fail on.
+func detectInvocation(c *Context, usage string) (flagStyle, inputStyle) {
+	styles := []flagStyle{flagSingleDash, flagDoubleDash, flagPositional}
+	// many flag packages take --fcfs for -fcfs, so only a positional usage
+	// reorders the runs.
+	if !usageMentions(usage, "fcfs") && flagPositional.mentioned(usage, "fcfs") {
+		styles = []flagStyle{flagPositional, flagSingleDash, flagDoubleDash}
+	}
+	inputs := []inputStyle{inputStdin, inputFile}
+	if c.opts.Sandbox != "" {
+		// the sandbox can't see gradebot's temporary files.
+		inputs = inputs[:1]
+	}
+	in := c.opts.fixture("fcfs.csv", fcfsIn)
+	for _, input := range inputs {
+		probe := *c
+		probe.input = input
+		for _, s := range styles {
+			if run := execScheduler(&probe, in, []string{s.flag("fcfs")}); run.err == nil && len(bytes.TrimSpace(run.stdout)) > 0 {
+				return s, input
+			}
+		}
+	}
+
+	return flagSingleDash, inputStdin
+}
+
+// algorithmFlags are the scheduler flags selecting each algorithm, e.g. -fcfs.
+func algorithmFlags() []string {
+	flags := make([]string, len(caseAlgorithms))
+	for i, algorithm := range caseAlgorithms {
+		flags[i] = "-" + algorithm
+	}
+
+	return flags
+}
+
+// usageMentions reports whether the usage lists the flag, as either -name or
+// --name, but not as a prefix of a longer flag (e.g. -sjf of -sjfp).
+func usageMentions(usage, name string) bool {
+	return regexp.MustCompile(`(^|[^\w-])--?` + regexp.QuoteMeta(name) + `($|[^\w-])`).MatchString(usage)
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package grader
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// killProcessGroup runs the scheduler in its own process group and makes
+// cancellation kill the whole group, so no children linger after a timeout.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// peakRSS is the exited process's peak resident set size, in bytes: getrusage
+// reports it in KiB, but in bytes on macOS.
+func peakRSS(state *os.ProcessState) uint64 {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru.Maxrss <= 0 {
+		return 0
+	}
+	if runtime.GOOS == "darwin" {
+		return uint64(ru.Maxrss)
+	}
+
+	return uint64(ru.Maxrss) << 10
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package grader
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// killProcessGroup kills the scheduler on cancellation. Windows has no process
+// groups to signal, so taskkill /T kills the scheduler's process tree, falling
+// back to killing just the scheduler.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
+
+// peakRSS is unknown on Windows: the process's handle, which its memory
+// counters need, is closed once it's waited for.
+func peakRSS(*os.ProcessState) uint64 { return 0 }
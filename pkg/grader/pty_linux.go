@@ -0,0 +1,70 @@
+//go:build linux
+
+package grader
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// startPTY starts cmd on a new pseudo-terminal, as its controlling terminal,
+// returning the terminal's master side. The terminal neither echoes input nor
+// translates newlines, so it reads like a pipe to gradebot but a terminal to
+// the shell.
+func startPTY(cmd *exec.Cmd) (*os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	tty, err := openTTY(master)
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+	defer tty.Close()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	// a new session is also a new process group, for cancellation to kill.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	if err := cmd.Start(); err != nil {
+		master.Close()
+		return nil, err
+	}
+
+	return master, nil
+}
+
+// openTTY unlocks and opens the master's terminal, turning off its echo and
+// newline translation.
+func openTTY(master *os.File) (*os.File, error) {
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		return nil, err
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		return nil, err
+	}
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	t, err := unix.IoctlGetTermios(int(tty.Fd()), unix.TCGETS)
+	if err == nil {
+		t.Lflag &^= unix.ECHO | unix.ECHONL
+		t.Oflag &^= unix.ONLCR
+		err = unix.IoctlSetTermios(int(tty.Fd()), unix.TCSETS, t)
+	}
+	if err != nil {
+		tty.Close()
+		return nil, err
+	}
+
+	return tty, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package grader
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// startPTY is unsupported outside Linux; shells run on pipes instead.
+func startPTY(*exec.Cmd) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
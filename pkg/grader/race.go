@@ -0,0 +1,112 @@
+package grader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// raceWarning starts each of the race detector's reports, on stderr.
+const raceWarning = "WARNING: DATA RACE"
+
+// CheckRace rebuilds the scheduler with the race detector and replays the
+// embedded inputs, awarding proportional credit for each run that reports
+// no data race.
+func CheckRace(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelRace,
+		Awarded:  0,
+		Possible: c.opts.possible(labelRace),
+	}
+	if c.lang != "" && c.lang != langGo {
+		return notApplicable(result, c.lang)
+	}
+	if len(c.opts.RunCmd) > 0 {
+		result.Awarded = result.Possible
+		result.Message = "not applicable with --run-cmd"
+		return result, nil
+	}
+	if len(c.run) == 0 {
+		result.Message = "scheduler was not compileable"
+		return result, errors.New("binary not found")
+	}
+	pkg, err := mainPackage(c.srcDir, c.opts.MainPkg)
+	if err != nil {
+		result.Message = err.Error()
+		return result, err
+	}
+	// not the build directory: this check runs on a copy of the Context.
+	dir, err := os.MkdirTemp("", "gradebot-race-")
+	if err != nil {
+		result.Message = "could not create a build directory"
+		return result, err
+	}
+	defer os.RemoveAll(dir)
+
+	race := *c
+	// the race runtime reserves far more address space than --mem-limit allows.
+	race.opts.MemLimit = 0
+	var cmd *exec.Cmd
+	if c.opts.Sandbox == sandboxDocker {
+		if cmd, err = sandboxGo(c.ctx, c, []string{"-v", dir + ":/out"}, "build", "-race", "-o", "/out/scheduler", pkg); err != nil {
+			result.Message = "could not set up the sandbox"
+			return result, err
+		}
+		// the same container, running the race build from its own mount.
+		run := slices.Clone(c.run)
+		run[len(run)-1] = "/race/scheduler"
+		race.run = slices.Insert(run, len(run)-2, "-v", dir+":/race:ro")
+	} else {
+		cmd = c.goCommand(c.ctx, "build", "-race", "-o", filepath.Join(dir, binaryName()), pkg)
+		race.run = []string{filepath.Join(dir, binaryName())}
+	}
+	cmd.Dir = c.srcDir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		// e.g. without cgo, or on a platform the race detector doesn't support.
+		result.Message = "could not build with -race"
+		if tail := tailLines(out.String(), stderrTailLines); len(tail) > 0 {
+			result.Message += ":\n" + strings.Join(tail, "\n")
+		}
+		return result, err
+	}
+
+	var (
+		clean   int
+		reports []string
+	)
+	for _, gr := range goldenRuns {
+		in := c.opts.fixture(gr.in, embeddedInputs[gr.in])
+		run := execScheduler(&race, in, gr.args)
+		if c.ctx.Err() != nil {
+			return result, c.ctx.Err()
+		}
+		name := strings.Join(gr.args, " ")
+		i := strings.Index(run.stderr, raceWarning)
+		if i < 0 {
+			clean++
+			continue
+		}
+		reports = append(reports, fmt.Sprintf("%s: %d data race(s)", name, strings.Count(run.stderr, raceWarning)))
+		// the first report, from its start: its tail is only a summary.
+		lines := strings.Split(run.stderr[i:], "\n")
+		c.stderr = append(c.stderr, "$ scheduler "+name+"\n"+strings.Join(lines[:min(len(lines), reportStderrLines)], "\n"))
+	}
+
+	result.Awarded = int(math.Round(float64(result.Possible) * float64(clean) / float64(len(goldenRuns))))
+	if clean < len(goldenRuns) {
+		result.Message = strings.Join(reports, "\n")
+		return result, errors.New("race detector fired")
+	}
+	result.Message = fmt.Sprintf("no data races in %d runs", len(goldenRuns))
+
+	return result, nil
+}
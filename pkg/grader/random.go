@@ -0,0 +1,359 @@
+package grader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// simProc is a process in the reference simulator.
+type simProc struct {
+	id                       string
+	burst, arrival, priority int
+	remaining, exit          int
+}
+
+// simSlice is a run of one process in the Gantt schedule.
+type simSlice struct {
+	id          string
+	start, stop int
+}
+
+// simTitles are the output titles of each algorithm, as in the golden files.
+var simTitles = map[string]string{
+	"fcfs": "First-come, first-serve",
+	"sjf":  "Shortest-job-first",
+	"sjfp": "Priority",
+	"rr":   "Round-robin",
+}
+
+// simulate schedules procs (sorted by arrival) like the reference scheduler.
+// ok is false when the schedule depends on a tie-break or an idle CPU, which
+// the assignment leaves unspecified, so the table shouldn't be graded.
+func simulate(algorithm string, procs []*simProc, quantum int) (gantt []simSlice, done []*simProc, ok bool) {
+	if algorithm == "rr" {
+		return simulateRR(procs, quantum)
+	}
+	var less func(a, b *simProc) bool
+	switch algorithm {
+	case "sjf":
+		less = func(a, b *simProc) bool { return a.remaining < b.remaining }
+	case "sjfp":
+		less = func(a, b *simProc) bool { return a.priority < b.priority }
+	}
+	tied := func(a, b *simProc) bool { return less != nil && !less(a, b) && !less(b, a) }
+
+	var (
+		ready []*simProc
+		cur   *simProc
+		next  int
+		t     = procs[0].arrival
+	)
+	for len(done) < len(procs) {
+		for next < len(procs) && procs[next].arrival <= t {
+			ready = append(ready, procs[next])
+			next++
+		}
+		if cur == nil || (less != nil && len(ready) > 0) {
+			best := -1
+			for i, p := range ready {
+				if best < 0 || (less != nil && less(p, ready[best])) {
+					best = i
+				}
+			}
+			if best >= 0 {
+				for i, p := range ready {
+					if i != best && tied(p, ready[best]) {
+						return nil, nil, false
+					}
+				}
+				if cur != nil && tied(ready[best], cur) {
+					return nil, nil, false
+				}
+			}
+			if best >= 0 && (cur == nil || less(ready[best], cur)) {
+				if cur != nil {
+					ready = append(ready, cur)
+				}
+				cur = ready[best]
+				ready = append(ready[:best], ready[best+1:]...)
+			}
+		}
+		if cur == nil {
+			return nil, nil, false
+		}
+		gantt = addSlice(gantt, cur.id, t, t+1)
+		cur.remaining--
+		t++
+		if cur.remaining == 0 {
+			cur.exit = t
+			done = append(done, cur)
+			cur = nil
+		}
+	}
+
+	return gantt, done, true
+}
+
+func simulateRR(procs []*simProc, quantum int) (gantt []simSlice, done []*simProc, ok bool) {
+	var (
+		queue []*simProc
+		next  int
+		t     = procs[0].arrival
+	)
+	for len(done) < len(procs) {
+		for next < len(procs) && procs[next].arrival <= t {
+			queue = append(queue, procs[next])
+			next++
+		}
+		if len(queue) == 0 {
+			return nil, nil, false
+		}
+		cur := queue[0]
+		queue = queue[1:]
+		run := min(quantum, cur.remaining)
+		gantt = addSlice(gantt, cur.id, t, t+run)
+		t += run
+		cur.remaining -= run
+		for next < len(procs) && procs[next].arrival < t {
+			queue = append(queue, procs[next])
+			next++
+		}
+		if cur.remaining == 0 {
+			cur.exit = t
+			done = append(done, cur)
+			continue
+		}
+		// whether an arrival at the end of a quantum queues before the
+		// preempted process is unspecified.
+		if next < len(procs) && procs[next].arrival == t {
+			return nil, nil, false
+		}
+		queue = append(queue, cur)
+	}
+
+	return gantt, done, true
+}
+
+func addSlice(gantt []simSlice, id string, start, stop int) []simSlice {
+	if n := len(gantt); n > 0 && gantt[n-1].id == id && gantt[n-1].stop == start {
+		gantt[n-1].stop = stop
+		return gantt
+	}
+
+	return append(gantt, simSlice{id, start, stop})
+}
+
+// writeSchedule prints the schedule in the golden output format.
+func writeSchedule(w io.Writer, title string, gantt []simSlice, done []*simProc) {
+	fmt.Fprintln(w, strings.Repeat("-", len(title)*2))
+	fmt.Fprintln(w, strings.Repeat(" ", len(title)/2), title)
+	fmt.Fprintln(w, strings.Repeat("-", len(title)*2))
+	fmt.Fprintln(w, "Gantt schedule")
+	fmt.Fprint(w, "|")
+	for _, s := range gantt {
+		pad := strings.Repeat(" ", (6-len(s.id))/2)
+		fmt.Fprint(w, pad, s.id, pad, "|")
+	}
+	fmt.Fprintln(w)
+	for i, s := range gantt {
+		fmt.Fprintf(w, "%-7d", s.start)
+		if i == len(gantt)-1 {
+			fmt.Fprint(w, s.stop)
+		}
+	}
+	fmt.Fprint(w, "\n\n")
+
+	fmt.Fprintln(w, "Schedule table")
+	header := []string{"ID", "PRIORITY", "BURST", "ARRIVAL", "WAIT", "TURNAROUND", "EXIT"}
+	var (
+		rows      [][]string
+		wait, tat float64
+	)
+	for _, p := range done {
+		turnaround := p.exit - p.arrival
+		wait += float64(turnaround - p.burst)
+		tat += float64(turnaround)
+		rows = append(rows, []string{p.id, strconv.Itoa(p.priority), strconv.Itoa(p.burst), strconv.Itoa(p.arrival),
+			strconv.Itoa(turnaround - p.burst), strconv.Itoa(turnaround), strconv.Itoa(p.exit)})
+	}
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+		for _, row := range rows {
+			widths[i] = max(widths[i], len(row[i]))
+		}
+	}
+	border := "+"
+	for _, width := range widths {
+		border += strings.Repeat("-", width+2) + "+"
+	}
+	fmt.Fprintln(w, border)
+	line := "|"
+	for i, h := range header {
+		left := (widths[i] - len(h)) / 2
+		line += " " + strings.Repeat(" ", left) + h + strings.Repeat(" ", widths[i]-len(h)-left) + " |"
+	}
+	fmt.Fprintln(w, line)
+	fmt.Fprintln(w, border)
+	for _, row := range rows {
+		line := "|"
+		for i, cell := range row {
+			if i == 0 {
+				line += fmt.Sprintf(" %-*s |", widths[i], cell)
+			} else {
+				line += fmt.Sprintf(" %*s |", widths[i], cell)
+			}
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w, border)
+
+	n := float64(len(done))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Average wait: %.2f\n", wait/n)
+	fmt.Fprintf(w, "Average turnaround: %.2f\n", tat/n)
+	fmt.Fprintf(w, "Throughput: %.2f\n", n/float64(gantt[len(gantt)-1].stop-gantt[0].start))
+}
+
+// randomCases generates n process tables per algorithm from seed, with their
+// expected output from the reference simulator. Tables whose schedule would
+// depend on unspecified behavior are regenerated.
+func randomCases(seed int64, n int) []schedulerCase {
+	rng := rand.New(rand.NewSource(seed))
+	var cases []schedulerCase
+	for _, algorithm := range caseAlgorithms {
+		for i := 1; i <= n; i++ {
+			for {
+				quantum := 1 + rng.Intn(4)
+				procs := randomProcs(rng)
+				var in bytes.Buffer
+				fmt.Fprintln(&in, "ProcessID,Burst Duration,Arrival Time,Priority")
+				for _, p := range procs {
+					fmt.Fprintf(&in, "%s,%d,%d,%d\n", p.id, p.burst, p.arrival, p.priority)
+				}
+				gantt, done, ok := simulate(algorithm, procs, quantum)
+				if !ok {
+					continue
+				}
+				var out bytes.Buffer
+				writeSchedule(&out, simTitles[algorithm], gantt, done)
+				args := []string{"-" + algorithm}
+				if algorithm == "rr" {
+					args = append(args, quantumFlag, strconv.Itoa(quantum))
+				}
+				cases = append(cases, schedulerCase{
+					name: fmt.Sprintf("%s #%d", strings.Join(args, " "), i),
+					args: args,
+					in:   in.Bytes(),
+					out:  out.Bytes(),
+				})
+				break
+			}
+		}
+	}
+
+	return cases
+}
+
+// randomProcs returns 4 to 8 processes with distinct, increasing arrival times.
+func randomProcs(rng *rand.Rand) []*simProc {
+	prefix := string(rune('A' + rng.Intn(26)))
+	procs := make([]*simProc, 4+rng.Intn(5))
+	arrival := 0
+	for i := range procs {
+		burst := 1 + rng.Intn(9)
+		procs[i] = &simProc{
+			id:        prefix + strconv.Itoa(i+1),
+			burst:     burst,
+			arrival:   arrival,
+			priority:  1 + rng.Intn(5),
+			remaining: burst,
+		}
+		arrival += 1 + rng.Intn(4)
+	}
+
+	return procs
+}
+
+// parseSimProcs parses a process table, as the scheduler reads it, sorted by
+// arrival.
+func parseSimProcs(in []byte) ([]*simProc, error) {
+	rows, err := csv.NewReader(bytes.NewReader(in)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, errors.New("no processes")
+	}
+	var procs []*simProc
+	for i, row := range rows[1:] {
+		if len(row) != 4 {
+			return nil, fmt.Errorf("row %d: %d fields, want 4", i+2, len(row))
+		}
+		var n [3]int
+		for j := range n {
+			if n[j], err = strconv.Atoi(strings.TrimSpace(row[j+1])); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+2, err)
+			}
+		}
+		procs = append(procs, &simProc{id: row[0], burst: n[0], arrival: n[1], priority: n[2], remaining: n[0]})
+	}
+	sort.SliceStable(procs, func(i, j int) bool { return procs[i].arrival < procs[j].arrival })
+
+	return procs, nil
+}
+
+// CheckRandom grades the scheduler on randomized process tables, so
+// hardcoding the embedded outputs earns nothing. The seed is reported, so a
+// run can be reproduced with --seed.
+func CheckRandom(result Result, seed int64, n int) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		var (
+			passed  int
+			credit  float64
+			reports []string
+			errs    []error
+		)
+		cases := randomCases(seed, n)
+		for _, sc := range cases {
+			fields, err := c.fieldSpecs(strings.TrimPrefix(sc.args[0], "-"))
+			if err != nil {
+				result.Message = "invalid expected output metadata"
+				return result, err
+			}
+			partial, msg, err := runScheduler(c, sc.in, golden{out: sc.out, fields: fields}, sc.args...)
+			credit += partial
+			if msg != "" {
+				reports = append(reports, fmt.Sprintf("%s: %s", sc.name, msg))
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", sc.name, err))
+				}
+				c.log.Info("randomized input", slog.String("case", sc.name), slog.String("input", string(sc.in)))
+				continue
+			}
+			passed++
+		}
+
+		result.Awarded = int(math.Round(float64(result.Possible) * credit / float64(len(cases))))
+		result.Message = fmt.Sprintf("%d/%d randomized inputs pass (--seed %d)", passed, len(cases), seed)
+		if passed < len(cases) {
+			result.Message += "\n" + strings.Join(reports, "\n")
+		}
+
+		return result, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,156 @@
+package grader
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestSimulateGoldens(t *testing.T) {
+	// some goldens break ties the simulator leaves unspecified, as a process
+	// preempted by an arrival at the same time, so their schedules aren't
+	// simulated; randomCases would have regenerated their tables.
+	tests := []struct {
+		name        string
+		algorithm   string
+		quantum     int
+		in, out     []byte
+		unspecified bool
+	}{
+		{name: "fcfs", algorithm: "fcfs", in: fcfsIn, out: fcfsOut},
+		{name: "sjf", algorithm: "sjf", in: sjfIn, out: sjfOut, unspecified: true},
+		{name: "sjfp", algorithm: "sjfp", in: sjfpIn, out: sjfpOut},
+		{name: "rr", algorithm: "rr", quantum: 4, in: rrIn, out: rrOut, unspecified: true},
+		{name: "rr q1", algorithm: "rr", quantum: 1, in: rrIn, out: rrQ1Out, unspecified: true},
+		{name: "rr q2", algorithm: "rr", quantum: 2, in: rrIn, out: rrQ2Out, unspecified: true},
+		{name: "rr q10", algorithm: "rr", quantum: 10, in: rrIn, out: rrQ10Out},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			procs, err := parseSimProcs(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gantt, done, ok := simulate(tt.algorithm, procs, tt.quantum)
+			if ok == tt.unspecified {
+				t.Fatalf("simulate() ok = %t, want %t", ok, !tt.unspecified)
+			}
+			if !ok {
+				return
+			}
+			var out bytes.Buffer
+			writeSchedule(&out, simTitles[tt.algorithm], gantt, done)
+			if !bytes.Equal(out.Bytes(), tt.out) {
+				t.Errorf("simulate() =\n%s\nwant\n%s", out.Bytes(), tt.out)
+			}
+		})
+	}
+}
+
+func TestSimulateUnspecified(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		quantum   int
+		procs     []*simProc
+	}{
+		{name: "idle CPU", algorithm: "fcfs", procs: []*simProc{
+			{id: "A", burst: 1, remaining: 1}, {id: "B", burst: 1, arrival: 5, remaining: 1}}},
+		{name: "sjf tie", algorithm: "sjf", procs: []*simProc{
+			{id: "A", burst: 3, remaining: 3}, {id: "B", burst: 2, arrival: 1, remaining: 2}, {id: "C", burst: 2, arrival: 2, remaining: 2}}},
+		{name: "sjfp tie with the running process", algorithm: "sjfp", procs: []*simProc{
+			{id: "A", burst: 3, priority: 2, remaining: 3}, {id: "B", burst: 2, arrival: 1, priority: 2, remaining: 2}}},
+		{name: "rr arrival at the end of a quantum", algorithm: "rr", quantum: 2, procs: []*simProc{
+			{id: "A", burst: 3, remaining: 3}, {id: "B", burst: 2, arrival: 2, remaining: 2}}},
+		{name: "rr idle CPU", algorithm: "rr", quantum: 2, procs: []*simProc{
+			{id: "A", burst: 1, remaining: 1}, {id: "B", burst: 1, arrival: 3, remaining: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, ok := simulate(tt.algorithm, tt.procs, tt.quantum); ok {
+				t.Error("simulate() = ok, want the schedule unspecified")
+			}
+		})
+	}
+}
+
+func TestSimulateRR(t *testing.T) {
+	procs := []*simProc{
+		{id: "A", burst: 5, remaining: 5},
+		{id: "B", burst: 2, arrival: 1, remaining: 2},
+		{id: "C", burst: 1, arrival: 3, remaining: 1},
+	}
+	gantt, done, ok := simulateRR(procs, 2)
+	if !ok {
+		t.Fatal("simulateRR() = not ok")
+	}
+	// B arrives mid-quantum, so queues before A is preempted.
+	want := []simSlice{{"A", 0, 2}, {"B", 2, 4}, {"A", 4, 6}, {"C", 6, 7}, {"A", 7, 8}}
+	if len(gantt) != len(want) {
+		t.Fatalf("simulateRR() gantt = %v, want %v", gantt, want)
+	}
+	for i := range want {
+		if gantt[i] != want[i] {
+			t.Errorf("simulateRR() gantt = %v, want %v", gantt, want)
+			break
+		}
+	}
+	var exits []int
+	for _, p := range done {
+		exits = append(exits, p.exit)
+	}
+	if len(done) != 3 || done[0].id != "B" || done[1].id != "C" || done[2].id != "A" || exits[2] != 8 {
+		t.Errorf("simulateRR() done in order %v, exits %v, want B C A, A at 8", done, exits)
+	}
+}
+
+func TestRandomCases(t *testing.T) {
+	cases := randomCases(42, 3)
+	if len(cases) != 3*len(caseAlgorithms) {
+		t.Fatalf("randomCases() = %d cases, want %d", len(cases), 3*len(caseAlgorithms))
+	}
+	again := randomCases(42, 3)
+	for i, c := range cases {
+		if !bytes.Equal(c.in, again[i].in) || !bytes.Equal(c.out, again[i].out) {
+			t.Errorf("randomCases(42) case %s differs between calls", c.name)
+		}
+		// the expected output round-trips through its input.
+		procs, err := parseSimProcs(c.in)
+		if err != nil {
+			t.Fatalf("case %s: %v", c.name, err)
+		}
+		if len(procs) < 4 || len(procs) > 8 {
+			t.Errorf("case %s: %d processes, want 4 to 8", c.name, len(procs))
+		}
+	}
+	if bytes.Equal(randomCases(43, 1)[0].in, cases[0].in) {
+		t.Error("randomCases() of another seed has the same first case")
+	}
+}
+
+func TestRandomProcs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		procs := randomProcs(rng)
+		for j := 1; j < len(procs); j++ {
+			if procs[j].arrival <= procs[j-1].arrival {
+				t.Fatalf("randomProcs() arrivals %d then %d, want increasing", procs[j-1].arrival, procs[j].arrival)
+			}
+		}
+	}
+}
+
+func TestParseSimProcs(t *testing.T) {
+	procs, err := parseSimProcs([]byte("ProcessID,Burst Duration,Arrival Time,Priority\nB,2, 3,1\nA,4,0,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 2 || procs[0].id != "A" || procs[1].arrival != 3 || procs[1].remaining != 2 {
+		t.Errorf("parseSimProcs() = %+v %+v, want A then B, sorted by arrival", *procs[0], *procs[1])
+	}
+	for _, bad := range []string{"ProcessID,Burst Duration,Arrival Time,Priority\n", "h\nA,1,2\n", "h,h,h,h\nA,x,0,1\n"} {
+		if _, err := parseSimProcs([]byte(bad)); err == nil {
+			t.Errorf("parseSimProcs(%q): no error", bad)
+		}
+	}
+}
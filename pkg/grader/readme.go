@@ -0,0 +1,82 @@
+package grader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// readmeSections are the README's required sections, each found by a
+// heading (or, for some, a line) matching its pattern.
+var readmeSections = []struct {
+	name             string
+	heading, anyLine *regexp.Regexp
+}{
+	{
+		name:    "name/EUID",
+		heading: regexp.MustCompile(`(?i)\b(name|author|student|euid)\b`),
+		anyLine: regexp.MustCompile(`(?i)\b(name|euid)\s*:|\b[a-z]{2,3}[0-9]{4}\b`),
+	},
+	{
+		name:    "build instructions",
+		heading: regexp.MustCompile(`(?i)\b(build|building|install|usage|run|running|compil\w*)\b`),
+		anyLine: regexp.MustCompile(`\bgo (build|run)\b`),
+	},
+	{
+		name:    "algorithm discussion",
+		heading: regexp.MustCompile(`(?i)\b(algorithms?|discussion|design|implementation|analysis)\b`),
+	},
+}
+
+// readmeProblems lists the README's unmet requirements: each required
+// section, at least minWords words of prose, and a fenced code block. total
+// is how many requirements there are.
+func readmeProblems(readme string, minWords int) (problems []string, total int) {
+	var (
+		headings, lines []string
+		words           int
+		fenced, inFence bool
+	)
+	for _, line := range strings.Split(readme, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			// an unclosed fence isn't a code block.
+			if inFence {
+				fenced = true
+			}
+			inFence = !inFence
+			continue
+		}
+		lines = append(lines, trimmed)
+		if inFence {
+			continue
+		}
+		if heading, ok := strings.CutPrefix(trimmed, "#"); ok {
+			headings = append(headings, strings.TrimLeft(heading, "#"))
+			continue
+		}
+		words += len(strings.Fields(trimmed))
+	}
+
+	for _, section := range readmeSections {
+		total++
+		found := false
+		for _, h := range headings {
+			found = found || section.heading.MatchString(h)
+		}
+		for _, line := range lines {
+			found = found || (section.anyLine != nil && section.anyLine.MatchString(line))
+		}
+		if !found {
+			problems = append(problems, "no "+section.name+" section")
+		}
+	}
+	if words < minWords {
+		problems = append(problems, fmt.Sprintf("%d words, want at least %d", words, minWords))
+	}
+	if !fenced {
+		problems = append(problems, "no fenced code block")
+	}
+
+	return problems, total + 2
+}
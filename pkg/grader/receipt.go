@@ -0,0 +1,220 @@
+package grader
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// receiptPublicKey is the default verify --key, the public half of the
+// instructor's receipt key (see keygen --signing), for a gradebot built with
+// -ldflags "-X github.com/jh125486/CSCE4600_gradebot/pkg/grader.receiptPublicKey=...".
+// Only the private key signs, so it stays where grading is trusted (the
+// instructor's runs, and serve): anything built into students' gradebot
+// could be extracted, and receipts forged with it.
+var receiptPublicKey string
+
+// receipt is the signed record of a grade that a student submits, so the
+// instructor can tell it apart from an edited screenshot of the results.
+type receipt struct {
+	Version  int    `json:"v"`
+	Dir      string `json:"dir"` // the submission's directory name
+	Awarded  int    `json:"awarded"`
+	Possible int    `json:"possible"`
+	Time     int64  `json:"time"` // Unix seconds
+	Hash     string `json:"hash"` // contentHash of the sources
+}
+
+const receiptVersion = 1
+
+var receiptEncoding = base64.RawURLEncoding
+
+// signToken returns a token of v and its Ed25519 signature by key:
+// "PAYLOAD.SIGNATURE", both base64url encoded.
+func signToken(key ed25519.PrivateKey, v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return receiptEncoding.EncodeToString(payload) + "." + receiptEncoding.EncodeToString(ed25519.Sign(key, payload)), nil
+}
+
+// openToken verifies token's signature by pub's private key, and returns its
+// payload.
+func openToken(pub ed25519.PublicKey, token string) ([]byte, error) {
+	payloadPart, sigPart, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return nil, errors.New("malformed receipt")
+	}
+	payload, err := receiptEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, errors.New("malformed receipt")
+	}
+	sig, err := receiptEncoding.DecodeString(sigPart)
+	if err != nil {
+		return nil, errors.New("malformed receipt")
+	}
+	if !ed25519.Verify(pub, payload, sig) {
+		return nil, errors.New("invalid receipt signature (forged, altered, or signed with another key)")
+	}
+
+	return payload, nil
+}
+
+// signReceipt returns a token of the receipt (see signToken).
+func signReceipt(key ed25519.PrivateKey, r receipt) (string, error) {
+	return signToken(key, r)
+}
+
+// openReceipt verifies token's signature by pub's private key, and returns
+// its receipt.
+func openReceipt(pub ed25519.PublicKey, token string) (receipt, error) {
+	var r receipt
+	payload, err := openToken(pub, token)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(payload, &r); err != nil {
+		return r, fmt.Errorf("malformed receipt: %w", err)
+	}
+	if r.Version != receiptVersion {
+		return r, fmt.Errorf("unsupported receipt version %d", r.Version)
+	}
+
+	return r, nil
+}
+
+// newReceipt records the graded submission s.
+func newReceipt(s submission) (receipt, error) {
+	r := receipt{Version: receiptVersion, Dir: s.id(), Time: time.Now().Unix()}
+	r.Awarded, r.Possible = s.totals()
+	hash, err := contentHash(s.dir)
+	if err != nil {
+		return r, err
+	}
+	r.Hash = hash
+
+	return r, nil
+}
+
+// loadSigningKey reads the receipt key file at path (see keygen --signing),
+// or is nil without one.
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := keyEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s: not a receipt key (see keygen --signing)", path)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// loadVerifyingKey reads the receipt public key file at path, or else the
+// built-in key.
+func loadVerifyingKey(path string) (ed25519.PublicKey, error) {
+	encoded := receiptPublicKey
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(b)
+	}
+	if encoded == "" {
+		return nil, errors.New("no receipt public key: set --key to the instructor's (see keygen --signing)")
+	}
+	b, err := keyEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("receipt public key: not an Ed25519 public key (see keygen --signing)")
+	}
+
+	return ed25519.PublicKey(b), nil
+}
+
+// signedReceipt signs a receipt of the graded submission s, or is "" without
+// a receipt key.
+func signedReceipt(opts options, s submission) (string, error) {
+	if opts.receiptKey == nil {
+		return "", nil
+	}
+	r, err := newReceipt(s)
+	if err != nil {
+		return "", fmt.Errorf("receipt: %w", err)
+	}
+	token, err := signReceipt(opts.receiptKey, r)
+	if err != nil {
+		return "", fmt.Errorf("receipt: %w", err)
+	}
+
+	return token, nil
+}
+
+// printReceipt prints a signed receipt of the graded submission in dir:
+// after the results for a table, otherwise on stderr so as not to break the
+// format.
+func printReceipt(w io.Writer, opts options, dir, token string) {
+	if token == "" {
+		return
+	}
+	switch opts.format() {
+	case "table", "markdown":
+		fmt.Fprintf(w, "Receipt (check it with gradebot verify): %s\n", token)
+	default:
+		fmt.Fprintf(os.Stderr, "receipt: %s %s\n", dir, token)
+	}
+}
+
+// VerifyConfig configures Verify, as the verify command's flags do.
+type VerifyConfig struct {
+	Token  string
+	Dir    string
+	Binary string
+	Key    string
+}
+
+// Verify checks the signature of the receipt or attestation cfg.Token.
+func Verify(cfg VerifyConfig) error {
+	pub, err := loadVerifyingKey(cfg.Key)
+	if err != nil {
+		return err
+	}
+	payload, err := openToken(pub, cfg.Token)
+	if err != nil {
+		return err
+	}
+	var a attestation
+	if json.Unmarshal(payload, &a) == nil && a.Kind == attestationKind {
+		return verifyAttestation(a, cfg.Binary)
+	}
+	r, err := openReceipt(pub, cfg.Token)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("valid receipt: %s scored %d/%d at %s\n", r.Dir, r.Awarded, r.Possible, time.Unix(r.Time, 0).Format(time.RFC3339))
+	fmt.Printf("sources: %s\n", r.Hash)
+	if cfg.Dir != "" {
+		hash, err := contentHash(cfg.Dir)
+		if err != nil {
+			return err
+		}
+		if hash != r.Hash {
+			return fmt.Errorf("%s doesn't match the graded sources", cfg.Dir)
+		}
+		fmt.Printf("%s matches the graded sources\n", cfg.Dir)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package grader
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testReceiptKey is a receipt key pair, its public half in a file.
+func testReceiptKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "receipts.pub")
+	if err := os.WriteFile(path, []byte(keyEncoding.EncodeToString(pub)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return key, path
+}
+
+func TestReceiptVerify(t *testing.T) {
+	key, pubFile := testReceiptKey(t)
+	other, _ := testReceiptKey(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := submission{dir: dir, source: "roster/alice.zip", results: []Result{{Label: "Compiles", Awarded: 10, Possible: 10}}}
+	token, err := signedReceipt(options{receiptKey: key}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := loadVerifyingKey(pubFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := openReceipt(pub, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Dir != "alice" || r.Awarded != 10 || r.Possible != 10 || r.Hash == "" {
+		t.Errorf("openReceipt() = %+v, want alice's 10/10", r)
+	}
+	if err := Verify(VerifyConfig{Token: token, Dir: dir, Key: pubFile}); err != nil {
+		t.Errorf("verify of the graded sources: %v", err)
+	}
+
+	// the receipt of higher marks, under the original signature.
+	payload, sig, _ := strings.Cut(token, ".")
+	edited := strings.Replace(string(mustDecode(t, payload)), `"awarded":10`, `"awarded":99`, 1)
+	forged, err := signToken(other, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tampered := range map[string]string{
+		"edited payload":    receiptEncoding.EncodeToString([]byte(edited)) + "." + sig,
+		"another key's":     forged,
+		"no signature":      payload,
+		"garbled signature": payload + ".!!",
+	} {
+		if _, err := openReceipt(pub, tampered); err == nil {
+			t.Errorf("%s: openReceipt() verified it", name)
+		}
+	}
+
+	// the sources changed since.
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(VerifyConfig{Token: token, Dir: dir, Key: pubFile}); err == nil {
+		t.Error("verify of edited sources: no error")
+	}
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := receiptEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestSignedReceiptWithoutKey(t *testing.T) {
+	token, err := signedReceipt(options{}, submission{dir: t.TempDir()})
+	if token != "" || err != nil {
+		t.Errorf("signedReceipt() without a key = %q, %v, want none", token, err)
+	}
+}
+
+func TestKeygenSigning(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "receipts")
+	if err := Keygen(KeygenConfig{Name: name, Signing: true}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := loadSigningKey(name + ".key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := loadVerifyingKey(name + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signToken(key, receipt{Version: receiptVersion, Dir: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openReceipt(pub, token); err != nil {
+		t.Errorf("a receipt signed by receipts.key doesn't verify with receipts.pub: %v", err)
+	}
+	if _, err := loadVerifyingKey(""); err == nil {
+		t.Error("loadVerifyingKey() without a key or a built-in one: no error")
+	}
+}
@@ -0,0 +1,140 @@
+package grader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// record is one non-blank output line split into fields, for --structured
+// comparisons.
+type record struct {
+	line   int // 1-based line number in the output
+	fields []string
+	// header names the fields of a table row, and row is its 1-based index
+	// in that table; both are zero outside of tables.
+	header []string
+	row    int
+}
+
+// parseRecords splits output into records on whitespace, commas and "|",
+// skipping blank lines, table borders like "+----+", and table header rows.
+func parseRecords(out []byte) []record {
+	var lines [][]string
+	for _, line := range strings.Split(string(out), "\n") {
+		lines = append(lines, strings.FieldsFunc(line, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ',' || r == '|'
+		}))
+	}
+
+	var (
+		records []record
+		header  []string
+		row     int
+	)
+	for i, fields := range lines {
+		if len(fields) == 0 || isBorder(fields) {
+			continue
+		}
+		// a header is a non-numeric row followed (past any border) by a numeric
+		// row of the same width.
+		if !anyNumeric(fields) {
+			if next := nextRecord(lines, i); len(next) == len(fields) && anyNumeric(next) {
+				header, row = fields, 0
+				continue
+			}
+		}
+		r := record{line: i + 1, fields: fields}
+		if header != nil && len(fields) == len(header) {
+			row++
+			r.header, r.row = header, row
+		} else {
+			header = nil
+		}
+		records = append(records, r)
+	}
+
+	return records
+}
+
+func nextRecord(lines [][]string, i int) []string {
+	for _, fields := range lines[i+1:] {
+		if len(fields) > 0 && !isBorder(fields) {
+			return fields
+		}
+	}
+
+	return nil
+}
+
+func isBorder(fields []string) bool {
+	for _, f := range fields {
+		if strings.Trim(f, "-+=") != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compareRecords compares actual to want record by record, returning a
+// description of the first mismatch ("" when they match), and how many of the
+// expected records match, out of the expected (or, if more, actual) records.
+func compareRecords(actual []byte, want golden) (mismatch string, matched, total int) {
+	act, exp := parseRecords(actual), parseRecords(want.out)
+	for i, e := range exp {
+		if i >= len(act) {
+			if mismatch == "" {
+				mismatch = fmt.Sprintf("got %d records, want %d", len(act), len(exp))
+			}
+			continue
+		}
+		detail := recordMismatch(act[i], e, want)
+		if detail == "" {
+			matched++
+		} else if mismatch == "" {
+			mismatch = detail
+		}
+	}
+	if mismatch == "" && len(act) > len(exp) {
+		mismatch = fmt.Sprintf("got %d records, want %d", len(act), len(exp))
+	}
+
+	return mismatch, matched, max(len(act), len(exp))
+}
+
+// recordMismatch describes how a differs from e, e.g. "row 3, EXIT: got 18, want 16".
+func recordMismatch(a, e record, want golden) string {
+	where := fmt.Sprintf("line %d", e.line)
+	if e.row > 0 {
+		where = fmt.Sprintf("row %d", e.row)
+	}
+	if len(a.fields) != len(e.fields) {
+		return fmt.Sprintf("%s: got %q, want %q", where, strings.Join(a.fields, " "), strings.Join(e.fields, " "))
+	}
+	for j := range e.fields {
+		if a.fields[j] == e.fields[j] {
+			continue
+		}
+		name := strconv.Itoa(j + 1)
+		if e.header != nil {
+			name = e.header[j]
+		}
+		if _, ok := want.fields[name]; ok {
+			if detail := compareField(name, a.fields[j], e.fields[j], want.fields); detail != "" {
+				return where + ", " + detail
+			}
+			continue
+		}
+		if want.epsilon > 0 && !numericMismatch(a.fields[j], e.fields[j], want.epsilon) {
+			continue
+		}
+		if e.header != nil {
+			return fmt.Sprintf("%s, %s: got %s, want %s", where, name, a.fields[j], e.fields[j])
+		}
+		return fmt.Sprintf("%s, field %s: got %s, want %s", where, name, a.fields[j], e.fields[j])
+	}
+
+	return ""
+}
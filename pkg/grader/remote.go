@@ -0,0 +1,109 @@
+package grader
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxBundleBytes bounds a downloaded answer key bundle.
+const maxBundleBytes = 16 << 20
+
+// errTestsUnreachable is a failure to download the tests, after which grading
+// falls back to the embedded testdata; any other fetch error is fatal.
+var errTestsUnreachable = errors.New("tests unreachable")
+
+// fetchAnswerKey downloads an answer key bundle (see answerKey) from url.
+// With a secret, the bundle must be signed: url+".sig" holds the hex
+// HMAC-SHA256 of the bundle, as printed by
+//
+//	openssl dgst -sha256 -hmac SECRET -hex bundle.json
+//
+// A token, if set, is sent as a bearer token, for a private endpoint.
+func fetchAnswerKey(ctx context.Context, url, token string, secret []byte) (answerKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	bundle, err := download(ctx, url, token)
+	if err != nil {
+		return answerKey{}, err
+	}
+	if secret != nil {
+		sig, err := download(ctx, url+".sig", token)
+		if err != nil {
+			return answerKey{}, fmt.Errorf("tests signature: %w", err)
+		}
+		// the last field, so openssl's "HMAC-SHA2-256(bundle.json)= ..." prefix is fine.
+		fields := strings.Fields(string(sig))
+		if len(fields) == 0 {
+			return answerKey{}, errors.New("tests signature is empty")
+		}
+		got, err := hex.DecodeString(fields[len(fields)-1])
+		if err != nil {
+			return answerKey{}, errors.New("tests signature is not hex")
+		}
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write(bundle)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return answerKey{}, errors.New("tests signature does not match (tampered with, or signed with another secret)")
+		}
+	}
+
+	return parseAnswerKey(bundle, url)
+}
+
+// download GETs url, wrapping network errors and 5xx responses in errTestsUnreachable.
+func download(ctx context.Context, url, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errTestsUnreachable, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: %s responded %s", errTestsUnreachable, url, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s responded %s", url, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBundleBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errTestsUnreachable, err)
+	}
+	if len(b) > maxBundleBytes {
+		return nil, fmt.Errorf("%s is larger than %d MiB", url, maxBundleBytes>>20)
+	}
+
+	return b, nil
+}
+
+// remoteAnswerKey fetches --tests-url; when it's unreachable, grading
+// continues with the embedded testdata (ok is false).
+func (o *Config) remoteAnswerKey(ctx context.Context) (key answerKey, ok bool, err error) {
+	var secret []byte
+	if o.TestsSecret != "" {
+		secret = []byte(o.TestsSecret)
+	}
+	key, err = fetchAnswerKey(ctx, o.TestsURL, o.TestsToken, secret)
+	if errors.Is(err, errTestsUnreachable) {
+		// logging is quieted for --format=total, so this goes to stderr directly.
+		fmt.Fprintf(os.Stderr, "warning: using the embedded testdata: %v\n", err)
+		return key, false, nil
+	}
+
+	return key, err == nil, err
+}
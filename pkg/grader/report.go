@@ -0,0 +1,451 @@
+package grader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+func printRubricResults(w io.Writer, opts options, dir string, results ...Result) {
+	totalPoints, possiblePoints := resultTotals(results)
+
+	switch opts.format() {
+	case "total":
+		if opts.NormalizeTo > 0 {
+			fmt.Fprintf(w, "%.2f\n", normalize(totalPoints, possiblePoints, opts.NormalizeTo))
+			return
+		}
+		fmt.Fprintln(w, totalPoints)
+	case "tap":
+		printTAP(w, opts, dir, results, totalPoints, possiblePoints)
+		if opts.manifest != nil {
+			fmt.Fprintf(w, "# environment: %s\n", opts.manifest)
+		}
+		if opts.attestation != "" {
+			fmt.Fprintf(w, "# attestation: %s\n", opts.attestation)
+		}
+	case "github":
+		printGitHub(w, opts, dir, results, totalPoints, possiblePoints)
+	case "json":
+		if opts.Summary {
+			printSummaryJSON(w, opts, dir, results, totalPoints, possiblePoints)
+			return
+		}
+		report := jsonReport{
+			Dir:         dir,
+			Results:     results,
+			Total:       totalPoints,
+			Possible:    possiblePoints,
+			Environment: opts.manifest,
+			Attestation: opts.attestation,
+		}
+		if raw, penalized := rawTotal(results); penalized {
+			report.RawTotal = &raw
+		}
+		if opts.NormalizeTo > 0 {
+			n := normalize(totalPoints, possiblePoints, opts.NormalizeTo)
+			report.Normalized = &n
+		}
+		if opts.Verbose {
+			report.Diffs = make(map[string]string)
+			for _, r := range results {
+				if r.Diff != "" {
+					report.Diffs[r.Label] = r.Diff
+				}
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	default:
+		if opts.Summary {
+			printSummary(w, opts, results, totalPoints, possiblePoints)
+			if opts.attestation != "" {
+				fmt.Fprintf(w, "Attestation (gradebot's, see verify): %s\n", opts.attestation)
+			}
+			return
+		}
+		t := table.NewWriter()
+		t.AppendHeader(table.Row{"Rubric Item", "Error?", "Possible", "Awarded", "Time", "CPU", "Peak RSS"})
+		t.SetStyle(table.StyleRounded)
+		t.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 2, AlignFooter: text.AlignRight},
+			// extra credit's "+N" is a string among the numbers.
+			{Number: 3, Align: text.AlignRight, AlignFooter: text.AlignRight},
+			{Number: 4, Align: text.AlignRight, AlignFooter: text.AlignRight},
+		})
+		for i := range results {
+			t.AppendRow([]any{results[i].Label, results[i].reportMessage(), results[i].possibleCell(), results[i].awardedCell(),
+				results[i].Duration.Round(time.Millisecond), results[i].Usage.cpu(), results[i].Usage.rss()})
+		}
+		if raw, penalized := rawTotal(results); penalized {
+			t.AppendFooter(table.Row{"", "Before late penalty", possiblePoints, raw})
+		}
+		t.AppendFooter(table.Row{"", "Total", possiblePoints, totalPoints})
+		if opts.NormalizeTo > 0 {
+			t.AppendFooter(table.Row{"", "Normalized", opts.NormalizeTo,
+				fmt.Sprintf("%.2f", normalize(totalPoints, possiblePoints, opts.NormalizeTo))})
+		}
+		fmt.Fprintln(w, opts.render(t))
+		printErrors(w, results)
+		if opts.Verbose {
+			printDetails(w, opts, results)
+		}
+		if opts.manifest != nil {
+			fmt.Fprintf(w, "Environment: %s\n", opts.manifest)
+		}
+		if opts.attestation != "" {
+			fmt.Fprintf(w, "Attestation (gradebot's, see verify): %s\n", opts.attestation)
+		}
+	}
+}
+
+// printErrors summarizes the checks' errors, after the results table.
+func printErrors(w io.Writer, results []Result) {
+	var lines []string
+	for _, r := range results {
+		if r.Error == "" {
+			continue
+		}
+		first, rest, _ := strings.Cut(r.Error, "\n")
+		lines = append(lines, "  "+r.Label+": "+first)
+		for _, line := range strings.Split(rest, "\n") {
+			if line != "" {
+				lines = append(lines, "    "+line)
+			}
+		}
+	}
+	if len(lines) > 0 {
+		fmt.Fprintf(w, "Errors:\n%s\n", strings.Join(lines, "\n"))
+	}
+}
+
+// rubricTotals are the points the options' rubric is out of, and those its
+// extra credit awards above them.
+func rubricTotals(opts Options) (possible, extra int) {
+	for _, item := range selectItems(rubricItems(opts), opts.Only, opts.Skip) {
+		if slices.Contains(extraCreditLabels, item.label) {
+			extra += opts.possible(item.label)
+		} else {
+			possible += opts.possible(item.label)
+		}
+	}
+
+	return possible, extra
+}
+
+// printRubric prints the rubric items that would be graded, and their points.
+func printRubric(w io.Writer, opts options, gradeOpts Options) {
+	type item struct {
+		ID          string `json:"id"`
+		Label       string `json:"label"`
+		Possible    int    `json:"possible"`
+		ExtraCredit bool   `json:"extra_credit,omitempty"`
+	}
+	var items []item
+	for _, ri := range selectItems(rubricItems(gradeOpts), gradeOpts.Only, gradeOpts.Skip) {
+		extra := slices.Contains(extraCreditLabels, ri.label)
+		items = append(items, item{ID: ri.id, Label: ri.label, Possible: gradeOpts.possible(ri.label), ExtraCredit: extra})
+	}
+	possible, _ := rubricTotals(gradeOpts)
+
+	switch opts.format() {
+	case "total":
+		fmt.Fprintln(w, possible)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Rubric   []item `json:"rubric"`
+			Possible int    `json:"possible"`
+		}{items, possible}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	default:
+		t := table.NewWriter()
+		t.AppendHeader(table.Row{"ID", "Rubric Item", "Possible"})
+		t.SetStyle(table.StyleRounded)
+		t.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 2, AlignFooter: text.AlignRight},
+			{Number: 3, Align: text.AlignRight, AlignFooter: text.AlignRight},
+		})
+		for _, it := range items {
+			t.AppendRow(table.Row{it.ID, it.Label, Result{Possible: it.Possible, ExtraCredit: it.ExtraCredit}.possibleCell()})
+		}
+		t.AppendFooter(table.Row{"", "Total", possible})
+		fmt.Fprintln(w, opts.render(t))
+	}
+}
+
+// printTAP prints the results as a TAP (Test Anything Protocol) stream, one
+// test per rubric item. Only full marks are "ok", skipped checks are SKIP
+// directives, and a message's extra lines become diagnostics: none with
+// --summary, and its stderr, diff and timings too with --verbose.
+func printTAP(w io.Writer, opts options, dir string, results []Result, total, possible int) {
+	fmt.Fprintf(w, "1..%d\n", len(results))
+	for i, r := range results {
+		if r.Awarded == r.Possible {
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, r.Label)
+			continue
+		}
+		if r.Skipped {
+			fmt.Fprintf(w, "not ok %d - %s # SKIP %s\n", i+1, r.Label, strings.TrimPrefix(r.Message, "skipped: "))
+			continue
+		}
+		first, rest, _ := strings.Cut(r.reportMessage(), "\n")
+		diag := fmt.Sprintf("%d/%d", r.Awarded, r.Possible)
+		if first != "" && !opts.Summary {
+			diag += ": " + first
+		}
+		// extra credit not earned isn't a failure, as it's not in the total.
+		if r.ExtraCredit {
+			fmt.Fprintf(w, "ok %d - %s # SKIP extra credit %s\n", i+1, r.Label, diag)
+		} else {
+			fmt.Fprintf(w, "not ok %d - %s # %s\n", i+1, r.Label, diag)
+		}
+		if opts.Summary {
+			continue
+		}
+		for _, line := range strings.Split(rest, "\n") {
+			if line != "" {
+				fmt.Fprintln(w, "#   "+line)
+			}
+		}
+		if opts.Verbose {
+			for _, line := range r.detailLines() {
+				fmt.Fprintln(w, "#   "+line)
+			}
+		}
+	}
+	fmt.Fprintf(w, "# %s: total %d/%d\n", dir, total, possible)
+}
+
+// possibleCell is the result's possible points, as the table shows them: "+N"
+// for extra credit, as they're not in the total.
+func (r Result) possibleCell() any {
+	if r.ExtraCredit {
+		return fmt.Sprintf("+%d", r.Possible)
+	}
+
+	return r.Possible
+}
+
+// awardedCell is the result's awarded points, as the table shows them:
+// "skipped" for a check that wasn't graded.
+func (r Result) awardedCell() any {
+	if r.Skipped {
+		return "skipped"
+	}
+
+	return r.Awarded
+}
+
+// reportMessage is the result's message, followed by its hints and warnings,
+// if any, a line each.
+func (r Result) reportMessage() string {
+	msg := r.Message
+	for _, note := range []struct{ prefix, lines string }{{"hint: ", r.Hint}, {"warning: ", r.Warning}} {
+		if note.lines != "" {
+			msg = strings.TrimPrefix(msg+"\n"+note.prefix+strings.ReplaceAll(note.lines, "\n", "\n"+note.prefix), "\n")
+		}
+	}
+
+	return msg
+}
+
+// render renders t for the selected format: as a markdown table, for pasting
+// into a pull request comment, or with box-drawing characters.
+func (o *options) render(t table.Writer) string {
+	if o.format() == "markdown" {
+		// a blank line ends the table before whatever markdown follows.
+		return t.RenderMarkdown() + "\n"
+	}
+
+	return t.Render()
+}
+
+// jsonReport is the --format=json rendering of one graded submission.
+type jsonReport struct {
+	Dir        string   `json:"dir"`
+	Results    []Result `json:"results"`
+	Total      int      `json:"total"`
+	Possible   int      `json:"possible"`
+	Normalized *float64 `json:"normalized,omitempty"`
+	// RawTotal is the total before the late penalty, when there was one.
+	RawTotal *int `json:"raw_total,omitempty"`
+	// Error is set when the submission couldn't be graded at all.
+	Error string `json:"error,omitempty"`
+	// Environment is the environment it was graded in.
+	Environment *manifest `json:"environment,omitempty"`
+	// Attestation is the signed attestation of the gradebot that graded.
+	Attestation string `json:"attestation,omitempty"`
+	// Receipt is serve's signed receipt of the grade, with a receipt key.
+	Receipt string `json:"receipt,omitempty"`
+	// Diffs are the checks' output mismatches, by label, with --verbose.
+	Diffs map[string]string `json:"diffs,omitempty"`
+}
+
+// status is the result's word in a --summary: PASS for full marks, SKIP for a
+// check that wasn't graded, or extra credit not earned (as printTAP has it,
+// it's not in the total), or FAIL.
+func (r Result) status() string {
+	switch {
+	case r.Skipped:
+		return "SKIP"
+	case r.Awarded == r.Possible:
+		return "PASS"
+	case r.ExtraCredit:
+		return "SKIP"
+	}
+
+	return "FAIL"
+}
+
+// points is the result's awarded of possible points, e.g. 15/20, or 0/+5 for
+// extra credit.
+func (r Result) points() string {
+	return fmt.Sprintf("%d/%v", r.Awarded, r.possibleCell())
+}
+
+// printSummary prints the --summary of the results, a line per check and the
+// total; in markdown, as a table.
+func printSummary(w io.Writer, opts options, results []Result, total, possible int) {
+	totalCell := fmt.Sprintf("%d/%d", total, possible)
+	if opts.NormalizeTo > 0 {
+		totalCell += fmt.Sprintf(" (%.2f/%d)", normalize(total, possible, opts.NormalizeTo), opts.NormalizeTo)
+	}
+	if opts.format() == "markdown" {
+		t := table.NewWriter()
+		t.AppendHeader(table.Row{"Status", "Rubric Item", "Points"})
+		t.SetColumnConfigs([]table.ColumnConfig{{Number: 3, Align: text.AlignRight}})
+		for _, r := range results {
+			t.AppendRow(table.Row{r.status(), r.Label, r.points()})
+		}
+		t.AppendFooter(table.Row{"", "Total", totalCell})
+		fmt.Fprintln(w, opts.render(t))
+		return
+	}
+
+	width := len("Total")
+	for _, r := range results {
+		width = max(width, len(r.Label))
+	}
+	for _, r := range results {
+		fmt.Fprintf(w, "%-4s  %-*s  %s\n", r.status(), width, r.Label, r.points())
+	}
+	fmt.Fprintf(w, "%-4s  %-*s  %s\n", "", width, "Total", totalCell)
+}
+
+type (
+	// summaryReport is the --format=json --summary rendering of one graded
+	// submission.
+	summaryReport struct {
+		Dir         string         `json:"dir"`
+		Checks      []summaryCheck `json:"checks"`
+		Total       int            `json:"total"`
+		Possible    int            `json:"possible"`
+		Normalized  *float64       `json:"normalized,omitempty"`
+		Attestation string         `json:"attestation,omitempty"`
+	}
+	summaryCheck struct {
+		Label       string `json:"label"`
+		Status      string `json:"status"`
+		Awarded     int    `json:"awarded"`
+		Possible    int    `json:"possible"`
+		ExtraCredit bool   `json:"extra_credit,omitempty"`
+	}
+)
+
+func printSummaryJSON(w io.Writer, opts options, dir string, results []Result, total, possible int) {
+	report := summaryReport{Dir: dir, Total: total, Possible: possible, Attestation: opts.attestation}
+	for _, r := range results {
+		report.Checks = append(report.Checks, summaryCheck{
+			Label: r.Label, Status: r.status(), Awarded: r.Awarded, Possible: r.Possible, ExtraCredit: r.ExtraCredit,
+		})
+	}
+	if opts.NormalizeTo > 0 {
+		n := normalize(total, possible, opts.NormalizeTo)
+		report.Normalized = &n
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// detailLines are what --verbose adds of the result: its run timings, stderr
+// and mismatch diffs.
+func (r Result) detailLines() []string {
+	var lines []string
+	if r.Usage != nil {
+		lines = append(lines, "timing: "+r.Usage.String())
+	}
+	for _, part := range []struct{ name, text string }{{"stderr", r.Stderr}, {"diff", r.Diff}} {
+		if strings.TrimSpace(part.text) == "" {
+			continue
+		}
+		lines = append(lines, part.name+":")
+		for _, line := range strings.Split(strings.TrimRight(part.text, "\n"), "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	return lines
+}
+
+// printDetails prints the --verbose details of the results having any, after
+// the results table: in markdown, a section each, with the stderr and diffs
+// fenced.
+func printDetails(w io.Writer, opts options, results []Result) {
+	if opts.format() != "markdown" {
+		header := false
+		for _, r := range results {
+			lines := r.detailLines()
+			if len(lines) == 0 {
+				continue
+			}
+			if !header {
+				fmt.Fprintln(w, "Details:")
+				header = true
+			}
+			fmt.Fprintf(w, "  %s:\n    %s\n", r.Label, strings.Join(lines, "\n    "))
+		}
+		return
+	}
+
+	for _, r := range results {
+		if r.Usage == nil && strings.TrimSpace(r.Stderr) == "" && r.Diff == "" {
+			continue
+		}
+		fmt.Fprintf(w, "#### %s\n\n", r.Label)
+		if r.Usage != nil {
+			fmt.Fprintf(w, "Timing: %s\n\n", r.Usage)
+		}
+		for _, part := range []struct{ name, lang, text string }{{"Stderr", "text", r.Stderr}, {"Diff", "diff", r.Diff}} {
+			if strings.TrimSpace(part.text) != "" {
+				fmt.Fprintf(w, "%s:\n\n```%s\n%s\n```\n\n", part.name, part.lang, strings.TrimRight(part.text, "\n"))
+			}
+		}
+	}
+}
+
+// normalize scales awarded/possible to a total of n points, rounded half away
+// from zero to two decimal places.
+func normalize(awarded, possible, n int) float64 {
+	if possible == 0 {
+		return 0
+	}
+
+	return math.Round(float64(awarded)*float64(n)/float64(possible)*100) / 100
+}
@@ -0,0 +1,262 @@
+package grader
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintTAP(t *testing.T) {
+	results := []Result{
+		{Label: "Compiles", Awarded: 10, Possible: 10},
+		{Label: "FCFS", Awarded: 5, Possible: 20, Message: "output does not match\nsee the diff", Hint: "check the arrival order"},
+		{Label: "SJF", Possible: 20, Skipped: true, Message: "skipped: Compiles failed"},
+		{Label: "Priority", Possible: 5, ExtraCredit: true, Message: "not implemented"},
+		{Label: "MLFQ", Awarded: 5, Possible: 5, ExtraCredit: true},
+	}
+	tests := []struct {
+		name string
+		opts options
+		want string
+	}{
+		{
+			name: "default",
+			want: `1..5
+ok 1 - Compiles
+not ok 2 - FCFS # 5/20: output does not match
+#   see the diff
+#   hint: check the arrival order
+not ok 3 - SJF # SKIP Compiles failed
+ok 4 - Priority # SKIP extra credit 0/5: not implemented
+ok 5 - MLFQ
+# sub: total 20/50
+`,
+		},
+		{
+			name: "summary",
+			opts: options{Config: Config{Summary: true}},
+			want: `1..5
+ok 1 - Compiles
+not ok 2 - FCFS # 5/20
+not ok 3 - SJF # SKIP Compiles failed
+ok 4 - Priority # SKIP extra credit 0/5
+ok 5 - MLFQ
+# sub: total 20/50
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			printTAP(&sb, tt.opts, "sub", results, 20, 50)
+			if got := sb.String(); got != tt.want {
+				t.Errorf("printTAP() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintRubricResults(t *testing.T) {
+	results := []Result{
+		{Label: "Compiles", Awarded: 10, Possible: 10},
+		{Label: "FCFS", Awarded: 5, Possible: 20, Message: "diverged", Error: "partial credit", Diff: "-a\n+b", Hint: "arrival order"},
+		{Label: "SJF", Possible: 20, Skipped: true, Message: "skipped: Compiles failed"},
+		{Label: "MLFQ", Possible: 5, ExtraCredit: true, Message: "not implemented"},
+	}
+	tests := []struct {
+		name string
+		opts options
+		want string
+	}{
+		{
+			name: "table",
+			opts: options{Config: Config{Format: "table"}},
+			want: `╭─────────────┬──────────────────────────┬──────────┬─────────┬──────┬─────┬──────────╮
+│ RUBRIC ITEM │ ERROR?                   │ POSSIBLE │ AWARDED │ TIME │ CPU │ PEAK RSS │
+├─────────────┼──────────────────────────┼──────────┼─────────┼──────┼─────┼──────────┤
+│ Compiles    │                          │       10 │      10 │   0s │     │          │
+│ FCFS        │ diverged                 │       20 │       5 │   0s │     │          │
+│             │ hint: arrival order      │          │         │      │     │          │
+│ SJF         │ skipped: Compiles failed │       20 │ skipped │   0s │     │          │
+│ MLFQ        │ not implemented          │       +5 │       0 │   0s │     │          │
+├─────────────┼──────────────────────────┼──────────┼─────────┼──────┼─────┼──────────┤
+│             │                    TOTAL │       50 │      15 │      │     │          │
+╰─────────────┴──────────────────────────┴──────────┴─────────┴──────┴─────┴──────────╯
+Errors:
+  FCFS: partial credit
+`,
+		},
+		{
+			name: "table normalized",
+			opts: options{Config: Config{Format: "table", NormalizeTo: 10}},
+			want: `╭─────────────┬──────────────────────────┬──────────┬─────────┬──────┬─────┬──────────╮
+│ RUBRIC ITEM │ ERROR?                   │ POSSIBLE │ AWARDED │ TIME │ CPU │ PEAK RSS │
+├─────────────┼──────────────────────────┼──────────┼─────────┼──────┼─────┼──────────┤
+│ Compiles    │                          │       10 │      10 │   0s │     │          │
+│ FCFS        │ diverged                 │       20 │       5 │   0s │     │          │
+│             │ hint: arrival order      │          │         │      │     │          │
+│ SJF         │ skipped: Compiles failed │       20 │ skipped │   0s │     │          │
+│ MLFQ        │ not implemented          │       +5 │       0 │   0s │     │          │
+├─────────────┼──────────────────────────┼──────────┼─────────┼──────┼─────┼──────────┤
+│             │                    TOTAL │       50 │      15 │      │     │          │
+│             │               NORMALIZED │       10 │    3.00 │      │     │          │
+╰─────────────┴──────────────────────────┴──────────┴─────────┴──────┴─────┴──────────╯
+Errors:
+  FCFS: partial credit
+`,
+		},
+		{
+			name: "markdown",
+			opts: options{Config: Config{Format: "markdown", Verbose: true}},
+			want: "| Rubric Item | Error? | Possible | Awarded | Time | CPU | Peak RSS |\n" +
+				"| --- | --- | ---:| ---:| ---:| --- | --- |\n" +
+				"| Compiles |  | 10 | 10 | 0s |  |  |\n" +
+				"| FCFS | diverged<br/>hint: arrival order | 20 | 5 | 0s |  |  |\n" +
+				"| SJF | skipped: Compiles failed | 20 | skipped | 0s |  |  |\n" +
+				"| MLFQ | not implemented | +5 | 0 | 0s |  |  |\n" +
+				"|  | Total | 50 | 15 |  |  |  |\n" +
+				"\n" +
+				"Errors:\n  FCFS: partial credit\n" +
+				"#### FCFS\n\nDiff:\n\n```diff\n-a\n+b\n```\n\n",
+		},
+		{
+			name: "summary",
+			opts: options{Config: Config{Format: "table", Summary: true, NormalizeTo: 100}},
+			want: `PASS  Compiles  10/10
+FAIL  FCFS      5/20
+SKIP  SJF       0/20
+SKIP  MLFQ      0/+5
+      Total     15/50 (30.00/100)
+`,
+		},
+		{
+			name: "markdown summary",
+			opts: options{Config: Config{Format: "markdown", Summary: true}},
+			want: `| Status | Rubric Item | Points |
+| --- | --- | ---:|
+| PASS | Compiles | 10/10 |
+| FAIL | FCFS | 5/20 |
+| SKIP | SJF | 0/20 |
+| SKIP | MLFQ | 0/+5 |
+|  | Total | 15/50 |
+
+`,
+		},
+		{name: "total", opts: options{Config: Config{Format: "total"}}, want: "15\n"},
+		{name: "total flag", opts: options{Config: Config{Format: "table", Total: true}}, want: "15\n"},
+		{name: "total normalized", opts: options{Config: Config{Format: "total", NormalizeTo: 10}}, want: "3.00\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			printRubricResults(&sb, tt.opts, "sub", results...)
+			if got := sb.String(); got != tt.want {
+				t.Errorf("printRubricResults() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintRubricResultsJSON(t *testing.T) {
+	results := []Result{
+		{Label: "Compiles", Awarded: 10, Possible: 10},
+		{Label: "FCFS", Awarded: 5, Possible: 20, Message: "diverged", Diff: "-a\n+b"},
+		{Label: "MLFQ", Possible: 5, ExtraCredit: true},
+		{Label: "Late penalty", Awarded: -3},
+	}
+	var sb strings.Builder
+	printRubricResults(&sb, options{Config: Config{Format: "json", Verbose: true, NormalizeTo: 10}}, "sub", results...)
+	var report jsonReport
+	if err := json.Unmarshal([]byte(sb.String()), &report); err != nil {
+		t.Fatalf("%v in\n%s", err, sb.String())
+	}
+	if report.Dir != "sub" || report.Total != 12 || report.Possible != 30 || len(report.Results) != len(results) {
+		t.Errorf("report = %+v, want sub's 4 results, 12/30", report)
+	}
+	if report.RawTotal == nil || *report.RawTotal != 15 {
+		t.Errorf("raw_total = %v, want 15, before the late penalty", report.RawTotal)
+	}
+	if report.Normalized == nil || *report.Normalized != 4 {
+		t.Errorf("normalized = %v, want 4", report.Normalized)
+	}
+	if want := map[string]string{"FCFS": "-a\n+b"}; !reflect.DeepEqual(report.Diffs, want) {
+		t.Errorf("diffs = %v, want %v", report.Diffs, want)
+	}
+
+	sb.Reset()
+	printRubricResults(&sb, options{Config: Config{Format: "json", Summary: true}}, "sub", results[:3]...)
+	var summary summaryReport
+	if err := json.Unmarshal([]byte(sb.String()), &summary); err != nil {
+		t.Fatalf("%v in\n%s", err, sb.String())
+	}
+	want := summaryReport{Dir: "sub", Total: 15, Possible: 30, Checks: []summaryCheck{
+		{Label: "Compiles", Status: "PASS", Awarded: 10, Possible: 10},
+		{Label: "FCFS", Status: "FAIL", Awarded: 5, Possible: 20},
+		{Label: "MLFQ", Status: "SKIP", Possible: 5, ExtraCredit: true},
+	}}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		awarded, possible, n int
+		want                 float64
+	}{
+		{15, 50, 100, 30},
+		{2, 3, 100, 66.67},
+		{105, 100, 100, 105},
+		{5, 0, 100, 0},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.awarded, tt.possible, tt.n); got != tt.want {
+			t.Errorf("normalize(%d, %d, %d) = %g, want %g", tt.awarded, tt.possible, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRubricTotals(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            Options
+		possible, extra int
+	}{
+		{name: "project1", possible: 100, extra: 10},
+		{name: "project2", opts: Options{Project: "project2"}, possible: 100},
+		// fcfs needs compile.
+		{name: "only", opts: Options{Only: []string{"fcfs", "mlfq"}}, possible: 30, extra: 5},
+		{name: "points", opts: Options{Points: map[string]int{labelRR: 15, labelMLFQ: 10}}, possible: 105, extra: 15},
+		{name: "optional", opts: Options{Style: true}, possible: 100 + defaultPoints[labelStyle], extra: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			possible, extra := rubricTotals(tt.opts)
+			if possible != tt.possible || extra != tt.extra {
+				t.Errorf("rubricTotals() = %d, %d, want %d, %d", possible, extra, tt.possible, tt.extra)
+			}
+		})
+	}
+
+	if possible, extra := ProjectTotals("project1"); possible != 100 || extra != 10 {
+		t.Errorf("ProjectTotals(project1) = %d, %d, want 100, 10", possible, extra)
+	}
+}
+
+func TestReportMessage(t *testing.T) {
+	tests := []struct {
+		r    Result
+		want string
+	}{
+		{Result{Message: "diverged"}, "diverged"},
+		{Result{Message: "diverged", Hint: "arrival order\nties"}, "diverged\nhint: arrival order\nhint: ties"},
+		{Result{Warning: "output is identical with empty stdin"}, "warning: output is identical with empty stdin"},
+		{Result{Message: "diverged", Hint: "ties", Warning: "slow"}, "diverged\nhint: ties\nwarning: slow"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.reportMessage(); got != tt.want {
+			t.Errorf("reportMessage(%+v) = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
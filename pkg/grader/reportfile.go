@@ -0,0 +1,78 @@
+package grader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reportFileFormats are the --output formats implied by file extensions;
+// other extensions get the --format one.
+var reportFileFormats = map[string]string{
+	".txt":      "table",
+	".md":       "markdown",
+	".markdown": "markdown",
+	".json":     "json",
+	".tap":      "tap",
+}
+
+// reports is where a grade's reports are rendered: stdout, in the selected
+// format, and with --output a file as well, in the format its extension
+// implies.
+type reports struct {
+	stdout   io.Writer
+	opts     options
+	file     *os.File
+	fileOpts options
+}
+
+// openReports creates the --output file at path, if any.
+func openReports(stdout io.Writer, opts options, path string) (*reports, error) {
+	r := &reports{stdout: stdout, opts: opts}
+	if path == "" {
+		return r, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r.file, r.fileOpts = f, opts
+	if format, ok := reportFileFormats[strings.ToLower(filepath.Ext(path))]; ok {
+		r.fileOpts.Format, r.fileOpts.Total = format, false
+	}
+
+	return r, nil
+}
+
+// each renders a report with fn to each destination, in its format.
+func (r *reports) each(fn func(w io.Writer, opts options)) {
+	fn(r.stdout, r.opts)
+	if r.file != nil {
+		fn(r.file, r.fileOpts)
+	}
+}
+
+// anyFormat reports whether any destination is in the format.
+func (r *reports) anyFormat(format string) bool {
+	return r.opts.format() == format || (r.file != nil && r.fileOpts.format() == format)
+}
+
+// rewind empties the file, for a report replacing the last one.
+func (r *reports) rewind() error {
+	if r.file == nil {
+		return nil
+	}
+	_, err := r.file.Seek(0, io.SeekStart)
+
+	return errors.Join(err, r.file.Truncate(0))
+}
+
+func (r *reports) Close() error {
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Close()
+}
@@ -0,0 +1,144 @@
+package grader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// resultCacheDir holds each submission's last results, for --rerun-failed.
+func resultCacheDir() string {
+	return filepath.Join(os.TempDir(), "gradebot-results")
+}
+
+// resultCache is a submission's last results, by rubric item id, each with
+// the key of the inputs it was graded with.
+type resultCache map[string]cachedResult
+
+type cachedResult struct {
+	Key    string `json:"key"`
+	Result Result `json:"result"`
+}
+
+// cacheFile is where dir's results are cached.
+func cacheFile(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+
+	return filepath.Join(resultCacheDir(), hex.EncodeToString(sum[:8])+".json")
+}
+
+// loadResultCache reads dir's cached results; none is an empty cache.
+func loadResultCache(dir string) resultCache {
+	cache := make(resultCache)
+	if b, err := os.ReadFile(cacheFile(dir)); err == nil {
+		_ = json.Unmarshal(b, &cache)
+	}
+
+	return cache
+}
+
+// save writes the cache for dir.
+func (rc resultCache) save(dir string) error {
+	b, err := json.Marshal(rc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(resultCacheDir(), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(cacheFile(dir), b, 0o644)
+}
+
+// resultKeys returns each item's cache key, of everything its result could
+// depend on: every file of the submission, gradebot's version and rubric,
+// the grading options and the item itself.
+func resultKeys(dir string, opts Options, items []rubricItem) (map[string]string, error) {
+	h := sha256.New()
+	if err := hashTree(h, dir); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", gradebotVersion(), rubricRevision(), optionsFingerprint(opts))
+	tree := h.Sum(nil)
+	keys := make(map[string]string, len(items))
+	for _, item := range items {
+		sum := sha256.Sum256(append(append([]byte{}, tree...), item.id+"\x00"+item.label...))
+		keys[item.id] = hex.EncodeToString(sum[:])
+	}
+
+	return keys, nil
+}
+
+// optionsFingerprint formats the options that decide results, less those
+// that only decide how they're shown or run.
+func optionsFingerprint(o Options) string {
+	o.OnResult, o.OnProgress, o.Out, o.LogLevel, o.LogJSON, o.Debug, o.KeepDiffs = nil, nil, nil, nil, false, false, false
+	o.Only, o.Skip, o.FailFast, o.Parallel, o.NoCache, o.RerunFailed = nil, nil, false, 0, false, false
+	forbidden := ""
+	if o.Forbidden != nil {
+		forbidden = fmt.Sprintf("%+v", *o.Forbidden)
+	}
+	o.Forbidden = nil
+
+	return fmt.Sprintf("%+v\x00%s", o, forbidden)
+}
+
+// hashTree writes every regular file under dir but .git's, and its path, to h.
+func hashTree(h io.Writer, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, _ = io.WriteString(h, filepath.ToSlash(rel)+"\x00")
+		_, err = io.Copy(h, f)
+		_, _ = h.Write([]byte{0})
+
+		return err
+	})
+}
+
+// cachedPasses are the items whose cached results passed with the same key,
+// by index, to be reused rather than re-run. The items a re-run check needs
+// are re-run too, for their setup, e.g. the compile check for the binary (its
+// build is cached anyway).
+func cachedPasses(cache resultCache, keys map[string]string, items []rubricItem) map[int]Result {
+	reused := make(map[int]Result)
+	needed := make(map[string]bool)
+	for i, item := range items {
+		c, ok := cache[item.id]
+		if ok && c.Key == keys[item.id] && c.Result.Error == "" && c.Result.Awarded == c.Result.Possible {
+			reused[i] = c.Result
+			continue
+		}
+		for _, id := range item.needs {
+			needed[id] = true
+		}
+	}
+	for i, item := range items {
+		if needed[item.id] {
+			delete(reused, i)
+		}
+	}
+
+	return reused
+}
@@ -0,0 +1,74 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// malformedInput is a bad scheduler input that should be rejected.
+type malformedInput struct {
+	name string
+	in   string
+}
+
+const csvHeader = "ProcessID,Burst Duration,Arrival Time,Priority\n"
+
+var malformedInputs = []malformedInput{
+	{name: "empty input", in: ""},
+	{name: "missing columns", in: csvHeader + "A1,5,0\nA2,3,1\n"},
+	{name: "non-numeric burst", in: csvHeader + "A1,five,0,1\nA2,3,1,2\n"},
+	{name: "trailing garbage", in: csvHeader + "A1,5,0,1\nA2,3,1,2\n#$%garbage\n"},
+}
+
+// CheckRobustness feeds the scheduler malformed inputs, awarding proportional
+// credit for each it rejects gracefully: a non-zero exit with a message, and
+// no panic.
+func CheckRobustness(result Result) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		var (
+			passed  int
+			reports []string
+		)
+		for _, mi := range malformedInputs {
+			run := execScheduler(c, []byte(mi.in), []string{"-fcfs"})
+			if c.ctx.Err() != nil {
+				return result, c.ctx.Err()
+			}
+			var problem string
+			switch {
+			case run.timedOut:
+				problem = fmt.Sprintf("timed out after %s", c.opts.Timeout)
+			case run.outputExceeded:
+				problem = "output exceeded limit"
+			case strings.Contains(run.stderr, "panic:") || strings.Contains(run.stderr, "goroutine "):
+				problem = "panicked"
+			case run.err == nil:
+				problem = "exited successfully"
+			case run.state != nil && !run.state.Exited():
+				problem = fmt.Sprintf("crashed (%v)", run.err)
+			case strings.TrimSpace(run.stderr) == "" && len(strings.TrimSpace(string(run.stdout))) == 0:
+				problem = "exited without an error message"
+			}
+			if problem != "" {
+				reports = append(reports, fmt.Sprintf("%s: %s", mi.name, problem))
+				continue
+			}
+			passed++
+		}
+
+		result.Awarded = int(math.Round(float64(result.Possible) * float64(passed) / float64(len(malformedInputs))))
+		if passed < len(malformedInputs) {
+			result.Message = strings.Join(reports, "\n")
+			return result, errors.New("malformed input not rejected gracefully")
+		}
+
+		return result, nil
+	}
+}
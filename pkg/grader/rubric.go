@@ -0,0 +1,285 @@
+package grader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rubricConfig is an instructor-supplied rubric customization, in YAML (or
+// JSON, which YAML accepts):
+//
+//	points:
+//	  Round-robin scheduling: 15
+//	hints:
+//	  Round-robin scheduling: Re-queue the running process before new arrivals.
+//	tolerances:
+//	  Average wait: {precision: 2}
+//	cases:
+//	  - {name: rr_q3, algorithm: rr, args: [-rr, -q, "3"], input: rr.csv, expected: rr_q3.out}
+//	forbidden:
+//	  imports: [os/exec, net/...]
+//	total: 105
+//
+// Points, hints, and tolerances are keyed by rubric item label (points and
+// hints) or golden output field (tolerances, see goldenMetaFS). Cases replace
+// the embedded testdata of their algorithms, as with --cases; their files are
+// relative to the config, and args default to the algorithm's flag. A hint is
+// shown when its item fails and the hint engine has none. Total, if set, is
+// the expected sum of all rubric points, of Project's rubric (project1 by
+// default). Forbidden replaces the default deny
+// list of --forbidden (see denyList). Files in a checks directory next to the
+// config are more rubric items, in Starlark (see scriptCheck).
+type rubricConfig struct {
+	Points     map[string]int       `yaml:"points"`
+	Hints      map[string]string    `yaml:"hints"`
+	Tolerances map[string]fieldSpec `yaml:"tolerances"`
+	Cases      []rubricCase         `yaml:"cases"`
+	Forbidden  *denyList            `yaml:"forbidden"`
+	Total      int                  `yaml:"total"`
+	Project    string               `yaml:"project"`
+
+	dir    string // the config's directory, which case files are relative to
+	checks []scriptCheck
+}
+
+type rubricCase struct {
+	Name      string   `yaml:"name"`
+	Algorithm string   `yaml:"algorithm"`
+	Args      []string `yaml:"args"`
+	Input     string   `yaml:"input"`
+	Expected  string   `yaml:"expected"`
+}
+
+// defaultPoints are the possible points of each rubric item, by label.
+var defaultPoints = map[string]int{
+	labelModule:      5,
+	labelCompilable:  10,
+	labelScreenshot:  10,
+	labelREADME:      10,
+	labelFCFS:        20,
+	labelSJF:         20,
+	labelSJFP:        20,
+	labelRR:          10,
+	labelPriority:    5,
+	labelMLFQ:        5,
+	labelStyle:       10,
+	labelRandom:      10,
+	labelDeterminism: 5,
+	labelStress:      5,
+	labelRobustness:  10,
+	labelFuzz:        10,
+	labelRace:        10,
+	labelForbidden:   10,
+	labelHygiene:     5,
+	labelHistory:     5,
+	labelTests:       10,
+	labelCoverage:    10,
+
+	labelShellBuiltins: 20,
+	labelShellEnv:      20,
+	labelShellPipes:    20,
+	labelShellRedirect: 20,
+	labelShellExit:     10,
+}
+
+// optionalLabels are the rubric items graded only when enabled by a flag.
+var optionalLabels = []string{labelModule, labelStyle, labelHygiene, labelHistory, labelRandom, labelDeterminism, labelStress, labelRobustness, labelFuzz, labelRace, labelForbidden, labelTests, labelCoverage}
+
+// extraCreditLabels are the rubric items awarding points above the total.
+var extraCreditLabels = []string{labelPriority, labelMLFQ}
+
+// goldenFields are the fields of the scheduler output that tolerances may reference.
+var goldenFields = []string{
+	"Average wait", "Average turnaround", "Throughput",
+	"ID", "PRIORITY", "BURST", "ARRIVAL", "WAIT", "TURNAROUND", "EXIT",
+}
+
+func loadRubricConfig(path string) (rubricConfig, error) {
+	var cfg rubricConfig
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	cfg.dir = filepath.Dir(path)
+	if cfg.checks, err = loadScriptChecks(cfg.dir); err != nil {
+		return cfg, fmt.Errorf("loading script checks: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// validate reports every problem with the config, not just the first.
+func (cfg rubricConfig) validate() error {
+	var errs []error
+	for _, label := range sortedKeys(cfg.Points) {
+		if !slices.Contains(rubricLabels(), label) {
+			errs = append(errs, fmt.Errorf("points: unknown rubric item %q%s", label, suggestLabel(label)))
+		}
+		if cfg.Points[label] < 0 {
+			errs = append(errs, fmt.Errorf("points: %q has negative points %d", label, cfg.Points[label]))
+		}
+	}
+	for _, label := range sortedKeys(cfg.Hints) {
+		if !slices.Contains(rubricLabels(), label) {
+			errs = append(errs, fmt.Errorf("hints: unknown rubric item %q%s", label, suggestLabel(label)))
+		}
+	}
+	for _, field := range sortedKeys(cfg.Tolerances) {
+		if !slices.Contains(goldenFields, field) {
+			errs = append(errs, fmt.Errorf("tolerances: unknown output field %q (known: %v)", field, goldenFields))
+		}
+		spec := cfg.Tolerances[field]
+		if spec.Tolerance < 0 {
+			errs = append(errs, fmt.Errorf("tolerances: %q has negative tolerance %g", field, spec.Tolerance))
+		}
+		if spec.Precision != nil && *spec.Precision < 0 {
+			errs = append(errs, fmt.Errorf("tolerances: %q has negative precision %d", field, *spec.Precision))
+		}
+	}
+	names := make(map[string]bool)
+	for i, rc := range cfg.Cases {
+		if rc.Name == "" {
+			errs = append(errs, fmt.Errorf("cases[%d]: missing name", i))
+		} else if names[rc.Name] {
+			errs = append(errs, fmt.Errorf("cases[%d]: duplicate name %q", i, rc.Name))
+		}
+		names[rc.Name] = true
+		if !slices.Contains(caseAlgorithms, rc.Algorithm) {
+			errs = append(errs, fmt.Errorf("cases[%d]: algorithm must be one of %s", i, strings.Join(caseAlgorithms, ", ")))
+		}
+		for _, f := range []struct{ key, path string }{{"input", rc.Input}, {"expected", rc.Expected}} {
+			if f.path == "" {
+				errs = append(errs, fmt.Errorf("cases[%d]: missing %s", i, f.key))
+			} else if _, err := os.Stat(filepath.Join(cfg.dir, f.path)); err != nil {
+				errs = append(errs, fmt.Errorf("cases[%d]: %s: %w", i, f.key, err))
+			}
+		}
+	}
+
+	if cfg.Forbidden != nil {
+		errs = append(errs, cfg.Forbidden.validate())
+	}
+	errs = append(errs, validateScriptChecks(cfg.checks))
+	if _, ok := projects[cfg.project()]; !ok {
+		errs = append(errs, fmt.Errorf("project: unknown project %q (known: %s)", cfg.Project, strings.Join(sortedKeys(projects), ", ")))
+	}
+
+	sum := 0
+	for _, label := range projectLabels(cfg.project()) {
+		if pts, ok := cfg.Points[label]; ok && !slices.Contains(extraCreditLabels, label) {
+			sum += pts
+		} else if !slices.Contains(optionalLabels, label) && !slices.Contains(extraCreditLabels, label) { // counted only when configured
+			sum += defaultPoints[label]
+		}
+	}
+	for _, sc := range cfg.checks {
+		sum += sc.Points
+	}
+	switch {
+	case sum <= 0:
+		errs = append(errs, errors.New("points: rubric awards no points"))
+	case cfg.Total < 0:
+		errs = append(errs, fmt.Errorf("total: negative total %d", cfg.Total))
+	case cfg.Total > 0 && sum != cfg.Total:
+		errs = append(errs, fmt.Errorf("points: rubric items sum to %d, but total is %d", sum, cfg.Total))
+	}
+
+	return errors.Join(errs...)
+}
+
+// project is the project whose rubric the config is for.
+func (cfg rubricConfig) project() string {
+	if cfg.Project == "" {
+		return defaultProject
+	}
+
+	return cfg.Project
+}
+
+// cases reads the config's cases, grouped by algorithm, as for --cases.
+func (cfg rubricConfig) cases() (map[string][]schedulerCase, error) {
+	cases := make(map[string][]schedulerCase)
+	for _, rc := range cfg.Cases {
+		in, err := os.ReadFile(filepath.Join(cfg.dir, rc.Input))
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", rc.Name, err)
+		}
+		out, err := os.ReadFile(filepath.Join(cfg.dir, rc.Expected))
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", rc.Name, err)
+		}
+		args := rc.Args
+		if len(args) == 0 {
+			args = []string{"-" + rc.Algorithm}
+		}
+		cases[rc.Algorithm] = append(cases[rc.Algorithm], schedulerCase{name: rc.Name, args: args, in: in, out: out})
+	}
+
+	return cases, nil
+}
+
+// suggestLabel hints at the intended label for a typo'd one.
+func suggestLabel(label string) string {
+	if known, ok := checkIDs()[strings.ToLower(strings.TrimLeft(label, "-"))]; ok {
+		return fmt.Sprintf(" (did you mean %q?)", known)
+	}
+	for _, known := range rubricLabels() {
+		if strings.Contains(strings.ToLower(known), strings.ToLower(label)) {
+			return fmt.Sprintf(" (did you mean %q?)", known)
+		}
+	}
+
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ValidateRubric checks the rubric config at path for mistakes, printing
+// them, without grading.
+func ValidateRubric(path string) error {
+	cfg, err := loadRubricConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := cfg.validate(); err != nil {
+		fmt.Printf("%s has problems:\n", path)
+		for _, e := range splitErrors(err) {
+			fmt.Println("  - " + e.Error())
+		}
+		return errors.New("invalid rubric config")
+	}
+	fmt.Printf("%s is valid\n", path)
+
+	return nil
+}
+
+// splitErrors unwraps an errors.Join error into its parts.
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+
+	return []error{err}
+}
@@ -0,0 +1,405 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Run grades the submissions cfg selects of the named project, or of project
+// 1 for a name that isn't a project's, reporting them as cfg says. A failed
+// --min-score or --fail-on-error gate is an error too (see ExitCode).
+func Run(ctx context.Context, project string, cfg GradeConfig) (err error) {
+	logs, err := cfg.setup()
+	if err != nil {
+		return err
+	}
+
+	if cfg.ShowEnv {
+		printEnv(cfg.MinGoVersion, cfg.GoToolchain)
+		return nil
+	}
+	// verify the grader's Go toolchain up front, rather than failing builds later.
+	if cfg.MinGoVersion != "" && cfg.needsGo() {
+		tc, err := detectGoToolchain()
+		if err != nil {
+			return fmt.Errorf("checking for go %s or newer, to build the submissions: %w", cfg.MinGoVersion, err)
+		}
+		if err := checkMinGoVersion(tc, cfg.MinGoVersion); err != nil {
+			return err
+		}
+	}
+
+	var deadline time.Time
+	if cfg.Before != "" {
+		var err error
+		if deadline, err = parseDeadline(cfg.Before); err != nil {
+			return err
+		}
+	}
+	if (cfg.Ref != "" || cfg.Before != "") && len(cfg.Repo) == 0 && cfg.Repos == "" {
+		return errors.New("--ref and --before require --repo or --repos")
+	}
+	if err := cfg.CanvasConfig.validate(); err != nil {
+		return err
+	}
+	if err := cfg.SheetsConfig.validate(); err != nil {
+		return err
+	}
+	if err := cfg.FeedbackConfig.validate(); err != nil {
+		return err
+	}
+	if cfg.Similarity < 0 || cfg.Similarity > 100 {
+		return fmt.Errorf("--similarity %g is not a percentage", cfg.Similarity)
+	}
+	if u, err := url.Parse(cfg.NotifyURL); cfg.NotifyURL != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		return fmt.Errorf("invalid --notify-url %q", cfg.NotifyURL)
+	}
+	if err := validateCheckIDs("--only", cfg.Only); err != nil {
+		return err
+	}
+	if err := validateCheckIDs("--skip", cfg.Skip); err != nil {
+		return err
+	}
+
+	gradeOpts, err := cfg.gradeOptions(ctx)
+	if err != nil {
+		return err
+	}
+	gradeOpts.Only, gradeOpts.Skip, gradeOpts.FailFast = cfg.Only, cfg.Skip, cfg.FailFast
+	if projects[project].items != nil {
+		gradeOpts.Project = project
+	}
+	if err := validateOnlyEnabled(cfg.Only, gradeOpts); err != nil {
+		return err
+	}
+	gradeOpts.KeepDiffs = cfg.Feedback || cfg.Report != "" || cfg.Verbose
+
+	o := options{Config: cfg.Config}
+	if o.receiptKey, err = loadSigningKey(cfg.ReceiptKey); err != nil {
+		return err
+	}
+	if o.attestation, err = signedAttestation(o.receiptKey); err != nil {
+		return err
+	}
+	out, err := openReports(os.Stdout, o, cfg.Output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	w := io.Writer(os.Stdout)
+	gradeOpts.Out = w
+	if cfg.List {
+		out.each(func(w io.Writer, opts options) { printRubric(w, opts, gradeOpts) })
+		return nil
+	}
+
+	dirs := cfg.PathToDirs
+	if cfg.Roster != "" {
+		var err error
+		if dirs, err = rosterDirs(cfg.Roster); err != nil {
+			return err
+		}
+	}
+	repos := cfg.Repo
+	if cfg.Repos != "" {
+		list, err := readRepoList(cfg.Repos)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, list...)
+	}
+	if len(repos) > 0 {
+		// sampled (below) before cloning, so unsampled repositories aren't cloned.
+		dirs = repos
+	}
+	if cfg.Gradescope {
+		if cwd, err := filepath.Abs("."); err == nil && len(dirs) == 1 && dirs[0] == cwd {
+			// --dir wasn't given (or was ".").
+			dirs = []string{gradescopeSubmissionDir}
+		}
+		if len(dirs) != 1 || cfg.Sample != "" {
+			return errors.New("--gradescope grades a single submission")
+		}
+	}
+	if cfg.Handle != "" && len(dirs) != 1 {
+		return errors.New("--handle is of a single submission")
+	}
+	if cfg.Badge != "" && (len(dirs) != 1 || cfg.Sample != "") {
+		return errors.New("--badge grades a single submission")
+	}
+	total := len(dirs)
+	if cfg.Sample != "" {
+		seed := cfg.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		var err error
+		if dirs, err = sampleDirs(dirs, cfg.Sample, seed); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "sampled %d of %d submissions (seed %d):\n", len(dirs), total, seed)
+		for _, dir := range dirs {
+			fmt.Fprintln(w, "  "+dir)
+		}
+	}
+
+	if len(repos) > 0 {
+		cloned, cleanup, err := cloneRepos(ctx, dirs, cfg.Ref, deadline)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		dirs = cloned
+	}
+	// a mistyped --dir would otherwise grade as an empty submission.
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+	}
+	// archives are graded extracted, but reported as given.
+	sources := dirs
+	dirs, cleanup, err := extractArchives(dirs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	batch := total > 1
+	if cfg.TUI {
+		if batch || cfg.Gradescope {
+			return errors.New("--tui grades a single submission")
+		}
+		return runTUI(ctx, dirs[0], gradeOpts)
+	}
+	if cfg.Watch {
+		if batch || cfg.Gradescope || len(repos) > 0 || dirs[0] != cfg.PathToDirs[0] {
+			return errors.New("--watch grades a single submission directory")
+		}
+		if cfg.WatchInterval <= 0 {
+			return errors.New("--watch-interval must be positive")
+		}
+		return watchAndGrade(ctx, out, logs, cfg, dirs[0], gradeOpts)
+	}
+	graded := make([]submission, 0, len(dirs))
+	for i, dir := range dirs {
+		if ctx.Err() != nil {
+			return errors.New("interrupted")
+		}
+		sub := submission{dir: dir}
+		if sources[i] != dir {
+			sub.source = sources[i]
+		}
+		out.each(func(w io.Writer, opts options) {
+			switch {
+			case batch && opts.format() == "table":
+				fmt.Fprintln(w, sub.name())
+			case batch && opts.format() == "markdown":
+				fmt.Fprintf(w, "### %s\n\n", sub.name())
+			}
+		})
+		results := Grade(ctx, dir, gradeOpts)
+		env := captureManifest(dir, gradeOpts)
+		printLogs(logs, results, gradeOpts.LogJSON)
+		if ctx.Err() != nil {
+			// partial results of an interrupted submission aren't a grade.
+			return errors.New("interrupted")
+		}
+		if cfg.Feedback {
+			addFeedback(ctx, cfg.FeedbackConfig, dir, results)
+		}
+		sub.results, sub.env = results, &env
+		receipt, err := signedReceipt(o, sub)
+		if err != nil {
+			return err
+		}
+		out.each(func(w io.Writer, opts options) {
+			// in a batch, totals are only printed in the summary.
+			if !batch || opts.format() != "total" {
+				opts.manifest = &env
+				printRubricResults(w, opts, sub.name(), results...)
+			}
+			printReceipt(w, opts, sub.name(), receipt)
+		})
+		graded = append(graded, sub)
+		if cfg.NotifyURL != "" {
+			notify(ctx, cfg.NotifyURL, cfg.NotifyFormat, graded[len(graded)-1])
+		}
+	}
+	if batch {
+		var (
+			dupes   []dupePair
+			similar []similarPair
+		)
+		if cfg.DetectDupes {
+			dupes = findDuplicates(graded)
+		}
+		if cfg.Similarity > 0 {
+			similar = findSimilar(graded, cfg.SimilarityBase, cfg.Similarity)
+		}
+		out.each(func(w io.Writer, opts options) {
+			printBatchSummary(w, opts, graded)
+			printBatchStats(w, opts, graded)
+			if cfg.DetectDupes {
+				printDuplicates(w, opts, dupes)
+			}
+			if cfg.Similarity > 0 {
+				printSimilar(w, opts, similar)
+			}
+		})
+	}
+
+	if out.anyFormat("github") {
+		if err := writeGitHubOutputs(o, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.Gradescope {
+		if err := writeGradescope(gradescopeResultsFile, o, graded[0]); err != nil {
+			return err
+		}
+	}
+	if cfg.Gradebook != "" {
+		if err := writeGradebook(cfg.Gradebook, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.CanvasConfig.enabled() {
+		if err := publishToCanvas(ctx, cfg.CanvasConfig, o, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.SheetsConfig.enabled() {
+		if err := publishToSheet(ctx, cfg.SheetsConfig, o, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.JUnit != "" {
+		if err := writeJUnit(cfg.JUnit, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.Leaderboard != "" {
+		if err := postLeaderboard(ctx, cfg.Leaderboard, cfg.Handle, cfg.HandlesFile, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.Record != "" {
+		if err := recordAttempts(cfg.Record, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.Report != "" {
+		if err := writeHTMLReport(ctx, cfg.Report, graded); err != nil {
+			return err
+		}
+	}
+	if cfg.Badge != "" {
+		if err := writeBadge(cfg.Badge, o, graded[0]); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(cfg.checkMinScore(graded), cfg.checkErrors(graded))
+}
+
+// printLogs writes each check's logs, grouped under its label in rubric order,
+// or as they are when they're JSON records, which are labeled.
+func printLogs(w io.Writer, results []Result, jsonLines bool) {
+	for _, r := range results {
+		if len(r.Logs) == 0 {
+			continue
+		}
+		if jsonLines {
+			fmt.Fprintln(w, strings.Join(r.Logs, "\n"))
+			continue
+		}
+		fmt.Fprintf(w, "[%s]\n", r.Label)
+		for _, line := range r.Logs {
+			fmt.Fprintln(w, "  "+line)
+		}
+	}
+}
+
+// checkMinScore fails the run when any submission scores below --min-score,
+// so CI pipelines can gate on the exit code.
+func (cfg GradeConfig) checkMinScore(graded []submission) error {
+	if cfg.MinScore <= 0 {
+		return nil
+	}
+	var failing []string
+	for _, s := range graded {
+		awarded, possible := s.totals()
+		score := float64(awarded)
+		if cfg.NormalizeTo > 0 {
+			score = normalize(awarded, possible, cfg.NormalizeTo)
+		}
+		if score < cfg.MinScore {
+			failing = append(failing, fmt.Sprintf("%s scored %g", s.name(), score))
+		}
+	}
+	if len(failing) > 0 {
+		return exitError{code: exitGate, err: fmt.Errorf("below minimum score %g: %s", cfg.MinScore, strings.Join(failing, ", "))}
+	}
+
+	return nil
+}
+
+// checkErrors fails the run, with --fail-on-error, when any check of any
+// submission reported an error.
+func (cfg GradeConfig) checkErrors(graded []submission) error {
+	if !cfg.FailOnError {
+		return nil
+	}
+	var failing []string
+	for _, s := range graded {
+		for _, r := range s.results {
+			if r.Error != "" {
+				failing = append(failing, fmt.Sprintf("%s: %s", s.name(), r.Label))
+			}
+		}
+	}
+	if len(failing) > 0 {
+		return exitError{code: exitGate, err: fmt.Errorf("checks reported errors: %s", strings.Join(failing, ", "))}
+	}
+
+	return nil
+}
+
+// exitGate is the exit status of a grade failing --min-score or
+// --fail-on-error; any other error exits with 1.
+const exitGate = 2
+
+// exitError is an error exiting with its own status.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string { return e.err.Error() }
+func (e exitError) Unwrap() error { return e.err }
+
+// ExitCode is the exit status of a command failing with err: 0 for none,
+// 2 for a failed gate, and 1 for any other error.
+func ExitCode(err error) int {
+	var exit exitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exit):
+		return exit.code
+	}
+
+	return 1
+}
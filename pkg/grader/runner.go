@@ -0,0 +1,67 @@
+package grader
+
+import (
+	"context"
+)
+
+// Runner grades submissions, for course tooling reusing gradebot's checks
+// without its command line.
+type Runner struct {
+	Options Options
+}
+
+// Item is a rubric item a Runner grades.
+type Item struct {
+	ID       string
+	Label    string
+	Possible int
+}
+
+// Report is a graded submission.
+type Report struct {
+	Dir      string
+	Results  []Result
+	Total    int
+	Possible int
+}
+
+// NewRunner is a Runner grading with cfg's checks, e.g. DefaultConfig's
+// with Stress set, selected by checks. Config fields that aren't about
+// grading a submission, like Format, are ignored.
+func NewRunner(ctx context.Context, cfg Config, checks Selection) (*Runner, error) {
+	if err := validateCheckIDs("--only", checks.Only); err != nil {
+		return nil, err
+	}
+	if err := validateCheckIDs("--skip", checks.Skip); err != nil {
+		return nil, err
+	}
+	opts, err := cfg.gradeOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.Only, opts.Skip, opts.FailFast = checks.Only, checks.Skip, checks.FailFast
+	if err := validateOnlyEnabled(checks.Only, opts); err != nil {
+		return nil, err
+	}
+
+	return &Runner{Options: opts}, nil
+}
+
+// Items are the rubric items the runner grades, in order.
+func (r *Runner) Items() []Item {
+	var items []Item
+	for _, ri := range selectItems(rubricItems(r.Options), r.Options.Only, r.Options.Skip) {
+		items = append(items, Item{ID: ri.id, Label: ri.label, Possible: r.Options.possible(ri.label)})
+	}
+
+	return items
+}
+
+// Grade grades the submission in dir.
+func (r *Runner) Grade(ctx context.Context, dir string) Report {
+	s := submission{dir: dir, results: Grade(ctx, dir, r.Options)}
+	report := Report{Dir: dir, Results: s.results}
+	report.Total, report.Possible = s.totals()
+
+	return report
+}
@@ -0,0 +1,138 @@
+package grader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// sandboxDocker builds and runs the scheduler in a container instead of on
+// the host (--sandbox=docker).
+const sandboxDocker = "docker"
+
+// default container limits, where --mem-limit and --proc-limit aren't set.
+const (
+	sandboxMemLimit  = 512 << 20
+	sandboxProcLimit = 64
+)
+
+// containerSeq numbers containers, so each run's can be named and killed.
+var containerSeq atomic.Int64
+
+// dockerArgs are the "docker run" arguments common to building and running:
+// no network, a read-only root filesystem with a scratch /tmp, no
+// capabilities, and the submission mounted read-only at /src.
+func dockerArgs(c *Context) []string {
+	args := []string{"run", "--rm", "-i",
+		"--network", "none",
+		"--read-only", "--tmpfs", "/tmp:exec",
+		"--cap-drop", "ALL", "--security-opt", "no-new-privileges",
+		"-e", "HOME=/tmp",
+		"-v", c.srcDir + ":/src:ro", "-w", "/src",
+	}
+	// files written to mounts belong to the grader, not the container's root.
+	if runtime.GOOS != "windows" {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+
+	return args
+}
+
+// checkSandboxed builds the submission's main package pkg in a container, and sets
+// it up to run in one too, limited by --mem-limit, --proc-limit and --cpu-limit.
+func checkSandboxed(c *Context, result Result, pkg string) (Result, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		result.Message = "docker executable not found in path"
+		return result, err
+	}
+	out, err := c.buildDir()
+	if err != nil {
+		result.Message = "could not create a build directory"
+		return result, err
+	}
+	cmd, err := sandboxGo(c.ctx, c, []string{"-v", out + ":/out"}, "build", "-o", "/out/scheduler", pkg)
+	if err != nil {
+		result.Message = "could not set up the sandbox"
+		return result, err
+	}
+	stderr := &tailBuffer{limit: maxStderrBytes}
+	cmd.Stdout = stderr
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		result.Message = "scheduler is not compileable"
+		if tail := tailLines(stderr.String(), stderrTailLines); len(tail) > 0 {
+			result.Message += ":\n" + strings.Join(tail, "\n")
+		}
+		return result, err
+	}
+	if err := checkExecutable(filepath.Join(out, "scheduler")); err != nil {
+		result.Message = "no main package / executable produced"
+		return result, err
+	}
+
+	mem, procs := c.opts.MemLimit, c.opts.ProcLimit
+	if mem == 0 {
+		mem = sandboxMemLimit
+	}
+	if procs == 0 {
+		procs = sandboxProcLimit
+	}
+	run := append([]string{"docker"}, dockerArgs(c)...)
+	run = append(run,
+		"-v", out+":/sandbox:ro",
+		"--memory", strconv.FormatUint(mem, 10), "--memory-swap", strconv.FormatUint(mem, 10),
+		"--pids-limit", strconv.FormatUint(procs, 10),
+		"--cpus", "1")
+	if c.opts.CPULimit > 0 {
+		secs := strconv.FormatUint(uint64(math.Ceil(c.opts.CPULimit.Seconds())), 10)
+		run = append(run, "--ulimit", "cpu="+secs+":"+secs)
+	}
+	c.run = append(run, c.opts.SandboxImage, "/sandbox/scheduler")
+
+	result.Awarded = result.Possible
+	result.Message = checkFlags(c)
+	c.log.Debug("scheduler is compileable (sandboxed)", slog.String("image", c.opts.SandboxImage), slog.Int("pts", result.Possible))
+
+	return result, nil
+}
+
+// sandboxGo returns a command running go with args in a container of the
+// submission, after the extra "docker run" arguments (e.g. mounts).
+func sandboxGo(ctx context.Context, c *Context, extra []string, args ...string) (*exec.Cmd, error) {
+	// a host build cache, so the standard library isn't recompiled every build.
+	goCache := filepath.Join(binaryCacheDir(), "docker-go-build")
+	if err := os.MkdirAll(goCache, 0o755); err != nil {
+		return nil, err
+	}
+	run := append(dockerArgs(c), extra...)
+	run = append(run,
+		"-v", goCache+":/cache", "-e", "GOCACHE=/cache",
+		// there's no network, so fail fast on missing modules and toolchains.
+		"-e", "GOPROXY=off", "-e", "GOTOOLCHAIN=local", "-e", "GOFLAGS=-buildvcs=false",
+		c.opts.SandboxImage, "go")
+	cmd := exec.CommandContext(ctx, "docker", append(run, args...)...)
+	sandboxCommand(cmd)
+
+	return cmd, nil
+}
+
+// sandboxCommand names cmd's container, so cancelling cmd kills the
+// container: killing the docker client alone would leave it running.
+func sandboxCommand(cmd *exec.Cmd) {
+	name := fmt.Sprintf("gradebot-%d-%d", os.Getpid(), containerSeq.Add(1))
+	// after "docker run".
+	cmd.Args = slices.Insert(cmd.Args, 2, "--name", name)
+	cmd.Cancel = func() error {
+		_ = exec.Command("docker", "kill", name).Run()
+		return cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,93 @@
+package grader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDocker puts a docker on PATH that logs its arguments, a line per run,
+// and "builds" an empty scheduler into the directory mounted at /out.
+func fakeDocker(t *testing.T) (logFile string) {
+	t.Helper()
+	bin := t.TempDir()
+	logFile = filepath.Join(bin, "docker.log")
+	script := `#!/bin/sh
+echo "$*" >> '` + logFile + `'
+prev=
+for a in "$@"; do
+	if [ "$prev" = -v ]; then
+		case "$a" in *:/out) printf '#!/bin/sh\n' > "${a%:/out}/scheduler" && chmod +x "${a%:/out}/scheduler" ;; esac
+	fi
+	prev=$a
+done
+`
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return logFile
+}
+
+func sandboxContext(t *testing.T, opts Options) *Context {
+	t.Helper()
+	opts.Sandbox, opts.SandboxImage = sandboxDocker, "golang:1.21"
+	opts.Timeout, opts.MaxOutput = time.Minute, 1<<20
+	c := &Context{
+		ctx:    context.Background(),
+		log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		opts:   opts,
+		srcDir: t.TempDir(),
+		usage:  &runUsage{},
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(c.work) })
+
+	return c
+}
+
+func TestCheckSandboxed(t *testing.T) {
+	logFile := fakeDocker(t)
+	c := sandboxContext(t, Options{MemLimit: 256 << 20, CPULimit: 1500 * time.Millisecond})
+	result, err := checkSandboxed(c, Result{Label: labelCompilable, Possible: 10}, ".")
+	if err != nil || result.Awarded != 10 {
+		t.Fatalf("checkSandboxed() = %+v, %v, want it built", result, err)
+	}
+
+	b, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build, _, _ := strings.Cut(string(b), "\n")
+	for _, want := range []string{
+		"--network none", "--read-only", "--cap-drop ALL", "--security-opt no-new-privileges",
+		"-v " + c.srcDir + ":/src:ro", "-e GOPROXY=off", "golang:1.21 go build -o /out/scheduler .",
+	} {
+		if !strings.Contains(build, want) {
+			t.Errorf("build ran docker %s, without %q", build, want)
+		}
+	}
+	run := strings.Join(c.run, " ")
+	for _, want := range []string{
+		"--network none", "--read-only", "--memory 268435456", "--memory-swap 268435456",
+		"--pids-limit 64", "--ulimit cpu=2:2", "golang:1.21 /sandbox/scheduler",
+	} {
+		if !strings.Contains(run, want) {
+			t.Errorf("scheduler runs as %s, without %q", run, want)
+		}
+	}
+}
+
+func TestCheckSandboxedWithoutDocker(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	c := sandboxContext(t, Options{})
+	result, err := checkSandboxed(c, Result{Label: labelCompilable, Possible: 10}, ".")
+	if err == nil || result.Awarded != 0 || result.Message != "docker executable not found in path" {
+		t.Errorf("checkSandboxed() = %+v, %v, want docker not found", result, err)
+	}
+}
@@ -0,0 +1,369 @@
+package grader
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// embedded testdata.
+var (
+	//go:embed testdata/fcfs.csv
+	fcfsIn []byte
+	//go:embed testdata/fcfs.out
+	fcfsOut []byte
+
+	//go:embed testdata/sjf.csv
+	sjfIn []byte
+	//go:embed testdata/sjf.out
+	sjfOut []byte
+
+	//go:embed testdata/sjfp.csv
+	sjfpIn []byte
+	//go:embed testdata/sjfp.out
+	sjfpOut []byte
+
+	//go:embed testdata/rr.csv
+	rrIn []byte
+	//go:embed testdata/rr.out
+	rrOut []byte
+	//go:embed testdata/rr_q1.out
+	rrQ1Out []byte
+	//go:embed testdata/rr_q2.out
+	rrQ2Out []byte
+	//go:embed testdata/rr_q10.out
+	rrQ10Out []byte
+)
+
+func CheckScheduler(result Result, flag string, in, out []byte) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		fields, err := c.fieldSpecs(strings.TrimPrefix(flag, "-"))
+		if err != nil {
+			result.Message = "invalid expected output metadata"
+			return result, err
+		}
+		credit, msg, err := runScheduler(c, in, golden{out: out, fields: fields}, flag)
+		if msg != "" {
+			result.Awarded = int(math.Round(credit * float64(result.Possible)))
+			result.Message = msg
+			return result, err
+		}
+
+		result.Awarded = result.Possible
+		c.log.Debug(fmt.Sprintf("%v Scheduler output matches expected", flag), slog.Int("pts", result.Possible))
+		c.probeStdin(golden{out: out, fields: fields}, []string{flag})
+
+		return result, nil
+	}
+}
+
+// fieldSpecs loads the named algorithm's golden metadata, applying any rubric tolerances.
+func (c *Context) fieldSpecs(name string) (map[string]fieldSpec, error) {
+	fields, err := loadFieldSpecs(name, c.opts.Testdata[name+".meta.json"])
+	if err != nil || len(c.opts.Tolerances) == 0 {
+		return fields, err
+	}
+	merged := make(map[string]fieldSpec, len(fields)+len(c.opts.Tolerances))
+	for field, spec := range fields {
+		merged[field] = spec
+	}
+	for field, spec := range c.opts.Tolerances {
+		merged[field] = spec
+	}
+
+	return merged, nil
+}
+
+// quantumFlag is how a time quantum is passed to the scheduler, e.g. "-rr -q 2".
+const quantumFlag = "-q"
+
+type quantumCase struct {
+	quantum int
+	out     []byte
+}
+
+// CheckRoundRobin grades round-robin across several time quanta, splitting
+// the points between them (see splitPoints) and awarding each quantum's share
+// in proportion to its credit.
+func CheckRoundRobin(result Result, in []byte, cases ...quantumCase) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		fields, err := c.fieldSpecs("rr")
+		if err != nil {
+			result.Message = "invalid expected output metadata"
+			return result, err
+		}
+
+		var (
+			passed  int
+			awarded float64
+			reports []string
+			errs    []error
+		)
+		shares := splitPoints(result.Possible, len(cases))
+		for i, qc := range cases {
+			partial, msg, err := runScheduler(c, in, golden{out: qc.out, fields: fields}, "-rr", quantumFlag, strconv.Itoa(qc.quantum))
+			awarded += partial * float64(shares[i])
+			if msg != "" {
+				reports = append(reports, fmt.Sprintf("q=%d (%d/%d): %s", qc.quantum,
+					int(math.Round(partial*float64(shares[i]))), shares[i], msg))
+				if err != nil {
+					errs = append(errs, fmt.Errorf("q=%d: %w", qc.quantum, err))
+				}
+				continue
+			}
+			passed++
+			reports = append(reports, fmt.Sprintf("q=%d: pass", qc.quantum))
+			c.log.Debug("-rr Scheduler output matches expected", slog.Int("quantum", qc.quantum))
+			if passed == 1 {
+				// once, of the first quantum passing, rather than of each.
+				c.probeStdin(golden{out: qc.out, fields: fields}, []string{"-rr", quantumFlag, strconv.Itoa(qc.quantum)})
+			}
+		}
+
+		result.Awarded = int(math.Round(awarded))
+		if passed < len(cases) {
+			result.Message = strings.Join(reports, "\n")
+		}
+
+		return result, errors.Join(errs...)
+	}
+}
+
+// splitPoints divides points between n cases as evenly as whole points allow,
+// the earlier cases taking the remainder, e.g. 10 between 4 is 3, 3, 2, 2.
+func splitPoints(points, n int) []int {
+	shares := make([]int, n)
+	for i := range shares {
+		shares[i] = points / n
+		if i < points%n {
+			shares[i]++
+		}
+	}
+
+	return shares
+}
+
+// runScheduler runs the scheduler binary with args, feeding in on stdin, and
+// compares its output to want. It returns the fraction of credit earned, and a
+// non-empty message when the run failed. Failed runs are retried up to
+// opts.Retries times, keeping the best attempt.
+func runScheduler(c *Context, in []byte, want golden, args ...string) (float64, string, error) {
+	credit, msg, err := runSchedulerOnce(c, in, want, args...)
+	for attempt := 1; attempt <= c.opts.Retries && msg != "" && c.ctx.Err() == nil; attempt++ {
+		c.log.Debug("retrying scheduler", slog.String("args", strings.Join(args, " ")),
+			slog.Int("attempt", attempt), slog.Float64("credit", credit), slog.String("result", msg))
+		retryCredit, retryMsg, retryErr := runSchedulerOnce(c, in, want, args...)
+		if retryMsg == "" || retryCredit > credit {
+			credit, msg, err = retryCredit, retryMsg, retryErr
+		}
+	}
+
+	return credit, msg, err
+}
+
+// probeStdin warns when a passing run's output is just the same without any
+// input, as from a scheduler reading its own copy of the input file. Checks
+// probe once, as it's another run of the scheduler.
+func (c *Context) probeStdin(want golden, args []string) {
+	if ignoresStdin(c, want, args) {
+		c.warnings = append(c.warnings, "output is identical with empty stdin (not reading stdin?)")
+	}
+}
+
+// runSchedulerOnce is a single run of runScheduler; each run gets a fresh stdin reader.
+func runSchedulerOnce(c *Context, in []byte, want golden, args ...string) (float64, string, error) {
+	var stream *outputStream
+	if streamable(c) {
+		stream = newOutputStream(c, want)
+		// without partial credit, there is no use reading past a mismatch.
+		stream.stopOnMismatch = !c.opts.Partial
+		stream.progress = func(line int) { c.reportProgress(args, line, stream.total()) }
+	}
+	run := execSchedulerStream(c, in, args, stream)
+	if stream != nil {
+		c.reportProgress(args, stream.line, stream.total())
+	}
+	if tail := tailLines(run.stderr, reportStderrLines); len(tail) > 0 {
+		c.stderr = append(c.stderr, "$ scheduler "+strings.Join(args, " ")+"\n"+strings.Join(tail, "\n"))
+	}
+	if run.outputExceeded {
+		return 0, fmt.Sprintf("output exceeded limit (%d MiB, see --max-output)", c.opts.MaxOutput>>20), errors.New("output exceeded limit")
+	}
+	if run.stopped {
+		c.log.Debug("stopped scheduler at first mismatch", slog.String("args", strings.Join(args, " ")), slog.String("mismatch", stream.mismatch))
+		actual := run.stdout
+		if !c.opts.Strict {
+			actual = normalizeOutput(actual, c.opts.Normalize)
+		}
+		c.noteMismatch(args, stream.want.out, actual)
+		return 0, "output does not match expected: " + stream.mismatch + " (stopped at the first mismatch)", errors.New("output does not match expected")
+	}
+	if err := run.err; err != nil {
+		c.log.Debug("scheduler stderr", slog.String("args", strings.Join(args, " ")), slog.String("stderr", run.stderr))
+		var (
+			exitErr *exec.ExitError
+			msg     string
+			credit  float64
+			cmpErr  error
+		)
+		switch {
+		case run.timedOut:
+			msg = fmt.Sprintf("scheduler timed out after %s", c.opts.Timeout)
+		case cpuLimitExceeded(run.state, c.opts.CPULimit):
+			msg = "scheduler exceeded CPU limit"
+		case c.opts.MemLimit > 0 && outOfMemory(run.stderr):
+			msg = "scheduler exceeded memory limit"
+		case c.opts.ProcLimit > 0 && outOfProcesses(run.stderr):
+			msg = "scheduler exceeded process limit"
+		case unrecognizedFlag(run.stderr):
+			msg = fmt.Sprintf("scheduler does not accept %s (unrecognized flag)", strings.Join(args, " "))
+		case errors.As(err, &exitErr):
+			msg = fmt.Sprintf("scheduler exited with code %d", exitErr.ExitCode())
+			if !exitErr.Exited() {
+				// e.g. "signal: segmentation fault".
+				msg = fmt.Sprintf("scheduler crashed (%s)", exitErr)
+			}
+			// whatever it printed before exiting can still earn (partial) credit.
+			if len(run.stdout) > 0 {
+				var mismatch string
+				credit, mismatch, cmpErr = compareOutput(c, run.stdout, want, args)
+				if mismatch == "" {
+					mismatch = "output matches expected"
+				}
+				msg += "; " + mismatch
+			}
+		default:
+			msg = "scheduler could not be started: " + err.Error()
+		}
+		// the tail of a flag error is just the usage message.
+		if tail := tailLines(run.stderr, stderrTailLines); len(tail) > 0 && !unrecognizedFlag(run.stderr) {
+			msg += ":\n" + strings.Join(tail, "\n")
+		}
+		return credit, msg, errors.Join(err, cmpErr)
+	}
+	if len(run.stdout) == 0 {
+		return 0, "scheduler ran with no output", nil
+	}
+
+	return compareOutput(c, run.stdout, want, args)
+}
+
+// compareOutput compares the scheduler's output to want, returning the
+// fraction of credit earned, and a non-empty message when it doesn't match.
+func compareOutput(c *Context, actual []byte, want golden, args []string) (float64, string, error) {
+	if !c.opts.Strict {
+		actual, want.out = normalizeOutput(actual, c.opts.Normalize), normalizeOutput(want.out, c.opts.Normalize)
+		want.epsilon = c.opts.Epsilon
+	}
+	if !c.opts.SkipPreamble {
+		return matchOutput(c, actual, want, args)
+	}
+	actual, preamble := trimPreamble(actual, want.out)
+	credit, msg, err := matchOutput(c, actual, want, args)
+	if preamble > 0 {
+		note := fmt.Sprintf("skipped %d line(s) of output before the expected output", preamble)
+		c.log.Debug("skipped preamble", slog.String("args", strings.Join(args, " ")), slog.Int("lines", preamble))
+		if msg == "" {
+			return credit, note, err
+		}
+		msg += "; " + note
+	}
+
+	return credit, msg, err
+}
+
+// reportProgress reports how far a run's output comparison has got, in the
+// debug logs and to Options.OnProgress.
+func (c *Context) reportProgress(args []string, line, total int) {
+	p := Progress{Label: c.label, Args: args, Line: line, Total: total}
+	c.log.Debug("comparing output", slog.String("args", strings.Join(args, " ")),
+		slog.Int("line", line), slog.Int("total", total), slog.String("percent", fmt.Sprintf("%.0f%%", p.Percent())))
+	if c.progress != nil {
+		c.progress(p)
+	}
+}
+
+// noteMismatch prints a mismatch's diff with Debug, keeps it, uncolored,
+// with KeepDiffs, and keeps the hint engine's hint for it, if any, with
+// Hints.
+func (c *Context) noteMismatch(args []string, expected, actual []byte) {
+	if c.opts.Debug {
+		// a single write, so concurrent checks don't interleave their diffs.
+		fmt.Fprint(c.opts.out(), formatDiff(c.opts, args, expected, actual))
+	}
+	if c.opts.KeepDiffs {
+		c.diffs = append(c.diffs, unifiedDiff("("+strings.Join(args, " ")+")", expected, actual, false, c.opts.DiffLines))
+	}
+	if !c.opts.Hints {
+		return
+	}
+	// runs with different arguments often make the same mistake.
+	if hint := hintFor(args, expected, actual); hint != "" && !slices.Contains(c.hints, hint) {
+		c.hints = append(c.hints, hint)
+	}
+}
+
+// matchOutput compares normalized output to want, as for compareOutput.
+func matchOutput(c *Context, actual []byte, want golden, args []string) (float64, string, error) {
+	if c.opts.Metrics && !c.opts.Strict {
+		matched, total, diverged := compareMetrics(actual, want)
+		if len(diverged) == 0 {
+			return 1, "", nil
+		}
+		c.noteMismatch(args, want.out, actual)
+		msg := fmt.Sprintf("%d/%d metrics correct; %s", matched, total, strings.Join(diverged, "; "))
+		if !c.opts.Partial {
+			return 0, msg, errors.New("output does not match expected")
+		}
+		return float64(matched) / float64(max(total, 1)), msg, errors.New("output does not match expected")
+	}
+	if c.opts.Structured && !c.opts.Strict {
+		mismatch, matched, total := compareRecords(actual, want)
+		if mismatch == "" {
+			return 1, "", nil
+		}
+		c.noteMismatch(args, want.out, actual)
+		if !c.opts.Partial {
+			return 0, "output does not match expected: " + mismatch, errors.New("output does not match expected")
+		}
+		return float64(matched) / float64(max(total, 1)),
+			fmt.Sprintf("%d/%d records matched; %s", matched, total, mismatch),
+			errors.New("output does not match expected")
+	}
+	mismatch, err := compareGolden(actual, want)
+	if err != nil {
+		return 0, "invalid expected output pattern", err
+	}
+	if mismatch != "" {
+		c.log.Debug("output comparison diverged", slog.String("args", strings.Join(args, " ")), slog.String("mismatch", mismatch))
+		c.noteMismatch(args, want.out, actual)
+		if !c.opts.Partial {
+			return 0, "output does not match expected: " + mismatch, errors.New("output does not match expected")
+		}
+		matched, total, err := countMatchingLines(actual, want)
+		if err != nil {
+			return 0, "invalid expected output pattern", err
+		}
+		return float64(matched) / float64(max(total, 1)),
+			fmt.Sprintf("%d/%d lines matched; %s", matched, total, mismatch),
+			errors.New("output does not match expected")
+	}
+
+	return 1, "", nil
+}
@@ -0,0 +1,367 @@
+package grader
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// scriptChecksDir is where script checks are, next to the rubric config.
+	scriptChecksDir = "checks"
+	// scriptExt is a script check's file extension: they're Starlark.
+	scriptExt = ".star"
+	// scriptHeader starts a script check's header comment.
+	scriptHeader = "gradebot:"
+	// scriptTimeout bounds each script check's run.
+	scriptTimeout = 2 * time.Minute
+)
+
+// scriptMaxSteps bounds a script check's computation, as a runaway loop's,
+// whatever the time it takes.
+var scriptMaxSteps uint64 = 1 << 28
+
+// scriptCheck is an instructor's rubric item in a Starlark file under the
+// rubric config's checks directory, e.g. checks/signals.star, with a header
+// comment in its first lines:
+//
+//	# gradebot: {label: Handles SIGINT, points: 5, binary: true}
+//
+// It defines check(ctx), which gradebot calls with the submission: ctx.dir
+// is its directory, ctx.possible the check's points, ctx.read(path),
+// ctx.exists(path) and ctx.glob(pattern) read its files, and, with binary,
+// ctx.run(args, stdin="") runs the built scheduler, returning a struct of its
+// stdout, stderr, exit_code and timed_out. check returns the points awarded,
+// optionally with a message, as (3, "2 of 5 signals ignored"); None or True
+// awards them all, and False or a fail() none. The label defaults to the
+// file's name, less its extension.
+//
+// Scripts are interpreted in gradebot, sandboxed: they can't load modules or
+// reach the file system, network or processes but through ctx.
+type scriptCheck struct {
+	ID     string `yaml:"-"`
+	Label  string `yaml:"label"`
+	Points int    `yaml:"points"`
+	Binary bool   `yaml:"binary"`
+
+	path string
+}
+
+// needs are the script check's rubric needs: the build, with binary.
+func (sc scriptCheck) needs() []string {
+	if sc.Binary {
+		return needsBuild
+	}
+
+	return nil
+}
+
+// loadScriptChecks reads the script checks in dir's checks directory, if any.
+func loadScriptChecks(dir string) ([]scriptCheck, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, scriptChecksDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var (
+		checks []scriptCheck
+		errs   []error
+	)
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || filepath.Ext(e.Name()) != scriptExt {
+			continue
+		}
+		sc, err := readScriptHeader(filepath.Join(dir, scriptChecksDir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		checks = append(checks, sc)
+	}
+
+	return checks, errors.Join(errs...)
+}
+
+// readScriptHeader reads a script check's header, in its first ten lines.
+func readScriptHeader(path string) (scriptCheck, error) {
+	sc := scriptCheck{ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		return sc, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for n := 0; n < 10 && s.Scan(); n++ {
+		_, header, ok := strings.Cut(s.Text(), scriptHeader)
+		if !ok {
+			continue
+		}
+		if err := yaml.Unmarshal([]byte(header), &sc); err != nil {
+			return sc, fmt.Errorf("%s: header: %w", path, err)
+		}
+		if sc.Label == "" {
+			sc.Label = sc.ID
+		}
+		// a syntax error is the config's, not the submission's.
+		if _, err := syntax.Parse(path, nil, 0); err != nil {
+			return sc, err
+		}
+		return sc, nil
+	}
+
+	return sc, fmt.Errorf("%s: no %q header comment in its first lines", path, scriptHeader)
+}
+
+// validateScriptChecks reports the script checks' problems: labels taken,
+// by rubric items or each other, and negative points.
+func validateScriptChecks(checks []scriptCheck) error {
+	var errs []error
+	seen := rubricLabels()
+	for _, sc := range checks {
+		if slices.Contains(seen, sc.Label) {
+			errs = append(errs, fmt.Errorf("%s: label %q is taken", sc.path, sc.Label))
+		}
+		if sc.Points < 0 {
+			errs = append(errs, fmt.Errorf("%s: negative points %d", sc.path, sc.Points))
+		}
+		seen = append(seen, sc.Label)
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckScript runs a script check (see scriptCheck).
+func CheckScript(sc scriptCheck) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		result := Result{Label: sc.Label, Possible: c.opts.possible(sc.Label)}
+		if sc.Binary && len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+		ctx, cancel := context.WithTimeout(c.ctx, scriptTimeout)
+		defer cancel()
+		var printed strings.Builder
+		thread := &starlark.Thread{
+			Name:  sc.path,
+			Print: func(_ *starlark.Thread, msg string) { fmt.Fprintln(&printed, msg) },
+			Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+				return nil, errors.New("check scripts can't load modules")
+			},
+		}
+		thread.SetMaxExecutionSteps(scriptMaxSteps)
+		stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+		defer stop()
+
+		v, err := runScript(thread, sc, scriptContext(c, sc, result.Possible))
+		if tail := tailLines(printed.String(), reportStderrLines); len(tail) > 0 {
+			c.stderr = append(c.stderr, strings.Join(tail, "\n"))
+		}
+		if ctx.Err() != nil {
+			result.Message = fmt.Sprintf("check script timed out after %s", scriptTimeout)
+			return result, ctx.Err()
+		}
+		if err != nil {
+			result.Message = scriptError(err)
+			return result, err
+		}
+		awarded, message, err := scriptVerdict(v, result.Possible)
+		if err != nil {
+			result.Message = "check script returned " + v.Type()
+			return result, err
+		}
+		result.Awarded = min(max(awarded, 0), result.Possible)
+		result.Message = message
+		if result.Awarded < result.Possible {
+			return result, errors.New("check script awarded partial credit")
+		}
+
+		return result, nil
+	}
+}
+
+// runScript executes the script check's file and calls its check function
+// with ctx.
+func runScript(thread *starlark.Thread, sc scriptCheck, ctx starlark.Value) (starlark.Value, error) {
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, sc.path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	check, ok := globals["check"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%s defines no check(ctx) function", sc.path)
+	}
+
+	return starlark.Call(thread, check, starlark.Tuple{ctx}, nil)
+}
+
+// scriptError is a script check's failure, for its result's message: a
+// fail()'s message, or else the error with the script's backtrace.
+func scriptError(err error) string {
+	var eval *starlark.EvalError
+	if !errors.As(err, &eval) {
+		return err.Error()
+	}
+	if msg, ok := strings.CutPrefix(eval.Msg, "fail: "); ok {
+		return msg
+	}
+
+	return eval.Backtrace()
+}
+
+// scriptVerdict is the points, and message, a check function's return value
+// v awards of possible.
+func scriptVerdict(v starlark.Value, possible int) (int, string, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return possible, "", nil
+	case starlark.Bool:
+		if v {
+			return possible, "", nil
+		}
+		return 0, "", nil
+	case starlark.Int:
+		awarded, err := starlark.AsInt32(v)
+		return awarded, "", err
+	case starlark.Tuple:
+		var (
+			awarded int
+			message string
+		)
+		if err := starlark.UnpackPositionalArgs("check", v, nil, 1, &awarded, &message); err != nil {
+			return 0, "", err
+		}
+		return awarded, message, nil
+	}
+
+	return 0, "", fmt.Errorf("check returned %s, want points, (points, message), a bool or None", v.Type())
+}
+
+// scriptContext is the ctx a script check's check function is called with,
+// for the submission c.
+func scriptContext(c *Context, sc scriptCheck, possible int) starlark.Value {
+	members := starlark.StringDict{
+		"dir":      starlark.String(c.srcDir),
+		"possible": starlark.MakeInt(possible),
+		"read": starlark.NewBuiltin("read", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var name string
+			if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &name); err != nil {
+				return nil, err
+			}
+			path, err := submissionPath(c.srcDir, name)
+			if err != nil {
+				return nil, err
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+			}
+			return starlark.String(b), nil
+		}),
+		"exists": starlark.NewBuiltin("exists", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var name string
+			if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &name); err != nil {
+				return nil, err
+			}
+			path, err := submissionPath(c.srcDir, name)
+			if err != nil {
+				return starlark.False, nil
+			}
+			_, err = os.Stat(path)
+			return starlark.Bool(err == nil), nil
+		}),
+		"glob": starlark.NewBuiltin("glob", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var pattern string
+			if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "pattern", &pattern); err != nil {
+				return nil, err
+			}
+			if !filepath.IsLocal(filepath.FromSlash(pattern)) {
+				return nil, fmt.Errorf("%s: %q is not in the submission", fn.Name(), pattern)
+			}
+			matches, err := filepath.Glob(filepath.Join(c.srcDir, filepath.FromSlash(pattern)))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+			}
+			var list []starlark.Value
+			for _, m := range matches {
+				if rel, err := filepath.Rel(c.srcDir, m); err == nil {
+					list = append(list, starlark.String(filepath.ToSlash(rel)))
+				}
+			}
+			return starlark.NewList(list), nil
+		}),
+		"run": starlark.NewBuiltin("run", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if !sc.Binary {
+				return nil, fmt.Errorf("%s: needs binary: true in the %s header", fn.Name(), sc.path)
+			}
+			var (
+				argv  *starlark.List
+				stdin string
+			)
+			if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "args?", &argv, "stdin?", &stdin); err != nil {
+				return nil, err
+			}
+			var flags []string
+			for i := 0; argv != nil && i < argv.Len(); i++ {
+				s, ok := starlark.AsString(argv.Index(i))
+				if !ok {
+					return nil, fmt.Errorf("%s: args[%d] is %s, want string", fn.Name(), i, argv.Index(i).Type())
+				}
+				flags = append(flags, s)
+			}
+			run := execScheduler(c, []byte(stdin), flags)
+			exitCode := 0
+			var exit *exec.ExitError
+			switch {
+			case run.state != nil:
+				exitCode = run.state.ExitCode()
+			case errors.As(run.err, &exit):
+				exitCode = exit.ExitCode()
+			case run.err != nil && !run.timedOut:
+				return nil, fmt.Errorf("%s: %w", fn.Name(), run.err)
+			}
+			return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+				"stdout":    starlark.String(run.stdout),
+				"stderr":    starlark.String(run.stderr),
+				"exit_code": starlark.MakeInt(exitCode),
+				"timed_out": starlark.Bool(run.timedOut),
+			}), nil
+		}),
+	}
+
+	return starlarkstruct.FromStringDict(starlark.String("ctx"), members)
+}
+
+// submissionPath resolves a script check's slash-separated path to a file
+// of the submission in dir, refusing any outside it, as by .. or a symlink.
+func submissionPath(dir, name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("%q is not in the submission", name)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path, nil // os's error says it best
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%q is not in the submission", name)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,165 @@
+package grader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadScriptHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    scriptCheck
+		wantErr bool
+	}{
+		{name: "header", src: "# gradebot: {label: Handles SIGINT, points: 5, binary: true}\ndef check(ctx):\n    pass\n",
+			want: scriptCheck{ID: "check", Label: "Handles SIGINT", Points: 5, Binary: true}},
+		{name: "default label", src: "# a check\n# gradebot: {points: 2}\ndef check(ctx):\n    pass\n",
+			want: scriptCheck{ID: "check", Label: "check", Points: 2}},
+		{name: "no header", src: "def check(ctx):\n    pass\n", wantErr: true},
+		{name: "bad header", src: "# gradebot: {points: [}\n", wantErr: true},
+		{name: "syntax error", src: "# gradebot: {points: 2}\ndef check(ctx)\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "check.star")
+			if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readScriptHeader(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readScriptHeader() error = %v, want error %t", err, tt.wantErr)
+			}
+			got.path = ""
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("readScriptHeader() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScriptChecks(t *testing.T) {
+	dir := t.TempDir()
+	checks := filepath.Join(dir, scriptChecksDir)
+	if err := os.Mkdir(checks, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range map[string]string{
+		"signals.star": "# gradebot: {points: 5}\ndef check(ctx):\n    pass\n",
+		"README.md":    "not a check\n",
+		"old.sh":       "# gradebot: {points: 5}\nexit 0\n",
+	} {
+		if err := os.WriteFile(filepath.Join(checks, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := loadScriptChecks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "signals" {
+		t.Errorf("loadScriptChecks() = %+v, want signals.star's only", got)
+	}
+	if got, err := loadScriptChecks(t.TempDir()); got != nil || err != nil {
+		t.Errorf("loadScriptChecks() without a checks directory = %v, %v", got, err)
+	}
+}
+
+func TestCheckScript(t *testing.T) {
+	defer func(n uint64) { scriptMaxSteps = n }(scriptMaxSteps)
+	scriptMaxSteps = 1 << 16
+	const label = "Custom"
+	tests := []struct {
+		name    string
+		src     string
+		binary  bool
+		awarded int
+		message string // a prefix of the result's
+		wantErr bool
+	}{
+		{name: "none awards all", src: "def check(ctx):\n    pass\n", awarded: 5},
+		{name: "true", src: "def check(ctx):\n    return True\n", awarded: 5},
+		{name: "false", src: "def check(ctx):\n    return False\n", wantErr: true},
+		{name: "points", src: "def check(ctx):\n    return ctx.possible - 2\n", awarded: 3, wantErr: true},
+		{name: "points and message", src: "def check(ctx):\n    return 1, \"2 of 5 signals ignored\"\n", awarded: 1, message: "2 of 5 signals ignored", wantErr: true},
+		{name: "clamped", src: "def check(ctx):\n    return 50\n", awarded: 5},
+		{name: "fail", src: "def check(ctx):\n    fail(\"no README\")\n", message: "no README", wantErr: true},
+		{name: "read", src: "def check(ctx):\n    return \"package main\" in ctx.read(\"main.go\")\n", awarded: 5},
+		{name: "exists", src: "def check(ctx):\n    return ctx.exists(\"main.go\") and not ctx.exists(\"README\")\n", awarded: 5},
+		{name: "glob", src: "def check(ctx):\n    return ctx.glob(\"*.go\") == [\"main.go\"]\n", awarded: 5},
+		{name: "read outside", src: "def check(ctx):\n    ctx.read(\"../secret\")\n", message: "Traceback", wantErr: true},
+		{name: "run without binary", src: "def check(ctx):\n    ctx.run()\n", message: "Traceback", wantErr: true},
+		{name: "no check", src: "x = 1\n", message: "", wantErr: true},
+		{name: "bad return", src: "def check(ctx):\n    return \"yes\"\n", message: "check script returned string", wantErr: true},
+		{name: "load", src: "load(\"x.star\", \"y\")\ndef check(ctx):\n    pass\n", wantErr: true},
+		{name: "runaway", src: "def check(ctx):\n    for i in range(1 << 30):\n        pass\n", message: "Traceback", wantErr: true},
+		{name: "run", binary: true, awarded: 5, src: `def check(ctx):
+    r = ctx.run(["-x"], stdin = "in")
+    return r.stdout == "in -x\n" and r.stderr == "oops\n" and r.exit_code == 3 and not r.timed_out
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.binary && runtime.GOOS == "windows" {
+				t.Skip("needs a POSIX shell")
+			}
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "custom.star")
+			if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			c := &Context{
+				ctx:    context.Background(),
+				log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+				opts:   Options{Points: map[string]int{label: 5}, Timeout: time.Minute, MaxOutput: 1 << 20},
+				srcDir: dir,
+				usage:  &runUsage{},
+			}
+			if tt.binary {
+				c.run = []string{"/bin/sh", "-c", `printf '%s %s\n' "$(cat)" "$1"; echo oops >&2; exit 3`, "sh"}
+			}
+			result, err := CheckScript(scriptCheck{ID: "custom", Label: label, Points: 5, Binary: tt.binary, path: path})(c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckScript() error = %v, want error %t", err, tt.wantErr)
+			}
+			if result.Awarded != tt.awarded || !strings.HasPrefix(result.Message, tt.message) {
+				t.Errorf("CheckScript() = %d, %q, want %d, %q", result.Awarded, result.Message, tt.awarded, tt.message)
+			}
+		})
+	}
+}
+
+func TestSubmissionPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.go", "pkg/missing.go"} {
+		if _, err := submissionPath(dir, name); err != nil {
+			t.Errorf("submissionPath(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"../x", "/etc/passwd", ""} {
+		if _, err := submissionPath(dir, name); err == nil {
+			t.Errorf("submissionPath(%q): no error", name)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("/etc", filepath.Join(dir, "etc")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := submissionPath(dir, "etc/passwd"); err == nil {
+			t.Error("submissionPath() via a symlink out of the submission: no error")
+		}
+	}
+}
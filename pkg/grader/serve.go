@@ -0,0 +1,438 @@
+package grader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxUploadBytes bounds a submission upload.
+const maxUploadBytes = 32 << 20
+
+// ServeConfig configures Serve, as the serve command's flags do.
+type ServeConfig struct {
+	Config
+	Addr         string
+	Workers      int
+	QueueSize    int
+	RateLimit    time.Duration
+	RunLog       string
+	ResultTTL    time.Duration
+	GRPCAddr     string
+	Tokens       string
+	UnsafeNative bool
+}
+
+// studentID is what a student may identify as, so it's safe in file names and logs.
+var studentID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// server grades uploaded submissions, one queued job at a time per worker.
+type server struct {
+	opts  Options
+	limit time.Duration
+	queue chan gradeJob
+
+	tokens map[string]string // by student, what their submissions must present
+	// receiptKey, if set, signs a receipt in each report.
+	receiptKey ed25519.PrivateKey
+
+	mu   sync.Mutex
+	last map[string]time.Time // by student, when their last submission was accepted
+	log  io.Writer            // the run log, or nil
+	// jobs are the /v1 API's submissions, by id, kept for ttl once graded.
+	jobs map[string]*apiJob
+	ttl  time.Duration
+}
+
+type gradeJob struct {
+	student string
+	remote  string
+	tmp     string // holds the uploaded zip, removed once graded
+	archive string
+	done    chan jsonReport // buffered, as the client may have gone
+	api     *apiJob         // a /v1 API submission's progress, or nil
+}
+
+// runLogEntry is one line of the --run-log.
+type runLogEntry struct {
+	Time     time.Time     `json:"time"`
+	Student  string        `json:"student"`
+	Remote   string        `json:"remote"`
+	Total    int           `json:"total"`
+	Possible int           `json:"possible"`
+	Duration time.Duration `json:"duration_ns"`
+	Results  []Result      `json:"results"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Serve grades the submissions uploaded to cfg.Addr (and cfg.GRPCAddr), by
+// the students with cfg.Tokens' tokens, until ctx is done.
+func Serve(ctx context.Context, cfg ServeConfig) error {
+	if _, err := cfg.setup(); err != nil {
+		return err
+	}
+	if cfg.Workers < 1 || cfg.QueueSize < 0 {
+		return errors.New("--workers must be at least 1, and --queue not negative")
+	}
+	// anyone with a token may upload code, which would otherwise run as us.
+	if cfg.Sandbox != sandboxDocker && !cfg.UnsafeNative {
+		return errors.New("serve grades uploaded code: run it in --sandbox=docker, or pass --unsafe-native to run it as this user")
+	}
+	tokens, err := loadTokens(cfg.Tokens)
+	if err != nil {
+		return err
+	}
+	receiptKey, err := loadSigningKey(cfg.ReceiptKey)
+	if err != nil {
+		return err
+	}
+	opts, err := cfg.gradeOptions(ctx)
+	if err != nil {
+		return err
+	}
+	// mismatch diffs (with --debug) are for the instructor, not the response.
+	opts.Out = os.Stderr
+
+	s := &server{
+		opts:       opts,
+		limit:      cfg.RateLimit,
+		queue:      make(chan gradeJob, cfg.QueueSize),
+		tokens:     tokens,
+		receiptKey: receiptKey,
+		last:       make(map[string]time.Time),
+		jobs:       make(map[string]*apiJob),
+		ttl:        cfg.ResultTTL,
+	}
+	if cfg.RunLog != "" {
+		f, err := os.OpenFile(cfg.RunLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		s.log = f
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		go s.work(ctx)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/grade", s.handleGrade)
+	mux.HandleFunc("/v1/submissions", s.handleSubmit)
+	mux.HandleFunc("/v1/submissions/", s.handleSubmission)
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	errCh := make(chan error, 2)
+	if cfg.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			return err
+		}
+		grpcSrv := newGRPCServer(ctx, s)
+		defer grpcSrv.GracefulStop()
+		go func() { errCh <- grpcSrv.Serve(lis) }()
+		slog.Info("serving gRPC", slog.String("addr", lis.Addr().String()))
+	}
+	go func() { errCh <- srv.ListenAndServe() }()
+	slog.Info("serving", slog.String("addr", cfg.Addr), slog.Int("workers", cfg.Workers))
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// handleGrade grades a zip of a project, POSTed as the request body, e.g.
+//
+//	curl -H "Authorization: Bearer $TOKEN" --data-binary @project.zip 'http://localhost:8080/grade?student=alice'
+//
+// and responds with the results as JSON, as for --format=json.
+func (s *server) handleGrade(w http.ResponseWriter, r *http.Request) {
+	job := gradeJob{done: make(chan jsonReport, 1)}
+	if !s.enqueue(w, r, &job) {
+		return
+	}
+
+	var report jsonReport
+	select {
+	case report = <-job.done:
+	case <-r.Context().Done():
+		// the client gave up (or the server is stopping); the run is still logged.
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+}
+
+// enqueue queues the zip POSTed by r's ?student=, with their bearer token,
+// as the job, or responds with why not.
+func (s *server) enqueue(w http.ResponseWriter, r *http.Request, job *gradeJob) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a zip of your project", http.StatusMethodNotAllowed)
+		return false
+	}
+	student := r.URL.Query().Get("student")
+	if !studentID.MatchString(student) {
+		http.Error(w, "missing or invalid ?student= (letters, digits, . _ -)", http.StatusBadRequest)
+		return false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+	if err != nil {
+		http.Error(w, errTooLarge.msg, errTooLarge.status)
+		return false
+	}
+	var rejected *submitError
+	if err := s.submit(student, bearerToken(r.Header.Get("Authorization")), r.RemoteAddr, body, job); errors.As(err, &rejected) {
+		if rejected.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(rejected.retryAfter.Seconds()+1)))
+		}
+		if rejected == errUnauthorized {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		http.Error(w, rejected.msg, rejected.status)
+		return false
+	}
+
+	return true
+}
+
+// submitError is why a submission wasn't queued, with the HTTP status it's
+// responded to with (and gRPC maps to a code).
+type submitError struct {
+	status int
+	msg    string
+	// retryAfter is how long until the student may submit again.
+	retryAfter time.Duration
+}
+
+func (e *submitError) Error() string { return e.msg }
+
+var (
+	errBadStudent   = &submitError{status: http.StatusBadRequest, msg: "missing or invalid student (letters, digits, . _ -)"}
+	errUnauthorized = &submitError{status: http.StatusUnauthorized, msg: "missing or wrong token for the student"}
+	errTooLarge     = &submitError{status: http.StatusRequestEntityTooLarge, msg: fmt.Sprintf("submission must be a zip of at most %d MiB", maxUploadBytes>>20)}
+	errNotZip       = &submitError{status: http.StatusUnsupportedMediaType, msg: "submission is not a zip"}
+	errInternal     = &submitError{status: http.StatusInternalServerError, msg: "internal error"}
+	errQueueFull    = &submitError{status: http.StatusServiceUnavailable, msg: "the grading queue is full, try again shortly"}
+)
+
+// submit queues student's zip, sent from remote with token, as the job, for
+// either transport, or returns a *submitError of why not.
+func (s *server) submit(student, token, remote string, body []byte, job *gradeJob) error {
+	switch {
+	case !studentID.MatchString(student):
+		return errBadStudent
+	case !s.authorized(student, token):
+		// before reserving, so others can't hold a student off.
+		slog.Warn("unauthorized submission", slog.String("student", student), slog.String("remote", remote))
+		return errUnauthorized
+	case len(body) > maxUploadBytes:
+		return errTooLarge
+	case !bytes.HasPrefix(body, []byte("PK\x03\x04")):
+		return errNotZip
+	}
+
+	if wait := s.reserve(student); wait > 0 {
+		return &submitError{
+			status:     http.StatusTooManyRequests,
+			msg:        fmt.Sprintf("too many submissions, try again in %s", wait.Round(time.Second)),
+			retryAfter: wait,
+		}
+	}
+	tmp, err := os.MkdirTemp("", "gradebot-upload-")
+	if err != nil {
+		s.release(student)
+		return errInternal
+	}
+	job.student, job.remote, job.tmp = student, remote, tmp
+	job.archive = filepath.Join(tmp, student+".zip")
+	if err := os.WriteFile(job.archive, body, 0o600); err != nil {
+		_ = os.RemoveAll(tmp)
+		s.release(student)
+		return errInternal
+	}
+	select {
+	case s.queue <- *job:
+	default:
+		_ = os.RemoveAll(tmp)
+		s.release(student)
+		return errQueueFull
+	}
+	slog.Info("queued submission", slog.String("student", student), slog.String("remote", remote))
+
+	return nil
+}
+
+// work grades queued submissions until ctx is cancelled.
+func (s *server) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			start := time.Now()
+			job.api.setState(stateRunning)
+			report := s.grade(ctx, job)
+			_ = os.RemoveAll(job.tmp)
+			s.logRun(runLogEntry{
+				Time:     start,
+				Student:  job.student,
+				Remote:   job.remote,
+				Total:    report.Total,
+				Possible: report.Possible,
+				Duration: time.Since(start),
+				Results:  report.Results,
+				Error:    report.Error,
+			})
+			job.api.finish(report)
+			job.done <- report
+		}
+	}
+}
+
+func (s *server) grade(ctx context.Context, job gradeJob) jsonReport {
+	report := jsonReport{Dir: job.student}
+	dirs, cleanup, err := extractArchives([]string{job.archive})
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	defer cleanup()
+	opts := s.opts
+	if job.api != nil {
+		opts.OnResult = job.api.add
+	}
+	report.Results = Grade(ctx, dirs[0], opts)
+	report.Total, report.Possible = resultTotals(report.Results)
+	env := captureManifest(dirs[0], opts)
+	report.Environment = &env
+	if s.receiptKey != nil && ctx.Err() == nil {
+		r, err := newReceipt(submission{dir: dirs[0], source: job.archive, results: report.Results})
+		if err == nil {
+			report.Receipt, err = signReceipt(s.receiptKey, r)
+		}
+		if err != nil {
+			slog.Warn("no receipt", slog.String("student", job.student), slog.String("err", err.Error()))
+		}
+	}
+
+	return report
+}
+
+// authorized reports whether token is student's.
+func (s *server) authorized(student, token string) bool {
+	want, ok := s.tokens[student]
+
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// bearerToken is the token of an "Authorization: Bearer" header, or "".
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// loadTokens reads a --tokens file: a student and their token per line,
+// leaving out blank lines and # comments.
+func loadTokens(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tokens := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !studentID.MatchString(fields[0]) {
+			return nil, fmt.Errorf("%s:%d: want a student and their token", path, n)
+		}
+		if _, ok := tokens[fields[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: a second token for %s", path, n, fields[0])
+		}
+		tokens[fields[0]] = fields[1]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s: no tokens", path)
+	}
+
+	return tokens, nil
+}
+
+// reserve records a submission by student, returning how long they must wait
+// instead when it's too soon after their last one.
+func (s *server) reserve(student string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.last[student]; ok && s.limit > 0 {
+		if wait := s.limit - time.Since(last); wait > 0 {
+			return wait
+		}
+	}
+	s.last[student] = time.Now()
+
+	return 0
+}
+
+// release forgets a reserved submission that was never graded.
+func (s *server) release(student string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.last, student)
+}
+
+func (s *server) logRun(e runLogEntry) {
+	slog.Info("graded submission", slog.String("student", e.Student), slog.String("remote", e.Remote),
+		slog.Int("total", e.Total), slog.Int("possible", e.Possible), slog.Duration("duration", e.Duration))
+	if s.log == nil {
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.log.Write(append(b, '\n')); err != nil {
+		slog.Warn("could not write the run log", slog.String("err", err.Error()))
+	}
+}
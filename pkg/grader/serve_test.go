@@ -0,0 +1,128 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleSubmit(t *testing.T) {
+	s := testServer(1)
+	zip := []byte("PK\x03\x04 not much of a zip")
+	tests := []struct {
+		name       string
+		student    string
+		token      string
+		body       []byte
+		want       int
+		wantHeader string
+	}{
+		{name: "no token", student: "alice", body: zip, want: http.StatusUnauthorized, wantHeader: "WWW-Authenticate"},
+		{name: "wrong token", student: "alice", token: "guess", body: zip, want: http.StatusUnauthorized, wantHeader: "WWW-Authenticate"},
+		{name: "unknown student", student: "mallory", token: "alice-token", body: zip, want: http.StatusUnauthorized},
+		{name: "bad student", student: "../alice", token: "alice-token", body: zip, want: http.StatusBadRequest},
+		{name: "not a zip", student: "alice", token: "alice-token", body: []byte("main.go"), want: http.StatusUnsupportedMediaType},
+		// the unauthorized attempts didn't use up alice's submission.
+		{name: "queued", student: "alice", token: "alice-token", body: zip, want: http.StatusAccepted},
+		{name: "too soon", student: "alice", token: "alice-token", body: zip, want: http.StatusTooManyRequests, wantHeader: "Retry-After"},
+		{name: "queue full", student: "bob", token: "bob-token", body: zip, want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/submissions?student="+tt.student, bytes.NewReader(tt.body))
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			s.handleSubmit(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), tt.want)
+			}
+			if tt.wantHeader != "" && w.Header().Get(tt.wantHeader) == "" {
+				t.Errorf("no %s header", tt.wantHeader)
+			}
+		})
+	}
+	// only alice's upload is queued, and kept until it's graded.
+	close(s.queue)
+	var queued []string
+	for job := range s.queue {
+		queued = append(queued, job.student)
+		if _, err := os.Stat(job.archive); err != nil {
+			t.Errorf("queued upload: %v", err)
+		}
+		_ = os.RemoveAll(job.tmp)
+	}
+	if len(queued) != 1 || queued[0] != "alice" {
+		t.Errorf("queued %q, want just alice's", queued)
+	}
+	if len(s.last) != 1 {
+		t.Errorf("reserved %v, want just alice", s.last)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer abc":    "abc",
+		"bearer  abc ":  "abc",
+		"Basic YWxpY2U": "",
+		"abc":           "",
+		"":              "",
+	} {
+		if got := bearerToken(header); got != want {
+			t.Errorf("bearerToken(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "tokens", file: "# section 1\nalice a1b2\n\n  bob c3d4  \n", want: map[string]string{"alice": "a1b2", "bob": "c3d4"}},
+		{name: "no token", file: "alice\n", wantErr: true},
+		{name: "bad student", file: "../alice a1b2\n", wantErr: true},
+		{name: "twice", file: "alice a1b2\nalice c3d4\n", wantErr: true},
+		{name: "empty", file: "# none yet\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadTokens(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadTokens() error = %v, want error %t", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("loadTokens() = %v, want %v", got, tt.want)
+			}
+			for student, token := range tt.want {
+				if got[student] != token {
+					t.Errorf("loadTokens() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestServeRequiresSandbox(t *testing.T) {
+	tokens := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokens, []byte("alice a1b2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := ServeConfig{Config: Config{Sandbox: "none"}, Workers: 1, Tokens: tokens}
+	err := Serve(context.Background(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "--sandbox=docker") {
+		t.Errorf("Serve() natively, without --unsafe-native = %v, want it to ask for the sandbox", err)
+	}
+}
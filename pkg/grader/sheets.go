@@ -0,0 +1,285 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sheetsScope is the OAuth scope appending to a sheet needs.
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// sheetsAPI is the Google Sheets API's base URL; tests point it elsewhere.
+var sheetsAPI = "https://sheets.googleapis.com/v4"
+
+// SheetsConfig publishes a batch's grades to a Google Sheet, as rows appended
+// with a service account's credentials.
+type SheetsConfig struct {
+	Sheet            string
+	SheetRange       string
+	SheetCredentials string
+}
+
+func (o SheetsConfig) enabled() bool { return o.Sheet != "" }
+
+func (o SheetsConfig) validate() error {
+	if !o.enabled() {
+		return nil
+	}
+	if o.SheetCredentials == "" {
+		return errors.New("--sheet requires --sheet-credentials (or $GOOGLE_APPLICATION_CREDENTIALS)")
+	}
+	if o.SheetRange == "" {
+		return errors.New("--sheet requires --sheet-range")
+	}
+
+	return nil
+}
+
+// serviceAccount is the part of a Google service account key gradebot uses.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// The sheet's fixed columns, around each rubric item's (by its label).
+const (
+	sheetIDColumn    = "id"
+	sheetTotalColumn = "total"
+	sheetTimeColumn  = "time"
+)
+
+// publishToSheet appends a row per submission, sorted by directory name, to
+// the sheet: its id (directory name), each rubric item's points, the total
+// (normalized, if at all) and the time graded. Values go in the columns of
+// the sheet's header row by name, so runs grading different items line up:
+// an empty sheet gets a header first, and items new to the sheet are added
+// to its header.
+func publishToSheet(ctx context.Context, o SheetsConfig, opts options, subs []submission) error {
+	sorted := append([]submission(nil), subs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name() < sorted[j].name() })
+	var labels []string
+	for _, s := range sorted {
+		for _, r := range s.results {
+			if !slices.Contains(labels, r.Label) {
+				labels = append(labels, r.Label)
+			}
+		}
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	token, err := serviceAccountToken(ctx, client, o.SheetCredentials)
+	if err != nil {
+		return fmt.Errorf("publishing to sheet: %w", err)
+	}
+	sheet := sheetsClient{client: client, token: token, id: o.Sheet}
+	var current struct {
+		Range  string  `json:"range"`
+		Values [][]any `json:"values"`
+	}
+	if err := sheet.do(ctx, http.MethodGet, "/values/"+url.PathEscape(o.SheetRange), nil, &current); err != nil {
+		return fmt.Errorf("publishing to sheet: reading its header: %w", err)
+	}
+	var header []string
+	if len(current.Values) > 0 {
+		for _, v := range current.Values[0] {
+			header = append(header, fmt.Sprint(v))
+		}
+	}
+	columns, changed := sheetColumns(header, labels)
+	if changed {
+		// the header row starts at the range's first cell, e.g. Sheet1!A1 of Sheet1!A1:Z1000.
+		at, _, _ := strings.Cut(current.Range, ":")
+		row := make([]any, len(columns))
+		for i, c := range columns {
+			row[i] = c
+		}
+		if err := sheet.do(ctx, http.MethodPut, "/values/"+url.PathEscape(at)+"?valueInputOption=RAW",
+			map[string]any{"values": [][]any{row}}, nil); err != nil {
+			return fmt.Errorf("publishing to sheet: writing its header: %w", err)
+		}
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	rows := make([][]any, 0, len(sorted))
+	for _, s := range sorted {
+		awarded, possible := s.totals()
+		var total any = awarded
+		if opts.NormalizeTo > 0 {
+			total = normalize(awarded, possible, opts.NormalizeTo)
+		}
+		values := map[string]any{sheetIDColumn: s.id(), sheetTotalColumn: total, sheetTimeColumn: now}
+		for _, r := range s.results {
+			values[r.Label] = r.Awarded
+		}
+		// what wasn't graded for this submission, or isn't gradebot's, is left blank.
+		row := make([]any, len(columns))
+		for i, c := range columns {
+			if v, ok := values[c]; ok {
+				row[i] = v
+			} else {
+				row[i] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := sheet.do(ctx, http.MethodPost, "/values/"+url.PathEscape(o.SheetRange)+":append?valueInputOption=RAW&insertDataOption=INSERT_ROWS",
+		map[string]any{"values": rows}, nil); err != nil {
+		return fmt.Errorf("publishing to sheet: %w", err)
+	}
+
+	return nil
+}
+
+// sheetColumns is the sheet's columns, by its header, with any of the
+// labels, or the fixed columns, it doesn't have yet added at the end, and
+// whether there were any. An empty sheet's are the id, the labels, the total
+// and the time.
+func sheetColumns(header, labels []string) ([]string, bool) {
+	want := append(append([]string{sheetIDColumn}, labels...), sheetTotalColumn, sheetTimeColumn)
+	columns := slices.Clone(header)
+	for _, c := range want {
+		if !slices.Contains(columns, c) {
+			columns = append(columns, c)
+		}
+	}
+
+	return columns, len(columns) > len(header)
+}
+
+// sheetsClient calls the Sheets API on a spreadsheet.
+type sheetsClient struct {
+	client *http.Client
+	token  string
+	id     string
+}
+
+// do sends body, as JSON, with method to the spreadsheet's path, decoding
+// the response into out, unless it's nil.
+func (c sheetsClient) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, sheetsAPI+"/spreadsheets/"+url.PathEscape(c.id)+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sheets responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 32<<20)).Decode(out)
+}
+
+// serviceAccountToken exchanges a JWT signed with the service account key at
+// path for an access token to sheetsScope.
+func serviceAccountToken(ctx context.Context, client *http.Client, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(b, &sa); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" || sa.TokenURI == "" {
+		return "", fmt.Errorf("%s is not a service account key", path)
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("%s: no PEM private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("%s: not an RSA private key", path)
+	}
+
+	enc := base64.RawURLEncoding
+	now := time.Now()
+	claims, err := json.Marshal(map[string]any{
+		"iss":   sa.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + enc.EncodeToString(sig)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sa.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("token endpoint responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tok); err != nil {
+		return "", fmt.Errorf("reading access token: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", errors.New("token endpoint gave no access token")
+	}
+
+	return tok.AccessToken, nil
+}
@@ -0,0 +1,148 @@
+package grader
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeSheet is a spreadsheet, over the parts of the Sheets API gradebot
+// calls, with a token endpoint for its service account.
+type fakeSheet struct {
+	mu   sync.Mutex
+	rows [][]any
+}
+
+func (f *fakeSheet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/token" {
+		writeJSON(w, http.StatusOK, map[string]string{"access_token": "tok"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer tok" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var body struct {
+		Values [][]any `json:"values"`
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/spreadsheets/sheet/values/Sheet1":
+		resp := map[string]any{"range": "Sheet1!A1:Z1000"}
+		if len(f.rows) > 0 {
+			resp["values"] = f.rows
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case r.Method == http.MethodPut && r.URL.Path == "/spreadsheets/sheet/values/Sheet1!A1":
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if len(f.rows) == 0 {
+			f.rows = [][]any{nil}
+		}
+		f.rows[0] = body.Values[0]
+		writeJSON(w, http.StatusOK, struct{}{})
+	case r.Method == http.MethodPost && r.URL.Path == "/spreadsheets/sheet/values/Sheet1:append":
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.rows = append(f.rows, body.Values...)
+		writeJSON(w, http.StatusOK, struct{}{})
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func TestPublishToSheet(t *testing.T) {
+	sheet := &fakeSheet{}
+	srv := httptest.NewServer(sheet)
+	defer srv.Close()
+	defer func(api string) { sheetsAPI = api }(sheetsAPI)
+	sheetsAPI = srv.URL
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := filepath.Join(t.TempDir(), "key.json")
+	b, _ := json.Marshal(serviceAccount{
+		ClientEmail: "gradebot@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    srv.URL + "/token",
+	})
+	if err := os.WriteFile(creds, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o := SheetsConfig{Sheet: "sheet", SheetRange: "Sheet1", SheetCredentials: creds}
+
+	// the first run writes the header.
+	if err := publishToSheet(context.Background(), o, options{}, []submission{
+		{dir: "bob", results: []Result{{Label: "Compiles", Awarded: 10}, {Label: "FCFS", Awarded: 5}}},
+		{dir: "alice", results: []Result{{Label: "Compiles", Awarded: 10}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// a later one, grading an optional item too, adds its column, and fills
+	// the rest in by the header.
+	if err := publishToSheet(context.Background(), o, options{}, []submission{
+		{dir: "carol", results: []Result{{Label: "Style", Awarded: 2}, {Label: "FCFS", Awarded: 20}, {Label: "Compiles", Awarded: 10}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]any
+	for i, row := range sheet.rows {
+		var cells []any
+		for j, v := range row {
+			if f, ok := v.(float64); ok {
+				v = int(f)
+			}
+			// the time graded varies.
+			if i > 0 && sheet.rows[0][j] == sheetTimeColumn {
+				v = "time"
+			}
+			cells = append(cells, v)
+		}
+		got = append(got, cells)
+	}
+	want := [][]any{
+		{"id", "Compiles", "FCFS", "total", "time", "Style"},
+		{"alice", 10, "", 10, "time"},
+		{"bob", 10, 5, 15, "time"},
+		{"carol", 10, 20, 32, "time", 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sheet =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestSheetColumns(t *testing.T) {
+	tests := []struct {
+		header, labels []string
+		want           []string
+		changed        bool
+	}{
+		{labels: []string{"Compiles", "FCFS"}, want: []string{"id", "Compiles", "FCFS", "total", "time"}, changed: true},
+		{header: []string{"id", "Compiles", "total", "time"}, labels: []string{"Compiles"}, want: []string{"id", "Compiles", "total", "time"}},
+		// the instructor's own columns, and order, are kept.
+		{header: []string{"notes", "time", "total", "Compiles", "id"}, labels: []string{"Compiles", "SJF"},
+			want: []string{"notes", "time", "total", "Compiles", "id", "SJF"}, changed: true},
+	}
+	for _, tt := range tests {
+		got, changed := sheetColumns(tt.header, tt.labels)
+		if !reflect.DeepEqual(got, tt.want) || changed != tt.changed {
+			t.Errorf("sheetColumns(%q, %q) = %q, %t, want %q, %t", tt.header, tt.labels, got, changed, tt.want, tt.changed)
+		}
+	}
+}
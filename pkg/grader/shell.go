@@ -0,0 +1,361 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// golden session transcripts (see parseSession).
+const (
+	sessionCommand  = "$ "
+	sessionAnywhere = "? "
+	// sessionMarker is echoed after each command, delimiting its output.
+	sessionMarker = "__gradebot_%d__"
+	// shellEnv is in the shell's environment, for env sessions.
+	shellEnv = "GB_GREETING=hello"
+	// shellTimeout bounds each command without a --timeout.
+	shellTimeout = 10 * time.Second
+)
+
+//go:embed testdata/shell/*.session
+var shellSessions embed.FS
+
+// sessionStep is a command of a session and the output it should print.
+type sessionStep struct {
+	command string
+	// want are its output lines, in order.
+	want []string
+	// anywhere are lines anywhere in its output, when the rest doesn't
+	// matter, e.g. of env.
+	anywhere []string
+}
+
+// parseSession parses a golden session: "$ " lines are commands, "? " lines
+// are output lines expected anywhere in the command's output and other lines
+// are its output, in order. "#" lines are comments.
+func parseSession(b []byte) ([]sessionStep, error) {
+	var steps []sessionStep
+	for n, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, sessionCommand):
+			steps = append(steps, sessionStep{command: strings.TrimPrefix(line, sessionCommand)})
+		case len(steps) == 0:
+			return nil, fmt.Errorf("line %d: output before a command", n+1)
+		case strings.HasPrefix(line, sessionAnywhere):
+			steps[len(steps)-1].anywhere = append(steps[len(steps)-1].anywhere, strings.TrimPrefix(line, sessionAnywhere))
+		default:
+			steps[len(steps)-1].want = append(steps[len(steps)-1].want, line)
+		}
+	}
+
+	return steps, nil
+}
+
+// isExit reports whether the step should end the shell.
+func (s sessionStep) isExit() bool {
+	fields := strings.Fields(s.command)
+
+	return len(fields) > 0 && fields[0] == "exit"
+}
+
+// transcript is the step as a golden session would have it, with got as its
+// output.
+func (s sessionStep) transcript(got []string) string {
+	return sessionCommand + s.command + "\n" + strings.Join(append(got, ""), "\n")
+}
+
+// CheckShellSession runs the shell through the named golden session in
+// testdata/shell, on a pseudo-terminal where there is one, in an empty
+// temporary directory. Each command's output is what the shell prints between
+// its prompts, and each command has --timeout to finish.
+func CheckShellSession(result Result, name string) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "shell was not compileable"
+			return result, errors.New("binary not found")
+		}
+		b, err := shellSessions.ReadFile("testdata/shell/" + name + ".session")
+		if err != nil {
+			return result, err
+		}
+		steps, err := parseSession(b)
+		if err != nil {
+			return result, fmt.Errorf("session %s: %w", name, err)
+		}
+		timeout := c.opts.Timeout
+		if timeout <= 0 {
+			timeout = shellTimeout
+		}
+		work, err := os.MkdirTemp("", "gradebot-shell-")
+		if err != nil {
+			result.Message = "could not create a working directory"
+			return result, err
+		}
+		defer os.RemoveAll(work)
+
+		sh, err := startShell(c, work)
+		if err != nil {
+			result.Message = "could not start the shell"
+			return result, err
+		}
+		defer sh.close()
+
+		// a prompt is whatever the shell prints before reading a command, so
+		// an echo of a marker alone is preceded by just the prompt.
+		prompt, state := sh.run(0, "", timeout)
+		if state != shellReady {
+			result.Message = "shell " + state.String() + " before its first command"
+			return result, errors.New(result.Message)
+		}
+		var (
+			passed           int
+			msgs             []string
+			expected, actual []string
+		)
+		for i, step := range steps {
+			if step.isExit() {
+				state := sh.exit(step.command, timeout)
+				actual = append(actual, step.transcript(nil))
+				expected = append(expected, step.transcript(nil))
+				if state == shellExited {
+					passed++
+				} else {
+					msgs = append(msgs, fmt.Sprintf("`%s`: shell did not exit", step.command))
+				}
+				break
+			}
+			out, state := sh.run(2*i+1, step.command, timeout)
+			next := ""
+			if state == shellReady {
+				// the prompt after a command may differ, e.g. showing a new directory.
+				next, state = sh.run(2*i+2, "", timeout)
+			}
+			got := commandOutput(out, prompt, next)
+			prompt = next
+			actual = append(actual, step.transcript(got))
+			expected = append(expected, step.transcript(append(step.want, step.anywhere...)))
+			if state != shellReady {
+				msgs = append(msgs, fmt.Sprintf("`%s`: shell %s", step.command, state))
+				break
+			}
+			if mismatch := step.mismatch(got); mismatch != "" {
+				msgs = append(msgs, fmt.Sprintf("`%s`: %s", step.command, mismatch))
+				continue
+			}
+			passed++
+		}
+		if passed == len(steps) {
+			result.Awarded = result.Possible
+			return result, nil
+		}
+		c.noteMismatch([]string{"shell", name}, []byte(strings.Join(expected, "")), []byte(strings.Join(actual, "")))
+		result.Message = fmt.Sprintf("%d/%d commands correct; %s", passed, len(steps), msgs[0])
+		if c.opts.Partial {
+			result.Awarded = int(math.Round(float64(result.Possible) * float64(passed) / float64(len(steps))))
+		}
+
+		return result, errors.New("session does not match its transcript")
+	}
+}
+
+// commandOutput is a command's output lines, less the prompts printed
+// before and after it, escape sequences and blank lines.
+func commandOutput(out, before, after string) []string {
+	out = strings.TrimSuffix(strings.TrimPrefix(out, before), after)
+	out = ansiEscape.ReplaceAllString(strings.ReplaceAll(out, "\r", ""), "")
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimRight(line, " \t"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// mismatch describes how got differs from the step's output, or is empty.
+func (s sessionStep) mismatch(got []string) string {
+	for _, want := range s.anywhere {
+		if !slices.ContainsFunc(got, func(line string) bool { return strings.HasSuffix(line, want) }) {
+			return fmt.Sprintf("no line %q", want)
+		}
+	}
+	if len(s.anywhere) > 0 && len(s.want) == 0 {
+		return ""
+	}
+	for i, want := range s.want {
+		if i >= len(got) {
+			return fmt.Sprintf("missing line %q", want)
+		}
+		if got[i] != want {
+			return fmt.Sprintf("printed %q, want %q", got[i], want)
+		}
+	}
+	if len(got) > len(s.want) {
+		return fmt.Sprintf("unexpected line %q", got[len(s.want)])
+	}
+
+	return ""
+}
+
+// shellState is how a shell's command ended.
+type shellState int
+
+const (
+	shellReady shellState = iota
+	shellExited
+	shellTimedOut
+)
+
+func (s shellState) String() string {
+	switch s {
+	case shellExited:
+		return "exited"
+	case shellTimedOut:
+		return "timed out"
+	}
+
+	return "ready"
+}
+
+// shellConn is a running shell: its input and its (combined) output.
+type shellConn struct {
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	in     io.WriteCloser
+	out    io.Closer
+	chunks chan []byte // closed when the output ends
+	buf    []byte
+}
+
+// startShell starts the built shell in dir, on a pseudo-terminal if the
+// platform has them, or else on pipes.
+func startShell(c *Context, dir string) (*shellConn, error) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	cmd := exec.CommandContext(ctx, c.run[0], c.run[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), shellEnv)
+	sh := &shellConn{cmd: cmd, cancel: cancel, chunks: make(chan []byte)}
+	var out io.ReadCloser
+	if tty, err := startPTY(cmd); err == nil {
+		sh.in, out = tty, tty
+	} else if !errors.Is(err, errors.ErrUnsupported) {
+		cancel()
+		return nil, err
+	} else if sh.in, out, err = startPipes(cmd); err != nil {
+		cancel()
+		return nil, err
+	}
+	sh.out = out
+	go func() {
+		defer close(sh.chunks)
+		for {
+			b := make([]byte, 4096)
+			n, err := out.Read(b)
+			if n > 0 {
+				sh.chunks <- b[:n]
+			}
+			// a terminal's master reads EIO once the shell exits.
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return sh, nil
+}
+
+// startPipes starts cmd with its stdin, and its stdout and stderr together,
+// on pipes.
+func startPipes(cmd *exec.Cmd) (io.WriteCloser, io.ReadCloser, error) {
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer w.Close()
+	cmd.Stdout, cmd.Stderr = w, w
+	killProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+
+	return in, r, nil
+}
+
+// run sends the command, then echoes the marker numbered n, returning what
+// the shell printed before it.
+func (sh *shellConn) run(n int, command string, timeout time.Duration) (string, shellState) {
+	marker := fmt.Sprintf(sessionMarker, n)
+	input := "echo " + marker + "\n"
+	if command != "" {
+		input = command + "\n" + input
+	}
+	if _, err := io.WriteString(sh.in, input); err != nil {
+		return string(sh.buf), shellExited
+	}
+	deadline := time.After(timeout)
+	for {
+		if i := bytes.Index(sh.buf, []byte(marker)); i >= 0 {
+			out := string(sh.buf[:i])
+			rest := sh.buf[i+len(marker):]
+			if j := bytes.IndexByte(rest, '\n'); j >= 0 {
+				rest = rest[j+1:]
+			}
+			sh.buf = rest
+			return out, shellReady
+		}
+		select {
+		case b, ok := <-sh.chunks:
+			if !ok {
+				return string(sh.buf), shellExited
+			}
+			sh.buf = append(sh.buf, b...)
+		case <-deadline:
+			return string(sh.buf), shellTimedOut
+		}
+	}
+}
+
+// exit sends the command, which should end the shell before the timeout.
+func (sh *shellConn) exit(command string, timeout time.Duration) shellState {
+	if _, err := io.WriteString(sh.in, command+"\n"); err != nil {
+		return shellExited
+	}
+	deadline := time.After(timeout)
+	for {
+		select {
+		case _, ok := <-sh.chunks:
+			if !ok {
+				return shellExited
+			}
+		case <-deadline:
+			return shellTimedOut
+		}
+	}
+}
+
+// close kills the shell, if it's still running.
+func (sh *shellConn) close() {
+	sh.cancel()
+	sh.in.Close()
+	_ = sh.cmd.Wait()
+	sh.out.Close()
+	for range sh.chunks {
+	}
+}
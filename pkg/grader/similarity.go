@@ -0,0 +1,223 @@
+package grader
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+const (
+	// winnowK is the length, in tokens, of the hashed runs: shorter matches
+	// are noise.
+	winnowK = 12
+	// winnowWindow is the winnowing window: any match of at least
+	// winnowK+winnowWindow-1 tokens shares a fingerprint.
+	winnowWindow = 8
+)
+
+// similarPair is a pair of submissions sharing much of their fingerprints.
+type similarPair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	// PctA and PctB are the percentages of A's and B's fingerprints shared.
+	PctA   float64 `json:"a_pct"`
+	PctB   float64 `json:"b_pct"`
+	Shared int     `json:"shared"`
+	// Match is the pair of files sharing the most, with the lines spanned,
+	// as a place to start reviewing.
+	Match string `json:"match"`
+}
+
+// fingerprintPos is where a fingerprint's run of tokens starts.
+type fingerprintPos struct {
+	file string
+	line int
+}
+
+// winnowed is a submission's winnowed fingerprints, each at its first
+// position.
+type winnowed struct {
+	dir    string
+	hashes map[uint64]fingerprintPos
+}
+
+// winnowDir fingerprints the submission's Go source MOSS-style: identifiers
+// and literals are normalized to their kind, so renaming variables or
+// changing constants doesn't hide copying, and of the hashes of each
+// winnowK-token run, the minimum in every winnowWindow is kept.
+func winnowDir(dir string) (winnowed, error) {
+	var (
+		tokens []string
+		pos    []fingerprintPos
+	)
+	err := scanGoSource(dir, func(p token.Position, tok token.Token, lit string) {
+		switch {
+		case tok == token.IDENT:
+			lit = "ident"
+		case tok.IsLiteral():
+			lit = tok.String()
+		}
+		tokens = append(tokens, lit)
+		pos = append(pos, fingerprintPos{file: p.Filename, line: p.Line})
+	})
+	w := winnowed{dir: dir, hashes: make(map[uint64]fingerprintPos)}
+	if err != nil || len(tokens) < winnowK {
+		return w, err
+	}
+
+	hashes := make([]uint64, len(tokens)-winnowK+1)
+	for i := range hashes {
+		hashes[i] = hashTokens(tokens[i : i+winnowK])
+	}
+	// the rightmost minimum of each window, recorded once while it stays
+	// the minimum.
+	last, windows := -1, max(len(hashes)-winnowWindow+1, 1)
+	for start := 0; start < windows; start++ {
+		end := min(start+winnowWindow, len(hashes))
+		m := start
+		for i := start; i < end; i++ {
+			if hashes[i] <= hashes[m] {
+				m = i
+			}
+		}
+		if m != last {
+			if _, ok := w.hashes[hashes[m]]; !ok {
+				w.hashes[hashes[m]] = pos[m]
+			}
+			last = m
+		}
+	}
+
+	return w, nil
+}
+
+// findSimilar compares every pair of submissions' winnowed fingerprints,
+// less those in base (if any), returning those sharing at least threshold
+// percent of either's, most similar first.
+func findSimilar(graded []submission, base string, threshold float64) []similarPair {
+	var starter map[uint64]fingerprintPos
+	if base != "" {
+		w, err := winnowDir(base)
+		if err != nil {
+			slog.Warn("not ignoring starter code", slog.String("dir", base), slog.String("err", err.Error()))
+		}
+		starter = w.hashes
+	}
+	var subs []winnowed
+	for _, s := range graded {
+		w, err := winnowDir(s.dir)
+		if err != nil {
+			slog.Warn("skipping similarity detection", slog.String("dir", s.name()), slog.String("err", err.Error()))
+			continue
+		}
+		w.dir = s.name()
+		for h := range starter {
+			delete(w.hashes, h)
+		}
+		if len(w.hashes) > 0 {
+			subs = append(subs, w)
+		}
+	}
+
+	var pairs []similarPair
+	for i := range subs {
+		for j := i + 1; j < len(subs); j++ {
+			if p := compareWinnowed(subs[i], subs[j]); max(p.PctA, p.PctB) >= threshold {
+				pairs = append(pairs, p)
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return max(pairs[i].PctA, pairs[i].PctB) > max(pairs[j].PctA, pairs[j].PctB)
+	})
+
+	return pairs
+}
+
+// compareWinnowed is how much of a's and b's fingerprints are shared, and
+// where most of them are.
+func compareWinnowed(a, b winnowed) similarPair {
+	type filePair struct{ a, b string }
+	type span struct{ n, aFirst, aLast, bFirst, bLast int }
+	var (
+		shared int
+		spans  = make(map[filePair]*span)
+	)
+	for h, pa := range a.hashes {
+		pb, ok := b.hashes[h]
+		if !ok {
+			continue
+		}
+		shared++
+		s := spans[filePair{pa.file, pb.file}]
+		if s == nil {
+			s = &span{aFirst: pa.line, bFirst: pb.line}
+			spans[filePair{pa.file, pb.file}] = s
+		}
+		s.n++
+		s.aFirst, s.aLast = min(s.aFirst, pa.line), max(s.aLast, pa.line)
+		s.bFirst, s.bLast = min(s.bFirst, pb.line), max(s.bLast, pb.line)
+	}
+
+	p := similarPair{
+		A:      a.dir,
+		B:      b.dir,
+		PctA:   100 * float64(shared) / float64(len(a.hashes)),
+		PctB:   100 * float64(shared) / float64(len(b.hashes)),
+		Shared: shared,
+	}
+	var best filePair
+	for fp, s := range spans {
+		// ties go to the first files by name, for stable output.
+		if b := spans[best]; b == nil || s.n > b.n || s.n == b.n && (fp.a < best.a || fp.a == best.a && fp.b < best.b) {
+			best = fp
+		}
+	}
+	if s := spans[best]; s != nil {
+		p.Match = fmt.Sprintf("%s:%d-%d ~ %s:%d-%d", best.a, s.aFirst, s.aLast, best.b, s.bFirst, s.bLast)
+	}
+
+	return p
+}
+
+// printSimilar reports the pairs of similar submissions, for review.
+func printSimilar(w io.Writer, opts options, pairs []similarPair) {
+	switch opts.format() {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Similar []similarPair `json:"similar"`
+		}{append([]similarPair{}, pairs...)}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	case "total", "tap", "github":
+		// as with duplicates, stdout is for scores.
+		for _, p := range pairs {
+			fmt.Fprintf(os.Stderr, "similar submissions: %s (%.0f%%) %s (%.0f%%), at %s\n", p.A, p.PctA, p.B, p.PctB, p.Match)
+		}
+	default:
+		t := table.NewWriter()
+		t.SetTitle("Similar submissions, for review")
+		t.AppendHeader(table.Row{"Submission", "Shared", "Submission", "Shared", "Most shared"})
+		t.SetStyle(table.StyleRounded)
+		t.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 2, Align: text.AlignRight},
+			{Number: 4, Align: text.AlignRight},
+		})
+		for _, p := range pairs {
+			t.AppendRow(table.Row{p.A, fmt.Sprintf("%.0f%%", p.PctA), p.B, fmt.Sprintf("%.0f%%", p.PctB), p.Match})
+		}
+		if len(pairs) == 0 {
+			t.AppendRow(table.Row{"none found", "", "", "", ""})
+		}
+		fmt.Fprintln(w, opts.render(t))
+	}
+}
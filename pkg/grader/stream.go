@@ -0,0 +1,129 @@
+package grader
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// outputStream compares a scheduler's output to the golden output line by
+// line, as the scheduler writes it, so a run whose output can no longer match
+// is stopped at its first mismatch instead of being read to the end, and a
+// long run reports how far it has got. A run that finishes is still compared
+// whole (see compareOutput), as partial credit and diffs need all of it; the
+// stream only decides when to give up.
+type outputStream struct {
+	want   golden // normalized, as the output is
+	exp    []string
+	header []string // the schedule table's, for field specs
+	strict bool
+	steps  []string
+	// line is how many lines have been read, and pending the last,
+	// incomplete one.
+	line    int
+	pending []byte
+	// mismatch is the first mismatch, if any. With stopOnMismatch, a
+	// mismatch calls stop and sets stopped; with partial credit, every
+	// matching line still counts.
+	mismatch       string
+	stopOnMismatch bool
+	stop           func()
+	stopped        bool
+	err            error
+	// progress, if set, is called with the lines read so far, at most every
+	// progressInterval.
+	progress func(line int)
+	reported time.Time
+}
+
+// progressInterval is how often a run's progress is reported.
+var progressInterval = 250 * time.Millisecond
+
+// newOutputStream starts a comparison to want, normalized as for
+// compareOutput.
+func newOutputStream(c *Context, want golden) *outputStream {
+	s := &outputStream{want: want, strict: c.opts.Strict, steps: c.opts.Normalize, reported: time.Now()}
+	if !s.strict {
+		s.want.out = normalizeOutput(want.out, s.steps)
+		s.want.epsilon = c.opts.Epsilon
+	}
+	s.exp = strings.Split(string(s.want.out), "\n")
+
+	return s
+}
+
+// streamable reports whether the output can be compared as it streams: not
+// by metrics or records, which need all of it, nor from a preamble yet to be
+// found.
+func streamable(c *Context) bool {
+	return !c.opts.Metrics && !c.opts.Structured && !c.opts.SkipPreamble
+}
+
+func (s *outputStream) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	rest := s.pending
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		// past a mismatch, lines are only counted.
+		if s.mismatch == "" && s.err == nil {
+			s.compare(string(rest[:i]))
+		}
+		s.line++
+		rest = rest[i+1:]
+	}
+	s.pending = append(s.pending[:0], rest...)
+	if s.progress != nil && time.Since(s.reported) >= progressInterval {
+		s.reported = time.Now()
+		s.progress(s.line)
+	}
+
+	return len(p), nil
+}
+
+// total is about how many lines are expected.
+func (s *outputStream) total() int {
+	return countLines(s.want.out)
+}
+
+// compare compares the next line of output.
+func (s *outputStream) compare(act string) {
+	if runtime.GOOS == "windows" {
+		act = strings.TrimSuffix(act, "\r") // as textOutput
+	}
+	if !s.strict {
+		act = normalizeLine(act, s.steps)
+	}
+	i := s.line
+	// past the expected output, blank lines may yet be normalized away.
+	if i >= len(s.exp) || (i == len(s.exp)-1 && s.exp[i] == "") {
+		if act != "" {
+			s.diverge(i, fmt.Sprintf("got more than %d lines", s.total()))
+		}
+		return
+	}
+	if cells := tableCells(s.exp[i]); cells != nil && !anyNumeric(cells) {
+		s.header = cells
+	}
+	detail, err := lineMismatch(act, s.exp[i], s.header, s.want)
+	if err != nil {
+		// an invalid pattern is compareOutput's to report.
+		s.err = err
+		return
+	}
+	if detail != "" {
+		s.diverge(i, detail)
+	}
+}
+
+func (s *outputStream) diverge(i int, detail string) {
+	s.mismatch = diverged(i+1, s.total(), detail)
+	if s.stopOnMismatch && s.stop != nil {
+		s.stopped = true
+		s.stop()
+	}
+}
@@ -0,0 +1,164 @@
+package grader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		want     string
+		out      string
+		mismatch string
+	}{
+		{name: "match", want: "a\nb\nc\n", out: "a\nb\nc\n"},
+		{name: "mismatch", want: "a\nb\nc\n", out: "a\nx\nc\n", mismatch: `diverged at line 2 of ~3: got "x", want "b"`},
+		{name: "extra line", want: "a\nb\nc\n", out: "a\nb\nc\nd\n", mismatch: "diverged at line 4 of ~3: got more than 3 lines"},
+		{name: "trailing blank lines", want: "a\nb\n", out: "a\nb\n\n\n"},
+		{name: "incomplete last line", want: "a\nb\n", out: "a\nb"},
+		{name: "trailing whitespace", want: "a\nb\n", out: "a  \nb\t\r\n"},
+		{name: "ansi", want: "a\nb\n", out: "\x1b[31ma\x1b[0m\nb\n"},
+		{name: "strict", opts: Options{Strict: true}, want: "a\nb\n", out: "a \nb\n", mismatch: `diverged at line 1 of ~2: got "a ", want "a"`},
+		{name: "without trailing step", opts: Options{Normalize: []string{"eol"}}, want: "a\n", out: "a \n", mismatch: `diverged at line 1 of ~1: got "a ", want "a"`},
+		{name: "regex marker", want: "took {{regex:\\d+}}ms\n", out: "took 12ms\n"},
+		{name: "regex marker mismatch", want: "took {{regex:\\d+}}ms\n", out: "took ms\n", mismatch: `diverged at line 1 of ~1: got "took ms", want "took {{regex:\\d+}}ms"`},
+		{name: "epsilon", opts: Options{Epsilon: 0.02}, want: "wait 1.00\n", out: "wait 1.01\n"},
+		{name: "beyond epsilon", opts: Options{Epsilon: 0.02}, want: "wait 1.00\n", out: "wait 1.10\n", mismatch: `diverged at line 1 of ~1: got "wait 1.10", want "wait 1.00"`},
+	}
+	for _, tt := range tests {
+		for _, chunk := range []int{0, 1} {
+			t.Run(tt.name, func(t *testing.T) {
+				stops := 0
+				s := newOutputStream(&Context{opts: tt.opts}, golden{out: []byte(tt.want)})
+				s.stopOnMismatch, s.stop = true, func() { stops++ }
+				writeChunks(s, tt.out, chunk)
+				if s.mismatch != tt.mismatch {
+					t.Errorf("mismatch = %q, want %q", s.mismatch, tt.mismatch)
+				}
+				if want := btoi(tt.mismatch != ""); stops != want || s.stopped != (want == 1) {
+					t.Errorf("stopped %d times (stopped %t), want %d", stops, s.stopped, want)
+				}
+			})
+		}
+	}
+}
+
+func TestOutputStreamInvalidPattern(t *testing.T) {
+	s := newOutputStream(&Context{}, golden{out: []byte("{{regex:(}}\n")})
+	s.stopOnMismatch, s.stop = true, func() { t.Error("stopped on an invalid pattern") }
+	writeChunks(s, "x\ny\n", 0)
+	if s.err == nil {
+		t.Error("no error for an invalid pattern")
+	}
+}
+
+func TestOutputStreamProgress(t *testing.T) {
+	defer func(d time.Duration) { progressInterval = d }(progressInterval)
+	progressInterval = 0
+
+	var lines []int
+	s := newOutputStream(&Context{}, golden{out: []byte("a\nb\nc\nd\n")})
+	s.progress = func(line int) { lines = append(lines, line) }
+	s.stop = func() { t.Error("stopped with partial credit") }
+	writeChunks(s, "a\nx\nc\nd", 2)
+	// lines are still read past the mismatch, for partial credit.
+	if want := []int{1, 2, 3, 3}; !slices.Equal(lines, want) {
+		t.Errorf("progress = %v, want %v", lines, want)
+	}
+	if want := `diverged at line 2 of ~4: got "x", want "b"`; s.mismatch != want {
+		t.Errorf("mismatch = %q, want %q", s.mismatch, want)
+	}
+}
+
+func TestProgressPercent(t *testing.T) {
+	tests := []struct {
+		p    Progress
+		want float64
+	}{
+		{Progress{Line: 0, Total: 4}, 0},
+		{Progress{Line: 1, Total: 4}, 25},
+		{Progress{Line: 4, Total: 4}, 100},
+		{Progress{Line: 9, Total: 4}, 100},
+		{Progress{Line: 3, Total: 0}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Percent(); got != tt.want {
+			t.Errorf("%+v.Percent() = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestRunSchedulerOnceStopsAtMismatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	tests := []struct {
+		name    string
+		partial bool
+		script  string
+		credit  float64
+		stopped bool
+		line    int
+	}{
+		{name: "match", script: "echo a; echo b; echo c", credit: 1, line: 3},
+		{name: "runaway after mismatch", script: "echo a; echo x; sleep 30; echo c", stopped: true, line: 2},
+		{name: "partial credit reads on", partial: true, script: "echo a; echo x; echo c", credit: 2.0 / 3, line: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last Progress
+			c := &Context{
+				ctx:      context.Background(),
+				log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+				opts:     Options{Timeout: time.Minute, MaxOutput: 1 << 20, Partial: tt.partial},
+				srcDir:   t.TempDir(),
+				run:      []string{"/bin/sh", "-c", tt.script, "sh"},
+				usage:    &runUsage{},
+				label:    "Some check",
+				progress: func(p Progress) { last = p },
+			}
+			start := time.Now()
+			credit, msg, _ := runSchedulerOnce(c, nil, golden{out: []byte("a\nb\nc\n")})
+			if credit != tt.credit {
+				t.Errorf("credit = %v, want %v (%s)", credit, tt.credit, msg)
+			}
+			if stopped := strings.Contains(msg, "stopped at the first mismatch"); stopped != tt.stopped {
+				t.Errorf("msg = %q, stopped %t, want %t", msg, stopped, tt.stopped)
+			}
+			if want := (Progress{Label: "Some check", Line: tt.line, Total: 3}); !reflect.DeepEqual(last, want) {
+				t.Errorf("last progress = %+v, want %+v", last, want)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("took %s, not stopped at the mismatch", elapsed)
+			}
+		})
+	}
+}
+
+// writeChunks writes out in chunks of n bytes, or all at once when n is 0.
+func writeChunks(w io.Writer, out string, n int) {
+	if n == 0 {
+		io.WriteString(w, out)
+		return
+	}
+	for i := 0; i < len(out); i += n {
+		io.WriteString(w, out[i:min(i+n, len(out))])
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
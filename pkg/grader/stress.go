@@ -0,0 +1,80 @@
+package grader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// stressInput generates a CSV of n processes from seed, arriving without
+// gaps so the CPU is never idle.
+func stressInput(seed int64, n int) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	var b bytes.Buffer
+	b.WriteString("ProcessID,Burst Duration,Arrival Time,Priority\n")
+	arrival := 0
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "P%d,%d,%d,%d\n", i, 1+rng.Intn(9), arrival, 1+rng.Intn(5))
+		arrival += rng.Intn(3)
+	}
+
+	return b.Bytes()
+}
+
+// CheckStress runs each algorithm on a table of n processes, awarding
+// proportional credit for each that finishes within budget, to catch
+// quadratic schedulers the small inputs don't.
+func CheckStress(result Result, seed int64, n int, budget time.Duration) func(c *Context) (Result, error) {
+	return func(c *Context) (Result, error) {
+		if len(c.run) == 0 {
+			result.Message = "scheduler was not compileable"
+			return result, errors.New("binary not found")
+		}
+
+		// the budget replaces the usual timeout for these runs.
+		stress := *c
+		stress.opts.Timeout = budget
+		in := stressInput(seed, n)
+		var (
+			passed  int
+			reports []string
+		)
+		for _, algorithm := range caseAlgorithms {
+			args := []string{"-" + algorithm}
+			if algorithm == "rr" {
+				args = append(args, quantumFlag, "2")
+			}
+			name := strings.Join(args, " ")
+			start := time.Now()
+			run := execScheduler(&stress, in, args)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			switch {
+			case c.ctx.Err() != nil:
+				return result, c.ctx.Err()
+			case run.timedOut:
+				reports = append(reports, fmt.Sprintf("%s: over the %s budget", name, budget))
+			case run.outputExceeded:
+				reports = append(reports, fmt.Sprintf("%s: output exceeded limit", name))
+			case run.err != nil:
+				reports = append(reports, fmt.Sprintf("%s: failed after %s (%v)", name, elapsed, run.err))
+			case len(run.stdout) == 0:
+				reports = append(reports, fmt.Sprintf("%s: printed nothing in %s", name, elapsed))
+			default:
+				passed++
+				reports = append(reports, fmt.Sprintf("%s: %s", name, elapsed))
+			}
+		}
+
+		result.Awarded = int(math.Round(float64(result.Possible) * float64(passed) / float64(len(caseAlgorithms))))
+		result.Message = fmt.Sprintf("%d processes:\n%s", n, strings.Join(reports, "\n"))
+		if passed < len(caseAlgorithms) {
+			return result, fmt.Errorf("%d of %d algorithms too slow or failing on large inputs", len(caseAlgorithms)-passed, len(caseAlgorithms))
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,245 @@
+package grader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxStyleFindings caps how many offending files are listed in the message.
+const maxStyleFindings = 3
+
+// styleTools are the style check's tools, in order, for --style-tools.
+var styleTools = []string{"gofmt", "vet", "staticcheck"}
+
+// styleFinding is a problem reported by a style tool, in file (empty when
+// it isn't in one, e.g. a vet build failure).
+type styleFinding struct {
+	tool, file, text string
+}
+
+// CheckStyle runs each of the style tools on the submission, awarding an
+// equal share of the points for each that reports nothing. staticcheck only
+// counts when it's installed.
+func CheckStyle(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelStyle,
+		Awarded:  0,
+		Possible: c.opts.possible(labelStyle),
+	}
+	if c.lang != "" && c.lang != langGo {
+		return notApplicable(result, c.lang)
+	}
+	tools := c.opts.StyleTools
+	if tools == nil {
+		tools = styleTools
+	}
+
+	var (
+		ran, clean int
+		findings   []styleFinding
+	)
+	for _, tool := range tools {
+		var (
+			found []styleFinding
+			err   error
+		)
+		switch tool {
+		case "gofmt":
+			found, err = runGofmt(c)
+		case "vet":
+			found = runVet(c)
+		case "staticcheck":
+			path, lookErr := staticcheckPath()
+			if lookErr != nil {
+				c.log.Info("staticcheck not installed, skipping it")
+				continue
+			}
+			if found, err = runStaticcheck(c, path); err != nil && c.ctx.Err() == nil {
+				// a staticcheck that can't run (e.g. built for an older Go)
+				// isn't the submission's fault.
+				c.log.Warn("staticcheck failed, skipping it", slog.String("err", err.Error()))
+				continue
+			}
+		}
+		if err != nil {
+			result.Message = tool + " failed"
+			return result, err
+		}
+		ran++
+		if len(found) == 0 {
+			clean++
+		}
+		findings = append(findings, found...)
+	}
+	for _, f := range findings {
+		c.log.Info("style finding", slog.String("tool", f.tool), slog.String("finding", f.text))
+	}
+
+	if ran == 0 {
+		result.Awarded = result.Possible
+		result.Message = "no style tools available"
+		return result, nil
+	}
+	result.Awarded = int(math.Round(float64(result.Possible) * float64(clean) / float64(ran)))
+	if clean < ran {
+		result.Message = summarizeByFile(findings)
+		return result, fmt.Errorf("%d of %d style tools reported findings", ran-clean, ran)
+	}
+	c.log.Debug("code is style clean", slog.Int("tools", ran), slog.Int("pts", result.Possible))
+
+	return result, nil
+}
+
+// runGofmt reports the files whose formatting differs from gofmt's.
+func runGofmt(c *Context) ([]styleFinding, error) {
+	gofmt, err := gofmtPath()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(c.ctx, gofmt, "-l", ".")
+	cmd.Dir = c.srcDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var found []styleFinding
+	for _, file := range strings.Fields(string(out)) {
+		found = append(found, styleFinding{tool: "gofmt", file: filepath.ToSlash(file), text: file + ": not gofmt'd"})
+	}
+
+	return found, nil
+}
+
+// runVet reports go vet's diagnostics, or the error when there are none (e.g.
+// the code doesn't build).
+func runVet(c *Context) []styleFinding {
+	// go vet reports diagnostics on stderr, with "# pkg" headers.
+	var stderr bytes.Buffer
+	cmd := c.goCommand(c.ctx, "vet", "./...")
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	found := diagnostics("go vet", stderr.String())
+	if len(found) == 0 {
+		found = append(found, styleFinding{tool: "go vet", text: err.Error()})
+	}
+
+	return found
+}
+
+// runStaticcheck reports staticcheck's diagnostics. It exits non-zero when
+// there are any, so an error without diagnostics means it couldn't run.
+func runStaticcheck(c *Context, path string) ([]styleFinding, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(c.ctx, path, "./...")
+	cmd.Dir = c.srcDir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	found := diagnostics("staticcheck", stdout.String())
+	if err != nil && len(found) == 0 {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return found, nil
+}
+
+// diagnostics parses a tool's "file.go:line:col: message" lines, skipping
+// "# pkg" headers. Lines that don't name a file are kept without one.
+func diagnostics(tool, out string) []styleFinding {
+	var found []styleFinding
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "vet: ")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := styleFinding{tool: tool, text: line}
+		if file, _, ok := strings.Cut(line, ":"); ok && strings.HasSuffix(file, ".go") {
+			f.file = strings.TrimPrefix(filepath.ToSlash(file), "./")
+		}
+		found = append(found, f)
+	}
+
+	return found
+}
+
+// summarizeByFile lists, for the first few files, how many findings each
+// tool reported in it, noting how many more files there are. Findings not in
+// a file are listed first, in full.
+func summarizeByFile(findings []styleFinding) string {
+	var (
+		lines  []string
+		files  []string
+		counts = make(map[string]map[string]int)
+	)
+	for _, f := range findings {
+		if f.file == "" {
+			lines = append(lines, f.tool+": "+f.text)
+			continue
+		}
+		if counts[f.file] == nil {
+			counts[f.file] = make(map[string]int)
+			files = append(files, f.file)
+		}
+		counts[f.file][f.tool]++
+	}
+	sort.Strings(files)
+
+	for i, file := range files {
+		if i == maxStyleFindings {
+			lines = append(lines, fmt.Sprintf("(and %d more files)", len(files)-maxStyleFindings))
+			break
+		}
+		var tools []string
+		for _, tool := range []string{"gofmt", "go vet", "staticcheck"} {
+			switch n := counts[file][tool]; {
+			case n == 0:
+			case tool == "gofmt":
+				tools = append(tools, "not gofmt'd")
+			default:
+				tools = append(tools, fmt.Sprintf("%s (%d)", tool, n))
+			}
+		}
+		lines = append(lines, file+": "+strings.Join(tools, ", "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// gofmtPath finds gofmt in PATH, or alongside the go toolchain.
+func gofmtPath() (string, error) {
+	if path, err := exec.LookPath("gofmt"); err == nil {
+		return path, nil
+	}
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return "", err
+	}
+
+	return exec.LookPath(filepath.Join(strings.TrimSpace(string(out)), "bin", "gofmt"))
+}
+
+// staticcheckPath finds staticcheck in PATH, or where go install puts it.
+func staticcheckPath() (string, error) {
+	if path, err := exec.LookPath("staticcheck"); err == nil {
+		return path, nil
+	}
+	out, err := exec.Command("go", "env", "GOPATH").Output()
+	if err != nil {
+		return "", err
+	}
+	gopath := filepath.SplitList(strings.TrimSpace(string(out)))
+	if len(gopath) == 0 {
+		return "", errors.New("GOPATH not set")
+	}
+
+	return exec.LookPath(filepath.Join(gopath[0], "bin", "staticcheck"))
+}
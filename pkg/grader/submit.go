@@ -0,0 +1,386 @@
+package grader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// submissionMagic starts a sealed submission (see sealSubmission).
+const submissionMagic = "gradebot-submission v1\n"
+
+// submissionExt is a sealed submission's file extension.
+const submissionExt = ".gbsub"
+
+// instructorKey is the default submit --key, a public key (see keygen), for
+// a gradebot built for a section with -ldflags "-X
+// github.com/jh125486/CSCE4600_gradebot/pkg/grader.instructorKey=...".
+var instructorKey string
+
+// submission bundle entries.
+const (
+	bundleSources = "source/"
+	bundleReport  = "report.json"
+	bundleReceipt = "receipt.txt"
+)
+
+var keyEncoding = base64.StdEncoding
+
+// SubmitConfig configures Submit, as the submit command's flags do.
+type SubmitConfig struct {
+	Dir        string
+	Project    string
+	Key        string
+	Output     string
+	ReceiptKey string
+}
+
+// Run grades the submission, then seals its sources and report for the
+// instructor: only their private key opens it. The report is the student's
+// own, so it's only signed, with a receipt, given the receipt key.
+// Submit grades cfg.Dir, then seals its sources and report into a file
+// only the instructor's key opens.
+func Submit(ctx context.Context, cfg SubmitConfig) error {
+	recipient, err := loadPublicKey(cfg.Key)
+	if err != nil {
+		return err
+	}
+	receiptKey, err := loadSigningKey(cfg.ReceiptKey)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return err
+	}
+	runner, err := NewRunner(ctx, DefaultConfig(), Selection{})
+	if err != nil {
+		return err
+	}
+	if cfg.Project != "project1" {
+		runner.Options.Project = cfg.Project
+	}
+	results := Grade(ctx, dir, runner.Options)
+	if ctx.Err() != nil {
+		return errors.New("interrupted")
+	}
+	env := captureManifest(dir, runner.Options)
+
+	opts := options{Config: Config{Format: "json"}, receiptKey: receiptKey, manifest: &env}
+	s := submission{dir: dir, results: results}
+	receipt, err := signedReceipt(opts, s)
+	if err != nil {
+		return err
+	}
+	if opts.attestation, err = signedAttestation(receiptKey); err != nil {
+		return err
+	}
+	var report bytes.Buffer
+	printRubricResults(&report, opts, filepath.Base(dir), results...)
+	bundle, err := bundleSubmission(dir, report.Bytes(), receipt)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealSubmission(recipient, bundle)
+	if err != nil {
+		return err
+	}
+
+	out := cfg.Output
+	if out == "" {
+		out = filepath.Base(dir) + submissionExt
+	}
+	if err := os.WriteFile(out, sealed, 0o644); err != nil {
+		return err
+	}
+	total, possible := s.totals()
+	fmt.Printf("%s: scored %d/%d, sealed for the instructor (submit this file)\n", out, total, possible)
+
+	return nil
+}
+
+// bundleSubmission zips the submission's files (but .git), its report and
+// its receipt, if signed.
+func bundleSubmission(dir string, report []byte, receipt string) ([]byte, error) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if total += info.Size(); total > maxArchiveBytes {
+			return fmt.Errorf("submission is more than %d MiB", maxArchiveBytes>>20)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name, hdr.Method = bundleSources+filepath.ToSlash(rel), zip.Deflate
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string][]byte{bundleReport: report}
+	if receipt != "" {
+		entries[bundleReceipt] = []byte(receipt + "\n")
+	}
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// sealSubmission encrypts the bundle to the recipient's X25519 key, with an
+// ephemeral key's shared secret: the magic, the ephemeral public key, then
+// the AES-256-GCM nonce and ciphertext, authenticating what precedes it.
+func sealSubmission(recipient *ecdh.PublicKey, bundle []byte) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := submissionAEAD(shared, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte(submissionMagic), ephemeral.PublicKey().Bytes()...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(append(header, nonce...), nonce, bundle, header), nil
+}
+
+// openSubmission decrypts a sealed submission with the recipient's key.
+func openSubmission(key *ecdh.PrivateKey, sealed []byte) ([]byte, error) {
+	if !bytes.HasPrefix(sealed, []byte(submissionMagic)) {
+		return nil, errors.New("not a sealed gradebot submission")
+	}
+	rest := sealed[len(submissionMagic):]
+	if len(rest) < 32 {
+		return nil, errors.New("truncated submission")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(rest[:32])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := submissionAEAD(shared, ephemeral, key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	header := sealed[:len(submissionMagic)+32]
+	rest = rest[32:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("truncated submission")
+	}
+	bundle, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, errors.New("submission can't be decrypted (altered, or sealed for another key)")
+	}
+
+	return bundle, nil
+}
+
+// submissionAEAD derives the AES-256-GCM key from the shared secret, bound to
+// both public keys.
+func submissionAEAD(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write([]byte(submissionMagic))
+	h.Write(shared)
+	h.Write(ephemeral.Bytes())
+	h.Write(recipient.Bytes())
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// loadPublicKey reads the public key file at path, or else the built-in key.
+func loadPublicKey(path string) (*ecdh.PublicKey, error) {
+	encoded := instructorKey
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(b)
+	}
+	if encoded == "" {
+		return nil, errors.New("no instructor key: set --key to the instructor's public key file")
+	}
+	b, err := keyEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("instructor key: %w", err)
+	}
+
+	return ecdh.X25519().NewPublicKey(b)
+}
+
+// loadPrivateKey reads the private key file at path.
+func loadPrivateKey(path string) (*ecdh.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = keyEncoding.DecodeString(strings.TrimSpace(string(b))); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return ecdh.X25519().NewPrivateKey(b)
+}
+
+// KeygenConfig configures Keygen, as the keygen command's flags do.
+type KeygenConfig struct {
+	Name    string
+	Signing bool
+}
+
+// Keygen writes a new key pair, sealing submissions or signing receipts.
+func Keygen(cfg KeygenConfig) error {
+	var private, public []byte
+	if cfg.Signing {
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		private, public = key.Seed(), pub
+	} else {
+		key, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		private, public = key.Bytes(), key.PublicKey().Bytes()
+	}
+	if err := os.WriteFile(cfg.Name+".key", []byte(keyEncoding.EncodeToString(private)+"\n"), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(cfg.Name+".pub", []byte(keyEncoding.EncodeToString(public)+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s.key (private) and %s.pub (public)\n", cfg.Name, cfg.Name)
+
+	return nil
+}
+
+// UnpackConfig configures Unpack, as the unpack command's flags do.
+type UnpackConfig struct {
+	Submission       string
+	Key              string
+	Out              string
+	ReceiptPublicKey string
+}
+
+// Run decrypts and extracts the submission: its sources under source, with
+// its report and receipt, then verifies the receipt, if it was signed,
+// against the sources.
+// Unpack decrypts and extracts a sealed submission, verifying any receipt
+// against its sources.
+func Unpack(cfg UnpackConfig) error {
+	key, err := loadPrivateKey(cfg.Key)
+	if err != nil {
+		return err
+	}
+	sealed, err := os.ReadFile(cfg.Submission)
+	if err != nil {
+		return err
+	}
+	bundle, err := openSubmission(key, sealed)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfg.Submission, err)
+	}
+	out := cfg.Out
+	if out == "" {
+		out = strings.TrimSuffix(cfg.Submission, submissionExt)
+	}
+	tmp, err := os.CreateTemp("", "gradebot-submission-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bundle)
+	if err := errors.Join(err, tmp.Close()); err != nil {
+		return err
+	}
+	if err := extractZip(tmp.Name(), out); err != nil {
+		return fmt.Errorf("extracting %s: %w", cfg.Submission, err)
+	}
+
+	var report jsonReport
+	if b, err := os.ReadFile(filepath.Join(out, bundleReport)); err == nil && json.Unmarshal(b, &report) == nil {
+		fmt.Printf("%s: reported %d/%d\n", out, report.Total, report.Possible)
+	}
+	sources := filepath.Join(out, strings.TrimSuffix(bundleSources, "/"))
+	token, err := os.ReadFile(filepath.Join(out, bundleReceipt))
+	if errors.Is(err, os.ErrNotExist) {
+		// the student's own report can't be trusted, without a receipt.
+		fmt.Printf("%s: no receipt, so the report is unverified: regrade %s\n", out, sources)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return Verify(VerifyConfig{Token: string(token), Dir: sources, Key: cfg.ReceiptPublicKey})
+}
@@ -0,0 +1,180 @@
+package grader
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestSealSubmission(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := []byte("PK\x03\x04 the bundle")
+	sealed, err := sealSubmission(key.PublicKey(), bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, bundle) {
+		t.Error("the sealed submission has the bundle in the clear")
+	}
+	got, err := openSubmission(key, sealed)
+	if err != nil || !bytes.Equal(got, bundle) {
+		t.Fatalf("openSubmission() = %q, %v, want the bundle", got, err)
+	}
+
+	flipped := func(i int) []byte {
+		b := slices.Clone(sealed)
+		b[i] ^= 1
+		return b
+	}
+	for name, tampered := range map[string][]byte{
+		"ciphertext":       flipped(len(sealed) - 1),
+		"ephemeral key":    flipped(len(submissionMagic)),
+		"nonce":            flipped(len(submissionMagic) + 32),
+		"truncated":        sealed[:len(submissionMagic)+16],
+		"not a submission": bundle,
+		"only the header":  sealed[:len(submissionMagic)+32],
+	} {
+		if _, err := openSubmission(key, tampered); err == nil {
+			t.Errorf("%s: openSubmission() opened it", name)
+		}
+	}
+	if _, err := openSubmission(other, sealed); err == nil {
+		t.Error("openSubmission() opened it with another key")
+	}
+}
+
+func TestBundleSubmission(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":         "package main\n",
+		"pkg/sched.go":    "package pkg\n",
+		".git/HEAD":       "ref: refs/heads/main\n",
+		".git/refs/x/y/z": "",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		receipt string
+		want    []string
+	}{
+		{name: "signed", receipt: "PAYLOAD.SIG", want: []string{bundleReceipt, bundleReport, "source/main.go", "source/pkg/sched.go"}},
+		{name: "unsigned", want: []string{bundleReport, "source/main.go", "source/pkg/sched.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := bundleSubmission(dir, []byte(`{"total":10}`), tt.receipt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, f := range zr.File {
+				names = append(names, f.Name)
+				if f.Name == bundleReceipt {
+					rc, _ := f.Open()
+					got, _ := io.ReadAll(rc)
+					rc.Close()
+					if string(got) != tt.receipt+"\n" {
+						t.Errorf("receipt = %q, want %q", got, tt.receipt)
+					}
+				}
+			}
+			sort.Strings(names)
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("bundled %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnpack opens a sealed submission, as the instructor, checking its
+// receipt against its sources.
+func TestUnpack(t *testing.T) {
+	root := t.TempDir()
+	name := filepath.Join(root, "instructor")
+	if err := Keygen(KeygenConfig{Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := loadPublicKey(name + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiptKey, pubFile := testReceiptKey(t)
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := submission{dir: src, results: []Result{{Label: labelCompilable, Awarded: 10, Possible: 10}}}
+
+	tests := []struct {
+		name    string
+		signed  bool
+		sources string // the sources bundled, if not those graded
+		wantErr bool
+	}{
+		{name: "signed", signed: true},
+		{name: "unsigned"},
+		{name: "sources changed since", signed: true, sources: "package main // edited\n", wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var receipt string
+			if tt.signed {
+				if receipt, err = signedReceipt(options{receiptKey: receiptKey}, s); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := src
+			if tt.sources != "" {
+				dir = t.TempDir()
+				if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(tt.sources), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			bundle, err := bundleSubmission(dir, []byte(`{"total":10,"possible":10}`), receipt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sealed, err := sealSubmission(recipient, bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(root, tt.name+submissionExt)
+			if err := os.WriteFile(path, sealed, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			out := filepath.Join(root, "out", string(rune('a'+i)))
+			err = Unpack(UnpackConfig{Submission: path, Key: name + ".key", Out: out, ReceiptPublicKey: pubFile})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, want error %t", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(out, "source", "main.go")); err != nil {
+				t.Errorf("sources not extracted: %v", err)
+			}
+		})
+	}
+}
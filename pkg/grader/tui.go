@@ -0,0 +1,310 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// tuiKeys is the TUI's key help, its last line.
+const tuiKeys = "↑/↓ select · enter expand · r re-run check · a re-run all · q quit"
+
+// tuiRow is a rubric item in the TUI, with its latest result.
+type tuiRow struct {
+	id, label string
+	running   bool
+	graded    bool
+	expanded  bool
+	result    Result
+}
+
+// tui is the bubbletea model of an interactive grading session (see runTUI).
+type tui struct {
+	ctx      context.Context
+	dir      string
+	opts     Options
+	rows     []tuiRow
+	selected int
+	// running is set while a Grade is in progress; status is a one-line
+	// note under the rows.
+	running bool
+	status  string
+	// events carries the background Grade's results, then its tuiGraded.
+	events        chan tea.Msg
+	width, height int
+}
+
+// tuiResult is a check's result, as it completes.
+type tuiResult Result
+
+// tuiGraded is a finished Grade, and how long it took.
+type tuiGraded struct {
+	results []Result
+	elapsed time.Duration
+}
+
+// runTUI grades the submission in dir interactively: each item's status as
+// its check completes, failures expandable to their message, hints and diff,
+// and single items re-runnable, until q or ctx is cancelled.
+func runTUI(ctx context.Context, dir string, opts Options) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return errors.New("--tui needs a terminal")
+	}
+	// stopping the session stops any grading in progress.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// logs and --debug diffs would scribble over the screen; diffs are kept
+	// in the results instead.
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// the alternate screen keeps the shell's scrollback intact.
+	_, err := tea.NewProgram(newTUI(ctx, dir, opts), tea.WithContext(ctx), tea.WithAltScreen()).Run()
+	if errors.Is(err, tea.ErrProgramKilled) {
+		return nil
+	}
+
+	return err
+}
+
+func newTUI(ctx context.Context, dir string, opts Options) *tui {
+	opts.Out, opts.Debug, opts.KeepDiffs = io.Discard, false, true
+	// a late penalty is of a whole grade, which re-running one check isn't.
+	opts.Deadline = time.Time{}
+
+	t := &tui{ctx: ctx, dir: dir, opts: opts, events: make(chan tea.Msg), width: 80, height: 24}
+	for _, item := range selectItems(rubricItems(opts), opts.Only, opts.Skip) {
+		t.rows = append(t.rows, tuiRow{id: item.id, label: item.label})
+	}
+
+	return t
+}
+
+func (t *tui) Init() tea.Cmd {
+	t.grade(nil)
+
+	return t.next
+}
+
+// next waits for the background Grade's next event.
+func (t *tui) next() tea.Msg {
+	select {
+	case msg := <-t.events:
+		return msg
+	case <-t.ctx.Done():
+		return tea.Quit()
+	}
+}
+
+// grade starts grading the items with ids (all of them, if none) in the
+// background, sending each result as it completes, then them all.
+func (t *tui) grade(ids []string) {
+	if t.running {
+		t.status = "still grading…"
+		return
+	}
+	opts := t.opts
+	if len(ids) > 0 {
+		opts.Only, opts.Skip = ids, nil
+	}
+	for i := range t.rows {
+		t.rows[i].running = len(ids) == 0 || slices.Contains(ids, t.rows[i].id)
+	}
+	t.running, t.status = true, "grading…"
+	send := func(msg tea.Msg) {
+		select {
+		case t.events <- msg:
+		case <-t.ctx.Done():
+		}
+	}
+	opts.OnResult = func(r Result) { send(tuiResult(r)) }
+	go func() {
+		start := time.Now()
+		rs := Grade(t.ctx, t.dir, opts)
+		send(tuiGraded{results: rs, elapsed: time.Since(start)})
+	}()
+}
+
+func (t *tui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		t.width, t.height = msg.Width, msg.Height
+	case tuiResult:
+		t.update(Result(msg))
+		return t, t.next
+	case tuiGraded:
+		for _, r := range msg.results {
+			t.update(r)
+		}
+		t.running = false
+		awarded, possible := t.totals()
+		t.status = fmt.Sprintf("graded in %s: %d/%d", msg.elapsed.Round(time.Millisecond), awarded, possible)
+		return t, t.next
+	case tea.KeyMsg:
+		return t, t.handleKey(msg.String())
+	}
+
+	return t, nil
+}
+
+// update records a result in its row, adding rows for results that aren't
+// rubric items, like the late penalty.
+func (t *tui) update(r Result) {
+	for i := range t.rows {
+		if t.rows[i].label == r.Label {
+			t.rows[i].result, t.rows[i].graded, t.rows[i].running = r, true, false
+			return
+		}
+	}
+	t.rows = append(t.rows, tuiRow{label: r.Label, graded: true, result: r})
+}
+
+// handleKey acts on a key press, returning tea.Quit to quit.
+func (t *tui) handleKey(key string) tea.Cmd {
+	switch key {
+	case "q", "ctrl+c", "esc":
+		return tea.Quit
+	case "k", "up":
+		t.selected = max(t.selected-1, 0)
+	case "j", "down":
+		t.selected = min(t.selected+1, len(t.rows)-1)
+	case "enter", " ":
+		t.rows[t.selected].expanded = !t.rows[t.selected].expanded
+	case "r":
+		if id := t.rows[t.selected].id; id != "" {
+			t.grade([]string{id})
+		}
+	case "a":
+		t.grade(nil)
+	}
+
+	return nil
+}
+
+func (t *tui) totals() (awarded, possible int) {
+	for _, row := range t.rows {
+		awarded += row.result.Awarded
+		if !row.result.ExtraCredit {
+			possible += row.result.Possible
+		}
+	}
+
+	return awarded, possible
+}
+
+// View is the screen: a header, a line per row (and its details, if
+// expanded), the status and the key help, scrolled to keep the selected row
+// in view.
+func (t *tui) View() string {
+	var (
+		lines    []string
+		selected int
+	)
+	for i, row := range t.rows {
+		if i == t.selected {
+			selected = len(lines)
+		}
+		lines = append(lines, t.rowLine(i, row))
+		if row.expanded {
+			lines = append(lines, rowDetails(row)...)
+		}
+	}
+	// the header, status and key lines are always shown.
+	view := max(t.height-4, 1)
+	top := 0
+	if selected >= view {
+		top = selected - view + 1
+	}
+	lines = lines[top:min(top+view, len(lines))]
+
+	awarded, possible := t.totals()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n\n", text.Snip(text.Bold.Sprintf("gradebot %s: %d/%d", t.dir, awarded, possible), t.width, "…"))
+	for _, line := range lines {
+		sb.WriteString(text.Snip(line, t.width, "…") + "\n")
+	}
+	fmt.Fprintf(&sb, "\n%s\n%s", text.Snip(t.status, t.width, "…"), text.Snip(text.Faint.Sprint(tuiKeys), t.width, "…"))
+
+	return sb.String()
+}
+
+// rowLine is a row's summary line: its status, label, points and time.
+func (t *tui) rowLine(i int, row tuiRow) string {
+	cursor := "  "
+	if i == t.selected {
+		cursor = "> "
+	}
+	status, points := text.Faint.Sprint("·"), ""
+	switch {
+	case row.running:
+		status = text.FgYellow.Sprint("…")
+	case row.graded && row.result.Skipped:
+		status = text.Faint.Sprint("–")
+	case row.graded && row.result.Awarded >= row.result.Possible:
+		status = text.FgGreen.Sprint("✓")
+	case row.graded:
+		status = text.FgRed.Sprint("✗")
+	}
+	if row.graded {
+		points = fmt.Sprintf("%3d/%-3d %s", row.result.Awarded, row.result.Possible, row.result.Duration.Round(time.Millisecond))
+	}
+
+	return fmt.Sprintf("%s%s %-45s %s", cursor, status, row.label, points)
+}
+
+// rowDetails are an expanded row's message and hints, stderr and diff,
+// indented under it.
+func rowDetails(row tuiRow) []string {
+	r := row.result
+	switch {
+	case row.running:
+		return []string{"      grading…"}
+	case !row.graded:
+		return []string{"      not graded yet"}
+	}
+	var lines []string
+	add := func(s string, paint func(string) string) {
+		for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+			lines = append(lines, "      "+paint(line))
+		}
+	}
+	plain := func(s string) string { return s }
+	if msg := r.reportMessage(); msg != "" {
+		add(msg, plain)
+	}
+	if r.Error != "" && r.Error != r.Message {
+		add("error: "+r.Error, func(s string) string { return text.FgRed.Sprint(s) })
+	}
+	if r.Stderr != "" {
+		add(r.Stderr, func(s string) string { return text.Faint.Sprint(s) })
+	}
+	if r.Diff != "" {
+		add(r.Diff, func(s string) string {
+			switch {
+			case strings.HasPrefix(s, "---"), strings.HasPrefix(s, "+++"):
+				return text.Bold.Sprint(s)
+			case strings.HasPrefix(s, "@@"):
+				return text.FgCyan.Sprint(s)
+			case strings.HasPrefix(s, "-"):
+				return text.FgGreen.Sprint(s)
+			case strings.HasPrefix(s, "+"):
+				return text.FgRed.Sprint(s)
+			}
+			return s
+		})
+	}
+	if len(lines) == 0 {
+		lines = []string{"      " + text.FgGreen.Sprint("passed")}
+	}
+
+	return lines
+}
@@ -0,0 +1,106 @@
+package grader
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTUIUpdate(t *testing.T) {
+	m := newTUI(context.Background(), "sub", Options{})
+	if len(m.rows) == 0 || m.rows[0].id != "compile" {
+		t.Fatalf("rows = %+v, want the rubric's, from compile", m.rows)
+	}
+	// as though grading, so r and a don't start a Grade.
+	m.running = true
+	steps := []struct {
+		msg      tea.Msg
+		selected int
+		check    func(*tui) string
+	}{
+		{msg: tea.KeyMsg{Type: tea.KeyUp}, selected: 0},
+		{msg: tea.KeyMsg{Type: tea.KeyDown}, selected: 1},
+		{msg: tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")}, selected: 2},
+		{msg: tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")}, selected: 1},
+		{msg: tea.KeyMsg{Type: tea.KeyEnter}, selected: 1, check: func(m *tui) string {
+			if !m.rows[1].expanded {
+				return "enter didn't expand the row"
+			}
+			return ""
+		}},
+		{msg: tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")}, selected: 1, check: func(m *tui) string {
+			if m.status != "still grading…" {
+				return "r re-ran a check during a grade"
+			}
+			return ""
+		}},
+		{msg: tuiResult{Label: labelCompilable, Awarded: 10, Possible: 10}, selected: 1, check: func(m *tui) string {
+			if !m.rows[0].graded || m.rows[0].result.Awarded != 10 {
+				return "the result isn't in its row"
+			}
+			return ""
+		}},
+		{msg: tuiResult{Label: "Late penalty", Awarded: -1}, selected: 1, check: func(m *tui) string {
+			if last := m.rows[len(m.rows)-1]; last.label != "Late penalty" || last.id != "" {
+				return "no row for a result that isn't a rubric item's"
+			}
+			return ""
+		}},
+		{msg: tuiGraded{results: []Result{{Label: labelCompilable, Awarded: 10, Possible: 10}}, elapsed: 1500 * time.Millisecond}, selected: 1, check: func(m *tui) string {
+			if m.running || !strings.HasPrefix(m.status, "graded in 1.5s: 9/") {
+				return "status " + m.status
+			}
+			return ""
+		}},
+		{msg: tea.WindowSizeMsg{Width: 40, Height: 10}, selected: 1, check: func(m *tui) string {
+			if m.width != 40 || m.height != 10 {
+				return "the window size isn't recorded"
+			}
+			return ""
+		}},
+	}
+	for i, step := range steps {
+		model, _ := m.Update(step.msg)
+		m = model.(*tui)
+		if m.selected != step.selected {
+			t.Errorf("step %d (%v): selected %d, want %d", i, step.msg, m.selected, step.selected)
+		}
+		if step.check != nil {
+			if problem := step.check(m); problem != "" {
+				t.Errorf("step %d (%v): %s", i, step.msg, problem)
+			}
+		}
+	}
+
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("q")}, {Type: tea.KeyCtrlC}, {Type: tea.KeyEsc}} {
+		if _, cmd := m.Update(key); cmd == nil {
+			t.Errorf("%v doesn't quit", key)
+		} else if _, ok := cmd().(tea.QuitMsg); !ok {
+			t.Errorf("%v doesn't quit", key)
+		}
+	}
+}
+
+func TestTUIView(t *testing.T) {
+	m := newTUI(context.Background(), "sub", Options{})
+	m.update(Result{Label: labelCompilable, Awarded: 10, Possible: 10})
+	m.update(Result{Label: labelFCFS, Possible: 20, Message: "output does not match expected", Diff: "-want\n+got"})
+	m.selected = 3
+	m.rows[m.selected].expanded = true
+
+	view := m.View()
+	for _, want := range []string{"gradebot sub: 10/", labelCompilable, "output does not match expected", "+got", tuiKeys} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() has no %q:\n%s", want, view)
+		}
+	}
+
+	// a short window scrolls to keep the selected row in view.
+	m.height = 6
+	if view := m.View(); strings.Contains(view, labelCompilable) || !strings.Contains(view, labelFCFS) {
+		t.Errorf("View() of a short window doesn't scroll to the selected row:\n%s", view)
+	}
+}
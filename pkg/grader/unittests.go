@@ -0,0 +1,212 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errTestsTimedOut is goTest's error when the tests exceed --test-timeout.
+var errTestsTimedOut = errors.New("tests timed out")
+
+// goTest runs "go test" with args in the submission, in the sandbox with
+// --sandbox=docker, within the --test-timeout. It returns the combined output.
+func goTest(c *Context, extra []string, args ...string) (string, error) {
+	ctx := c.ctx
+	if c.opts.TestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.TestTimeout)
+		defer cancel()
+	}
+	args = append([]string{"test"}, args...)
+	var cmd *exec.Cmd
+	if c.opts.Sandbox == sandboxDocker {
+		var err error
+		if cmd, err = sandboxGo(ctx, c, extra, args...); err != nil {
+			return "", err
+		}
+	} else {
+		cmd = c.goCommand(ctx, args...)
+		// the tests' own subprocesses are killed on timeout too.
+		killProcessGroup(cmd)
+	}
+	cmd.Dir = c.srcDir
+	cmd.WaitDelay = time.Second
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && c.ctx.Err() == nil {
+		return out.String(), fmt.Errorf("%w after %s", errTestsTimedOut, c.opts.TestTimeout)
+	}
+
+	return out.String(), err
+}
+
+// testPackages parses go test's summary lines into the packages that passed
+// and failed (including those that didn't build), in order.
+func testPackages(out string) (passed, failed []string) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "ok":
+			passed = append(passed, fields[1])
+		case "FAIL":
+			failed = append(failed, fields[1])
+		}
+	}
+
+	return passed, failed
+}
+
+// CheckTests runs the submission's own tests with "go test ./...", awarding
+// the points when there are some and they all pass.
+func CheckTests(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelTests,
+		Awarded:  0,
+		Possible: c.opts.possible(labelTests),
+	}
+	if c.lang != "" && c.lang != langGo {
+		return notApplicable(result, c.lang)
+	}
+	out, err := goTest(c, nil, "./...")
+	if c.ctx.Err() != nil {
+		return result, c.ctx.Err()
+	}
+	passed, failed := testPackages(out)
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--- FAIL:") {
+			c.log.Info("failing test", slog.String("test", strings.TrimSpace(line)))
+		}
+	}
+	switch {
+	case errors.Is(err, errTestsTimedOut):
+		result.Message = err.Error()
+		return result, err
+	case len(failed) > 0:
+		result.Message = "tests fail in " + strings.Join(failed, ", ")
+		if err == nil {
+			err = errors.New("tests failed")
+		}
+		return result, err
+	case err != nil:
+		result.Message = "go test failed"
+		if tail := tailLines(out, stderrTailLines); len(tail) > 0 {
+			result.Message += ":\n" + strings.Join(tail, "\n")
+		}
+		return result, err
+	case len(passed) == 0:
+		result.Message = "no tests found"
+		return result, errors.New("no tests")
+	}
+	result.Awarded = result.Possible
+	result.Message = fmt.Sprintf("tests pass in %d package(s)", len(passed))
+	c.log.Debug("tests pass", slog.Int("packages", len(passed)), slog.Int("pts", result.Possible))
+
+	return result, nil
+}
+
+// CheckCoverage runs the submission's own tests with a coverage profile,
+// awarding the points in proportion to the total statement coverage, up to
+// full credit at the --coverage threshold.
+func CheckCoverage(c *Context) (Result, error) {
+	result := Result{
+		Label:    labelCoverage,
+		Awarded:  0,
+		Possible: c.opts.possible(labelCoverage),
+	}
+	if c.lang != "" && c.lang != langGo {
+		return notApplicable(result, c.lang)
+	}
+	// not the build directory: this check runs on a copy of the Context.
+	dir, err := os.MkdirTemp("", "gradebot-cover-")
+	if err != nil {
+		result.Message = "could not create a coverage directory"
+		return result, err
+	}
+	defer os.RemoveAll(dir)
+	profile, extra := filepath.Join(dir, "cover.out"), []string(nil)
+	args := []string{"-coverprofile", profile, "./..."}
+	if c.opts.Sandbox == sandboxDocker {
+		extra, args[1] = []string{"-v", dir + ":/out"}, "/out/cover.out"
+	}
+	out, err := goTest(c, extra, args...)
+	if c.ctx.Err() != nil {
+		return result, c.ctx.Err()
+	}
+	if errors.Is(err, errTestsTimedOut) {
+		result.Message = err.Error()
+		return result, err
+	}
+	b, readErr := os.ReadFile(profile)
+	if readErr != nil {
+		result.Message = "no coverage profile (are there tests?)"
+		if tail := tailLines(out, stderrTailLines); len(tail) > 0 {
+			result.Message += ":\n" + strings.Join(tail, "\n")
+		}
+		return result, errors.Join(err, readErr)
+	}
+	covered, total := coverage(b)
+	if total == 0 {
+		result.Message = "no statements covered by the profile"
+		return result, errors.New("empty coverage profile")
+	}
+	pct := 100 * float64(covered) / float64(total)
+	result.Awarded = int(math.Round(float64(result.Possible) * min(pct/c.opts.Coverage, 1)))
+	result.Message = fmt.Sprintf("%.1f%% statement coverage (full credit at %g%%)", pct, c.opts.Coverage)
+	if _, failed := testPackages(out); len(failed) > 0 {
+		result.Message += "; tests fail in " + strings.Join(failed, ", ")
+	}
+	if pct < c.opts.Coverage {
+		return result, fmt.Errorf("coverage %.1f%% below %g%%", pct, c.opts.Coverage)
+	}
+
+	return result, nil
+}
+
+// coverage totals a coverage profile's statements and those run at least
+// once. A block profiled by several packages' tests counts once.
+func coverage(profile []byte) (covered, total int) {
+	type block struct {
+		stmts int
+		run   bool
+	}
+	blocks := make(map[string]block)
+	for _, line := range strings.Split(string(profile), "\n") {
+		// file.go:line.col,line.col statements count
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		stmts, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		b := blocks[fields[0]]
+		b.stmts = stmts
+		b.run = b.run || count > 0
+		blocks[fields[0]] = b
+	}
+	for _, b := range blocks {
+		total += b.stmts
+		if b.run {
+			covered += b.stmts
+		}
+	}
+
+	return covered, total
+}
@@ -0,0 +1,69 @@
+package grader
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// runUsage is the resources a check's scheduler runs used, summed over the
+// runs but for the peak RSS, the largest run's.
+type runUsage struct {
+	Runs int `json:"runs"`
+	// Wall is the runs' wall-clock time, less gradebot's own work between them.
+	Wall time.Duration `json:"wall_ns"`
+	User time.Duration `json:"user_ns"`
+	Sys  time.Duration `json:"sys_ns"`
+	// PeakRSS is in bytes; zero where the platform doesn't report it (Windows).
+	PeakRSS uint64 `json:"peak_rss_bytes,omitempty"`
+
+	mu sync.Mutex
+}
+
+// add records a finished run, as checks may run the scheduler concurrently.
+func (u *runUsage) add(state *os.ProcessState, wall time.Duration) {
+	if u == nil || state == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Runs++
+	u.Wall += wall
+	u.User += state.UserTime()
+	u.Sys += state.SystemTime()
+	u.PeakRSS = max(u.PeakRSS, peakRSS(state))
+}
+
+// cpu is the table's CPU column: user+system time.
+func (u *runUsage) cpu() string {
+	if u == nil {
+		return ""
+	}
+
+	return (u.User + u.Sys).Round(time.Millisecond).String()
+}
+
+// String is the runs' timings, as --verbose prints them.
+func (u *runUsage) String() string {
+	runs := "runs"
+	if u.Runs == 1 {
+		runs = "run"
+	}
+	s := fmt.Sprintf("%d %s, %s wall, %s user, %s sys", u.Runs, runs, u.Wall.Round(time.Millisecond),
+		u.User.Round(time.Millisecond), u.Sys.Round(time.Millisecond))
+	if rss := u.rss(); rss != "" {
+		s += ", " + rss + " peak RSS"
+	}
+
+	return s
+}
+
+// rss is the table's peak RSS column, in MiB.
+func (u *runUsage) rss() string {
+	if u == nil || u.PeakRSS == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%.1f MiB", float64(u.PeakRSS)/(1<<20))
+}
@@ -0,0 +1,325 @@
+package grader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// releaseAPI is the latest gradebot release, as goreleaser publishes it.
+const releaseAPI = "https://api.github.com/repos/jh125486/CSCE4600_gradebot/releases/latest"
+
+// version is the release's, set by goreleaser (see .goreleaser.yaml).
+var version string
+
+// pseudoVersion matches a Go pseudo-version's timestamp and commit, of a
+// build that isn't of a release.
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}`)
+
+// gradebotVersion is the release gradebot was built as: the release's, the
+// module's when installed with go install, or else "dev".
+func gradebotVersion() string {
+	if version != "" {
+		return version
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" && bi.Main.Version != "(devel)" && !pseudoVersion.MatchString(bi.Main.Version) {
+		return strings.TrimPrefix(bi.Main.Version, "v")
+	}
+
+	return "dev"
+}
+
+// rubricRevision identifies the embedded rubric: a hash of its points and
+// testdata, so two gradebots grading alike can be told from their versions.
+func rubricRevision() string {
+	h := sha256.New()
+	for _, label := range sortedKeys(defaultPoints) {
+		fmt.Fprintf(h, "%s\x00%d\x00", label, defaultPoints[label])
+	}
+	for _, b := range [][]byte{fcfsIn, fcfsOut, sjfIn, sjfOut, sjfpIn, sjfpOut, rrIn, rrOut, rrQ1Out, rrQ2Out, rrQ10Out,
+		priorityIn, priorityOut, mlfqIn, mlfqOut} {
+		h.Write(b)
+		h.Write([]byte{0})
+	}
+	for _, fsys := range []fs.FS{goldenMetaFS, shellSessions} {
+		_ = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			b, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%s\x00", path, b)
+			return nil
+		})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Version is what --version prints: gradebot's version, its embedded
+// rubric's revision and its platform.
+func Version() string {
+	return fmt.Sprintf("gradebot %s (rubric %s, %s/%s)", gradebotVersion(), rubricRevision(), runtime.GOOS, runtime.GOARCH)
+}
+
+// release is the subset of GitHub's release JSON gradebot uses.
+type release struct {
+	Tag    string `json:"tag_name"`
+	Assets []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// latestRelease fetches the latest release.
+func latestRelease(ctx context.Context, client *http.Client) (release, error) {
+	var r release
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseAPI, nil)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return r, fmt.Errorf("%s: %s", releaseAPI, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return r, fmt.Errorf("%s: %w", releaseAPI, err)
+	}
+
+	return r, nil
+}
+
+// outdated reports whether the release is newer than this gradebot; a dev
+// build never is.
+func outdated(r release) bool {
+	v := gradebotVersion()
+
+	return v != "dev" && compareGoVersions(v, strings.TrimPrefix(r.Tag, "v")) < 0
+}
+
+// CheckForUpdate warns, through the returned func, when a newer release is
+// out. It checks in the background, so the func only warns if the check has
+// finished (by the time the command has); the release endpoint being down or
+// slow must never hold up grading.
+func CheckForUpdate(ctx context.Context) func() {
+	if gradebotVersion() == "dev" || os.Getenv("GRADEBOT_NO_UPDATE_CHECK") != "" {
+		return func() {}
+	}
+	done := make(chan release, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if r, err := latestRelease(ctx, &http.Client{}); err == nil {
+			done <- r
+		}
+	}()
+
+	return func() {
+		select {
+		case r := <-done:
+			if outdated(r) {
+				slog.Warn("gradebot is out of date, its rubric may be too: run gradebot update",
+					slog.String("version", gradebotVersion()), slog.String("latest", r.Tag))
+			}
+		default:
+		}
+	}
+}
+
+// UpdateConfig configures Update, as the update command's flags do.
+type UpdateConfig struct {
+	Check bool
+	Force bool
+}
+
+// Update replaces the running gradebot with the latest release's, after
+// verifying its archive against the release's checksums.
+func Update(ctx context.Context, cfg UpdateConfig) error {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	r, err := latestRelease(ctx, client)
+	if err != nil {
+		return err
+	}
+	if !outdated(r) && !cfg.Force {
+		fmt.Printf("%s is up to date (latest %s)\n", Version(), r.Tag)
+		return nil
+	}
+	if cfg.Check {
+		fmt.Printf("%s: %s is out\n", Version(), r.Tag)
+		return nil
+	}
+
+	archive, sums := releaseAssets(r)
+	if archive == "" || sums == "" {
+		return fmt.Errorf("release %s has no %s/%s archive and checksums", r.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	tmp, err := os.MkdirTemp("", "gradebot-update-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, filepath.Base(archive))
+	if err := downloadFile(ctx, client, archive, path); err != nil {
+		return err
+	}
+	if err := verifyChecksum(ctx, client, sums, path); err != nil {
+		return err
+	}
+
+	extract := extractTarGz
+	if strings.HasSuffix(path, ".zip") {
+		extract = extractZip
+	}
+	dest := filepath.Join(tmp, "release")
+	if err := extract(path, dest); err != nil {
+		return fmt.Errorf("extracting %s: %w", filepath.Base(path), err)
+	}
+	name := "gradebot"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if err := replaceExecutable(filepath.Join(dest, name)); err != nil {
+		return err
+	}
+	fmt.Printf("updated gradebot %s to %s\n", gradebotVersion(), r.Tag)
+
+	return nil
+}
+
+// releaseAssets finds the release's archive for this platform, as named by
+// .goreleaser.yaml (e.g. CSCE4600_gradebot_Linux_x86_64.tar.gz), and its
+// checksums.
+func releaseAssets(r release) (archive, sums string) {
+	arch := runtime.GOARCH
+	if arch == "amd64" {
+		arch = "x86_64"
+	}
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	suffix := "_" + strings.ToUpper(runtime.GOOS[:1]) + runtime.GOOS[1:] + "_" + arch + ext
+	for _, a := range r.Assets {
+		switch {
+		case a.Name == "checksums.txt":
+			sums = a.URL
+		case strings.HasSuffix(a.Name, suffix):
+			archive = a.URL
+		}
+	}
+
+	return archive, sums
+}
+
+// downloadFile writes url's body to path.
+func downloadFile(ctx context.Context, client *http.Client, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, resp.Body)
+
+	return errors.Join(err, f.Close())
+}
+
+// verifyChecksum checks path's SHA-256 against its line in the checksums
+// file at url ("<hex>  <name>", as sha256sum writes).
+func verifyChecksum(ctx context.Context, client *http.Client, url, path string) error {
+	sums := filepath.Join(filepath.Dir(path), "checksums.txt")
+	if err := downloadFile(ctx, client, url, sums); err != nil {
+		return err
+	}
+	b, err := os.ReadFile(sums)
+	if err != nil {
+		return err
+	}
+	var want string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		if fields := strings.Fields(s.Text()); len(fields) == 2 && fields[1] == filepath.Base(path) {
+			want = fields[0]
+		}
+	}
+	if want == "" {
+		return fmt.Errorf("checksums.txt has no checksum of %s", filepath.Base(path))
+	}
+	archive, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if got := sha256.Sum256(archive); hex.EncodeToString(got[:]) != want {
+		return fmt.Errorf("%s does not match its checksum; not updating", filepath.Base(path))
+	}
+
+	return nil
+}
+
+// replaceExecutable replaces the running executable with the one at path.
+// The old one is renamed aside first, as Windows can't overwrite a running
+// executable but can rename it.
+func replaceExecutable(path string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if self, err = filepath.EvalSymlinks(self); err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("release archive: %w", err)
+	}
+	next := self + ".new"
+	if err := os.WriteFile(next, b, 0o755); err != nil {
+		return err
+	}
+	old := self + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(self, old); err != nil {
+		os.Remove(next)
+		return err
+	}
+	if err := os.Rename(next, self); err != nil {
+		// put the old one back, rather than leave no gradebot at all.
+		return errors.Join(err, os.Rename(old, self))
+	}
+	// a running Windows executable can't be removed: it's left for next time.
+	_ = os.Remove(old)
+
+	return nil
+}
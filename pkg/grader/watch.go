@@ -0,0 +1,160 @@
+package grader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchAndGrade grades the submission in dir, then again each time its
+// sources change, until ctx is cancelled. Changes are found with fsnotify,
+// and a grade waits for them to settle for an interval, as editors and git
+// write several files in a row; changes that leave the sources' contentHash
+// as it was, like a touch, don't re-grade. An --output file holds the latest
+// grade.
+func watchAndGrade(ctx context.Context, out *reports, logs io.Writer, cfg GradeConfig, dir string, opts Options) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watchTree(watcher, dir); err != nil {
+		return err
+	}
+	last, err := contentHash(dir)
+	if err != nil {
+		return err
+	}
+	w := out.stdout
+	for {
+		if f, ok := w.(*os.File); ok && isTerminal(f) {
+			// a fresh screen (and scrollback) per grade.
+			fmt.Fprint(w, "\x1b[H\x1b[2J\x1b[3J")
+		}
+		fmt.Fprintf(w, "%s graded at %s\n", dir, time.Now().Format(time.TimeOnly))
+		results := Grade(ctx, dir, opts)
+		if ctx.Err() != nil {
+			return nil
+		}
+		printLogs(logs, results, opts.LogJSON)
+		if err := out.rewind(); err != nil {
+			return err
+		}
+		env := captureManifest(dir, opts)
+		out.each(func(w io.Writer, o options) {
+			o.manifest = &env
+			printRubricResults(w, o, dir, results...)
+		})
+		fmt.Fprintln(os.Stderr, "watching for changes (ctrl-c to stop)...")
+
+		for {
+			if !awaitChange(ctx, watcher, dir, cfg.WatchInterval) {
+				return nil
+			}
+			hash, err := contentHash(dir)
+			if err != nil {
+				// e.g. a file removed mid-walk; the next change will tell.
+				slog.Debug("watch", slog.String("err", err.Error()))
+				continue
+			}
+			if hash != last {
+				last = hash
+				break
+			}
+		}
+	}
+}
+
+// awaitChange waits for a change to the sources under dir, then for settle
+// to pass without another, reporting false if ctx is cancelled first. New
+// directories are watched as they're created.
+func awaitChange(ctx context.Context, watcher *fsnotify.Watcher, dir string, settle time.Duration) bool {
+	var (
+		timer   *time.Timer
+		settled <-chan time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-settled:
+			return true
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return false
+			}
+			// e.g. an overflowed event queue, which may have dropped a change.
+			slog.Debug("watch", slog.String("err", err.Error()))
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return false
+			}
+			if ev.Has(fsnotify.Create) {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() && watchedDir(dir, ev.Name) {
+					// its files may predate the watch, e.g. from a git checkout.
+					if err := watchTree(watcher, ev.Name); err != nil {
+						slog.Debug("watch", slog.String("dir", ev.Name), slog.String("err", err.Error()))
+					}
+				}
+			}
+			if !watchedEvent(ev) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(settle)
+				defer timer.Stop()
+				settled = timer.C
+				continue
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(settle)
+		}
+	}
+}
+
+// watchTree watches root and its subdirectories, except those the go
+// command ignores, as hashSources does.
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if path != root && !watchedDir(root, path) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+// watchedDir reports whether the directory at path, under root, may hold
+// sources: it's not hidden or underscore-prefixed.
+func watchedDir(root, path string) bool {
+	name := filepath.Base(path)
+
+	return path == root || !(strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"))
+}
+
+// watchedEvent reports whether ev may have changed the sources contentHash
+// hashes: a .go file, go.mod or go.sum, or, by a name without an extension,
+// a directory of them. Those that turn out not to have are weeded out by the
+// hash.
+func watchedEvent(ev fsnotify.Event) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Base(ev.Name)
+
+	return strings.HasSuffix(name, ".go") || name == "go.mod" || name == "go.sum" ||
+		(filepath.Ext(name) == "" && !strings.HasPrefix(name, "_"))
+}
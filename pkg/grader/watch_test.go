@@ -0,0 +1,106 @@
+package grader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchedEvent(t *testing.T) {
+	tests := []struct {
+		ev   fsnotify.Event
+		want bool
+	}{
+		{fsnotify.Event{Name: "sub/main.go", Op: fsnotify.Write}, true},
+		{fsnotify.Event{Name: "sub/go.mod", Op: fsnotify.Create}, true},
+		{fsnotify.Event{Name: "sub/go.sum", Op: fsnotify.Remove}, true},
+		{fsnotify.Event{Name: "sub/pkg", Op: fsnotify.Rename}, true},
+		{fsnotify.Event{Name: "sub/main.go", Op: fsnotify.Chmod}, false},
+		{fsnotify.Event{Name: "sub/out.txt", Op: fsnotify.Write}, false},
+		{fsnotify.Event{Name: "sub/.main.go.swp", Op: fsnotify.Write}, false},
+		{fsnotify.Event{Name: "sub/_scratch", Op: fsnotify.Create}, false},
+	}
+	for _, tt := range tests {
+		if got := watchedEvent(tt.ev); got != tt.want {
+			t.Errorf("watchedEvent(%v) = %t, want %t", tt.ev, got, tt.want)
+		}
+	}
+}
+
+func TestAwaitChange(t *testing.T) {
+	const settle = 50 * time.Millisecond
+	tests := []struct {
+		name   string
+		change func(dir string) error
+		want   bool
+	}{
+		{name: "source", want: true, change: func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644)
+		}},
+		{name: "not a source", change: func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("todo\n"), 0o644)
+		}},
+		{name: "hidden directory", change: func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, ".git", "main.go"), []byte("package main\n"), 0o644)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			watcher, err := fsnotify.NewWatcher()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer watcher.Close()
+			if err := watchTree(watcher, dir); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			errs := make(chan error, 1)
+			go func() { errs <- tt.change(dir) }()
+			if got := awaitChange(ctx, watcher, dir, settle); got != tt.want {
+				t.Errorf("awaitChange() = %t, want %t", got, tt.want)
+			}
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestAwaitChangeNewDirectory(t *testing.T) {
+	dir := t.TempDir()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	if err := watchTree(watcher, dir); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := os.Mkdir(filepath.Join(dir, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if !awaitChange(ctx, watcher, dir, 50*time.Millisecond) {
+		t.Fatal("awaitChange() missed a new directory")
+	}
+	// the new directory is watched too.
+	if err := os.WriteFile(filepath.Join(dir, "pkg", "pkg.go"), []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !awaitChange(ctx, watcher, dir, 50*time.Millisecond) {
+		t.Error("awaitChange() missed a source in a new directory")
+	}
+}